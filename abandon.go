@@ -0,0 +1,98 @@
+package prober
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// abandonedRuns is the number of abandoned runs of all probes that
+// are still running.
+var abandonedRuns int64
+
+func init() {
+	expvar.Publish("prober_abandoned_runs", expvar.Func(func() interface{} {
+		return AbandonedRuns()
+	}))
+}
+
+type (
+	// ContextProber is implemented by Probers that can stop a run when
+	// asked to. If the Prober is a ContextProber, ProbeContext() is
+	// called instead of Probe(), with a context that is cancelled if
+	// the run times out or the probe is stopped, so the run doesn't
+	// linger after its result is no longer wanted.
+	ContextProber interface {
+		ProbeContext(ctx context.Context) Result
+	}
+
+	// probeRun tracks whether a run of a probe finished before it was
+	// abandoned, e.g. after timing out.
+	probeRun struct {
+		start     time.Time
+		abandoned bool
+		finished  bool
+		lock      sync.Mutex // protects abandoned and finished
+	}
+)
+
+// AbandonedRuns returns the number of runs of all probes that were
+// abandoned, e.g. after timing out, and are still running. Probers
+// that don't return even long after their runs time out leak a
+// goroutine for each run, which shows up as a growing number here.
+func AbandonedRuns() int {
+	return int(atomic.LoadInt64(&abandonedRuns))
+}
+
+// abandon marks the run as abandoned, returning false if it already
+// finished.
+func (r *probeRun) abandon() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.finished {
+		return false
+	}
+	r.abandoned = true
+	return true
+}
+
+// finish marks the run as finished, returning true if it had been
+// abandoned.
+func (r *probeRun) finish() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.finished = true
+	return r.abandoned
+}
+
+// callProbe calls ProbeContext() with ctx if the Prober is a
+// ContextProber, and Probe() otherwise.
+func (p *Probe) callProbe(ctx context.Context) Result {
+	if cp, ok := p.Prober.(ContextProber); ok {
+		return cp.ProbeContext(ctx)
+	}
+	return p.Probe()
+}
+
+// recordAbandoned updates the scheduler statistics with a run that was
+// abandoned.
+func (p *Probe) recordAbandoned() {
+	atomic.AddInt64(&abandonedRuns, 1)
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	p.stats.Abandoned++
+	p.stats.AbandonedRunning++
+}
+
+// recordAbandonedDone updates the scheduler statistics with an
+// abandoned run that finally returned.
+func (p *Probe) recordAbandonedDone(run *probeRun) {
+	atomic.AddInt64(&abandonedRuns, -1)
+	p.statsLock.Lock()
+	p.stats.AbandonedRunning--
+	p.statsLock.Unlock()
+	log.Printf("[%s] Abandoned run returned after %v\n", p.Name, time.Since(run.start))
+}
@@ -0,0 +1,99 @@
+package prober
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// blockingProber is a Prober whose Probe() blocks until release is
+// closed.
+type blockingProber struct {
+	testProber
+	release chan struct{}
+}
+
+func (bp blockingProber) Probe() Result {
+	<-bp.release
+	return Passed()
+}
+
+// contextProber is a ContextProber whose ProbeContext() blocks until
+// its context is cancelled.
+type contextProber struct{ testProber }
+
+func (contextProber) ProbeContext(ctx context.Context) Result {
+	<-ctx.Done()
+	return FailedWith(ctx.Err())
+}
+
+// waitFor polls cond until it's true or a second has passed, returning
+// the last value of cond.
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for !cond() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
+func TestProbe_probeOnce_abandoned(t *testing.T) {
+	release := make(chan struct{})
+	cases := []struct {
+		prober Prober
+		// release lets the abandoned run return, if it doesn't on its
+		// own.
+		release func()
+	}{
+		{blockingProber{release: release}, func() { close(release) }},
+		{contextProber{}, func() {}},
+	}
+	for i, tt := range cases {
+		goroutines := runtime.NumGoroutine()
+		p := &Probe{
+			Prober:   tt.prober,
+			Name:     "AbandonedProber",
+			Interval: 10 * time.Millisecond,
+			t:        realTime{},
+		}
+		if _, ok := p.probeOnce(context.Background()); ok {
+			t.Fatalf("[%d] probeOnce() => ok; want timeout\n", i)
+		}
+		if s := p.Stats(); s.Abandoned != 1 {
+			t.Errorf("[%d] Stats().Abandoned => %d; want 1\n", i, s.Abandoned)
+		}
+		tt.release()
+		if !waitFor(func() bool { return p.Stats().AbandonedRunning == 0 }) {
+			t.Errorf("[%d] Stats().AbandonedRunning => %d; want 0 once the run returned\n", i, p.Stats().AbandonedRunning)
+		}
+		if !waitFor(func() bool { return runtime.NumGoroutine() <= goroutines }) {
+			t.Errorf("[%d] %d goroutines after abandoned run returned; want %d, leaked?\n", i, runtime.NumGoroutine(), goroutines)
+		}
+	}
+	if got := AbandonedRuns(); got != 0 {
+		t.Errorf("AbandonedRuns() => %d; want 0\n", got)
+	}
+}
+
+func TestProbe_probeOnce_stillRunning(t *testing.T) {
+	release := make(chan struct{})
+	p := &Probe{
+		Prober:   blockingProber{release: release},
+		Name:     "StuckProber",
+		Interval: 10 * time.Millisecond,
+		t:        realTime{},
+	}
+	p.probeOnce(context.Background())
+	p.probeOnce(context.Background())
+	if s := p.Stats(); s.Abandoned != 2 || s.AbandonedRunning != 2 {
+		t.Errorf("Stats() => %d abandoned, %d running; want 2 and 2\n", s.Abandoned, s.AbandonedRunning)
+	}
+	if got := AbandonedRuns(); got < 2 {
+		t.Errorf("AbandonedRuns() => %d; want at least 2\n", got)
+	}
+	close(release)
+	if !waitFor(func() bool { return p.Stats().AbandonedRunning == 0 }) {
+		t.Errorf("Stats().AbandonedRunning => %d; want 0 once the runs returned\n", p.Stats().AbandonedRunning)
+	}
+}
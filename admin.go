@@ -0,0 +1,599 @@
+package prober
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// AdminOption is a setting for the handler returned by
+	// NewAdminHandler.
+	AdminOption func(*adminHandler)
+
+	// adminHandler serves the admin API for the probes in a registry.
+	adminHandler struct {
+		registry *Registry
+		// Checks of which at least one must accept each request.
+		auth      []func(*http.Request) bool
+		basicAuth bool // whether basic auth is one of the accepted checks
+		insecure  bool // whether to allow all requests if no checks are set
+	}
+
+	// recordPage is a page of the records of a probe, newest first.
+	recordPage struct {
+		Total   int // number of records matching the filters
+		Offset  int // number of matching records before this page
+		Records Records
+	}
+
+	// timeBoxedStatus is the status of a probe with its history limited
+	// to a time range, and the page of its records in the range.
+	timeBoxedStatus struct {
+		Status
+		History recordPage
+	}
+
+	// explanation is the explanation of a probe, with its text.
+	explanation struct {
+		Explanation
+		Text string // see Explanation.String()
+	}
+)
+
+const (
+	defaultRecordPageSize = 50      // records per page, unless ?limit= is given
+	maxRecordPageSize     = 500     // largest allowed ?limit=
+	maxAdminBody          = 1 << 20 // largest allowed request body
+)
+
+// NewAdminHandler returns a handler serving a JSON API to view and
+// manage the probes in the registry:
+//
+//	GET  /probes                        status of all probes
+//	GET  /probes/{name}                 status of one probe
+//	GET  /probes/{name}/records         recent records of one probe
+//	GET  /probes/{name}/explain         why one probe is or isn't alerting
+//	POST /probes/{name}/silence?for=2h  silence a probe
+//	POST /probes/{name}/disable         stop running a probe
+//	POST /probes/{name}/enable          start running a disabled probe again
+//	POST /probes/{name}/run?wait=30s    run a probe once, immediately
+//	POST /probes/{name}/chaos?runs=3    fail the next runs of a probe on purpose
+//	GET  /silences                      active silences of probes
+//	POST /silences?match=env=dev&for=2h silence all matching probes
+//	GET  /silences/export               state of silences, to import elsewhere
+//	POST /silences/import               add silences from an exported state
+//	GET  /components                    aggregate status of components
+//	GET  /events/deploy                 recent deploys
+//	POST /events/deploy?match=svc=web   hold alerts of matching probes after a deploy
+//
+// Since silencing or disabling probes is a privileged operation, the
+// handler must be given at least one of the BearerToken(), BasicAuth()
+// or ClientCert() options, and rejects all requests otherwise, unless
+// it's given Insecure(). If several are given, a request is allowed if
+// it satisfies any of them.
+//
+// Records are returned newest first, and can be paged through with
+// ?limit= and ?offset=, filtered to a time range with ?since= and
+// ?until= in RFC 3339 format, and to only passing or failing runs with
+// ?result=pass or ?result=fail.
+//
+// The same parameters can be given to /probes and /probes/{name}, in
+// which case the status of each probe has its BadnessHistory limited to
+// the time range, and a History with the page of its records, so that
+// dashboards can get the status and recent history of probes at once
+// without pulling all records.
+//
+// Silences added via /silences apply to all probes matching the
+// selector given by ?match=, see Registry.SilenceMatching().
+//
+// Runs started via /probes/{name}/run happen in the background, so
+// that slow probes don't hold up requests. The response is the result
+// of the run if it finishes within the time given by ?wait=, and
+// otherwise the status of the probe, with 202 Accepted.
+//
+// The state exported from /silences/export can be posted as is to
+// /silences/import of another prober, see Registry.ExportSilences().
+//
+// Deploys posted to /events/deploy apply to all probes matching the
+// selector given by ?match=, for the grace period given by ?grace=, and
+// can have the ?version= that was deployed, see Registry.Deployed().
+// The parameters can also be sent as a JSON object with the keys
+// "match", "grace" and "version", as from a CI webhook.
+func NewAdminHandler(reg *Registry, opts ...AdminOption) http.Handler {
+	h := &adminHandler{registry: reg}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if len(h.auth) == 0 && !h.insecure {
+		log.Printf("admin handler has no BearerToken(), BasicAuth() or ClientCert(), and will reject all requests\n")
+	}
+	return h
+}
+
+// BearerToken allows requests with an "Authorization: Bearer <token>"
+// header with any of the tokens. Empty tokens are ignored.
+func BearerToken(tokens ...string) AdminOption {
+	return func(h *adminHandler) {
+		h.auth = append(h.auth, func(r *http.Request) bool {
+			got := r.Header.Get("Authorization")
+			if !strings.HasPrefix(got, "Bearer ") {
+				return false
+			}
+			got = strings.TrimPrefix(got, "Bearer ")
+			for _, t := range tokens {
+				if t == "" {
+					continue
+				}
+				if subtle.ConstantTimeCompare([]byte(got), []byte(t)) == 1 {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
+
+// BasicAuth allows requests using HTTP basic auth with the username
+// and password. Empty usernames or passwords are ignored, e.g. from
+// unset environment variables, since they'd let anyone in.
+func BasicAuth(username, password string) AdminOption {
+	return func(h *adminHandler) {
+		if username == "" || password == "" {
+			log.Printf("ignoring BasicAuth() for admin handler with empty username or password\n")
+			return
+		}
+		h.basicAuth = true
+		h.auth = append(h.auth, func(r *http.Request) bool {
+			u, p, ok := r.BasicAuth()
+			if !ok {
+				return false
+			}
+			userOK := subtle.ConstantTimeCompare([]byte(u), []byte(username)) == 1
+			passOK := subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+			return userOK && passOK
+		})
+	}
+}
+
+// ClientCert allows requests made with a verified TLS client
+// certificate, i.e. mTLS. If any common names are given, the
+// certificate must be for one of them.
+//
+// Verification of the certificate itself is done by the server, which
+// must be configured with a tls.Config that has ClientCAs set and
+// ClientAuth set to at least tls.VerifyClientCertIfGiven.
+func ClientCert(commonNames ...string) AdminOption {
+	return func(h *adminHandler) {
+		h.auth = append(h.auth, func(r *http.Request) bool {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+				return false
+			}
+			if len(commonNames) == 0 {
+				return true
+			}
+			cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+			for _, n := range commonNames {
+				if cn == n {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
+
+// Insecure allows all requests if no other options to check them are
+// given, e.g. when the handler is only served on localhost or behind
+// an authenticating proxy.
+func Insecure() AdminOption {
+	return func(h *adminHandler) {
+		h.insecure = true
+	}
+}
+
+// authorized returns true if the request should be allowed.
+func (h *adminHandler) authorized(r *http.Request) bool {
+	if len(h.auth) == 0 {
+		return h.insecure
+	}
+	for _, ok := range h.auth {
+		if ok(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP serves the admin API.
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		log.Printf("rejected unauthorized admin request %s %s from %s\n", r.Method, r.URL.Path, r.RemoteAddr)
+		if h.basicAuth {
+			w.Header().Set("WWW-Authenticate", `Basic realm="prober"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 1 && parts[0] == "silences" {
+		h.serveSilences(w, r)
+		return
+	}
+	if len(parts) == 2 && parts[0] == "silences" && (parts[1] == "export" || parts[1] == "import") {
+		h.serveSilenceState(w, r, parts[1])
+		return
+	}
+	if len(parts) == 2 && parts[0] == "events" && parts[1] == "deploy" {
+		h.serveDeploys(w, r)
+		return
+	}
+	if len(parts) == 1 && parts[0] == "components" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, h.registry.Components())
+		return
+	}
+	if parts[0] != "probes" || len(parts) > 3 {
+		http.NotFound(w, r)
+		return
+	}
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		ps := h.registry.Probes()
+		if timeBoxed(r) {
+			statuses := make([]timeBoxedStatus, len(ps))
+			for i, p := range ps {
+				s, err := timeBoxedStatusOf(p, r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				statuses[i] = s
+			}
+			writeJSON(w, statuses)
+			return
+		}
+		statuses := make([]Status, len(ps))
+		for i, p := range ps {
+			statuses[i] = p.Status()
+		}
+		writeJSON(w, statuses)
+		return
+	}
+
+	p, ok := h.registry.Get(parts[1])
+	if !ok {
+		http.Error(w, fmt.Sprintf("no probe %q", parts[1]), http.StatusNotFound)
+		return
+	}
+	if len(parts) == 2 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		if timeBoxed(r) {
+			s, err := timeBoxedStatusOf(p, r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, s)
+			return
+		}
+		writeJSON(w, p.Status())
+		return
+	}
+	if parts[2] == "records" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		page, err := recordsPage(p.Records(), r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, page)
+		return
+	}
+	if parts[2] == "explain" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		e := p.Explain()
+		writeJSON(w, explanation{Explanation: e, Text: e.String()})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	switch parts[2] {
+	case "silence":
+		d, err := time.ParseDuration(r.FormValue("for"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("bad duration to silence for: %v", err), http.StatusBadRequest)
+			return
+		}
+		if _, err := h.registry.SilenceProbe(p.Name, time.Now().Add(d)); err != nil {
+			log.Printf("failed to save silences: %v\n", err)
+		}
+	case "disable":
+		p.Disable()
+	case "enable":
+		p.Enable()
+	case "chaos":
+		n, err := intParam(r, "runs", 1)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if n < 1 {
+			http.Error(w, fmt.Sprintf("bad runs %d", n), http.StatusBadRequest)
+			return
+		}
+		p.InjectFailures(n)
+	case "run":
+		h.serveRun(w, r, p)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, p.Status())
+}
+
+// serveRun runs the probe once in the background, writing the result
+// if the run finishes within ?wait=, and the status of the probe with
+// 202 Accepted otherwise.
+func (h *adminHandler) serveRun(w http.ResponseWriter, r *http.Request, p *Probe) {
+	var wait time.Duration
+	if v := r.FormValue("wait"); v != "" {
+		var err error
+		if wait, err = time.ParseDuration(v); err != nil {
+			http.Error(w, fmt.Sprintf("bad duration to wait for: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	done := make(chan Result, 1)
+	go func() {
+		res, _ := p.probeOnce(context.Background())
+		p.handleResult(res)
+		done <- res
+	}()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		writeJSON(w, res)
+		return
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, p.Status())
+}
+
+// serveSilences serves the active silences, or adds a silence.
+func (h *adminHandler) serveSilences(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, h.registry.Silences())
+	case http.MethodPost:
+		d, err := time.ParseDuration(r.FormValue("for"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("bad duration to silence for: %v", err), http.StatusBadRequest)
+			return
+		}
+		s, err := h.registry.SilenceMatching(r.FormValue("match"), d)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, s)
+	default:
+		http.Error(w, "only GET and POST are supported", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveSilenceState exports the state of the silences, or imports a
+// state sent as JSON.
+func (h *adminHandler) serveSilenceState(w http.ResponseWriter, r *http.Request, op string) {
+	if op == "export" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, h.registry.ExportSilences())
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	var st SilenceState
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBody)).Decode(&st); err != nil {
+		http.Error(w, fmt.Sprintf("bad silences: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := h.registry.ImportSilences(st); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, h.registry.ExportSilences())
+}
+
+// serveDeploys serves the recent deploys, or registers a deploy.
+func (h *adminHandler) serveDeploys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, h.registry.Deploys())
+	case http.MethodPost:
+		params := struct {
+			Match, Grace, Version string
+		}{r.FormValue("match"), r.FormValue("grace"), r.FormValue("version")}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBody)).Decode(&params); err != nil {
+				http.Error(w, fmt.Sprintf("bad deploy event: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+		var grace time.Duration
+		if params.Grace != "" {
+			var err error
+			if grace, err = time.ParseDuration(params.Grace); err != nil {
+				http.Error(w, fmt.Sprintf("bad grace period: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+		d, err := h.registry.Deployed(params.Match, params.Version, grace)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, d)
+	default:
+		http.Error(w, "only GET and POST are supported", http.StatusMethodNotAllowed)
+	}
+}
+
+// timeBoxed returns true if the request asks for the status of probes
+// to be limited to a time range or number of records.
+func timeBoxed(r *http.Request) bool {
+	for _, param := range []string{"since", "until", "limit", "offset", "result"} {
+		if r.FormValue(param) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// timeBoxedStatusOf returns the status of the probe, limited to the
+// time range that the query parameters of the request ask for.
+func timeBoxedStatusOf(p *Probe, r *http.Request) (timeBoxedStatus, error) {
+	since, until, err := timeRange(r)
+	if err != nil {
+		return timeBoxedStatus{}, err
+	}
+	page, err := recordsPage(p.Records(), r)
+	if err != nil {
+		return timeBoxedStatus{}, err
+	}
+	s := timeBoxedStatus{Status: p.Status(), History: page}
+	var history []BadnessSample
+	for _, b := range s.BadnessHistory {
+		if (since.IsZero() || !b.Time.Before(since)) && (until.IsZero() || !b.Time.After(until)) {
+			history = append(history, b)
+		}
+	}
+	s.BadnessHistory = history
+	return s, nil
+}
+
+// timeRange returns the times given by the ?since= and ?until= query
+// parameters of the request, or zero times if they're not set.
+func timeRange(r *http.Request) (since, until time.Time, err error) {
+	for _, t := range []struct {
+		param string
+		dst   *time.Time
+	}{{"since", &since}, {"until", &until}} {
+		v := r.FormValue(t.param)
+		if v == "" {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("bad %s time: %v", t.param, err)
+		}
+		*t.dst = ts
+	}
+	return since, until, nil
+}
+
+// recordsPage returns the page of the records, newest first, that the
+// query parameters of the request ask for.
+func recordsPage(records Records, r *http.Request) (recordPage, error) {
+	since, until, err := timeRange(r)
+	if err != nil {
+		return recordPage{}, err
+	}
+	result := r.FormValue("result")
+	if result != "" && result != "pass" && result != "fail" {
+		return recordPage{}, fmt.Errorf("bad result %q, want pass or fail", result)
+	}
+	limit, err := intParam(r, "limit", defaultRecordPageSize)
+	if err != nil {
+		return recordPage{}, err
+	}
+	if limit < 1 || limit > maxRecordPageSize {
+		return recordPage{}, fmt.Errorf("bad limit %d, want 1 to %d", limit, maxRecordPageSize)
+	}
+	offset, err := intParam(r, "offset", 0)
+	if err != nil {
+		return recordPage{}, err
+	}
+	if offset < 0 {
+		return recordPage{}, fmt.Errorf("bad offset %d", offset)
+	}
+
+	var matching Records
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+		if !since.IsZero() && rec.Timestamp.Before(since) {
+			continue
+		}
+		if !until.IsZero() && rec.Timestamp.After(until) {
+			continue
+		}
+		if result != "" && rec.Result.Passed() != (result == "pass") {
+			continue
+		}
+		matching = append(matching, rec)
+	}
+	page := recordPage{Total: len(matching), Offset: offset, Records: Records{}}
+	if offset < len(matching) {
+		end := offset + limit
+		if end > len(matching) {
+			end = len(matching)
+		}
+		page.Records = matching[offset:end]
+	}
+	return page, nil
+}
+
+// intParam returns the integer value of the query parameter, or def if
+// it isn't set.
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.FormValue(name)
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("bad %s %q: %v", name, v, err)
+	}
+	return i, nil
+}
+
+// writeJSON writes the value as the JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to write JSON response: %v\n", err)
+	}
+}
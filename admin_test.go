@@ -0,0 +1,399 @@
+package prober
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler(t *testing.T) {
+	newRegistry := func() *Registry {
+		return NewRegistry(&Probe{
+			Prober:   testProber{Passed()},
+			Name:     "TestProber1",
+			Interval: time.Minute,
+			t:        realTime{},
+		})
+	}
+	withCert := func(cn string) *tls.ConnectionState {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+	cases := []struct {
+		opts         []AdminOption
+		method, path string
+		header       http.Header
+		tls          *tls.ConnectionState
+		user, pass   string
+		body         string
+		want         int
+		wantSilenced bool
+		wantDisabled bool
+	}{
+		{
+			method: "GET",
+			path:   "/probes",
+			want:   http.StatusOK,
+		},
+		{
+			method: "GET",
+			path:   "/probes/NoSuchProbe",
+			want:   http.StatusNotFound,
+		},
+		{
+			method: "GET",
+			path:   "/probes/TestProber1/silence?for=2h",
+			want:   http.StatusMethodNotAllowed,
+		},
+		{
+			method:       "POST",
+			path:         "/probes/TestProber1/silence?for=2h",
+			want:         http.StatusOK,
+			wantSilenced: true,
+		},
+		{
+			method: "POST",
+			path:   "/probes/TestProber1/silence?for=forever",
+			want:   http.StatusBadRequest,
+		},
+		{
+			method: "GET",
+			path:   "/silences",
+			want:   http.StatusOK,
+		},
+		{
+			method:       "POST",
+			path:         "/silences?match=TestProber*&for=2h",
+			want:         http.StatusOK,
+			wantSilenced: true,
+		},
+		{
+			method: "POST",
+			path:   "/silences?match=env=[&for=2h",
+			want:   http.StatusBadRequest,
+		},
+		{
+			method: "DELETE",
+			path:   "/silences",
+			want:   http.StatusMethodNotAllowed,
+		},
+		{
+			method: "GET",
+			path:   "/silences/export",
+			want:   http.StatusOK,
+		},
+		{
+			method: "GET",
+			path:   "/silences/import",
+			want:   http.StatusMethodNotAllowed,
+		},
+		{
+			method: "POST",
+			path:   "/silences/import",
+			want:   http.StatusBadRequest,
+		},
+		{
+			method: "POST",
+			path:   "/silences/import",
+			body:   `{"Probes": {}}`,
+			want:   http.StatusOK,
+		},
+		{
+			method: "POST",
+			path:   "/silences/import",
+			body:   `{"Probes": {}, "Padding": "` + strings.Repeat("x", maxAdminBody) + `"}`,
+			want:   http.StatusBadRequest,
+		},
+		{
+			method: "POST",
+			path:   "/events/deploy?match=TestProber*&grace=5m&version=1.2.3",
+			want:   http.StatusOK,
+		},
+		{
+			method: "POST",
+			path:   "/events/deploy?match=TestProber*&grace=soon",
+			want:   http.StatusBadRequest,
+		},
+		{
+			method: "DELETE",
+			path:   "/events/deploy",
+			want:   http.StatusMethodNotAllowed,
+		},
+		{
+			method: "POST",
+			path:   "/probes/TestProber1/chaos?runs=2",
+			want:   http.StatusOK,
+		},
+		{
+			method: "POST",
+			path:   "/probes/TestProber1/chaos?runs=0",
+			want:   http.StatusBadRequest,
+		},
+		{
+			opts:   []AdminOption{},
+			method: "POST",
+			path:   "/probes/TestProber1/disable",
+			want:   http.StatusUnauthorized,
+		},
+		{
+			opts:   []AdminOption{BearerToken("s3cret")},
+			method: "POST",
+			path:   "/probes/TestProber1/disable",
+			want:   http.StatusUnauthorized,
+		},
+		{
+			opts:   []AdminOption{BearerToken("s3cret")},
+			method: "POST",
+			path:   "/probes/TestProber1/disable",
+			header: http.Header{"Authorization": {"s3cret"}},
+			want:   http.StatusUnauthorized,
+		},
+		{
+			opts:   []AdminOption{BearerToken("")},
+			method: "POST",
+			path:   "/probes/TestProber1/disable",
+			want:   http.StatusUnauthorized,
+		},
+		{
+			opts:   []AdminOption{BearerToken("")},
+			method: "POST",
+			path:   "/probes/TestProber1/disable",
+			header: http.Header{"Authorization": {"Bearer "}},
+			want:   http.StatusUnauthorized,
+		},
+		{
+			opts:         []AdminOption{BearerToken("s3cret")},
+			method:       "POST",
+			path:         "/probes/TestProber1/disable",
+			header:       http.Header{"Authorization": {"Bearer s3cret"}},
+			want:         http.StatusOK,
+			wantDisabled: true,
+		},
+		{
+			opts:   []AdminOption{BearerToken("s3cret"), BasicAuth("admin", "hunter2")},
+			method: "GET",
+			path:   "/probes",
+			user:   "admin",
+			pass:   "wrong",
+			want:   http.StatusUnauthorized,
+		},
+		{
+			opts:   []AdminOption{BearerToken("s3cret"), BasicAuth("admin", "hunter2")},
+			method: "GET",
+			path:   "/probes",
+			user:   "admin",
+			pass:   "hunter2",
+			want:   http.StatusOK,
+		},
+		{
+			opts:   []AdminOption{BasicAuth("", "")},
+			method: "GET",
+			path:   "/probes",
+			header: http.Header{"Authorization": {"Basic Og=="}},
+			want:   http.StatusUnauthorized,
+		},
+		{
+			opts:   []AdminOption{BasicAuth("admin", "")},
+			method: "GET",
+			path:   "/probes",
+			header: http.Header{"Authorization": {"Basic YWRtaW46"}},
+			want:   http.StatusUnauthorized,
+		},
+		{
+			opts:   []AdminOption{ClientCert("ops")},
+			method: "GET",
+			path:   "/probes",
+			tls:    withCert("ops"),
+			want:   http.StatusOK,
+		},
+		{
+			opts:   []AdminOption{ClientCert("ops")},
+			method: "GET",
+			path:   "/probes",
+			tls:    withCert("intruder"),
+			want:   http.StatusUnauthorized,
+		},
+		{
+			opts:   []AdminOption{ClientCert()},
+			method: "GET",
+			path:   "/probes",
+			tls:    &tls.ConnectionState{},
+			want:   http.StatusUnauthorized,
+		},
+	}
+	for i, tt := range cases {
+		reg := newRegistry()
+		opts := tt.opts
+		if opts == nil {
+			opts = []AdminOption{Insecure()}
+		}
+		h := NewAdminHandler(reg, opts...)
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		for k, vs := range tt.header {
+			req.Header[k] = vs
+		}
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, tt.pass)
+		}
+		req.TLS = tt.tls
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("[%d] %s %s => %d; want %d\n", i, tt.method, tt.path, w.Code, tt.want)
+		}
+		p, _ := reg.Get("TestProber1")
+		if p.Silenced() != tt.wantSilenced {
+			t.Errorf("[%d] %s %s => Silenced() %v; want %v\n", i, tt.method, tt.path, p.Silenced(), tt.wantSilenced)
+		}
+		if p.IsDisabled() != tt.wantDisabled {
+			t.Errorf("[%d] %s %s => IsDisabled() %v; want %v\n", i, tt.method, tt.path, p.IsDisabled(), tt.wantDisabled)
+		}
+	}
+}
+
+func TestAdminHandler_run(t *testing.T) {
+	release := make(chan struct{})
+	p := &Probe{
+		Prober:   blockingProber{release: release},
+		Name:     "SlowProber",
+		Interval: time.Minute,
+		t:        realTime{},
+	}
+	h := NewAdminHandler(NewRegistry(p), Insecure())
+	run := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", path, nil))
+		return w
+	}
+
+	// A slow run doesn't hold up the request.
+	for _, path := range []string{"/probes/SlowProber/run", "/probes/SlowProber/run?wait=10ms"} {
+		if w := run(path); w.Code != http.StatusAccepted {
+			t.Errorf("POST %s of blocked probe => %d; want %d\n", path, w.Code, http.StatusAccepted)
+		}
+	}
+	if w := run("/probes/SlowProber/run?wait=soon"); w.Code != http.StatusBadRequest {
+		t.Errorf("POST with bad wait => %d; want %d\n", w.Code, http.StatusBadRequest)
+	}
+	close(release)
+	w := run("/probes/SlowProber/run?wait=5s")
+	var res struct{ Code string }
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil || w.Code != http.StatusOK || res.Code != "Pass" {
+		t.Errorf("POST of released probe => %d %s; want %d with the result\n", w.Code, w.Body, http.StatusOK)
+	}
+}
+
+func TestAdminHandler_records(t *testing.T) {
+	start := time.Date(2016, time.June, 15, 15, 0, 0, 0, time.UTC)
+	p := &Probe{Name: "TestProber1", Interval: time.Minute, t: realTime{}}
+	for i := 0; i < 10; i++ {
+		r := Passed()
+		if i%3 == 0 {
+			r = FailedWith(fmt.Errorf("failure %d", i))
+		}
+		p.records = append(p.records, Record{Timestamp: start.Add(time.Duration(i) * time.Minute), Result: r})
+	}
+	h := NewAdminHandler(NewRegistry(p), Insecure())
+	cases := []struct {
+		query     string
+		want      int
+		wantTotal int
+		wantFirst time.Time
+	}{
+		{query: "", want: 10, wantTotal: 10, wantFirst: start.Add(9 * time.Minute)},
+		{query: "?limit=3&offset=8", want: 2, wantTotal: 10, wantFirst: start.Add(time.Minute)},
+		{query: "?offset=20", want: 0, wantTotal: 10},
+		{query: "?result=fail", want: 4, wantTotal: 4, wantFirst: start.Add(9 * time.Minute)},
+		{query: "?result=pass&limit=2", want: 2, wantTotal: 6, wantFirst: start.Add(8 * time.Minute)},
+		{query: "?since=2016-06-15T15:02:00Z&until=2016-06-15T15:04:00Z", want: 3, wantTotal: 3, wantFirst: start.Add(4 * time.Minute)},
+		{query: "?result=maybe", want: -1},
+		{query: "?limit=0", want: -1},
+		{query: "?offset=-1", want: -1},
+		{query: "?since=yesterday", want: -1},
+	}
+	for i, tt := range cases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/probes/TestProber1/records"+tt.query, nil))
+		if tt.want < 0 {
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("[%d] GET records%s => %d; want %d\n", i, tt.query, w.Code, http.StatusBadRequest)
+			}
+			continue
+		}
+		var got struct {
+			Total   int
+			Records []struct{ Timestamp time.Time }
+		}
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("[%d] GET records%s => %d, bad JSON: %v", i, tt.query, w.Code, err)
+		}
+		if got.Total != tt.wantTotal || len(got.Records) != tt.want {
+			t.Errorf("[%d] GET records%s => %d of %d records; want %d of %d\n", i, tt.query, len(got.Records), got.Total, tt.want, tt.wantTotal)
+			continue
+		}
+		if tt.want > 0 && !got.Records[0].Timestamp.Equal(tt.wantFirst) {
+			t.Errorf("[%d] GET records%s => first record at %v; want %v\n", i, tt.query, got.Records[0].Timestamp, tt.wantFirst)
+		}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/probes/TestProber1/records", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST records => %d; want %d\n", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestAdminHandler_timeBoxedStatus(t *testing.T) {
+	start := time.Date(2016, time.June, 15, 15, 0, 0, 0, time.UTC)
+	p := &Probe{Name: "TestProber1", Interval: time.Minute, t: realTime{}}
+	for i := 0; i < 10; i++ {
+		p.records = append(p.records, Record{Timestamp: start.Add(time.Duration(i) * time.Minute), Result: Passed()})
+		p.badnessHistory = append(p.badnessHistory, BadnessSample{Time: start.Add(time.Duration(i) * time.Minute), Badness: i})
+	}
+	h := NewAdminHandler(NewRegistry(p), Insecure())
+
+	type status struct {
+		Name           string
+		BadnessHistory []BadnessSample
+		History        *struct {
+			Total   int
+			Records Records
+		}
+	}
+	query := "?since=2016-06-15T15:02:00Z&until=2016-06-15T15:05:00Z&limit=2"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/probes/TestProber1"+query, nil))
+	var got status
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("GET /probes/TestProber1%s => %d, bad JSON: %v", query, w.Code, err)
+	}
+	if got.Name != "TestProber1" || len(got.BadnessHistory) != 4 || got.History == nil || got.History.Total != 4 || len(got.History.Records) != 2 {
+		t.Errorf("GET /probes/TestProber1%s => %+v; want 4 badness samples and 2 of 4 records\n", query, got)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/probes"+query, nil))
+	var all []status
+	if err := json.NewDecoder(w.Body).Decode(&all); err != nil || len(all) != 1 || all[0].History == nil {
+		t.Errorf("GET /probes%s => %+v, %v; want time-boxed statuses\n", query, all, err)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/probes/TestProber1", nil))
+	got = status{}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil || got.History != nil || len(got.BadnessHistory) != 10 {
+		t.Errorf("GET /probes/TestProber1 => %+v, %v; want full status without history\n", got, err)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/probes?since=yesterday", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("GET /probes?since=yesterday => %d; want %d\n", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,246 @@
+package prober
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// Aggregator collects records of the same probes run from many
+	// locations, and alerts when a quorum of the locations see a probe
+	// failing.
+	//
+	// Requiring a quorum keeps network blips close to a single
+	// location from causing alerts.
+	//
+	// The zero value is ready to use once AlertFn is set.
+	Aggregator struct {
+		// Number of locations that must see a probe failing for it to
+		// alert, or 0 to require a majority of the locations.
+		Quorum int
+		// How long records from a location are considered, after which
+		// the location no longer counts towards the quorum, or 0 for 3×DefaultInterval.
+		MaxAge  time.Duration
+		AlertFn AlertFn // called when a probe fails in a quorum of locations
+		// latest holds the most recent record for each probe name and location.
+		latest    map[string]map[string]Record
+		lastAlert map[string]time.Time // time of last alert for each probe name
+		lock      sync.Mutex           // protects latest and lastAlert
+	}
+
+	// shippedRecord is the form of a Record sent to an Aggregator.
+	shippedRecord struct {
+		Probe     string     `json:"probe" yaml:"probe"`
+		Location  string     `json:"location" yaml:"location"`
+		Timestamp time.Time  `json:"timestamp" yaml:"timestamp"`
+		Code      ResultCode `json:"code" yaml:"code"`
+		Error     string     `json:"error,omitempty" yaml:"error,omitempty"`
+		Info      string     `json:"info,omitempty" yaml:"info,omitempty"`
+		InfoUrl   string     `json:"info_url,omitempty" yaml:"info_url,omitempty"`
+		RunID     string     `json:"run_id,omitempty" yaml:"run_id,omitempty"`
+		// Annotations of the record, see Annotate().
+		Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	}
+)
+
+// maxShippedRecordBytes is the largest shipped record the aggregator
+// accepts.
+const maxShippedRecordBytes = 1 << 20
+
+// NewAggregator returns a new aggregator, which calls the function when
+// at least quorum locations see a probe failing.
+func NewAggregator(quorum int, fn AlertFn) *Aggregator {
+	return &Aggregator{
+		Quorum:    quorum,
+		AlertFn:   fn,
+		latest:    map[string]map[string]Record{},
+		lastAlert: map[string]time.Time{},
+	}
+}
+
+// Add adds a record for the named probe, alerting if a quorum of
+// locations now see the probe failing.
+func (a *Aggregator) Add(name string, r Record) {
+	a.lock.Lock()
+	if a.latest == nil {
+		a.latest = map[string]map[string]Record{}
+		a.lastAlert = map[string]time.Time{}
+	}
+	byLocation, ok := a.latest[name]
+	if !ok {
+		byLocation = map[string]Record{}
+		a.latest[name] = byLocation
+	}
+	if prev, ok := byLocation[r.Location]; ok && prev.Timestamp.After(r.Timestamp) {
+		// We already have a newer record from this location.
+		a.lock.Unlock()
+		return
+	}
+	byLocation[r.Location] = r
+	failing, total := a.failing(name, time.Now())
+	alert := len(failing) >= a.quorum(total) && time.Since(a.lastAlert[name]) >= MaxAlertFrequency
+	if alert {
+		a.lastAlert[name] = time.Now()
+	}
+	a.lock.Unlock()
+
+	if !alert {
+		return
+	}
+	log.Printf("[%s] is failing in %d of %d locations, alerting\n", name, len(failing), total)
+	desc := fmt.Sprintf("%s is failing in %d of %d locations", name, len(failing), total)
+	if err := a.AlertFn.Alert(name, desc, len(failing), failing); err != nil {
+		log.Printf("[%s] Failed to alert: %v\n", name, err)
+		a.lock.Lock()
+		delete(a.lastAlert, name)
+		a.lock.Unlock()
+	}
+}
+
+// Failing returns the most recent failing records of the named probe,
+// one per location, together with the number of locations that
+// recently reported results for the probe.
+func (a *Aggregator) Failing(name string) (Records, int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.failing(name, time.Now())
+}
+
+// failing is like Failing, but expects the caller to hold the lock.
+func (a *Aggregator) failing(name string, now time.Time) (Records, int) {
+	maxAge := a.MaxAge
+	if maxAge == 0 {
+		maxAge = 3 * DefaultInterval
+	}
+	failing := Records{}
+	total := 0
+	for _, r := range a.latest[name] {
+		if now.Sub(r.Timestamp) > maxAge {
+			continue
+		}
+		total++
+		if !r.Result.Passed() {
+			failing = append(failing, r)
+		}
+	}
+	sort.Sort(failing)
+	return failing, total
+}
+
+// quorum returns the number of failing locations needed to alert,
+// given the total number of locations.
+func (a *Aggregator) quorum(total int) int {
+	if a.Quorum > 0 {
+		return a.Quorum
+	}
+	return total/2 + 1
+}
+
+// ServeHTTP accepts records POSTed as JSON, or encoded with any
+// registered Codec with its content type, which lets probes running in
+// other locations ship their records to the aggregator.
+func (a *Aggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	c, ok := codecForContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		http.Error(w, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")), http.StatusUnsupportedMediaType)
+		return
+	}
+	b, err := io.ReadAll(io.LimitReader(r.Body, maxShippedRecordBytes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read record: %v", err), http.StatusBadRequest)
+		return
+	}
+	var sr shippedRecord
+	if err := c.Unmarshal(b, &sr); err != nil {
+		http.Error(w, fmt.Sprintf("bad record: %v", err), http.StatusBadRequest)
+		return
+	}
+	if sr.Probe == "" {
+		http.Error(w, "record is missing probe name", http.StatusBadRequest)
+		return
+	}
+	if sr.Timestamp.IsZero() {
+		sr.Timestamp = time.Now()
+	}
+	a.Add(sr.Probe, sr.record())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// shipped returns the record in the form sent to an Aggregator.
+func (r Record) shipped(name string) shippedRecord {
+	sr := shippedRecord{
+		Probe:       name,
+		Location:    r.Location,
+		Timestamp:   r.Timestamp,
+		Code:        r.Result.Code,
+		Info:        r.Result.Info,
+		InfoUrl:     r.Result.InfoUrl,
+		RunID:       r.RunID,
+		Annotations: r.Annotations,
+	}
+	if r.Result.Error != nil {
+		sr.Error = r.Result.Error.Error()
+	}
+	return sr
+}
+
+// record returns the Record that was shipped.
+func (sr shippedRecord) record() Record {
+	r := Record{
+		Timestamp:   sr.Timestamp,
+		TimeMillis:  TimeFormat{}.Format(sr.Timestamp),
+		Location:    sr.Location,
+		RunID:       sr.RunID,
+		Annotations: sr.Annotations,
+		Result: Result{
+			Code:    sr.Code,
+			Info:    sr.Info,
+			InfoUrl: sr.InfoUrl,
+		},
+	}
+	if sr.Error != "" {
+		r.Result.Error = errors.New(sr.Error)
+	}
+	return r
+}
+
+// ShipTo sends each record of the probe to the Aggregator served at the
+// URL, e.g. "https://central.example.com/records".
+func ShipTo(aggregatorURL string) func(*Probe) {
+	return func(p *Probe) {
+		p.shipURL = aggregatorURL
+	}
+}
+
+// ship sends the record to the aggregator.
+func (p *Probe) ship(r Record) {
+	c := p.shipCodec
+	if c == nil {
+		c = JSON
+	}
+	b, err := c.Marshal(r.shipped(p.Name))
+	if err != nil {
+		log.Printf("[%s] failed to encode record for shipping: %v\n", p.Name, err)
+		return
+	}
+	resp, err := http.Post(p.shipURL, c.ContentType(), bytes.NewReader(b))
+	if err != nil {
+		log.Printf("[%s] failed to ship record to %s: %v\n", p.Name, p.shipURL, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("[%s] failed to ship record to %s: %s\n", p.Name, p.shipURL, resp.Status)
+	}
+}
@@ -0,0 +1,100 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAggregator_Add(t *testing.T) {
+	pass := Passed()
+	fail := FailedWith(errors.New("failing on purpose"))
+	type in struct {
+		location string
+		result   Result
+		age      time.Duration
+	}
+	cases := []struct {
+		quorum int
+		in     []in
+		want   int // number of alerts
+	}{
+		{
+			in: []in{
+				{"us", pass, 0},
+				{"asia", pass, 0},
+				{"eu", fail, 0},
+			},
+			want: 0,
+		},
+		{
+			in: []in{
+				{"eu", fail, 0},
+				{"us", fail, 0},
+				{"asia", pass, 0},
+			},
+			want: 1,
+		},
+		{
+			quorum: 3,
+			in: []in{
+				{"eu", fail, 0},
+				{"us", fail, 0},
+				{"asia", pass, 0},
+			},
+			want: 0,
+		},
+		{
+			// The passing record from "us" is too old to count.
+			in: []in{
+				{"us", pass, time.Hour},
+				{"eu", fail, 0},
+			},
+			want: 1,
+		},
+		{
+			// Only the newest record from each location counts.
+			in: []in{
+				{"us", pass, 0},
+				{"asia", pass, 0},
+				{"eu", fail, 0},
+				{"us", fail, time.Second},
+			},
+			want: 0,
+		},
+	}
+	for i, tt := range cases {
+		alerts := 0
+		a := NewAggregator(tt.quorum, func(name, desc string, badness int, records Records) error {
+			alerts++
+			return nil
+		})
+		now := time.Now()
+		for _, r := range tt.in {
+			a.Add("TestProbe", Record{
+				Timestamp: now.Add(-r.age),
+				Location:  r.location,
+				Result:    r.result,
+			})
+		}
+		if alerts != tt.want {
+			t.Errorf("[%d] Aggregator{Quorum: %d} sent %d alerts; want %d\n", i, tt.quorum, alerts, tt.want)
+		}
+	}
+}
+
+func TestAggregator_zero(t *testing.T) {
+	alerts := 0
+	a := &Aggregator{AlertFn: func(name, desc string, badness int, records Records) error {
+		alerts++
+		return nil
+	}}
+	fail := FailedWith(errors.New("failing on purpose"))
+	a.Add("TestProbe", Record{Timestamp: time.Now(), Location: "eu", Result: fail})
+	if alerts != 1 {
+		t.Errorf("Aggregator{} sent %d alerts; want 1\n", alerts)
+	}
+	if got, total := a.Failing("TestProbe"); len(got) != 1 || total != 1 {
+		t.Errorf("Aggregator{}.Failing() => %v, %d; want 1 failing record of 1\n", got, total)
+	}
+}
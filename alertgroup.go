@@ -0,0 +1,108 @@
+package prober
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AlertGroup collects alerts from many probes, so that a mass outage
+// sends one notification listing the affected probes instead of one
+// alert per probe.
+//
+// Alerts of probes in the group are held for Window. If more than
+// Threshold probes alerted in that time, Notify is called once with all
+// of them; otherwise each probe alerts as usual.
+type AlertGroup struct {
+	// More than this many probes alerting within Window is a mass outage.
+	Threshold int
+	// How long to hold alerts, or 0 for one minute.
+	Window time.Duration
+	// Function to send the mass outage notification with.
+	Notify  func(probes Probes) error
+	pending Probes     // probes with alerts held
+	lock    sync.Mutex // protects pending
+}
+
+// InAlertGroup makes the alerts of the probe go through the group.
+func InAlertGroup(g *AlertGroup) func(*Probe) {
+	return func(p *Probe) {
+		p.alertGroup = g
+	}
+}
+
+// add holds the alert of the probe until the end of the current
+// window.
+func (g *AlertGroup) add(p *Probe) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	for _, pending := range g.pending {
+		if pending == p {
+			return
+		}
+	}
+	g.pending = append(g.pending, p)
+	if len(g.pending) == 1 {
+		window := g.Window
+		if window == 0 {
+			window = time.Minute
+		}
+		time.AfterFunc(window, g.flush)
+	}
+}
+
+// flush sends the held alerts, either as one mass outage notification
+// or individually.
+func (g *AlertGroup) flush() {
+	g.lock.Lock()
+	probes := g.pending
+	g.pending = nil
+	g.lock.Unlock()
+
+	if len(probes) <= g.Threshold {
+		for _, p := range probes {
+			go p.sendAlert()
+		}
+		return
+	}
+	log.Printf("%d probes alerted at once, sending mass outage notification instead of individual alerts\n", len(probes))
+	if err := g.notify(probes); err != nil {
+		log.Printf("Failed to send mass outage notification, alerting individually: %v\n", err)
+		for _, p := range probes {
+			go p.sendAlert()
+		}
+		return
+	}
+	for _, p := range probes {
+		log.Printf("[%s] Was part of mass outage notification, resetting badness to 0\n", p.Name)
+		p.setLastAlert(p.t.Now())
+		p.recordAlertSent()
+		p.setBadness(0)
+	}
+}
+
+// alert sends an alert for the probe, via its AlertGroup if any.
+func (p *Probe) alert() {
+	if p.alertGroup != nil {
+		p.alertGroup.add(p)
+		return
+	}
+	go p.sendAlert()
+}
+
+// notify sends the mass outage notification, unless all the probes are
+// dry runs, in which case it's only logged.
+func (g *AlertGroup) notify(probes Probes) error {
+	for _, p := range probes {
+		if !p.isDryRun() {
+			return g.Notify(probes)
+		}
+	}
+	names := make([]string, len(probes))
+	for i, p := range probes {
+		names[i] = p.Name
+	}
+	log.Printf("[dry run] would send mass outage notification for %s\n", strings.Join(names, ", "))
+	return nil
+}
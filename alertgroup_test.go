@@ -0,0 +1,71 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingProber is a testProber that counts its alerts.
+type countingProber struct {
+	testProber
+	alerts chan string
+}
+
+func (p countingProber) Alert(name, desc string, badness int, records Records) error {
+	p.alerts <- name
+	return nil
+}
+
+func TestAlertGroup(t *testing.T) {
+	alerts := make(chan string, 10)
+	outages := make(chan Probes, 1)
+	g := &AlertGroup{
+		Threshold: 2,
+		Window:    10 * time.Millisecond,
+		Notify: func(ps Probes) error {
+			outages <- ps
+			return nil
+		},
+	}
+	newProbe := func(name string) *Probe {
+		p := &Probe{
+			Prober: countingProber{testProber{FailedWith(errors.New("failing on purpose"))}, alerts},
+			Name:   name,
+			t:      fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+		}
+		InAlertGroup(g)(p)
+		return p
+	}
+
+	a, b, c := newProbe("a"), newProbe("b"), newProbe("c")
+	for _, p := range []*Probe{a, b, a, c} {
+		p.alert()
+	}
+	select {
+	case ps := <-outages:
+		if len(ps) != 3 {
+			t.Errorf("Notify() called with %d probes; want 3\n", len(ps))
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Notify() was not called for mass outage\n")
+	}
+	select {
+	case name := <-alerts:
+		t.Errorf("%s alerted individually during mass outage\n", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if a.getLastAlert().IsZero() {
+		t.Errorf("after mass outage notification, lastAlert of a is unset\n")
+	}
+
+	newProbe("d").alert()
+	select {
+	case name := <-alerts:
+		if name != "d" {
+			t.Errorf("%s alerted; want d\n", name)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("d did not alert individually\n")
+	}
+}
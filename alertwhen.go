@@ -0,0 +1,136 @@
+package prober
+
+import (
+	"log"
+	"time"
+)
+
+// AlertWhen makes the probe alert when the expression holds after a
+// run, rather than when its badness reaches the alert threshold, e.g.
+//
+//	AlertWhen("badness > 80 || consecutive_failures >= 5 && hour(now) > 8")
+//
+// See Expr for the language, which is the same in all builds; see
+// AlertWhenExpr() for conditions in expr-lang instead. The variables
+// are:
+//
+//	badness               current badness
+//	threshold             badness at which the probe alerts by default
+//	consecutive_failures  number of runs in a row that failed
+//	since_success         time since the probe last passed, or since it
+//	                      started if it never has
+//	health_score          see HealthScore()
+//	rate                  growth of Value per minute, see MaxRate()
+//	alerting, degraded    whether the probe was alerting or degraded
+//	                      before the run
+//	now                   the time of the run
+//
+// Silenced probes never alert. If the expression doesn't parse, it's
+// reported by Validate() and the probe alerts on badness as usual.
+func AlertWhen(expr string) func(*Probe) {
+	return func(p *Probe) {
+		p.alertWhenSrc = expr
+		p.alertWhenExprLang = false
+		p.setAlertWhen()
+	}
+}
+
+// AlertWhenExpr is like AlertWhen(), but with the condition in
+// expr-lang, see https://expr-lang.org/docs/language-definition, e.g.
+//
+//	AlertWhenExpr(`badness > threshold / 2 and since_success > duration("10m")`)
+//
+// The variables are those of AlertWhen(), along with the functions
+// hour() and weekday(); durations are written as duration("10m").
+//
+// Only builds with the "exprlang" tag support AlertWhenExpr(), since it
+// depends on expr-lang. In other builds, the condition is reported by
+// Validate() and the probe alerts on badness as usual.
+func AlertWhenExpr(expr string) func(*Probe) {
+	return func(p *Probe) {
+		p.alertWhenSrc = expr
+		p.alertWhenExprLang = true
+		p.setAlertWhen()
+	}
+}
+
+// setAlertWhen compiles the condition of AlertWhen() or
+// AlertWhenExpr(), logging it if it's bad.
+func (p *Probe) setAlertWhen() {
+	c, err := p.compileAlertWhen()
+	if err != nil {
+		log.Printf("[%s] bad AlertWhen() condition, alerting on badness instead: %v\n", p.Name, err)
+		p.alertWhen = nil
+		return
+	}
+	p.alertWhen = c
+}
+
+// compileAlertWhen compiles the condition of AlertWhen() or
+// AlertWhenExpr() in its language.
+func (p *Probe) compileAlertWhen() (condition, error) {
+	if p.alertWhenExprLang {
+		return compileExprLang(p.alertWhenSrc)
+	}
+	return ParseExpr(p.alertWhenSrc)
+}
+
+// condition is a compiled AlertWhen() condition.
+type condition interface {
+	// Check returns true if the condition holds with the variables.
+	Check(vars Vars) (bool, error)
+}
+
+// ConsecutiveFailures returns the number of runs in a row that have
+// failed.
+func (p *Probe) ConsecutiveFailures() int {
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	return p.consecutiveFailures
+}
+
+// alertCondition returns true if the probe should be alerting after a
+// run at the time.
+func (p *Probe) alertCondition(now time.Time) bool {
+	if p.rateExceeded(now) {
+		return true
+	}
+	if p.alertWhen == nil {
+		if p.sloTarget > 0 {
+			_, burning := p.burning(now)
+			return burning
+		}
+		return p.Badness() >= p.threshold()
+	}
+	ok, err := p.alertWhen.Check(p.alertVars(now))
+	if err != nil {
+		log.Printf("[%s] failed to check AlertWhen() condition, alerting on badness instead: %v\n", p.Name, err)
+		return p.Badness() >= p.threshold()
+	}
+	return ok
+}
+
+// alertVars returns the variables for the AlertWhen() condition.
+func (p *Probe) alertVars(now time.Time) Vars {
+	p.alertLock.RLock()
+	since := p.lastSuccess
+	if since.IsZero() {
+		since = p.firstRun
+	}
+	vars := Vars{
+		"badness":              p.badness,
+		"consecutive_failures": p.consecutiveFailures,
+		"alerting":             p.alerting,
+		"degraded":             p.degraded,
+		"now":                  now,
+	}
+	p.alertLock.RUnlock()
+	vars["since_success"] = time.Duration(0)
+	if !since.IsZero() {
+		vars["since_success"] = now.Sub(since)
+	}
+	vars["rate"], _ = p.rate(now)
+	vars["threshold"] = p.threshold()
+	vars["health_score"] = p.HealthScore()
+	return vars
+}
@@ -0,0 +1,89 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAlertWhen(t *testing.T) {
+	start := time.Date(1998, 11, 19, 6, 0, 0, 0, time.UTC)
+	fail := FailedWith(errors.New("failing on purpose"))
+	var records Records
+	// Failures from 06:00 until 10:00, one run every 10 minutes.
+	for i := 0; i < 24; i++ {
+		records = append(records, Record{Timestamp: start.Add(time.Duration(i) * 10 * time.Minute), Result: fail})
+	}
+	p := &Probe{Name: "AlertWhenProber", failurePenalty: 1, successReward: 1}
+
+	cases := []struct {
+		cond      string
+		wantFirst time.Time // time of the first alert, if any
+	}{
+		{cond: "consecutive_failures >= 3", wantFirst: start.Add(20 * time.Minute)},
+		{cond: "consecutive_failures >= 5 && hour(now) >= 8", wantFirst: start.Add(2 * time.Hour)},
+		{cond: "since_success > 1h", wantFirst: start.Add(70 * time.Minute)},
+		{cond: "badness > 100"},
+		// Bad conditions fall back to alerting on badness.
+		{cond: "consecutive_failures >="},
+	}
+	for i, tt := range cases {
+		got := p.Replay(records, AlertWhen(tt.cond))
+		if tt.wantFirst.IsZero() {
+			if got.Alerts() != 0 {
+				t.Errorf("[%d] Replay() with AlertWhen(%q) => %v; want no alerts\n", i, tt.cond, got)
+			}
+			continue
+		}
+		if got.Alerts() == 0 || !got.Notifications[0].Time.Equal(tt.wantFirst) {
+			t.Errorf("[%d] Replay() with AlertWhen(%q) => %v; want first alert at %v\n", i, tt.cond, got, tt.wantFirst)
+		}
+	}
+}
+
+func TestAlertWhen_handleResult(t *testing.T) {
+	now := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{Name: "AlertWhenProber", failurePenalty: 1, t: fakeTime{now}}
+	AlertWhen("consecutive_failures >= 2")(p)
+	p.SilencedUntil = SilenceTime{now.Add(time.Hour)}
+	for i := 0; i < 3; i++ {
+		p.handleResult(FailedWith(errors.New("failing on purpose")))
+	}
+	if p.IsAlerting() {
+		t.Errorf("silenced probe is alerting; want it not to\n")
+	}
+	if got := p.ConsecutiveFailures(); got != 3 {
+		t.Errorf("ConsecutiveFailures() => %d; want 3\n", got)
+	}
+	p.handleResult(Passed())
+	if got := p.ConsecutiveFailures(); got != 0 {
+		t.Errorf("ConsecutiveFailures() after passing => %d; want 0\n", got)
+	}
+}
+
+func TestAlertWhen_Validate(t *testing.T) {
+	cases := []struct {
+		cond    string
+		wantErr bool
+	}{
+		{cond: "badness > threshold / 2", wantErr: true},
+		{cond: "badness > 80 || consecutive_failures >= 5 && hour(now) > 8"},
+		{cond: "health_score < 0.5 && !alerting && !degraded"},
+		{cond: "uptime > 1h", wantErr: true},
+		{cond: "badness > 1h", wantErr: true},
+		{cond: "badness", wantErr: true},
+	}
+	for i, tt := range cases {
+		p := &Probe{Name: "AlertWhenProber", Prober: testProber{Passed()}, Interval: time.Minute}
+		AlertWhen(tt.cond)(p)
+		var got bool
+		for _, e := range p.validate() {
+			if e.Path == "AlertWhenProber.AlertWhen" {
+				got = true
+			}
+		}
+		if got != tt.wantErr {
+			t.Errorf("[%d] validate() with AlertWhen(%q) => %v; want AlertWhen problem: %v\n", i, tt.cond, p.validate(), tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package prober
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Annotate makes the probe attach the annotations that fn returns to
+// each of its records when they're created, e.g. the deployed version,
+// feature flags or region of the host application, so failures can be
+// correlated with deploys in logs and alerts.
+//
+// Annotate can be given several times, with annotations from later
+// functions taking precedence. fn is called once per run, and must be
+// safe to call from several goroutines.
+func Annotate(fn func() map[string]string) func(*Probe) {
+	return func(p *Probe) {
+		p.annotators = append(p.annotators, fn)
+	}
+}
+
+// annotations returns the annotations for a new record, or nil if
+// there are none.
+func (p *Probe) annotations() (annotations map[string]string) {
+	for _, fn := range p.annotators {
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					log.Printf("[%s] annotator panicked: %v\n", p.Name, v)
+				}
+			}()
+			for k, v := range fn() {
+				if annotations == nil {
+					annotations = map[string]string{}
+				}
+				annotations[k] = v
+			}
+		}()
+	}
+	return annotations
+}
+
+// annotationNote returns a note on the annotations of the most recent
+// record for notifications, or "" if there are none.
+func (p *Probe) annotationNote() string {
+	rs := p.Records()
+	if len(rs) == 0 || len(rs[len(rs)-1].Annotations) == 0 {
+		return ""
+	}
+	a := rs[len(rs)-1].Annotations
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%s", k, a[k])
+	}
+	return fmt.Sprintf(" [%s]", strings.Join(parts, ", "))
+}
+
+// equalStrings returns true if the maps hold the same keys and values.
+func equalStrings(m1, m2 map[string]string) bool {
+	if len(m1) != len(m2) {
+		return false
+	}
+	for k, v := range m1 {
+		if v2, ok := m2[k]; !ok || v != v2 {
+			return false
+		}
+	}
+	return true
+}
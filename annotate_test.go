@@ -0,0 +1,45 @@
+package prober
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnnotate(t *testing.T) {
+	version := "1.2.3"
+	p := &Probe{
+		Name: "AnnotatedProber",
+		Desc: "Web server is up.",
+		t:    fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	for _, opt := range []Option{
+		Annotate(func() map[string]string { return map[string]string{"version": version, "region": "eu"} }),
+		Annotate(func() map[string]string { return map[string]string{"region": "us"} }),
+		Annotate(func() map[string]string { panic("annotating on purpose") }),
+		CompactRecords(),
+	} {
+		opt(p)
+	}
+
+	p.logResult(Passed())
+	version = "1.2.4"
+	p.logResult(Passed())
+	p.logResult(FailedWith(errors.New("failing on purpose")))
+
+	rs := p.Records()
+	if len(rs) != 3 {
+		t.Fatalf("after runs across a deploy, got %d records; want 3 since records of different versions aren't merged", len(rs))
+	}
+	want := map[string]string{"version": "1.2.4", "region": "us"}
+	if got := rs[2].Annotations; !equalStrings(got, want) {
+		t.Errorf("Annotations => %v; want %v\n", got, want)
+	}
+	if desc := p.alertDesc(); !strings.Contains(desc, "[region=us, version=1.2.4]") {
+		t.Errorf("alertDesc() => %q; want annotations\n", desc)
+	}
+	if got := rs[0].shipped(p.Name).record().Annotations; got["version"] != "1.2.3" {
+		t.Errorf("shipped record has annotations %v; want version=1.2.3\n", got)
+	}
+}
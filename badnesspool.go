@@ -0,0 +1,111 @@
+package prober
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BadnessPool adds up the badness of a group of related probes, e.g.
+// one per replica of a service, so the group alerts once when the
+// failures across all of them are bad enough, instead of each probe
+// alerting on its own.
+//
+// Probes in the pool don't alert or warn individually. When the total
+// badness of the probes reaches Threshold, AlertFn is called with the
+// most recent record of each probe that has any badness, and the
+// badness of all the probes is reset.
+type BadnessPool struct {
+	Name      string  // name of the pool, passed to AlertFn
+	Threshold int     // total badness at which the pool alerts
+	AlertFn   AlertFn // function to alert with
+	probes    Probes
+	sending   bool       // whether an alert is being sent
+	lock      sync.Mutex // protects probes and sending
+}
+
+// InBadnessPool makes the probe part of the pool.
+func InBadnessPool(bp *BadnessPool) func(*Probe) {
+	return func(p *Probe) {
+		bp.lock.Lock()
+		defer bp.lock.Unlock()
+		bp.probes = append(bp.probes, p)
+		p.badnessPool = bp
+	}
+}
+
+// Badness returns the total badness of the probes in the pool.
+func (bp *BadnessPool) Badness() int {
+	total := 0
+	for _, p := range bp.Probes() {
+		total += p.Badness()
+	}
+	return total
+}
+
+// Probes returns the probes in the pool.
+func (bp *BadnessPool) Probes() Probes {
+	bp.lock.Lock()
+	defer bp.lock.Unlock()
+	return append(Probes{}, bp.probes...)
+}
+
+// update alerts if the total badness of the pool has reached the
+// threshold, after a run of the probe.
+func (bp *BadnessPool) update(p *Probe) {
+	total := bp.Badness()
+	if total < bp.Threshold {
+		return
+	}
+	if p.alertsDisabled() {
+		log.Printf("[%s] would now be alerting with total badness %d, but alerts are disabled\n", bp.Name, total)
+		return
+	}
+	bp.lock.Lock()
+	if bp.sending {
+		bp.lock.Unlock()
+		return
+	}
+	bp.sending = true
+	bp.lock.Unlock()
+	go bp.alert(total)
+}
+
+// alert calls AlertFn for the pool, and resets the badness of its
+// probes if the alert was sent.
+func (bp *BadnessPool) alert(total int) {
+	defer func() {
+		bp.lock.Lock()
+		bp.sending = false
+		bp.lock.Unlock()
+	}()
+	probes := bp.Probes()
+	var (
+		records Records
+		bad     []string
+	)
+	for _, p := range probes {
+		b := p.Badness()
+		if b == 0 {
+			continue
+		}
+		bad = append(bad, fmt.Sprintf("%s (%d)", p.Name, b))
+		if rs := p.Records(); len(rs) > 0 {
+			records = append(records, rs[len(rs)-1])
+		}
+	}
+	sort.Sort(records)
+	desc := fmt.Sprintf("%s has total badness %d across %d probes: %s", bp.Name, total, len(probes), strings.Join(bad, ", "))
+	log.Printf("[%s] is alerting: %s\n", bp.Name, desc)
+	if err := bp.AlertFn(bp.Name, desc, total, records); err != nil {
+		log.Printf("[%s] Failed to alert: %v\n", bp.Name, err)
+		return
+	}
+	for _, p := range probes {
+		p.setLastAlert(p.t.Now())
+		p.recordAlertSent()
+		p.setBadness(0)
+	}
+}
@@ -0,0 +1,65 @@
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBadnessPool(t *testing.T) {
+	alerts := make(chan string, 1)
+	bp := &BadnessPool{
+		Name:      "ReplicaPool",
+		Threshold: 25,
+		AlertFn: func(name, desc string, badness int, records Records) error {
+			alerts <- fmt.Sprintf("%s %d %d", name, badness, len(records))
+			return nil
+		},
+	}
+	var replicas Probes
+	for i := 0; i < 3; i++ {
+		p := &Probe{
+			Prober:         testProber{FailedWith(errors.New("failing on purpose"))},
+			Name:           fmt.Sprintf("Replica%d", i),
+			Interval:       time.Minute,
+			failurePenalty: 10,
+			t:              fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+		}
+		InBadnessPool(bp)(p)
+		replicas = append(replicas, p)
+	}
+
+	replicas[0].handleResult(replicas[0].Probe())
+	replicas[1].handleResult(replicas[1].Probe())
+	if got := bp.Badness(); got != 20 {
+		t.Errorf("Badness() => %d; want 20\n", got)
+	}
+	select {
+	case a := <-alerts:
+		t.Fatalf("pool alerted below threshold: %s\n", a)
+	case <-time.After(10 * time.Millisecond):
+	}
+	for _, p := range replicas {
+		if p.IsAlerting() {
+			t.Errorf("%s.IsAlerting() => true; want pool members to not alert individually\n", p.Name)
+		}
+	}
+
+	replicas[2].handleResult(replicas[2].Probe())
+	select {
+	case a := <-alerts:
+		if want := "ReplicaPool 30 3"; a != want {
+			t.Errorf("pool alerted with %q; want %q\n", a, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("pool didn't alert at threshold\n")
+	}
+	deadline := time.Now().Add(time.Second)
+	for bp.Badness() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := bp.Badness(); got != 0 {
+		t.Errorf("Badness() after alert => %d; want 0\n", got)
+	}
+}
@@ -0,0 +1,231 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+type (
+	// BatchResult is the outcome of a single probe during RunAllOnce().
+	BatchResult struct {
+		Name     string        `json:"name"`
+		Result   Result        `json:"result"`
+		Skipped  bool          `json:"skipped"`  // whether the probe was never run, e.g. since a dependency failed
+		Duration time.Duration `json:"duration"` // how long the probe took to run
+	}
+
+	// BatchReport is the consolidated outcome of RunAllOnce(), in the order
+	// the probes were run.
+	BatchReport []BatchResult
+
+	// BatchSummary is a machine-readable summary of a BatchReport.
+	BatchSummary struct {
+		OK      bool          `json:"ok"`
+		Passed  int           `json:"passed"`
+		Failed  int           `json:"failed"`
+		Skipped int           `json:"skipped"`
+		Results []BatchResult `json:"results"`
+	}
+
+	// BatchOption is a setting for RunAllOnce().
+	BatchOption func(*batchConfig)
+
+	// batchConfig holds the settings for a RunAllOnce() run.
+	batchConfig struct {
+		failFast bool // whether to skip all remaining probes once one fails
+	}
+)
+
+// FailFast makes RunAllOnce() skip all remaining probes as soon as any
+// probe fails, e.g. for use as a deployment gate.
+func FailFast() BatchOption {
+	return func(c *batchConfig) {
+		c.failFast = true
+	}
+}
+
+// RunAllOnce runs each of the probes once, returning a report of the
+// outcomes.
+//
+// Probes are run one at a time, in an order where each probe runs after
+// the probes it DependsOn(). If any dependency of a probe fails or is
+// skipped, the probe itself is skipped. If ctx is done, the remaining
+// probes are skipped.
+//
+// RunAllOnce doesn't affect badness of the probes or send any alerts,
+// which makes it suitable for e.g. smoke tests from CI before a
+// deploy. An error is returned if the dependencies can't be satisfied.
+func RunAllOnce(ctx context.Context, probes Probes, opts ...BatchOption) (BatchReport, error) {
+	conf := batchConfig{}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	ordered, err := probes.inDependencyOrder()
+	if err != nil {
+		return nil, err
+	}
+	report := make(BatchReport, 0, len(ordered))
+	passed := map[string]bool{}
+	var failed *Probe // first probe that failed, if any
+	for _, p := range ordered {
+		if conf.failFast && failed != nil {
+			report = append(report, p.skipped(fmt.Errorf("%s was skipped since %s failed", p.Name, failed.Name)))
+			continue
+		}
+		if ctx.Err() != nil {
+			report = append(report, p.skipped(fmt.Errorf("%s was skipped: %v", p.Name, ctx.Err())))
+			continue
+		}
+		if dep, ok := p.failedDependency(passed); ok {
+			report = append(report, p.skipped(fmt.Errorf("%s was skipped since dependency %s did not pass", p.Name, dep)))
+			continue
+		}
+		start := time.Now()
+		r, _ := p.probeOnce(ctx)
+		p.resultLock.Lock()
+		p.logResult(r)
+		p.resultLock.Unlock()
+		passed[p.Name] = r.Passed()
+		if !r.Passed() && failed == nil {
+			failed = p
+		}
+		report = append(report, BatchResult{
+			Name:     p.Name,
+			Result:   r,
+			Duration: time.Since(start),
+		})
+	}
+	return report, nil
+}
+
+// skipped returns a report describing that the probe wasn't run.
+func (p *Probe) skipped(err error) BatchResult {
+	log.Printf("[%s] %v\n", p.Name, err)
+	return BatchResult{
+		Name:    p.Name,
+		Result:  FailedWith(err),
+		Skipped: true,
+	}
+}
+
+// failedDependency returns the name of the first dependency of the
+// probe which didn't pass, if any.
+func (p *Probe) failedDependency(passed map[string]bool) (string, bool) {
+	for _, dep := range p.dependencies {
+		if !passed[dep] {
+			return dep, true
+		}
+	}
+	return "", false
+}
+
+// inDependencyOrder returns the probes ordered so that every probe
+// comes after its dependencies. Probes without dependencies between
+// them keep their relative order.
+func (ps Probes) inDependencyOrder() (Probes, error) {
+	byName := make(map[string]*Probe, len(ps))
+	for _, p := range ps {
+		if _, ok := byName[p.Name]; ok {
+			return nil, fmt.Errorf("more than one probe named %q", p.Name)
+		}
+		byName[p.Name] = p
+	}
+	for _, p := range ps {
+		for _, dep := range p.dependencies {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("%s depends on unknown probe %q", p.Name, dep)
+			}
+		}
+	}
+
+	ordered := make(Probes, 0, len(ps))
+	done := map[string]bool{}
+	for len(ordered) < len(ps) {
+		progress := false
+		for _, p := range ps {
+			if done[p.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range p.dependencies {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, p)
+				done[p.Name] = true
+				progress = true
+			}
+		}
+		if !progress {
+			var stuck []string
+			for _, p := range ps {
+				if !done[p.Name] {
+					stuck = append(stuck, p.Name)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between probes %s", strings.Join(stuck, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+// Passed returns true if every probe in the report passed.
+func (r BatchReport) Passed() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the reports of probes that failed or were skipped.
+func (r BatchReport) Failed() BatchReport {
+	var failed BatchReport
+	for _, br := range r {
+		if !br.Result.Passed() {
+			failed = append(failed, br)
+		}
+	}
+	return failed
+}
+
+// Summary returns a machine-readable summary of the report.
+func (r BatchReport) Summary() BatchSummary {
+	s := BatchSummary{Results: r}
+	for _, br := range r {
+		switch {
+		case br.Skipped:
+			s.Skipped++
+		case br.Result.Passed():
+			s.Passed++
+		default:
+			s.Failed++
+		}
+	}
+	s.OK = s.Failed == 0 && s.Skipped == 0
+	return s
+}
+
+// String returns a human-readable summary of the report, one line per probe.
+func (r BatchReport) String() string {
+	lines := make([]string, len(r))
+	for i, br := range r {
+		lines[i] = br.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// String returns a human-readable summary of the outcome of the probe.
+func (br BatchResult) String() string {
+	outcome := br.Result.Code.String()
+	if br.Skipped {
+		outcome = "Skipped"
+	}
+	s := fmt.Sprintf("%s: %s (%v)", br.Name, outcome, br.Duration)
+	if br.Result.Error != nil {
+		s += fmt.Sprintf(": %v", br.Result.Error)
+	}
+	return s
+}
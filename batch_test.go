@@ -0,0 +1,100 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunAllOnce(t *testing.T) {
+	newProbe := func(name string, r Result, deps ...string) *Probe {
+		return &Probe{
+			Prober:       testProber{r},
+			Name:         name,
+			Interval:     time.Minute,
+			dependencies: deps,
+			t:            fakeTime{},
+		}
+	}
+	type want struct {
+		name    string
+		code    ResultCode
+		skipped bool
+	}
+	cases := []struct {
+		in      Probes
+		opts    []BatchOption
+		want    []want
+		wantErr bool
+	}{
+		{
+			in: Probes{
+				newProbe("web", Passed(), "db", "dns"),
+				newProbe("db", Passed(), "dns"),
+				newProbe("dns", Passed()),
+			},
+			want: []want{
+				{name: "dns", code: Pass},
+				{name: "db", code: Pass},
+				{name: "web", code: Pass},
+			},
+		},
+		{
+			in: Probes{
+				newProbe("dns", FailedWith(errors.New("no such host"))),
+				newProbe("db", Passed(), "dns"),
+				newProbe("web", Passed(), "db"),
+				newProbe("ntp", Passed()),
+			},
+			want: []want{
+				{name: "dns", code: Fail},
+				{name: "db", code: Fail, skipped: true},
+				{name: "web", code: Fail, skipped: true},
+				{name: "ntp", code: Pass},
+			},
+		},
+		{
+			in: Probes{
+				newProbe("dns", Passed()),
+				newProbe("db", FailedWith(errors.New("connection refused"))),
+				newProbe("ntp", Passed()),
+			},
+			opts: []BatchOption{FailFast()},
+			want: []want{
+				{name: "dns", code: Pass},
+				{name: "db", code: Fail},
+				{name: "ntp", code: Fail, skipped: true},
+			},
+		},
+		{
+			in: Probes{
+				newProbe("a", Passed(), "b"),
+				newProbe("b", Passed(), "a"),
+			},
+			wantErr: true,
+		},
+		{
+			in: Probes{
+				newProbe("a", Passed(), "missing"),
+			},
+			wantErr: true,
+		},
+	}
+	for i, tt := range cases {
+		got, err := RunAllOnce(context.Background(), tt.in, tt.opts...)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("[%d] RunAllOnce() => error %v; want error: %v\n", i, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("[%d] RunAllOnce() => %d reports; want %d\n", i, len(got), len(tt.want))
+			continue
+		}
+		for j, w := range tt.want {
+			if got[j].Name != w.name || got[j].Result.Code != w.code || got[j].Skipped != w.skipped {
+				t.Errorf("[%d] RunAllOnce()[%d] => %v; want %+v\n", i, j, got[j], w)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+)
+
+// errChaos is the error of results forced to fail by Chaos() or
+// InjectFailures().
+var errChaos = errors.New("failure injected for chaos testing")
+
+// Chaos makes a fraction of the probe's runs fail on purpose, without
+// calling Probe(), so alert routing can be tested end-to-end without
+// breaking real targets, e.g. Chaos(0.1) fails about one in ten runs.
+//
+// Injected failures have "chaos" set in their Details, so they can be
+// told apart from real ones. See also InjectFailures(), which forces
+// failures on demand.
+func Chaos(rate float64) func(*Probe) {
+	return func(p *Probe) {
+		p.chaosRate = rate
+	}
+}
+
+// InjectFailures forces the next n runs of the probe to fail, without
+// calling Probe(), e.g. to check that an alert reaches whoever is on
+// call. It works whether or not the probe has the Chaos() option.
+func (p *Probe) InjectFailures(n int) {
+	p.chaosLock.Lock()
+	defer p.chaosLock.Unlock()
+	p.chaosPending += n
+}
+
+// chaosResult returns a failed result and true if the run should fail
+// on purpose, and false if Probe() should be called as usual.
+func (p *Probe) chaosResult() (Result, bool) {
+	p.chaosLock.Lock()
+	defer p.chaosLock.Unlock()
+	reason := ""
+	if p.chaosPending > 0 {
+		p.chaosPending--
+		reason = "injected"
+	} else if p.chaosRate > 0 && rand.Float64() < p.chaosRate {
+		reason = "random"
+	}
+	if reason == "" {
+		return Result{}, false
+	}
+	log.Printf("[%s] Failing on purpose (%s chaos)\n", p.Name, reason)
+	return Result{
+		Code:    Fail,
+		Error:   fmt.Errorf("%s: %v", p.Name, errChaos),
+		Info:    "The run was failed on purpose, to test alerting",
+		Details: map[string]string{"chaos": reason},
+	}, true
+}
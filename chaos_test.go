@@ -0,0 +1,40 @@
+package prober
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestChaos(t *testing.T) {
+	cases := []struct {
+		rate        float64
+		inject      int
+		wantPassing []bool
+		wantChaos   []string
+	}{
+		{0, 0, []bool{true, true}, []string{"", ""}},
+		{0, 2, []bool{false, false, true}, []string{"injected", "injected", ""}},
+		{1, 0, []bool{false, false}, []string{"random", "random"}},
+		{1, 1, []bool{false, false}, []string{"injected", "random"}},
+	}
+	for i, tt := range cases {
+		p := &Probe{
+			Prober:   testProber{Passed()},
+			Name:     "ChaosProber",
+			Interval: time.Minute,
+			t:        realTime{},
+		}
+		Chaos(tt.rate)(p)
+		p.InjectFailures(tt.inject)
+		for j, want := range tt.wantPassing {
+			r, _ := p.probeOnce(context.Background())
+			if r.Passed() != want {
+				t.Errorf("[%d] run %d: probeOnce() => passed %v; want %v\n", i, j, r.Passed(), want)
+			}
+			if got := r.Details["chaos"]; got != tt.wantChaos[j] {
+				t.Errorf("[%d] run %d: probeOnce() => Details[chaos] %q; want %q\n", i, j, got, tt.wantChaos[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+)
+
+type (
+	// TelegramNotifier sends alerts and recovery notices as messages
+	// from a Telegram bot to a chat.
+	//
+	// Use its Alert and Recover methods as the AlertFn and RecoverFn of
+	// probers, see TicketNotifier for an example.
+	TelegramNotifier struct {
+		Token   string       // token of the bot, from @BotFather
+		ChatID  string       // ID of the chat to send to, e.g. "-1001234567890" or "@channel"
+		BaseURL string       // URL of the Bot API, or "" for "https://api.telegram.org"
+		Client  *http.Client // client to use, or nil for http.DefaultClient
+	}
+
+	// DiscordNotifier sends alerts and recovery notices to a Discord
+	// channel through a webhook.
+	//
+	// Use its Alert and Recover methods as the AlertFn and RecoverFn of
+	// probers, see TicketNotifier for an example.
+	DiscordNotifier struct {
+		WebhookURL string       // URL of the webhook, from the channel's integrations
+		Username   string       // name to post as, or "" for the webhook's name
+		Client     *http.Client // client to use, or nil for http.DefaultClient
+	}
+)
+
+// Colors of Discord embeds for alerts and recoveries.
+const (
+	discordRed   = 0xd9534f
+	discordGreen = 0x5cb85c
+)
+
+// Alert sends a message that the probe is alerting.
+func (tn TelegramNotifier) Alert(name, desc string, badness int, records Records) error {
+	text := fmt.Sprintf("🔴 <b>%s is alerting</b> (badness %d)\n%s", html.EscapeString(name), badness, html.EscapeString(desc))
+	if err := lastError(records); err != "" {
+		text += fmt.Sprintf("\n<pre>%s</pre>", html.EscapeString(err))
+	}
+	return tn.send(text)
+}
+
+// Recover sends a message that the probe has recovered.
+func (tn TelegramNotifier) Recover(name, desc string, records Records) error {
+	return tn.send(fmt.Sprintf("✅ <b>%s recovered</b>\n%s", html.EscapeString(name), html.EscapeString(desc)))
+}
+
+// Validate checks that the token of the bot and the chat are set, see
+// Notifiers().
+func (tn TelegramNotifier) Validate() ConfigErrors {
+	var errs ConfigErrors
+	if tn.Token == "" {
+		errs.add("Token", "must be set")
+	}
+	if tn.ChatID == "" {
+		errs.add("ChatID", "must be set")
+	}
+	return errs
+}
+
+// send sends the message, formatted as HTML.
+func (tn TelegramNotifier) send(text string) error {
+	base := tn.BaseURL
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	in := map[string]interface{}{
+		"chat_id":                  tn.ChatID,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	u := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimSuffix(base, "/"), tn.Token)
+	if err := sendJSON(tn.Client, "POST", u, nil, in, nil); err != nil {
+		// Don't leak the token of the bot, which is part of the URL.
+		return fmt.Errorf("failed to send Telegram message: %v", strings.ReplaceAll(err.Error(), tn.Token, "[REDACTED]"))
+	}
+	return nil
+}
+
+// Alert posts a message that the probe is alerting.
+func (dn DiscordNotifier) Alert(name, desc string, badness int, records Records) error {
+	embed := map[string]interface{}{
+		"title":       fmt.Sprintf("%s is alerting", name),
+		"description": desc,
+		"color":       discordRed,
+		"fields": []map[string]interface{}{
+			{"name": "Badness", "value": fmt.Sprint(badness), "inline": true},
+		},
+	}
+	if err := lastError(records); err != "" {
+		embed["fields"] = append(embed["fields"].([]map[string]interface{}), map[string]interface{}{
+			"name": "Last error", "value": "```" + err + "```",
+		})
+	}
+	return dn.send(embed)
+}
+
+// Recover posts a message that the probe has recovered.
+func (dn DiscordNotifier) Recover(name, desc string, records Records) error {
+	return dn.send(map[string]interface{}{
+		"title":       fmt.Sprintf("%s recovered", name),
+		"description": desc,
+		"color":       discordGreen,
+	})
+}
+
+// Validate checks that the URL of the webhook is set, see Notifiers().
+func (dn DiscordNotifier) Validate() ConfigErrors {
+	var errs ConfigErrors
+	validateURL(&errs, "WebhookURL", dn.WebhookURL, "http", "https")
+	return errs
+}
+
+// send posts the embed.
+func (dn DiscordNotifier) send(embed map[string]interface{}) error {
+	in := map[string]interface{}{"embeds": []interface{}{embed}}
+	if dn.Username != "" {
+		in["username"] = dn.Username
+	}
+	if err := sendJSON(dn.Client, "POST", dn.WebhookURL, nil, in, nil); err != nil {
+		// Don't leak the webhook URL, which works as a secret.
+		return fmt.Errorf("failed to post to Discord: %v", strings.ReplaceAll(err.Error(), dn.WebhookURL, "[REDACTED]"))
+	}
+	return nil
+}
+
+// lastError returns the error of the most recent failed record,
+// truncated to fit in a chat message, or "" if there is none.
+func lastError(records Records) string {
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i].Result
+		if r.Passed() || r.Error == nil {
+			continue
+		}
+		return truncate(r.Error.Error(), 1000)
+	}
+	return ""
+}
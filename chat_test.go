@@ -0,0 +1,118 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelegramNotifier(t *testing.T) {
+	var got []map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botsecret/sendMessage" {
+			http.Error(w, `{"ok": false}`, http.StatusNotFound)
+			return
+		}
+		var in map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&in)
+		got = append(got, in)
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer s.Close()
+
+	tn := TelegramNotifier{Token: "secret", ChatID: "@ops", BaseURL: s.URL}
+	records := Records{{Timestamp: time.Now(), Result: FailedWith(errors.New("status <503>"))}}
+	if err := tn.Alert("web", "Web is up.", 200, records); err != nil {
+		t.Fatalf("Alert() => %v; want nil\n", err)
+	}
+	if err := tn.Recover("web", "Web is up.", nil); err != nil {
+		t.Fatalf("Recover() => %v; want nil\n", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Telegram got %d messages; want 2\n", len(got))
+	}
+	if text := got[0]["text"].(string); !strings.Contains(text, "<b>web is alerting</b>") || !strings.Contains(text, "status &lt;503&gt;") {
+		t.Errorf("alert message is %q; want name and escaped error\n", text)
+	}
+	if text := got[1]["text"].(string); !strings.Contains(text, "web recovered") {
+		t.Errorf("recovery message is %q; want recovery\n", text)
+	}
+	if got[0]["chat_id"] != "@ops" || got[0]["parse_mode"] != "HTML" {
+		t.Errorf("got message %v; want chat ID and HTML parse mode\n", got[0])
+	}
+
+	tn.BaseURL = s.URL + "/missing"
+	if err := tn.Alert("web", "Web is up.", 200, nil); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("Alert() to failing API => %v; want error without token\n", err)
+	}
+}
+
+func TestDiscordNotifier(t *testing.T) {
+	var got []map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&in)
+		got = append(got, in)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	dn := DiscordNotifier{WebhookURL: s.URL + "/api/webhooks/1/token", Username: "prober"}
+	records := Records{{Timestamp: time.Now(), Result: FailedWith(errors.New("failing on purpose"))}}
+	if err := dn.Alert("web", "Web is up.", 200, records); err != nil {
+		t.Fatalf("Alert() => %v; want nil\n", err)
+	}
+	if err := dn.Recover("web", "Web is up.", nil); err != nil {
+		t.Fatalf("Recover() => %v; want nil\n", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Discord got %d messages; want 2\n", len(got))
+	}
+	for i, want := range []struct {
+		title string
+		color float64
+	}{
+		{"web is alerting", discordRed},
+		{"web recovered", discordGreen},
+	} {
+		embed := got[i]["embeds"].([]interface{})[0].(map[string]interface{})
+		if embed["title"] != want.title || embed["color"] != want.color {
+			t.Errorf("[%d] got embed %v; want title %q and color %x\n", i, embed, want.title, int(want.color))
+		}
+	}
+	if !strings.Contains(toJSON(t, got[0]), "failing on purpose") {
+		t.Errorf("alert %v doesn't include last error\n", got[0])
+	}
+}
+
+func toJSON(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestChatNotifiers_Validate(t *testing.T) {
+	cases := []struct {
+		v    Validator
+		want []string
+	}{
+		{TelegramNotifier{Token: "123:abc", ChatID: "@ops"}, nil},
+		{TelegramNotifier{ChatID: "@ops"}, []string{"Token"}},
+		{DiscordNotifier{WebhookURL: "https://discord.com/api/webhooks/1/abc"}, nil},
+		{DiscordNotifier{}, []string{"WebhookURL"}},
+	}
+	for i, tt := range cases {
+		if got := errPaths(tt.v.Validate()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%d] Validate() => %v; want problems at %v\n", i, tt.v.Validate(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package prober
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ChecksumProber is a Prober that downloads a URL and checks the
+// SHA-256 checksum of its contents, e.g. to monitor an artifact mirror
+// or detect tampering.
+//
+// If SHA256 is set, the contents must have that checksum. Otherwise the
+// checksum seen on the first successful download is pinned, and the
+// probe fails whenever the contents differ from it.
+//
+// The checksum and size of the contents are included in the Details of
+// the Result, as "sha256" and "size".
+//
+// The Alert() part of the Prober interface is provided by the embedded
+// AlertFn.
+type ChecksumProber struct {
+	AlertFn
+	URL    string       // URL to download
+	SHA256 string       // expected checksum in hex, or "" to pin the first one seen
+	Client *http.Client // client to use, or nil for http.DefaultClient
+	pinned atomic.Value // checksum seen on the first download, if SHA256 is ""
+}
+
+// Probe downloads the URL and checks its checksum.
+func (cp *ChecksumProber) Probe() Result {
+	client := cp.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(cp.URL)
+	if err != nil {
+		return FailedWith(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return FailedWith(fmt.Errorf("%s returned unexpected status %q", cp.URL, resp.Status))
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, resp.Body)
+	if err != nil {
+		return FailedWith(fmt.Errorf("failed to download %s: %v", cp.URL, err))
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	details := map[string]string{
+		"sha256": sum,
+		"size":   strconv.FormatInt(n, 10),
+	}
+
+	want := strings.ToLower(cp.SHA256)
+	if want == "" {
+		cp.pinned.CompareAndSwap(nil, sum)
+		want = cp.pinned.Load().(string)
+	}
+	if sum != want {
+		return Result{
+			Code:    Fail,
+			Error:   fmt.Errorf("%s has SHA-256 %s, want %s", cp.URL, sum, want),
+			InfoUrl: cp.URL,
+			Details: details,
+		}
+	}
+	r := PassedWith(fmt.Sprintf("%s has expected SHA-256 %s", cp.URL, sum), cp.URL)
+	r.Details = details
+	return r
+}
@@ -0,0 +1,40 @@
+package prober
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChecksumProber_Probe(t *testing.T) {
+	body := "firmware v1"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+	b := sha256.Sum256([]byte(body))
+	sum := hex.EncodeToString(b[:])
+
+	pinned := &ChecksumProber{URL: ts.URL}
+	if got := pinned.Probe(); !got.Passed() {
+		t.Fatalf("first Probe() => %v; want pass\n", got)
+	}
+	if got := pinned.Probe().Details["sha256"]; got != sum {
+		t.Errorf("Probe() has sha256 detail %q; want %q\n", got, sum)
+	}
+	if got := (&ChecksumProber{URL: ts.URL, SHA256: sum}).Probe(); !got.Passed() {
+		t.Errorf("Probe() with SHA256 %s => %v; want pass\n", sum, got)
+	}
+	if got := (&ChecksumProber{URL: ts.URL, SHA256: strings.Repeat("0", 64)}).Probe(); got.Passed() {
+		t.Errorf("Probe() with wrong SHA256 => %v; want fail\n", got)
+	}
+
+	body = "firmware v1, tampered"
+	if got := pinned.Probe(); got.Passed() {
+		t.Errorf("Probe() after contents changed => %v; want fail\n", got)
+	}
+}
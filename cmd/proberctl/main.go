@@ -0,0 +1,305 @@
+// Command proberctl manages the probes of a running prober over the
+// admin API served by prober.NewAdminHandler.
+//
+// Usage:
+//
+//	proberctl [flags] list
+//	proberctl [flags] top
+//	proberctl [flags] status <probe>
+//	proberctl [flags] explain <probe>
+//	proberctl [flags] silence <probe> <duration>
+//	proberctl [flags] run <probe>
+//	proberctl [flags] disable <probe>
+//	proberctl [flags] enable <probe>
+//	proberctl [flags] chaos <probe> <runs>
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type (
+	// client speaks the admin API of a prober.
+	client struct {
+		addr       string // base URL of the admin API
+		token      string // bearer token to send, if any
+		user, pass string // basic auth credentials to send, if any
+		http       *http.Client
+	}
+
+	// status is the part of a probe's status that proberctl shows.
+	status struct {
+		Name, Desc    string
+		Labels        map[string]string
+		Interval      time.Duration
+		Disabled      bool
+		SilencedUntil time.Time
+		Badness       int
+		Alerting      bool
+		Degraded      bool
+		LastSuccess   time.Time
+		LastFailure   time.Time
+	}
+
+	// result is the part of a probe result that proberctl shows.
+	result struct {
+		Code    string // "Pass" or "Fail"
+		Error   string
+		Info    string
+		Details map[string]string
+	}
+)
+
+var (
+	addr     = flag.String("addr", envOr("PROBERCTL_ADDR", "http://localhost:8080"), "base URL of the admin API")
+	token    = flag.String("token", os.Getenv("PROBERCTL_TOKEN"), "bearer token for the admin API")
+	user     = flag.String("user", "", "username for basic auth to the admin API")
+	password = flag.String("password", os.Getenv("PROBERCTL_PASSWORD"), "password for basic auth to the admin API")
+	timeout  = flag.Duration("timeout", time.Minute, "timeout for requests to the admin API")
+	refresh  = flag.Duration("refresh", 2*time.Second, "how often top refreshes the probes")
+	wait     = flag.Duration("wait", 30*time.Second, "how long run waits for the result, shorter than -timeout")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), `Usage: proberctl [flags] <command> [args]
+
+Commands:
+  list                        show all probes
+  top                         show probes live, with keys to silence, disable or run them
+  status <probe>              show the status of a probe
+  explain <probe>             show why a probe is or isn't alerting
+  silence <probe> <duration>  silence a probe, e.g. for 2h
+  run <probe>                 run a probe once, immediately, waiting up to -wait for the result
+  disable <probe>             stop running a probe
+  enable <probe>              start running a disabled probe again
+  chaos <probe> <runs>        fail the next runs of a probe on purpose
+
+Flags:
+`)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	c := &client{
+		addr:  *addr,
+		token: *token,
+		user:  *user,
+		pass:  *password,
+		http:  &http.Client{Timeout: *timeout},
+	}
+	if err := run(c, flag.Args(), os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "proberctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// envOr returns the value of the environment variable, or def if it's
+// not set.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// run runs the command given by args, writing its output to w.
+func run(c *client, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command given, see -help")
+	}
+	cmd, args := args[0], args[1:]
+	want := 1
+	switch cmd {
+	case "list", "top":
+		want = 0
+	case "silence", "chaos":
+		want = 2
+	}
+	if len(args) != want {
+		return fmt.Errorf("%s takes %d arguments, got %d", cmd, want, len(args))
+	}
+
+	switch cmd {
+	case "list":
+		var ss []status
+		if err := c.do(http.MethodGet, "/probes", nil, &ss); err != nil {
+			return err
+		}
+		return writeList(w, ss)
+	case "top":
+		return runTop(c, *refresh, w)
+	case "status", "disable", "enable":
+		method, path := http.MethodGet, probePath(args[0])
+		if cmd != "status" {
+			method, path = http.MethodPost, probePath(args[0], cmd)
+		}
+		var s status
+		if err := c.do(method, path, nil, &s); err != nil {
+			return err
+		}
+		return writeStatus(w, s)
+	case "silence":
+		d, err := time.ParseDuration(args[1])
+		if err != nil {
+			return fmt.Errorf("bad duration to silence for: %v", err)
+		}
+		var s status
+		if err := c.do(http.MethodPost, probePath(args[0], "silence"), url.Values{"for": {d.String()}}, &s); err != nil {
+			return err
+		}
+		return writeStatus(w, s)
+	case "chaos":
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("bad number of runs to fail: %v", err)
+		}
+		var s status
+		if err := c.do(http.MethodPost, probePath(args[0], "chaos"), url.Values{"runs": {strconv.Itoa(n)}}, &s); err != nil {
+			return err
+		}
+		return writeStatus(w, s)
+	case "explain":
+		var e struct{ Text string }
+		if err := c.do(http.MethodGet, probePath(args[0], "explain"), nil, &e); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, e.Text)
+		return err
+	case "run":
+		var r result
+		if err := c.do(http.MethodPost, probePath(args[0], "run"), url.Values{"wait": {wait.String()}}, &r); err != nil {
+			return err
+		}
+		if r.Code == "" {
+			// The run didn't finish in time, and the response is the
+			// status of the probe instead.
+			_, err := fmt.Fprintf(w, "%s is still running, see proberctl status %s\n", args[0], args[0])
+			return err
+		}
+		return writeResult(w, r)
+	default:
+		return fmt.Errorf("unknown command %q, see -help", cmd)
+	}
+}
+
+// probePath returns the path in the admin API for the probe.
+func probePath(name string, parts ...string) string {
+	return "/probes/" + url.PathEscape(name) + strings.Join(append([]string{""}, parts...), "/")
+}
+
+// do sends the request to the admin API and decodes the JSON response
+// into v.
+func (c *client) do(method, path string, query url.Values, v interface{}) error {
+	u := strings.TrimSuffix(c.addr, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.pass)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("bad response to %s %s: %v", method, path, err)
+	}
+	return nil
+}
+
+// state returns a short description of the state of the probe.
+func (s status) state() string {
+	switch {
+	case s.Disabled:
+		return "disabled"
+	case s.SilencedUntil.After(time.Now()):
+		return "silenced"
+	case s.Alerting:
+		return "alerting"
+	case s.Degraded:
+		return "degraded"
+	}
+	return "ok"
+}
+
+// writeList writes a table of the probes.
+func writeList(w io.Writer, ss []status) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATE\tBADNESS\tINTERVAL\tLAST SUCCESS")
+	for _, s := range ss {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%v\t%s\n", s.Name, s.state(), s.Badness, s.Interval, when(s.LastSuccess))
+	}
+	return tw.Flush()
+}
+
+// writeStatus writes the status of a probe.
+func writeStatus(w io.Writer, s status) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", s.Name)
+	fmt.Fprintf(tw, "Description:\t%s\n", s.Desc)
+	if len(s.Labels) > 0 {
+		fmt.Fprintf(tw, "Labels:\t%v\n", s.Labels)
+	}
+	fmt.Fprintf(tw, "State:\t%s\n", s.state())
+	if s.SilencedUntil.After(time.Now()) {
+		fmt.Fprintf(tw, "Silenced until:\t%s\n", s.SilencedUntil.Format(time.RFC3339))
+	}
+	fmt.Fprintf(tw, "Badness:\t%d\n", s.Badness)
+	fmt.Fprintf(tw, "Interval:\t%v\n", s.Interval)
+	fmt.Fprintf(tw, "Last success:\t%s\n", when(s.LastSuccess))
+	fmt.Fprintf(tw, "Last failure:\t%s\n", when(s.LastFailure))
+	return tw.Flush()
+}
+
+// writeResult writes the result of a probe run.
+func writeResult(w io.Writer, r result) error {
+	if r.Error != "" {
+		fmt.Fprintf(w, "%s: %s\n", r.Code, r.Error)
+	} else {
+		fmt.Fprintln(w, r.Code)
+	}
+	if r.Info != "" {
+		fmt.Fprintln(w, r.Info)
+	}
+	keys := make([]string, 0, len(r.Details))
+	for k := range r.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s: %s\n", k, r.Details[k])
+	}
+	return nil
+}
+
+// when returns the time in RFC 3339 format, or "never" for the zero
+// value.
+func when(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
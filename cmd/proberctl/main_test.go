@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+// testProber always passes.
+type testProber struct{ prober.AlertFn }
+
+func (testProber) Probe() prober.Result { return prober.Passed() }
+
+func TestRun(t *testing.T) {
+	p := prober.NewProbe(testProber{}, "web", "Web server is up.", prober.Interval(time.Hour))
+	defer p.Disable()
+	reg := prober.NewRegistry(p)
+	srv := httptest.NewServer(prober.NewAdminHandler(reg, prober.BearerToken("s3cret")))
+	defer srv.Close()
+	c := &client{addr: srv.URL, token: "s3cret", http: http.DefaultClient}
+
+	cases := []struct {
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{args: []string{"list"}, want: "web "},
+		{args: []string{"status", "web"}, want: "Web server is up."},
+		{args: []string{"silence", "web", "2h"}, want: "silenced"},
+		{args: []string{"chaos", "web", "1"}, want: "web"},
+		{args: []string{"run", "web"}, want: "Fail"},
+		{args: []string{"run", "web"}, want: "Pass"},
+		{args: []string{"explain", "web"}, want: "not alerting, since silenced"},
+		{args: []string{"disable", "web"}, want: "disabled"},
+		{args: []string{"enable", "web"}, want: "silenced"},
+		{args: []string{"status", "nosuchprobe"}, wantErr: true},
+		{args: []string{"silence", "web", "forever"}, wantErr: true},
+		{args: []string{"chaos", "web", "some"}, wantErr: true},
+		{args: []string{"status"}, wantErr: true},
+		{args: []string{"frobnicate", "web"}, wantErr: true},
+		{args: nil, wantErr: true},
+	}
+	for i, tt := range cases {
+		var out bytes.Buffer
+		err := run(c, tt.args, &out)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("[%d] run(%q) => %v; want error: %v\n", i, tt.args, err, tt.wantErr)
+			continue
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("[%d] run(%q) wrote %q; want it to contain %q\n", i, tt.args, out.String(), tt.want)
+		}
+	}
+
+	c.token = "wrong"
+	if err := run(c, []string{"list"}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("run(list) with wrong token => %v; want 401 error\n", err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// rawMode puts the terminal in raw mode, so keys can be read as they
+// are pressed, and returns a function restoring its previous mode.
+func rawMode(f *os.File) (func(), error) {
+	return setRaw(int(f.Fd()), unix.TIOCGETA, unix.TIOCSETA)
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package main
+
+import "os"
+
+// rawMode does nothing on platforms without termios, so keys are only
+// read once Enter is pressed.
+func rawMode(f *os.File) (func(), error) {
+	return func() {}, nil
+}
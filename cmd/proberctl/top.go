@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Escape sequences used by top.
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	reverse     = "\x1b[7m"
+	reset       = "\x1b[0m"
+)
+
+// top is an interactive view of the probes, refreshed periodically.
+type top struct {
+	c        *client
+	w        io.Writer
+	statuses []status // probes as of the last refresh, in the order of /probes
+	selected int      // index of the selected probe in statuses
+	msg      string   // outcome of the last action, shown at the bottom
+}
+
+// runTop shows the probes until q is pressed, refreshing them every
+// refresh and reading keys from stdin.
+func runTop(c *client, refresh time.Duration, w io.Writer) error {
+	restore, err := rawMode(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read keys from the terminal: %v", err)
+	}
+	defer restore()
+	keys := make(chan byte)
+	go func() {
+		b := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(b); err != nil {
+				close(keys)
+				return
+			}
+			keys <- b[0]
+		}
+	}()
+
+	t := &top{c: c, w: w}
+	tick := time.NewTicker(refresh)
+	defer tick.Stop()
+	for {
+		t.refresh()
+		t.render()
+		select {
+		case k, ok := <-keys:
+			if !ok || !t.handleKey(k) {
+				fmt.Fprint(w, clearScreen)
+				return nil
+			}
+		case <-tick.C:
+		}
+	}
+}
+
+// refresh fetches the status of the probes.
+func (t *top) refresh() {
+	var ss []status
+	if err := t.c.do(http.MethodGet, "/probes", nil, &ss); err != nil {
+		t.msg = err.Error()
+		return
+	}
+	// Keep the same probe selected even if it moved.
+	name := ""
+	if s, ok := t.current(); ok {
+		name = s.Name
+	}
+	t.statuses = ss
+	t.selected = 0
+	for i, s := range ss {
+		if s.Name == name {
+			t.selected = i
+		}
+	}
+}
+
+// current returns the selected probe, if any.
+func (t *top) current() (status, bool) {
+	if t.selected < 0 || t.selected >= len(t.statuses) {
+		return status{}, false
+	}
+	return t.statuses[t.selected], true
+}
+
+// handleKey acts on the key, returning false if top should quit.
+func (t *top) handleKey(k byte) bool {
+	switch k {
+	case 'q', 3: // 3 is ctrl-c, which raw mode doesn't turn into a signal
+		return false
+	case 'j', 'B': // 'B' ends the escape sequence of the down arrow
+		if t.selected < len(t.statuses)-1 {
+			t.selected++
+		}
+	case 'k', 'A': // 'A' ends the escape sequence of the up arrow
+		if t.selected > 0 {
+			t.selected--
+		}
+	case 's':
+		t.act("silence", url.Values{"for": {time.Hour.String()}}, "silenced for 1h")
+	case 'u':
+		t.act("silence", url.Values{"for": {"0s"}}, "unsilenced")
+	case 'd':
+		if s, ok := t.current(); ok && s.Disabled {
+			t.act("enable", nil, "enabled")
+		} else {
+			t.act("disable", nil, "disabled")
+		}
+	case 'r':
+		s, ok := t.current()
+		if !ok {
+			return true
+		}
+		var r result
+		if err := t.c.do(http.MethodPost, probePath(s.Name, "run"), url.Values{"wait": {wait.String()}}, &r); err != nil {
+			t.msg = err.Error()
+			return true
+		}
+		if r.Code == "" {
+			t.msg = fmt.Sprintf("%s: still running", s.Name)
+			return true
+		}
+		t.msg = fmt.Sprintf("%s: %s %s", s.Name, r.Code, r.Error)
+	}
+	return true
+}
+
+// act posts the action for the selected probe to the admin API.
+func (t *top) act(action string, query url.Values, done string) {
+	s, ok := t.current()
+	if !ok {
+		return
+	}
+	var updated status
+	if err := t.c.do(http.MethodPost, probePath(s.Name, action), query, &updated); err != nil {
+		t.msg = err.Error()
+		return
+	}
+	t.statuses[t.selected] = updated
+	t.msg = fmt.Sprintf("%s: %s", s.Name, done)
+}
+
+// render draws the probes, with the selected one highlighted.
+func (t *top) render() {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	fmt.Fprintf(&b, "proberctl top - %s - %d probes\r\n\r\n", t.c.addr, len(t.statuses))
+	var table strings.Builder
+	tw := tabwriter.NewWriter(&table, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATE\tBADNESS\tINTERVAL\tLAST SUCCESS\t")
+	for _, s := range t.statuses {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%v\t%s\t\n", s.Name, s.state(), s.Badness, s.Interval, when(s.LastSuccess))
+	}
+	tw.Flush()
+	for i, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		if i > 0 && i-1 == t.selected {
+			line = reverse + line + reset
+		}
+		b.WriteString(line + "\r\n")
+	}
+	fmt.Fprintf(&b, "\r\n%s\r\n", t.msg)
+	b.WriteString("j/k: move  s: silence 1h  u: unsilence  d: disable/enable  r: run  q: quit\r\n")
+	fmt.Fprint(t.w, b.String())
+}
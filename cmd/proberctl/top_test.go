@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+func TestTop(t *testing.T) {
+	web := prober.NewProbe(testProber{}, "web", "Web server is up.", prober.Interval(time.Hour))
+	defer web.Disable()
+	db := prober.NewProbe(testProber{}, "db", "Database is up.", prober.Interval(time.Hour))
+	defer db.Disable()
+	srv := httptest.NewServer(prober.NewAdminHandler(prober.NewRegistry(web, db), prober.Insecure()))
+	defer srv.Close()
+	var out bytes.Buffer
+	tp := &top{c: &client{addr: srv.URL, http: http.DefaultClient}, w: &out}
+
+	cases := []struct {
+		key      byte
+		wantMsg  string
+		wantQuit bool
+	}{
+		{key: 'j'},
+		{key: 's', wantMsg: "web: silenced for 1h"},
+		{key: 'd', wantMsg: "web: disabled"},
+		{key: 'd', wantMsg: "web: enabled"},
+		{key: 'k'},
+		{key: 'r', wantMsg: "db: Pass"},
+		{key: 'q', wantQuit: true},
+	}
+	for i, tt := range cases {
+		tp.refresh()
+		if got := tp.handleKey(tt.key); got == tt.wantQuit {
+			t.Errorf("[%d] handleKey(%q) => %v; want %v\n", i, tt.key, got, !tt.wantQuit)
+		}
+		if !strings.HasPrefix(tp.msg, tt.wantMsg) {
+			t.Errorf("[%d] handleKey(%q) set message %q; want %q\n", i, tt.key, tp.msg, tt.wantMsg)
+		}
+	}
+
+	tp.refresh()
+	out.Reset()
+	tp.render()
+	lines := strings.Split(out.String(), "\r\n")
+	if len(lines) < 5 || !strings.Contains(lines[3], reverse+"db ") || !strings.Contains(lines[4], "silenced") {
+		t.Errorf("render() => %q; want db selected, then web silenced\n", out.String())
+	}
+}
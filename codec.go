@@ -0,0 +1,147 @@
+package prober
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var logFormat = flag.String("log_format", "yaml", "encoding of records in the log file, the name of a registered Codec such as yaml, json or protobuf")
+
+type (
+	// Codec encodes and decodes records, for the log file and for
+	// shipping records to an Aggregator. YAML, JSON and Protobuf are
+	// built in, and others, e.g. CBOR, can be added with
+	// RegisterCodec().
+	Codec interface {
+		// Name is a short name for the encoding, as given to
+		// -log_format, e.g. "yaml".
+		Name() string
+		// ContentType is the MIME type of encoded values, used to
+		// decode shipped records.
+		ContentType() string
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(b []byte, v interface{}) error
+	}
+
+	// yamlCodec encodes values as YAML documents.
+	yamlCodec struct{}
+
+	// jsonCodec encodes values as JSON, one per line.
+	jsonCodec struct{}
+)
+
+var (
+	// YAML encodes records as YAML, which is the default for the log
+	// file.
+	YAML Codec = yamlCodec{}
+	// JSON encodes records as JSON, one per line, which is the default
+	// for shipping records.
+	JSON Codec = jsonCodec{}
+)
+
+var (
+	codecs     = map[string]Codec{"yaml": YAML, "json": JSON} // registered codecs by name
+	codecsLock sync.RWMutex                                   // protects codecs
+)
+
+func (yamlCodec) Name() string                            { return "yaml" }
+func (yamlCodec) ContentType() string                     { return "application/yaml" }
+func (yamlCodec) Marshal(v interface{}) ([]byte, error)   { return yaml.Marshal(v) }
+func (yamlCodec) Unmarshal(b []byte, v interface{}) error { return yaml.Unmarshal(b, v) }
+
+func (jsonCodec) Name() string        { return "json" }
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func (jsonCodec) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(bytes.TrimSpace(b), v)
+}
+
+// RegisterCodec makes the codec available by its name, e.g. for
+// -log_format, and by its content type for decoding shipped records.
+func RegisterCodec(c Codec) {
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+	codecs[c.Name()] = c
+}
+
+// CodecNamed returns the registered codec with the name.
+func CodecNamed(name string) (Codec, error) {
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+	c, ok := codecs[name]
+	if !ok {
+		names := make([]string, 0, len(codecs))
+		for n := range codecs {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("no codec %q, want one of %s", name, strings.Join(names, ", "))
+	}
+	return c, nil
+}
+
+// codecForContentType returns the registered codec with the content
+// type, ignoring any parameters like charset, or JSON if it's empty.
+func codecForContentType(contentType string) (Codec, bool) {
+	ct := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	if ct == "" {
+		return JSON, true
+	}
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+	for _, c := range codecs {
+		if c.ContentType() == ct {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// LogCodec makes the probe encode its records in the log file with the
+// codec, instead of the one given by -log_format or the LogCodec of its
+// Engine. Since all records in a log file should have the same
+// encoding, setting Engine.LogCodec is usually simpler.
+func LogCodec(c Codec) func(*Probe) {
+	return func(p *Probe) {
+		p.logCodec = c
+	}
+}
+
+// ShipCodec makes the probe encode the records it ships to an
+// Aggregator with the codec, instead of JSON. The codec must be
+// registered with the aggregator's process too.
+func ShipCodec(c Codec) func(*Probe) {
+	return func(p *Probe) {
+		p.shipCodec = c
+	}
+}
+
+// recordCodec returns the codec for records in the probe's log file.
+func (p *Probe) recordCodec() Codec {
+	if p.logCodec != nil {
+		return p.logCodec
+	}
+	if p.engine != nil && p.engine.LogCodec != nil {
+		return p.engine.LogCodec
+	}
+	c, err := CodecNamed(*logFormat)
+	if err != nil {
+		return YAML
+	}
+	return c
+}
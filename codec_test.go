@@ -0,0 +1,99 @@
+package prober
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodecs(t *testing.T) {
+	ts := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	in := Record{
+		Timestamp: ts,
+		Location:  "eu",
+		Result:    FailedWith(errors.New("failing on purpose")),
+	}.shipped("CodecProber")
+	for _, name := range []string{"yaml", "json"} {
+		c, err := CodecNamed(name)
+		if err != nil {
+			t.Fatalf("CodecNamed(%q) => %v; want nil\n", name, err)
+		}
+		b, err := c.Marshal(in)
+		if err != nil {
+			t.Fatalf("[%s] Marshal() => %v; want nil\n", name, err)
+		}
+		var out shippedRecord
+		if err := c.Unmarshal(b, &out); err != nil {
+			t.Fatalf("[%s] Unmarshal(%q) => %v; want nil\n", name, b, err)
+		}
+		if !out.record().Equal(in.record()) {
+			t.Errorf("[%s] round trip => %+v; want %+v\n", name, out, in)
+		}
+		if got, ok := codecForContentType(c.ContentType() + "; charset=utf-8"); !ok || got != c {
+			t.Errorf("[%s] codecForContentType(%q) => %v, %v; want the codec\n", name, c.ContentType(), got, ok)
+		}
+	}
+	if _, err := CodecNamed("cbor"); err == nil {
+		t.Errorf("CodecNamed(cbor) => nil; want error for unregistered codec\n")
+	}
+	if _, ok := codecForContentType("application/cbor"); ok {
+		t.Errorf("codecForContentType(application/cbor) => ok; want unregistered\n")
+	}
+}
+
+func TestLogCodec(t *testing.T) {
+	e := &Engine{LogPath: filepath.Join(t.TempDir(), "records.log"), LogCodec: JSON}
+	p := e.NewProbe(testProber{Passed()}, "CodecProber", "Logs JSON.")
+	defer p.Disable()
+	p.handleResult(p.Probe())
+	p.handleResult(p.Probe())
+	e.FlushLog()
+	b, err := os.ReadFile(e.LogPath)
+	if err != nil {
+		t.Fatalf("ReadFile() => %v; want nil\n", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log file has %d lines; want 2 JSON records: %q\n", len(lines), b)
+	}
+	for i, line := range lines {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil || !r.Result.Passed() {
+			t.Errorf("[%d] log line %q => %+v, %v; want passed record\n", i, line, r, err)
+		}
+	}
+}
+
+func TestAggregator_ServeHTTP_codecs(t *testing.T) {
+	a := NewAggregator(1, func(string, string, int, Records) error { return nil })
+	in := Record{Timestamp: time.Now(), Location: "eu", Result: Passed()}.shipped("CodecProber")
+	yml, _ := YAML.Marshal(in)
+	cases := []struct {
+		contentType string
+		body        []byte
+		want        int
+	}{
+		{"application/yaml", yml, http.StatusNoContent},
+		{"", []byte(`{"probe": "CodecProber", "location": "us"}`), http.StatusNoContent},
+		{"application/cbor", []byte{0xa0}, http.StatusUnsupportedMediaType},
+		{"application/yaml", []byte("probe: [unclosed"), http.StatusBadRequest},
+	}
+	for i, tt := range cases {
+		req := httptest.NewRequest("POST", "/records", bytes.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("[%d] POST with %q => %d; want %d\n", i, tt.contentType, w.Code, tt.want)
+		}
+	}
+}
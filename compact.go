@@ -0,0 +1,61 @@
+package prober
+
+// CompactRecords makes the probe merge runs of passing records into a
+// single record, so that probes with short intervals can keep long
+// history in memory and in the YAML log.
+//
+// A passing record is merged into the previous one if that passed too
+// with the same Info, InfoUrl, Location, Weight and Annotations, and
+// neither has a Value, since the history of values is kept; Details,
+// e.g. timings, of the merged records are not kept. The merged record
+// counts the further runs in Repeats, and the time of the last one in
+// Until.
+// In the YAML log, the first record of a run is written as usual, and
+// written again with Repeats set once the run ends.
+func CompactRecords() func(*Probe) {
+	return func(p *Probe) {
+		p.compact = true
+	}
+}
+
+// mergeable returns true if the record can be merged into r.
+func (r Record) mergeable(next Record) bool {
+	return r.Result.Passed() && next.Result.Passed() &&
+		r.Location == next.Location &&
+		r.Result.Info == next.Result.Info &&
+		r.Result.InfoUrl == next.Result.InfoUrl &&
+		r.Result.Weight == next.Result.Weight &&
+		r.Result.Value == nil && next.Result.Value == nil &&
+		equalStrings(r.Annotations, next.Annotations)
+}
+
+// mergeRecord merges the record into the most recent one if possible,
+// returning true if it did. If it didn't, and the most recent record
+// had records merged into it, a copy of it is returned as well, since
+// that run of records just ended.
+func (p *Probe) mergeRecord(r Record) (*Record, bool) {
+	p.recordsLock.Lock()
+	if len(p.records) == 0 {
+		p.recordsLock.Unlock()
+		return nil, false
+	}
+	last := &p.records[len(p.records)-1]
+	if !last.mergeable(r) {
+		var ended *Record
+		if last.Repeats > 0 {
+			c := *last
+			ended = &c
+		}
+		p.recordsLock.Unlock()
+		return ended, false
+	}
+	before := last.size()
+	last.Repeats++
+	last.Until = r.Timestamp
+	last.UntilMillis = p.formatTime(r.Timestamp)
+	added := last.size() - before
+	p.recordBytes += added
+	p.recordsLock.Unlock()
+	trackRecordBytes(p, added)
+	return nil, true
+}
@@ -0,0 +1,69 @@
+package prober
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProbe_mergeRecord(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{Name: "CompactProber"}
+	defer forgetRecords(p)
+	fail := FailedWith(errors.New("failing on purpose"))
+	ended := 0
+	for i, res := range []Result{Passed(), Passed(), Passed(), fail, fail, Passed(), Passed()} {
+		r := Record{Timestamp: start.Add(time.Duration(i) * time.Minute), Result: res}
+		e, merged := p.mergeRecord(r)
+		if e != nil {
+			ended++
+			if e.Repeats != 2 {
+				t.Errorf("[%d] mergeRecord() returned ended record %v; want Repeats 2\n", i, e)
+			}
+		}
+		if !merged {
+			p.addRecord(r)
+		}
+	}
+	if ended != 1 {
+		t.Errorf("mergeRecord() returned %d ended records; want 1\n", ended)
+	}
+	rs := p.Records()
+	if len(rs) != 4 {
+		t.Fatalf("after merging, Records() => %v; want 4 records\n", rs)
+	}
+	if rs[0].Repeats != 2 || !rs[0].Until.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("first record is %v; want 2 repeats until %v\n", rs[0], start.Add(2*time.Minute))
+	}
+	if rs[1].Repeats != 0 || rs[2].Repeats != 0 || rs[3].Repeats != 1 {
+		t.Errorf("Records() => %v; want failures unmerged and last passes merged\n", rs)
+	}
+}
+
+func TestProbe_mergeRecord_concurrent(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{Name: "CompactProber"}
+	defer forgetRecords(p)
+	p.addRecord(Record{Timestamp: start, Result: Passed()})
+
+	// Readers of Records() must not see the last record change under
+	// them while runs are merged into it.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 1000; i++ {
+			p.mergeRecord(Record{Timestamp: start.Add(time.Duration(i) * time.Minute), Result: Passed()})
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		for _, r := range p.Records() {
+			_ = r.Repeats + len(r.UntilMillis)
+		}
+	}
+	wg.Wait()
+	if rs := p.Records(); len(rs) != 1 || rs[0].Repeats != 1000 {
+		t.Errorf("Records() => %v; want 1 record with 1000 repeats\n", rs)
+	}
+}
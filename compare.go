@@ -0,0 +1,133 @@
+package prober
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CompareProber is a Prober that sends the same request to two
+// targets, e.g. a canary and production, and fails if the responses
+// diverge by more than the configured tolerances.
+//
+// The Alert() part of the Prober interface is provided by the embedded
+// AlertFn.
+type CompareProber struct {
+	AlertFn
+	Client              *http.Client  // client to use, or nil for http.DefaultClient
+	Method              string        // HTTP method to use, or "" for GET
+	Header              http.Header   // extra headers to send with both requests
+	Baseline, Candidate string        // URLs of the targets to compare, e.g. production and canary
+	IgnoreStatus        bool          // whether differing status codes are tolerated
+	MaxLatencyDelta     time.Duration // how much slower Candidate may be than Baseline, or 0 to not compare latency
+	IgnoreBody          bool          // whether to skip comparing response bodies
+	MaxBodyDiff         float64       // fraction of body lines that may differ, where 0 requires identical bodies
+}
+
+// response is the outcome of a single request made by CompareProber.
+type response struct {
+	status  int
+	body    []byte
+	latency time.Duration
+	err     error
+}
+
+// Probe sends the request to both targets and compares the responses.
+func (cp CompareProber) Probe() Result {
+	c := make(chan response, 1)
+	go func() { c <- cp.fetch(cp.Candidate) }()
+	base := cp.fetch(cp.Baseline)
+	cand := <-c
+
+	if base.err != nil {
+		return FailedWith(fmt.Errorf("baseline %s: %v", cp.Baseline, base.err))
+	}
+	if cand.err != nil {
+		return FailedWith(fmt.Errorf("candidate %s: %v", cp.Candidate, cand.err))
+	}
+	info := fmt.Sprintf(
+		"baseline %s: %d in %v, candidate %s: %d in %v",
+		cp.Baseline, base.status, base.latency,
+		cp.Candidate, cand.status, cand.latency)
+	if !cp.IgnoreStatus && base.status != cand.status {
+		return FailedWithInfo(
+			fmt.Errorf("status %d from candidate differs from %d from baseline", cand.status, base.status),
+			info, cp.Candidate)
+	}
+	if cp.MaxLatencyDelta > 0 {
+		if delta := cand.latency - base.latency; delta > cp.MaxLatencyDelta {
+			return FailedWithInfo(
+				fmt.Errorf("candidate was %v slower than baseline, more than allowed %v", delta, cp.MaxLatencyDelta),
+				info, cp.Candidate)
+		}
+	}
+	if !cp.IgnoreBody {
+		if diff := bodyDiff(base.body, cand.body); diff > cp.MaxBodyDiff {
+			return FailedWithInfo(
+				fmt.Errorf("%.1f%% of body lines differ, more than allowed %.1f%%", diff*100, cp.MaxBodyDiff*100),
+				info, cp.Candidate)
+		}
+	}
+	return PassedWith(info, cp.Candidate)
+}
+
+// fetch sends the request to the URL and reads the response.
+func (cp CompareProber) fetch(url string) response {
+	client := cp.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	method := cp.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return response{err: err}
+	}
+	for k, vs := range cp.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return response{err: err}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return response{err: err}
+	}
+	return response{
+		status:  resp.StatusCode,
+		body:    body,
+		latency: time.Since(start),
+	}
+}
+
+// bodyDiff returns the fraction of lines that differ between the two
+// bodies, ignoring the order of the lines.
+func bodyDiff(b1, b2 []byte) float64 {
+	lines1 := strings.Split(string(b1), "\n")
+	lines2 := strings.Split(string(b2), "\n")
+	counts := map[string]int{}
+	for _, l := range lines1 {
+		counts[l]++
+	}
+	common := 0
+	for _, l := range lines2 {
+		if counts[l] > 0 {
+			counts[l]--
+			common++
+		}
+	}
+	total := len(lines1)
+	if len(lines2) > total {
+		total = len(lines2)
+	}
+	return float64(total-common) / float64(total)
+}
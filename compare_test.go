@@ -0,0 +1,65 @@
+package prober
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompareProber_Probe(t *testing.T) {
+	serve := func(status int, body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			fmt.Fprint(w, body)
+		}))
+	}
+	base := serve(200, "a\nb\nc\nd")
+	defer base.Close()
+	sameBody := serve(200, "d\nc\nb\na")
+	defer sameBody.Close()
+	otherStatus := serve(500, "a\nb\nc\nd")
+	defer otherStatus.Close()
+	otherBody := serve(200, "a\nb\nc\nx")
+	defer otherBody.Close()
+
+	cases := []struct {
+		in   CompareProber
+		want ResultCode
+	}{
+		{
+			in:   CompareProber{Baseline: base.URL, Candidate: sameBody.URL},
+			want: Pass,
+		},
+		{
+			in:   CompareProber{Baseline: base.URL, Candidate: otherStatus.URL},
+			want: Fail,
+		},
+		{
+			in:   CompareProber{Baseline: base.URL, Candidate: otherStatus.URL, IgnoreStatus: true},
+			want: Pass,
+		},
+		{
+			in:   CompareProber{Baseline: base.URL, Candidate: otherBody.URL},
+			want: Fail,
+		},
+		{
+			in:   CompareProber{Baseline: base.URL, Candidate: otherBody.URL, MaxBodyDiff: 0.25},
+			want: Pass,
+		},
+		{
+			in:   CompareProber{Baseline: base.URL, Candidate: otherBody.URL, IgnoreBody: true},
+			want: Pass,
+		},
+		{
+			in:   CompareProber{Baseline: base.URL, Candidate: "http://127.0.0.1:0"},
+			want: Fail,
+		},
+	}
+	for i, tt := range cases {
+		got := tt.in.Probe()
+		if got.Code != tt.want {
+			t.Errorf("[%d] %+v.Probe() => %v; want code %v\n", i, tt.in, got, tt.want)
+		}
+	}
+}
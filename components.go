@@ -0,0 +1,199 @@
+package prober
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+// The states of probes and components, from best to worst, see
+// Probe.State().
+const (
+	StateDisabled    = "disabled"
+	StateOperational = "operational"
+	StateDegraded    = "degraded"
+	StateOutage      = "outage"
+)
+
+// stateRank orders the states from best to worst.
+var stateRank = map[string]int{
+	StateDisabled:    0,
+	StateOperational: 1,
+	StateDegraded:    2,
+	StateOutage:      3,
+}
+
+type (
+	// ComponentStatus is the aggregate status of the probes of a
+	// component, see InComponent().
+	ComponentStatus struct {
+		Name   string
+		State  string         // worst state of the probes, see Probe.State()
+		Probes []string       // names of the probes in the component
+		Counts map[string]int // number of probes in each state
+	}
+
+	// componentRule is a condition on the status of a component on
+	// which to alert, see Registry.AlertOnComponent().
+	componentRule struct {
+		component string
+		when      *Expr
+		alert     func(ComponentStatus) error
+		alerting  bool // whether the condition held at the last check
+	}
+)
+
+// InComponent makes the probe part of the named component, e.g. "api"
+// for the probes of the endpoints of an API, so that its status counts
+// towards the aggregate status of the component, see
+// Registry.Components().
+func InComponent(name string) func(*Probe) {
+	return func(p *Probe) {
+		p.component = name
+	}
+}
+
+// Component returns the name of the component the probe is part of, or
+// "" if none.
+func (p *Probe) Component() string {
+	return p.component
+}
+
+// State returns the state of the probe: StateOutage if it's alerting,
+// StateDegraded if it's degraded, StateDisabled if it's disabled and
+// StateOperational otherwise.
+func (p *Probe) State() string {
+	switch {
+	case p.IsDisabled():
+		return StateDisabled
+	case p.IsAlerting():
+		return StateOutage
+	case p.IsDegraded():
+		return StateDegraded
+	}
+	return StateOperational
+}
+
+// WorstState returns the worst of the states, or StateDisabled if
+// there are none.
+func WorstState(states ...string) string {
+	worst := StateDisabled
+	for _, s := range states {
+		if stateRank[s] > stateRank[worst] {
+			worst = s
+		}
+	}
+	return worst
+}
+
+// Components returns the status of each component of the probes in the
+// registry, sorted by name. The state of a component is the worst state
+// of its probes.
+func (r *Registry) Components() []ComponentStatus {
+	byName := map[string]*ComponentStatus{}
+	for _, p := range r.Probes() {
+		name := p.Component()
+		if name == "" {
+			continue
+		}
+		c, ok := byName[name]
+		if !ok {
+			c = &ComponentStatus{Name: name, State: StateDisabled, Counts: map[string]int{}}
+			byName[name] = c
+		}
+		state := p.State()
+		c.Probes = append(c.Probes, p.Name)
+		c.Counts[state]++
+		c.State = WorstState(c.State, state)
+	}
+	cs := make([]ComponentStatus, 0, len(byName))
+	for _, c := range byName {
+		sort.Strings(c.Probes)
+		cs = append(cs, *c)
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Name < cs[j].Name })
+	return cs
+}
+
+// AlertOnComponent makes the registry call fn when the expression over
+// the status of the named component starts to hold, e.g.
+//
+//	r.AlertOnComponent("api", `outage >= 2 || state == "outage" && probes == 1`, notify)
+//
+// See Expr for the language. The variables are the number of probes of
+// the component that are operational, degraded, in outage or disabled,
+// the total number of probes, and the aggregate state of the component.
+//
+// The conditions are checked while the registry runs, as often as it
+// checks for stalled probes. fn is called again only after the
+// condition has stopped holding for a while.
+func (r *Registry) AlertOnComponent(component, expr string, fn func(ComponentStatus) error) error {
+	e, err := ParseExpr(expr)
+	if err != nil {
+		return err
+	}
+	if _, err := e.Check(componentVars(ComponentStatus{State: StateOperational})); err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.componentRules = append(r.componentRules, &componentRule{component: component, when: e, alert: fn})
+	return nil
+}
+
+// checkComponents checks the conditions of AlertOnComponent(), calling
+// the functions of those that started to hold.
+func (r *Registry) checkComponents() {
+	r.lock.RLock()
+	rules := r.componentRules
+	r.lock.RUnlock()
+	if len(rules) == 0 {
+		return
+	}
+	statuses := map[string]ComponentStatus{}
+	for _, c := range r.Components() {
+		statuses[c.Name] = c
+	}
+	for _, rule := range rules {
+		c, ok := statuses[rule.component]
+		if !ok {
+			c = ComponentStatus{Name: rule.component, State: StateOperational}
+		}
+		holds, err := rule.when.Check(componentVars(c))
+		if err != nil {
+			log.Printf("failed to check alert condition %v of component %s: %v\n", rule.when, rule.component, err)
+			continue
+		}
+		r.lock.Lock()
+		start := holds && !rule.alerting
+		rule.alerting = holds
+		r.lock.Unlock()
+		if !start {
+			continue
+		}
+		log.Printf("component %s is alerting, since %v holds\n", rule.component, rule.when)
+		go func(rule *componentRule) {
+			if err := rule.alert(c); err != nil {
+				log.Printf("failed to alert on component %s: %v\n", rule.component, err)
+			}
+		}(rule)
+	}
+}
+
+// componentVars returns the variables for conditions over the status
+// of the component.
+func componentVars(c ComponentStatus) Vars {
+	return Vars{
+		"operational": c.Counts[StateOperational],
+		"degraded":    c.Counts[StateDegraded],
+		"outage":      c.Counts[StateOutage],
+		"disabled":    c.Counts[StateDisabled],
+		"probes":      len(c.Probes),
+		"state":       c.State,
+	}
+}
+
+// String returns a description of the component status.
+func (c ComponentStatus) String() string {
+	return fmt.Sprintf("%s is %s (%d of %d probes in outage)", c.Name, c.State, c.Counts[StateOutage], len(c.Probes))
+}
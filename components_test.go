@@ -0,0 +1,111 @@
+package prober
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Components(t *testing.T) {
+	newProbe := func(name, component string) *Probe {
+		p := &Probe{Name: name, t: fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)}}
+		InComponent(component)(p)
+		return p
+	}
+	api1, api2, api3 := newProbe("api1", "api"), newProbe("api2", "api"), newProbe("api3", "api")
+	db := newProbe("db", "storage")
+	dns := newProbe("dns", "")
+	api1.setIsAlerting(true)
+	api2.degraded = true
+	db.Disabled = true
+	r := NewRegistry(api1, api2, api3, db, dns)
+
+	want := []ComponentStatus{
+		{
+			Name:   "api",
+			State:  StateOutage,
+			Probes: []string{"api1", "api2", "api3"},
+			Counts: map[string]int{StateOutage: 1, StateDegraded: 1, StateOperational: 1},
+		},
+		{
+			Name:   "storage",
+			State:  StateDisabled,
+			Probes: []string{"db"},
+			Counts: map[string]int{StateDisabled: 1},
+		},
+	}
+	if got := r.Components(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Components() => %+v; want %+v\n", got, want)
+	}
+	if got := api1.Status(); got.Component != "api" || got.State != StateOutage {
+		t.Errorf("Status() has component %q and state %q; want api and outage\n", got.Component, got.State)
+	}
+
+	w := httptest.NewRecorder()
+	NewAdminHandler(r, Insecure()).ServeHTTP(w, httptest.NewRequest("GET", "/components", nil))
+	var served []ComponentStatus
+	if err := json.NewDecoder(w.Body).Decode(&served); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET /components => %d, %v; want 200 with JSON\n", w.Code, err)
+	}
+	if !reflect.DeepEqual(served, want) {
+		t.Errorf("GET /components => %+v; want %+v\n", served, want)
+	}
+}
+
+func TestWorstState(t *testing.T) {
+	cases := []struct {
+		in   []string
+		want string
+	}{
+		{nil, StateDisabled},
+		{[]string{StateDisabled, StateOperational}, StateOperational},
+		{[]string{StateOperational, StateDegraded, StateOperational}, StateDegraded},
+		{[]string{StateOutage, StateDegraded}, StateOutage},
+	}
+	for i, tt := range cases {
+		if got := WorstState(tt.in...); got != tt.want {
+			t.Errorf("[%d] WorstState(%v) => %q; want %q\n", i, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRegistry_AlertOnComponent(t *testing.T) {
+	api1 := &Probe{Name: "api1", component: "api"}
+	api2 := &Probe{Name: "api2", component: "api"}
+	r := NewRegistry(api1, api2)
+	if err := r.AlertOnComponent("api", "outage >=", nil); err == nil {
+		t.Errorf("AlertOnComponent() with bad expression => nil; want error\n")
+	}
+	if err := r.AlertOnComponent("api", "outage + 1", nil); err == nil {
+		t.Errorf("AlertOnComponent() with non-boolean expression => nil; want error\n")
+	}
+	alerted := make(chan ComponentStatus, 2)
+	if err := r.AlertOnComponent("api", `outage >= 2 || state == "degraded"`, func(c ComponentStatus) error {
+		alerted <- c
+		return nil
+	}); err != nil {
+		t.Fatalf("AlertOnComponent() => %v; want nil\n", err)
+	}
+
+	api1.setIsAlerting(true)
+	r.checkComponents()
+	api2.setIsAlerting(true)
+	r.checkComponents()
+	r.checkComponents()
+	select {
+	case c := <-alerted:
+		if c.Counts[StateOutage] != 2 {
+			t.Errorf("alerted with %v; want 2 probes in outage\n", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("component didn't alert\n")
+	}
+	select {
+	case c := <-alerted:
+		t.Errorf("alerted again with %v; want one alert while the condition holds\n", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,109 @@
+package prober
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// defaultDeployGrace is how long probes affected by a deploy hold
+	// off alerting, unless another grace period is given.
+	defaultDeployGrace = 10 * time.Minute
+	// deployHistorySize is the number of deploys a registry keeps.
+	deployHistorySize = 100
+)
+
+// Deploy is a deployment of the services probed by all probes in a
+// registry that match a selector, see Registry.Deployed().
+type Deploy struct {
+	Selector string    // the selector probes are matched by, e.g. "service=web"
+	Version  string    // version that was deployed, if known
+	Time     time.Time // when the deploy was registered
+	Until    time.Time // when the grace period of matching probes ends
+}
+
+// Deployed registers a deploy of the services probed by all probes in
+// the registry matching the selector, returning the deploy. For the
+// grace period after it, or defaultDeployGrace if 0, the probes don't
+// start alerting, so restarts during the deploy don't page anyone.
+//
+// Unlike with a silence, the probes keep track of their badness during
+// the grace period, so a deploy that breaks things alerts as soon as
+// the period ends. Probes that were already alerting keep alerting.
+// Probes added during the grace period are affected too.
+//
+// The selector is the same as for SilenceMatching().
+func (r *Registry) Deployed(sel, version string, grace time.Duration) (Deploy, error) {
+	parsed, err := parseSelector(sel)
+	if err != nil {
+		return Deploy{}, err
+	}
+	if grace < 0 {
+		return Deploy{}, fmt.Errorf("bad grace period: %v", grace)
+	}
+	if grace == 0 {
+		grace = defaultDeployGrace
+	}
+	now := time.Now()
+	d := Deploy{Selector: sel, Version: version, Time: now, Until: now.Add(grace)}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.deploys = append(r.deploys, d)
+	if over := len(r.deploys) - deployHistorySize; over > 0 {
+		r.deploys = append(r.deploys[:0], r.deploys[over:]...)
+	}
+	n := 0
+	for _, p := range r.probes {
+		if parsed.matches(p) {
+			p.addDeploy(d)
+			n++
+		}
+	}
+	log.Printf("deploy of %q to %d probes, holding their alerts until %v\n", sel, n, d.Until)
+	return d, nil
+}
+
+// Deploys returns the most recent deploys registered, oldest first.
+func (r *Registry) Deploys() []Deploy {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]Deploy{}, r.deploys...)
+}
+
+// applyDeploys starts the grace period of any deploys still in theirs
+// matching the probe. The caller must hold the lock.
+func (r *Registry) applyDeploys(p *Probe) {
+	now := time.Now()
+	for _, d := range r.deploys {
+		// Selectors were validated when the deploy was registered.
+		if sel, err := parseSelector(d.Selector); err == nil && d.Until.After(now) && sel.matches(p) {
+			p.addDeploy(d)
+		}
+	}
+}
+
+// addDeploy starts the grace period of the deploy for the probe,
+// unless it's already in one lasting longer.
+func (p *Probe) addDeploy(d Deploy) {
+	p.alertLock.Lock()
+	defer p.alertLock.Unlock()
+	if d.Until.After(p.deployGraceUntil) {
+		p.deployGraceUntil = d.Until
+	}
+}
+
+// DeployGraceUntil returns when the grace period after the latest
+// deploy matching the probe ends, see Registry.Deployed().
+func (p *Probe) DeployGraceUntil() time.Time {
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	return p.deployGraceUntil
+}
+
+// inDeployGrace returns true if the probe is in the grace period after
+// a deploy at the time now.
+func (p *Probe) inDeployGrace(now time.Time) bool {
+	return p.DeployGraceUntil().After(now)
+}
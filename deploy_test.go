@@ -0,0 +1,84 @@
+package prober
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Deployed(t *testing.T) {
+	newProbe := func(name, service string) *Probe {
+		return &Probe{
+			Prober:         testProber{FailedWith(errors.New("restarting"))},
+			Name:           name,
+			Labels:         map[string]string{"service": service},
+			failurePenalty: 100,
+			successReward:  1,
+			critThreshold:  50,
+			t:              realTime{},
+		}
+	}
+	web, db := newProbe("web", "web"), newProbe("db", "db")
+	reg := NewRegistry(web, db)
+	if _, err := reg.Deployed("service=[", "", 0); err == nil {
+		t.Errorf("Deployed() with bad selector => nil error; want error\n")
+	}
+	d, err := reg.Deployed("service=web", "1.2.3", 0)
+	if err != nil {
+		t.Fatalf("Deployed() => %v; want nil", err)
+	}
+	if got := d.Until.Sub(d.Time); got != defaultDeployGrace {
+		t.Errorf("Deployed() => grace period %v; want %v\n", got, defaultDeployGrace)
+	}
+	if got := reg.Deploys(); len(got) != 1 || got[0] != d {
+		t.Errorf("Deploys() => %v; want [%v]\n", got, d)
+	}
+
+	// The deployed probe holds off alerting, but keeps its badness.
+	for _, p := range []*Probe{web, db} {
+		p.handleResult(p.Probe())
+	}
+	if web.IsAlerting() || web.Badness() != 100 {
+		t.Errorf("%s during grace period => alerting %v, badness %d; want not alerting, badness 100\n", web.Name, web.IsAlerting(), web.Badness())
+	}
+	if !db.IsAlerting() {
+		t.Errorf("%s not deployed => not alerting; want alerting\n", db.Name)
+	}
+	if got := web.Status().DeployGraceUntil; !got.Equal(d.Until) {
+		t.Errorf("Status().DeployGraceUntil => %v; want %v\n", got, d.Until)
+	}
+
+	later := newProbe("web-canary", "web")
+	if err := reg.Add(later); err != nil {
+		t.Fatal(err)
+	}
+	if !later.DeployGraceUntil().Equal(d.Until) {
+		t.Errorf("probe added during grace period => DeployGraceUntil() %v; want %v\n", later.DeployGraceUntil(), d.Until)
+	}
+
+	// Once the grace period ends, the failing probe alerts.
+	web.deployGraceUntil = time.Now().Add(-time.Second)
+	web.handleResult(web.Probe())
+	if !web.IsAlerting() {
+		t.Errorf("%s after grace period => not alerting; want alerting\n", web.Name)
+	}
+}
+
+func TestAdminHandler_deployWebhook(t *testing.T) {
+	p := &Probe{Name: "web", Labels: map[string]string{"service": "web"}, t: realTime{}}
+	h := NewAdminHandler(NewRegistry(p), Insecure())
+	req := httptest.NewRequest("POST", "/events/deploy", strings.NewReader(`{"match": "service=web", "grace": "5m", "version": "abc123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /events/deploy => %d; want %d\n", w.Code, http.StatusOK)
+	}
+	got := p.DeployGraceUntil()
+	if want := time.Now().Add(5 * time.Minute); got.Before(want.Add(-time.Minute)) || got.After(want) {
+		t.Errorf("DeployGraceUntil() after webhook => %v; want about %v\n", got, want)
+	}
+}
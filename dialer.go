@@ -0,0 +1,60 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+type (
+	// dialerKey identifies the transport of a Dialer and proxy.
+	dialerKey struct {
+		d     *net.Dialer
+		proxy string
+	}
+)
+
+var (
+	dialerTransports     = map[dialerKey]*http.Transport{} // transports for each dialer and proxy
+	dialerTransportsLock sync.Mutex                        // protects dialerTransports
+)
+
+// ResolverAt returns a resolver that sends DNS queries to the server at
+// the address, e.g. "10.0.0.53:53", rather than the system's servers.
+// Probers given a net.Dialer with it as its Resolver check names as
+// that server sees them, e.g. the internal view of split-horizon DNS.
+func ResolverAt(addr string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// dialerClient returns a client that opens its connections with the
+// dialer, through the proxy if it's not nil.
+//
+// Clients for the same dialer and proxy share a transport, so
+// connections can be reused across probe runs.
+func dialerClient(d *net.Dialer, proxyURL *url.URL) *http.Client {
+	key := dialerKey{d: d}
+	if proxyURL != nil {
+		key.proxy = proxyURL.String()
+	}
+	dialerTransportsLock.Lock()
+	defer dialerTransportsLock.Unlock()
+	t, ok := dialerTransports[key]
+	if !ok {
+		t = http.DefaultTransport.(*http.Transport).Clone()
+		t.DialContext = d.DialContext
+		if proxyURL != nil {
+			t.Proxy = http.ProxyURL(proxyURL)
+		}
+		dialerTransports[key] = t
+	}
+	return &http.Client{Transport: t}
+}
@@ -0,0 +1,22 @@
+package prober
+
+import (
+	"syscall"
+)
+
+// BindToDevice returns a function for the Control field of a
+// net.Dialer, which binds its connections to the network interface,
+// e.g. a VRF device like "vrf-mgmt" to probe from that routing table,
+// or "eth1" to probe over a particular link. Binding needs the
+// CAP_NET_RAW capability.
+func BindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var err error
+		if cerr := c.Control(func(fd uintptr) {
+			err = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface)
+		}); cerr != nil {
+			return cerr
+		}
+		return err
+	}
+}
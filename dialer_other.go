@@ -0,0 +1,18 @@
+//go:build !linux
+
+package prober
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// BindToDevice returns a function for the Control field of a
+// net.Dialer, which binds its connections to the network interface.
+// It's only supported on Linux, and fails to connect elsewhere.
+func BindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return fmt.Errorf("can't bind to %s: binding to a device isn't supported on %s", iface, runtime.GOOS)
+	}
+}
@@ -0,0 +1,42 @@
+package prober
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+func TestDialer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	var dials int32
+	d := &net.Dialer{
+		LocalAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		Control: func(network, address string, c syscall.RawConn) error {
+			atomic.AddInt32(&dials, 1)
+			return nil
+		},
+	}
+	cases := []struct {
+		p    Prober
+		want ResultCode
+	}{
+		{HTTPProber{URL: ts.URL, Dialer: d, NewConnection: true}, Pass},
+		{TCPProber{Addr: ts.Listener.Addr().String(), Dialer: d}, Pass},
+		{TCPProber{Addr: ts.Listener.Addr().String(), Dialer: &net.Dialer{LocalAddr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1)}}}, Fail},
+	}
+	for i, tt := range cases {
+		if got := tt.p.Probe(); got.Code != tt.want {
+			t.Errorf("[%d] Probe() => %v; want %v\n", i, got, tt.want)
+		}
+	}
+	if got := atomic.LoadInt32(&dials); got != 2 {
+		t.Errorf("Dialer.Control called %d times; want 2\n", got)
+	}
+	if d.Timeout != 0 {
+		t.Errorf("Probe() => Dialer.Timeout %v; want Dialer left unchanged\n", d.Timeout)
+	}
+}
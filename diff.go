@@ -0,0 +1,170 @@
+package prober
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Diff returns human-readable differences between the probes, e.g.
+// `Interval: 1m0s != 30s`, or nil if they are equal.
+//
+// Both the settings of the probes and their state, e.g. records,
+// badness and whether they are silenced, are compared. Prober fields holding functions, e.g. AlertFn,
+// are not compared.
+func (p1 *Probe) Diff(p2 *Probe) []string {
+	if p2 == nil {
+		return []string{"other probe is nil"}
+	}
+	diffs := p1.configDiff(p2)
+	d := differ{&diffs}
+	rs1, rs2 := p1.Records(), p2.Records()
+	if len(rs1) != len(rs2) {
+		d.add("Records", "%d records != %d records", len(rs1), len(rs2))
+	} else {
+		for i := range rs1 {
+			if !rs1[i].Equal(rs2[i]) {
+				d.add(fmt.Sprintf("Records[%d]", i), "%v != %v", rs1[i], rs2[i])
+			}
+		}
+	}
+	d.compare("Disabled", p1.IsDisabled(), p2.IsDisabled())
+	if s1, s2 := p1.silencedUntil(), p2.silencedUntil(); !s1.Equal(s2) {
+		d.add("SilencedUntil", "%v != %v", SilenceTime{s1}, SilenceTime{s2})
+	}
+	d.compare("Badness", p1.Badness(), p2.Badness())
+	d.compare("Alerting", p1.IsAlerting(), p2.IsAlerting())
+	if a1, a2 := p1.getLastAlert(), p2.getLastAlert(); !a1.Equal(a2) {
+		d.add("LastAlert", "%v != %v", a1, a2)
+	}
+	return diffs
+}
+
+// configDiff returns the differences between the settings of the
+// probes, which are the ones that require restarting a probe to apply.
+// Settings that are changed at runtime, e.g. by silencing or disabling
+// a probe, are not included.
+func (p1 *Probe) configDiff(p2 *Probe) []string {
+	var diffs []string
+	d := differ{&diffs}
+	d.compare("Name", p1.Name, p2.Name)
+	d.compare("Desc", p1.Desc, p2.Desc)
+	d.compare("Location", p1.Location, p2.Location)
+	d.compareMaps("Labels", p1.Labels, p2.Labels)
+	d.compare("Interval", p1.Interval, p2.Interval)
+	d.compare("Schedule", p1.scheduleString(), p2.scheduleString())
+	d.compare("FailurePenalty", p1.failurePenalty, p2.failurePenalty)
+	d.compare("SuccessReward", p1.successReward, p2.successReward)
+	d.compare("Thresholds.warning", p1.warnThreshold, p2.warnThreshold)
+	d.compare("Thresholds.critical", p1.critThreshold, p2.critThreshold)
+	d.compare("FastRecheck", p1.fastRecheck, p2.fastRecheck)
+	d.compare("ReAlertWindow", p1.reAlertWindow, p2.reAlertWindow)
+	d.compare("DegradedWeight", p1.degradedWeight(), p2.degradedWeight())
+	d.compare("AlertWhen", p1.alertWhenSrc, p2.alertWhenSrc)
+	d.compare("AlertWhenExpr", p1.alertWhenExprLang, p2.alertWhenExprLang)
+	d.compare("MaxRate", fmt.Sprintf("%v/%v", p1.maxRate, p1.rateWindow), fmt.Sprintf("%v/%v", p2.maxRate, p2.rateWindow))
+	d.compare("InComponent", p1.component, p2.component)
+	d.compare("DependsOn", strings.Join(p1.dependencies, ","), strings.Join(p2.dependencies, ","))
+	d.compare("ExpectFailure", p1.expectFailure, p2.expectFailure)
+	d.compare("AlignToInterval", p1.aligned, p2.aligned)
+	d.compare("ProbeImmediately", p1.immediate, p2.immediate)
+	d.compare("InitialDelay", p1.initialDelay, p2.initialDelay)
+	d.compare("MaxResultLen", p1.maxResultLen, p2.maxResultLen)
+	d.compare("CompactRecords", p1.compact, p2.compact)
+	d.compare("DryRun", p1.dryRun, p2.dryRun)
+	d.compare("ShipTo", p1.shipURL, p2.shipURL)
+	d.compareValues("Prober", reflect.ValueOf(p1.Prober), reflect.ValueOf(p2.Prober))
+	return diffs
+}
+
+// differ collects differences.
+type differ struct {
+	diffs *[]string
+}
+
+// add adds a difference at the path.
+func (d differ) add(path, format string, args ...interface{}) {
+	*d.diffs = append(*d.diffs, path+": "+fmt.Sprintf(format, args...))
+}
+
+// compare adds a difference at the path if the comparable values
+// differ.
+func (d differ) compare(path string, v1, v2 interface{}) {
+	if v1 == v2 {
+		return
+	}
+	if _, ok := v1.(string); ok {
+		d.add(path, "%q != %q", v1, v2)
+		return
+	}
+	d.add(path, "%v != %v", v1, v2)
+}
+
+// compareMaps adds a difference for each key with different values in
+// the maps.
+func (d differ) compareMaps(path string, m1, m2 map[string]string) {
+	keys := map[string]bool{}
+	for k := range m1 {
+		keys[k] = true
+	}
+	for k := range m2 {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		v1, ok1 := m1[k]
+		v2, ok2 := m2[k]
+		switch {
+		case !ok1:
+			d.add(fmt.Sprintf("%s[%q]", path, k), "missing != %q", v2)
+		case !ok2:
+			d.add(fmt.Sprintf("%s[%q]", path, k), "%q != missing", v1)
+		case v1 != v2:
+			d.add(fmt.Sprintf("%s[%q]", path, k), "%q != %q", v1, v2)
+		}
+	}
+}
+
+// compareValues adds differences between the values, looking into the
+// exported fields of structs and skipping functions, which can't be
+// compared.
+func (d differ) compareValues(path string, v1, v2 reflect.Value) {
+	if !v1.IsValid() || !v2.IsValid() {
+		if v1.IsValid() != v2.IsValid() {
+			d.add(path, "%v != %v", v1, v2)
+		}
+		return
+	}
+	if v1.Type() != v2.Type() {
+		d.add(path, "%v != %v", v1.Type(), v2.Type())
+		return
+	}
+	switch v1.Kind() {
+	case reflect.Func:
+		return
+	case reflect.Interface, reflect.Ptr:
+		if v1.IsNil() || v2.IsNil() {
+			if v1.IsNil() != v2.IsNil() {
+				d.add(path, "%v != %v", v1, v2)
+			}
+			return
+		}
+		d.compareValues(path, v1.Elem(), v2.Elem())
+	case reflect.Struct:
+		t := v1.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath == "" {
+				d.compareValues(path+"."+f.Name, v1.Field(i), v2.Field(i))
+			}
+		}
+	default:
+		if !reflect.DeepEqual(v1.Interface(), v2.Interface()) {
+			d.add(path, "%v != %v", v1.Interface(), v2.Interface())
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package prober
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProbe_Diff(t *testing.T) {
+	p1 := newProbe(TCPProber{Addr: "a:80"}, "tcp", "Probes a.", Label("env", "dev"))
+	p2 := newProbe(TCPProber{Addr: "b:80"}, "tcp", "Probes a.", Label("env", "prod"), Interval(time.Second))
+	want := []string{
+		`Labels["env"]: "dev" != "prod"`,
+		`Interval: 1m0s != 1s`,
+		`Prober.Addr: a:80 != b:80`,
+	}
+	got := p1.Diff(p2)
+	if len(got) != len(want) {
+		t.Fatalf("Diff() => %q; want %q\n", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Diff()[%d] => %q; want %q\n", i, got[i], want[i])
+		}
+	}
+	if diff := p1.Diff(p1); len(diff) != 0 {
+		t.Errorf("Diff() with itself => %q; want none\n", diff)
+	}
+}
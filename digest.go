@@ -0,0 +1,303 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Digest sends a periodic report on the health of the probes in a
+	// registry, e.g. a daily email for stakeholders who don't watch
+	// dashboards. See DigestReport for what it includes.
+	//
+	// Once Run() is called, the digest collects the records of the
+	// probes every minute, so reports cover the whole period even if
+	// probes keep fewer records than that in memory.
+	Digest struct {
+		Registry *Registry
+		Period   time.Duration // how often to send reports, or 0 for daily
+		// Function to send reports with, e.g. SMTPSender(...) to email
+		// them, or a function posting them to a chat.
+		Send    func(subject, body string) error
+		Slowest int                       // number of slowest probes to list, or 0 for 5
+		probes  map[string]*digestProbe   // collected runs since the last report, by name
+		stats   map[string]SchedulerStats // scheduler stats at the last report, by name
+		from    time.Time                 // when the current period started
+		t       timeT
+		lock    sync.Mutex // protects probes, stats and from
+	}
+
+	// DigestReport is a report on the health of the probes in a
+	// registry over a period.
+	DigestReport struct {
+		From, To  time.Time
+		Probes    []ProbeDigest // all probes, least available first
+		Incidents []Incident    // runs of failures during the period, oldest first
+		Slowest   []ProbeDigest // probes with the slowest runs, slowest first
+		Added     []string      // probes added since the last report
+		Removed   []string      // probes removed since the last report
+	}
+
+	// ProbeDigest is the health of a probe over the period of a
+	// DigestReport.
+	ProbeDigest struct {
+		Name         string
+		Runs         int
+		Passes       int
+		Availability float64       // ratio of runs that passed, or 1 if there were none
+		MeanDuration time.Duration // mean time the runs took
+	}
+
+	// Incident is a run of failures of a probe.
+	Incident struct {
+		Probe      string
+		Start, End time.Time // when the probe started failing and passed again, or zero End if it's still failing
+		Failures   int       // number of failed runs
+		Error      string    // error of the first failure
+	}
+
+	// digestProbe holds the runs of a probe collected for the current
+	// report.
+	digestProbe struct {
+		runs, passes int
+		incidents    []Incident
+		open         *Incident // ongoing incident, if any
+		seen         time.Time // time of the most recent record collected
+		seenRepeats  int       // Repeats of the most recent record collected
+	}
+)
+
+// Run collects records every minute, and sends a report every Period,
+// until ctx is done.
+func (d *Digest) Run(ctx context.Context) {
+	collect := time.NewTicker(time.Minute)
+	defer collect.Stop()
+	report := time.NewTicker(d.period())
+	defer report.Stop()
+	d.collect()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-collect.C:
+			d.collect()
+		case <-report.C:
+			if err := d.send(d.Report()); err != nil {
+				log.Printf("Failed to send digest: %v\n", err)
+			}
+		}
+	}
+}
+
+// Report returns a report on the period since the last report, or
+// since the digest started, and starts a new period.
+func (d *Digest) Report() DigestReport {
+	d.collect()
+	now := d.now()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	rep := DigestReport{From: d.from, To: now}
+	stats := map[string]SchedulerStats{}
+	for _, p := range d.Registry.Probes() {
+		s := p.Stats()
+		stats[p.Name] = s
+		pd := ProbeDigest{Name: p.Name, Availability: 1}
+		if dp := d.probes[p.Name]; dp != nil {
+			pd.Runs, pd.Passes = dp.runs, dp.passes
+			if dp.runs > 0 {
+				pd.Availability = float64(dp.passes) / float64(dp.runs)
+			}
+			rep.Incidents = append(rep.Incidents, dp.incidents...)
+			if dp.open != nil {
+				rep.Incidents = append(rep.Incidents, *dp.open)
+			}
+		}
+		last, ok := d.stats[p.Name]
+		if !ok && d.stats != nil {
+			rep.Added = append(rep.Added, p.Name)
+		}
+		if runs := s.Runs - last.Runs; runs > 0 {
+			pd.MeanDuration = (s.Duration - last.Duration) / time.Duration(runs)
+		}
+		rep.Probes = append(rep.Probes, pd)
+	}
+	for name := range d.stats {
+		if _, ok := stats[name]; !ok {
+			rep.Removed = append(rep.Removed, name)
+		}
+	}
+	sort.Strings(rep.Removed)
+	sort.SliceStable(rep.Probes, func(i, j int) bool {
+		return rep.Probes[i].Availability < rep.Probes[j].Availability
+	})
+	sort.Slice(rep.Incidents, func(i, j int) bool {
+		return rep.Incidents[i].Start.Before(rep.Incidents[j].Start)
+	})
+	rep.Slowest = append([]ProbeDigest{}, rep.Probes...)
+	sort.SliceStable(rep.Slowest, func(i, j int) bool {
+		return rep.Slowest[i].MeanDuration > rep.Slowest[j].MeanDuration
+	})
+	n := d.Slowest
+	if n == 0 {
+		n = 5
+	}
+	if len(rep.Slowest) > n {
+		rep.Slowest = rep.Slowest[:n]
+	}
+
+	// Start a new period, carrying over ongoing incidents.
+	d.stats = stats
+	d.from = now
+	for name, dp := range d.probes {
+		if _, ok := stats[name]; !ok {
+			delete(d.probes, name)
+			continue
+		}
+		dp.runs, dp.passes, dp.incidents = 0, 0, nil
+	}
+	return rep
+}
+
+// collect adds the records of the probes since the last collection.
+func (d *Digest) collect() {
+	probes := d.Registry.Probes()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.probes == nil {
+		d.probes = map[string]*digestProbe{}
+	}
+	if d.from.IsZero() {
+		d.from = d.now()
+	}
+	for _, p := range probes {
+		dp, ok := d.probes[p.Name]
+		if !ok {
+			dp = &digestProbe{}
+			d.probes[p.Name] = dp
+		}
+		for _, r := range p.Records() {
+			switch {
+			case r.Timestamp.Before(dp.seen) || r.Timestamp.Before(d.from):
+				continue
+			case r.Timestamp.Equal(dp.seen):
+				// A record that was collected before, which may have had
+				// more runs merged into it since.
+				if n := r.Repeats - dp.seenRepeats; n > 0 {
+					dp.add(p.Name, r, n)
+				}
+			default:
+				dp.add(p.Name, r, 1+r.Repeats)
+			}
+			dp.seen, dp.seenRepeats = r.Timestamp, r.Repeats
+		}
+	}
+}
+
+// add adds n runs with the outcome of the record.
+func (dp *digestProbe) add(name string, r Record, n int) {
+	dp.runs += n
+	if r.Result.Passed() {
+		dp.passes += n
+		if dp.open != nil {
+			dp.open.End = r.Timestamp
+			dp.incidents = append(dp.incidents, *dp.open)
+			dp.open = nil
+		}
+		return
+	}
+	if dp.open == nil {
+		dp.open = &Incident{Probe: name, Start: r.Timestamp}
+		if r.Result.Error != nil {
+			dp.open.Error = r.Result.Error.Error()
+		}
+	}
+	dp.open.Failures += n
+}
+
+// send sends the report with the Send function.
+func (d *Digest) send(rep DigestReport) error {
+	if d.Send == nil {
+		return fmt.Errorf("no Send function for digest")
+	}
+	return d.Send(rep.Subject(), rep.String())
+}
+
+func (d *Digest) period() time.Duration {
+	if d.Period == 0 {
+		return 24 * time.Hour
+	}
+	return d.Period
+}
+
+func (d *Digest) now() time.Time {
+	if d.t == nil {
+		return time.Now()
+	}
+	return d.t.Now()
+}
+
+// Subject returns a one-line summary of the report.
+func (rep DigestReport) Subject() string {
+	failing := 0
+	for _, pd := range rep.Probes {
+		if pd.Availability < 1 {
+			failing++
+		}
+	}
+	return fmt.Sprintf("Prober digest %s: %d probes, %d with failures, %d incidents", rep.To.Format("2006-01-02"), len(rep.Probes), failing, len(rep.Incidents))
+}
+
+// String returns the report as plain text.
+func (rep DigestReport) String() string {
+	var b strings.Builder
+	const layout = "2006-01-02 15:04 MST"
+	fmt.Fprintf(&b, "Probe health from %s to %s\n", rep.From.Format(layout), rep.To.Format(layout))
+
+	b.WriteString("\nAvailability:\n")
+	for _, pd := range rep.Probes {
+		fmt.Fprintf(&b, "  %-30s %7.3f%% (%d/%d runs passed)\n", pd.Name, pd.Availability*100, pd.Passes, pd.Runs)
+	}
+
+	if len(rep.Incidents) > 0 {
+		b.WriteString("\nIncidents:\n")
+		for _, inc := range rep.Incidents {
+			end := "ongoing"
+			if !inc.End.IsZero() {
+				end = fmt.Sprintf("lasted %v", inc.End.Sub(inc.Start))
+			}
+			fmt.Fprintf(&b, "  %s at %s, %s, %d failed runs: %s\n", inc.Probe, inc.Start.Format(layout), end, inc.Failures, inc.Error)
+		}
+	}
+
+	if len(rep.Slowest) > 0 {
+		b.WriteString("\nSlowest probes:\n")
+		for _, pd := range rep.Slowest {
+			fmt.Fprintf(&b, "  %-30s %v\n", pd.Name, pd.MeanDuration)
+		}
+	}
+
+	if len(rep.Added) > 0 {
+		fmt.Fprintf(&b, "\nNew probes: %s\n", strings.Join(rep.Added, ", "))
+	}
+	if len(rep.Removed) > 0 {
+		fmt.Fprintf(&b, "\nRemoved probes: %s\n", strings.Join(rep.Removed, ", "))
+	}
+	return b.String()
+}
+
+// SMTPSender returns a function that emails reports of a Digest via the
+// SMTP server at addr, e.g. "smtp.example.com:587".
+func SMTPSender(addr string, auth smtp.Auth, from string, to ...string) func(subject, body string) error {
+	return func(subject, body string) error {
+		msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+			from, strings.Join(to, ", "), subject, strings.ReplaceAll(body, "\n", "\r\n"))
+		return smtp.SendMail(addr, auth, from, to, []byte(msg))
+	}
+}
@@ -0,0 +1,90 @@
+package prober
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigest_Report(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	web := &Probe{Name: "web", t: fakeTime{start}}
+	db := &Probe{Name: "db", t: fakeTime{start}, compact: true}
+	r := NewRegistry(web, db)
+	d := &Digest{Registry: r, Slowest: 1, t: fakeTime{start}}
+	d.collect()
+
+	run := func(p *Probe, at time.Duration, res Result) {
+		p.t = fakeTime{start.Add(at)}
+		p.logResult(res)
+	}
+	fail := FailedWith(errors.New("failing on purpose"))
+	run(web, time.Minute, Passed())
+	run(web, 2*time.Minute, fail)
+	run(web, 3*time.Minute, fail)
+	d.collect()
+	run(web, 4*time.Minute, Passed())
+	run(db, time.Minute, Passed())
+	run(db, 2*time.Minute, Passed())
+	d.collect()
+	run(db, 3*time.Minute, Passed())
+	run(db, 4*time.Minute, fail)
+	db.recordStart(start)
+	db.recordDuration(3 * time.Second)
+
+	d.t = fakeTime{start.Add(time.Hour)}
+	rep := d.Report()
+	wantProbes := []ProbeDigest{
+		{Name: "web", Runs: 4, Passes: 2, Availability: 0.5},
+		{Name: "db", Runs: 4, Passes: 3, Availability: 0.75, MeanDuration: 3 * time.Second},
+	}
+	if !reflect.DeepEqual(rep.Probes, wantProbes) {
+		t.Errorf("Report().Probes => %+v; want %+v\n", rep.Probes, wantProbes)
+	}
+	wantIncidents := []Incident{
+		{Probe: "web", Start: start.Add(2 * time.Minute), End: start.Add(4 * time.Minute), Failures: 2, Error: "failing on purpose"},
+		{Probe: "db", Start: start.Add(4 * time.Minute), Failures: 1, Error: "failing on purpose"},
+	}
+	if !reflect.DeepEqual(rep.Incidents, wantIncidents) {
+		t.Errorf("Report().Incidents => %+v; want %+v\n", rep.Incidents, wantIncidents)
+	}
+	if len(rep.Slowest) != 1 || rep.Slowest[0].Name != "db" {
+		t.Errorf("Report().Slowest => %+v; want db\n", rep.Slowest)
+	}
+	if rep.Added != nil || rep.Removed != nil {
+		t.Errorf("first Report() has added %v and removed %v; want none\n", rep.Added, rep.Removed)
+	}
+
+	r.Remove("web")
+	r.Add(&Probe{Name: "dns", t: fakeTime{start}})
+	run(db, 61*time.Minute, Passed())
+	d.t = fakeTime{start.Add(2 * time.Hour)}
+	var subject, body string
+	d.Send = func(s, b string) error {
+		subject, body = s, b
+		return nil
+	}
+	rep = d.Report()
+	if err := d.send(rep); err != nil {
+		t.Fatalf("send() => %v; want nil\n", err)
+	}
+	if want := []string{"dns"}; !reflect.DeepEqual(rep.Added, want) {
+		t.Errorf("second Report().Added => %v; want %v\n", rep.Added, want)
+	}
+	if want := []string{"web"}; !reflect.DeepEqual(rep.Removed, want) {
+		t.Errorf("second Report().Removed => %v; want %v\n", rep.Removed, want)
+	}
+	if len(rep.Incidents) != 1 || rep.Incidents[0].End != start.Add(61*time.Minute) {
+		t.Errorf("second Report().Incidents => %+v; want db incident carried over and ended\n", rep.Incidents)
+	}
+	if !strings.Contains(subject, "1 incidents") {
+		t.Errorf("sent subject %q; want incident count\n", subject)
+	}
+	for _, want := range []string{"New probes: dns", "Removed probes: web", "db at 1998-11-19 15:18 UTC, lasted 57m0s"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("sent body %q; want it to contain %q\n", body, want)
+		}
+	}
+}
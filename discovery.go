@@ -0,0 +1,318 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Discoverer finds targets to probe.
+	Discoverer interface {
+		Targets(ctx context.Context) ([]Target, error)
+	}
+
+	// Watcher is implemented by Discoverers that can tell when their
+	// targets may have changed, e.g. since a file was modified.
+	Watcher interface {
+		// Changes returns a channel that receives a value when the
+		// targets may have changed, until ctx is done.
+		Changes(ctx context.Context) <-chan struct{}
+	}
+
+	// Discovery keeps probes in a registry in sync with the targets
+	// found by a Discoverer, creating probes for new targets from a
+	// template and removing probes for targets that went away.
+	//
+	// If the Discoverer is also a Watcher, targets are looked for as
+	// soon as they change, as well as every Interval.
+	Discovery struct {
+		Discoverer Discoverer
+		Template   Template
+		// Templates to use instead of Template for targets with a
+		// "template" label, keyed by the value of that label.
+		Templates map[string]Template
+		Registry  *Registry
+		Interval  time.Duration   // how often to look for targets, or 0 for DefaultInterval
+		managed   map[string]bool // names of probes this discovery has created
+	}
+
+	// SRVDiscoverer finds targets from DNS SRV records, as described in
+	// net.LookupSRV. Each target has the address "host:port".
+	SRVDiscoverer struct {
+		Service, Proto, Name string
+		Resolver             *net.Resolver // resolver to use, or nil for net.DefaultResolver
+	}
+
+	// ConsulDiscoverer finds targets from the instances of a service in
+	// the Consul catalog. Each target has the address "host:port".
+	ConsulDiscoverer struct {
+		Addr    string       // address of the Consul HTTP API, e.g. "http://localhost:8500"
+		Service string       // name of the service
+		Tag     string       // tag that instances must have, if any
+		Client  *http.Client // client to use, or nil for http.DefaultClient
+	}
+
+	// FileDiscoverer finds targets listed in a JSON or YAML file, which
+	// is read again each time targets are needed. The file holds a list
+	// of targets:
+	//
+	//	- addr: a.example.com
+	//	  labels:
+	//	    env: prod
+	//
+	// Lists in the format of Prometheus' file_sd_config are also
+	// accepted, where each entry has many targets sharing labels:
+	//
+	//	- targets: ["a.example.com", "b.example.com"]
+	//	  labels:
+	//	    env: prod
+	//
+	// FileDiscoverer is a Watcher, which reports changes when the
+	// modification time or size of the file changes.
+	FileDiscoverer struct {
+		Path         string
+		PollInterval time.Duration // how often to check for changes, or 0 for every 5 seconds
+	}
+)
+
+// Run keeps the registry in sync with the discovered targets, blocking
+// until ctx is done.
+func (d *Discovery) Run(ctx context.Context) {
+	interval := d.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	var changes <-chan struct{}
+	if w, ok := d.Discoverer.(Watcher); ok {
+		changes = w.Changes(ctx)
+	}
+	for {
+		if err := d.Sync(ctx); err != nil {
+			log.Printf("failed to discover targets: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changes:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Sync looks for targets once, adding probes for new targets to the
+// registry and removing probes for targets that are gone. Probes whose
+// settings changed, e.g. since the labels of their target did, are
+// replaced.
+//
+// If the Discoverer fails, no probes are removed.
+func (d *Discovery) Sync(ctx context.Context) error {
+	targets, err := d.Discoverer.Targets(ctx)
+	if err != nil {
+		return err
+	}
+	if d.managed == nil {
+		d.managed = map[string]bool{}
+	}
+	seen := map[string]bool{}
+	for _, t := range targets {
+		tmpl := d.template(t)
+		name := tmpl.name(t)
+		seen[name] = true
+		p := tmpl.probe(t)
+		if d.managed[name] {
+			old, ok := d.Registry.Get(name)
+			if !ok {
+				continue
+			}
+			diff := old.configDiff(p)
+			if len(diff) == 0 {
+				continue
+			}
+			log.Printf("[%s] changed, replacing it: %s\n", name, strings.Join(diff, "; "))
+			d.Registry.Remove(name)
+		}
+		p.publish()
+		if err := d.Registry.Add(p); err != nil {
+			log.Printf("failed to add probe for discovered target %q: %v\n", t.Addr, err)
+			continue
+		}
+		log.Printf("[%s] was added for discovered target %q\n", p.Name, t.Addr)
+		d.managed[p.Name] = true
+	}
+	for name := range d.managed {
+		if !seen[name] {
+			d.Registry.Remove(name)
+			delete(d.managed, name)
+		}
+	}
+	return nil
+}
+
+// template returns the template to use for the target.
+func (d *Discovery) template(t Target) Template {
+	if tmpl, ok := d.Templates[t.Labels["template"]]; ok {
+		return tmpl
+	}
+	return d.Template
+}
+
+// Targets returns a target for each SRV record.
+func (sd SRVDiscoverer) Targets(ctx context.Context) ([]Target, error) {
+	r := sd.Resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	_, addrs, err := r.LookupSRV(ctx, sd.Service, sd.Proto, sd.Name)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]Target, len(addrs))
+	for i, a := range addrs {
+		host := strings.TrimSuffix(a.Target, ".")
+		targets[i] = Target{
+			Addr: net.JoinHostPort(host, strconv.Itoa(int(a.Port))),
+			Labels: map[string]string{
+				"host": host,
+				"port": strconv.Itoa(int(a.Port)),
+			},
+		}
+	}
+	return targets, nil
+}
+
+// Targets returns a target for each instance of the service.
+func (cd ConsulDiscoverer) Targets(ctx context.Context) ([]Target, error) {
+	client := cd.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := fmt.Sprintf("%s/v1/catalog/service/%s", strings.TrimSuffix(cd.Addr, "/"), url.PathEscape(cd.Service))
+	if cd.Tag != "" {
+		u += "?tag=" + url.QueryEscape(cd.Tag)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul returned %q for %s", resp.Status, u)
+	}
+	var instances []struct {
+		Node           string
+		Address        string
+		ServiceAddress string
+		ServicePort    int
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&instances); err != nil {
+		return nil, fmt.Errorf("bad response from consul: %v", err)
+	}
+	targets := make([]Target, len(instances))
+	for i, inst := range instances {
+		host := inst.ServiceAddress
+		if host == "" {
+			host = inst.Address
+		}
+		targets[i] = Target{
+			Addr: net.JoinHostPort(host, strconv.Itoa(inst.ServicePort)),
+			Labels: map[string]string{
+				"node":    inst.Node,
+				"host":    host,
+				"port":    strconv.Itoa(inst.ServicePort),
+				"service": cd.Service,
+			},
+		}
+	}
+	return targets, nil
+}
+
+// Targets returns the targets listed in the file.
+func (fd FileDiscoverer) Targets(ctx context.Context) ([]Target, error) {
+	b, err := os.ReadFile(fd.Path)
+	if err != nil {
+		return nil, err
+	}
+	return parseTargets(b)
+}
+
+// Changes returns a channel that receives a value when the file
+// changes, until ctx is done.
+func (fd FileDiscoverer) Changes(ctx context.Context) <-chan struct{} {
+	interval := fd.PollInterval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	c := make(chan struct{}, 1)
+	var lastMod time.Time
+	var lastSize int64
+	if fi, err := os.Stat(fd.Path); err == nil {
+		lastMod, lastSize = fi.ModTime(), fi.Size()
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			fi, err := os.Stat(fd.Path)
+			if err != nil {
+				continue
+			}
+			if fi.ModTime().Equal(lastMod) && fi.Size() == lastSize {
+				continue
+			}
+			lastMod, lastSize = fi.ModTime(), fi.Size()
+			log.Printf("%s changed, reloading targets\n", fd.Path)
+			select {
+			case c <- struct{}{}:
+			default:
+				// A reload is already pending.
+			}
+		}
+	}()
+	return c
+}
+
+// parseTargets parses a list of targets in YAML, or JSON which is a
+// subset of YAML.
+func parseTargets(b []byte) ([]Target, error) {
+	var entries []struct {
+		Addr    string            `yaml:"addr"`
+		Targets []string          `yaml:"targets"` // as in Prometheus' file_sd_config
+		Labels  map[string]string `yaml:"labels"`
+	}
+	if err := yaml.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("bad list of targets: %v", err)
+	}
+	targets := make([]Target, 0, len(entries))
+	for _, e := range entries {
+		if e.Addr == "" && len(e.Targets) == 0 {
+			return nil, fmt.Errorf("target is missing addr")
+		}
+		if e.Addr != "" {
+			targets = append(targets, Target{Addr: e.Addr, Labels: e.Labels})
+		}
+		for _, addr := range e.Targets {
+			targets = append(targets, Target{Addr: addr, Labels: e.Labels})
+		}
+	}
+	return targets, nil
+}
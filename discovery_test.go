@@ -0,0 +1,153 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeDiscoverer is a Discoverer returning the targets, or err if set.
+type fakeDiscoverer struct {
+	targets []Target
+	err     error
+}
+
+func (fd *fakeDiscoverer) Targets(context.Context) ([]Target, error) { return fd.targets, fd.err }
+
+func TestDiscovery_Sync(t *testing.T) {
+	fd := &fakeDiscoverer{}
+	reg := NewRegistry()
+	d := &Discovery{
+		Discoverer: fd,
+		Template: Template{
+			Name: "tcp-{target}",
+			New:  func(t Target) Prober { return TCPProber{Addr: t.Addr} },
+		},
+		Registry: reg,
+	}
+	names := func() map[string]bool {
+		m := map[string]bool{}
+		for _, p := range reg.Probes() {
+			m[p.Name] = true
+		}
+		return m
+	}
+	steps := []struct {
+		targets []Target
+		err     error
+		want    []string
+	}{
+		{
+			targets: []Target{{Addr: "a:80"}, {Addr: "b:80"}},
+			want:    []string{"tcp-a:80", "tcp-b:80"},
+		},
+		{
+			targets: []Target{{Addr: "b:80"}, {Addr: "c:80"}},
+			want:    []string{"tcp-b:80", "tcp-c:80"},
+		},
+		{
+			// Failing discovery shouldn't remove any probes.
+			err:  errors.New("discovery failing on purpose"),
+			want: []string{"tcp-b:80", "tcp-c:80"},
+		},
+		{
+			want: []string{},
+		},
+	}
+	for i, step := range steps {
+		fd.targets, fd.err = step.targets, step.err
+		err := d.Sync(context.Background())
+		if (err != nil) != (step.err != nil) {
+			t.Errorf("[%d] Sync() => %v; want error %v\n", i, err, step.err)
+		}
+		got := names()
+		if len(got) != len(step.want) {
+			t.Errorf("[%d] after Sync(), registry has %v; want %v\n", i, got, step.want)
+			continue
+		}
+		for _, n := range step.want {
+			if !got[n] {
+				t.Errorf("[%d] after Sync(), registry has %v; want %v\n", i, got, step.want)
+			}
+		}
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "- addr: a.example.com\n  labels:\n    env: prod\n- addr: b.example.com\n", want: 2},
+		{in: `[{"addr": "a.example.com", "labels": {"env": "prod"}}]`, want: 1},
+		{in: `[{"targets": ["a:80", "b:80"], "labels": {"env": "prod"}}, {"targets": ["c:80"]}]`, want: 3},
+		{in: "- labels:\n    env: prod\n", wantErr: true},
+		{in: "not a list", wantErr: true},
+	}
+	for i, tt := range cases {
+		got, err := parseTargets([]byte(tt.in))
+		if (err != nil) != tt.wantErr || len(got) != tt.want {
+			t.Errorf("[%d] parseTargets(%q) => %v, %v; want %d targets, error: %v\n", i, tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFileDiscoverer_Changes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.yaml")
+	if err := os.WriteFile(path, []byte("- addr: a:80\n"), 0644); err != nil {
+		t.Fatalf("failed to write targets: %v", err)
+	}
+	fd := FileDiscoverer{Path: path, PollInterval: time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := fd.Changes(ctx)
+
+	if err := os.WriteFile(path, []byte("- addr: a:80\n- addr: b:80\n"), 0644); err != nil {
+		t.Fatalf("failed to write targets: %v", err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Changes() didn't report change to %s", path)
+	}
+	got, err := fd.Targets(ctx)
+	if err != nil || len(got) != 2 {
+		t.Errorf("Targets() => %v, %v; want 2 targets\n", got, err)
+	}
+}
+
+func TestDiscovery_Sync_changed(t *testing.T) {
+	fd := &fakeDiscoverer{targets: []Target{{Addr: "a:80", Labels: map[string]string{"env": "dev"}}}}
+	reg := NewRegistry()
+	d := &Discovery{
+		Discoverer: fd,
+		Template: Template{
+			Name: "tcp-{target}",
+			New:  func(t Target) Prober { return TCPProber{Addr: t.Addr} },
+		},
+		Registry: reg,
+	}
+	if err := d.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := reg.Get("tcp-a:80")
+	if err := d.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := reg.Get("tcp-a:80"); p != first {
+		t.Errorf("after Sync() with unchanged targets, probe was replaced\n")
+	}
+	fd.targets[0].Labels = map[string]string{"env": "prod"}
+	if err := d.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := reg.Get("tcp-a:80")
+	if p == first || p.Labels["env"] != "prod" {
+		t.Errorf("after Sync() with changed labels, probe has labels %v; want it replaced with env=prod\n", p.Labels)
+	}
+	reg.Remove("tcp-a:80")
+}
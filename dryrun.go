@@ -0,0 +1,42 @@
+package prober
+
+import (
+	"flag"
+	"log"
+)
+
+var dryRun = flag.Bool("dry_run", false, "runs probes and records their results, but only logs alerts and warnings instead of sending them")
+
+// DryRun makes the probe run and record results as usual, but only log
+// its alerts and warnings instead of sending them, e.g. to try out a new
+// probe in production. The -dry_run flag does the same for all probes.
+//
+// Apart from not being sent, alerts are handled as if they were, e.g.
+// badness is reset.
+func DryRun() func(*Probe) {
+	return func(p *Probe) {
+		p.dryRun = true
+	}
+}
+
+// isDryRun returns true if the probe's notifications should only be
+// logged.
+func (p *Probe) isDryRun() bool {
+	return p.dryRun || *dryRun || p.engine != nil && p.engine.DryRun
+}
+
+// notify calls the Alert() implementation of the probe, or
+// AlertFingerprint() if it's a FingerprintAlerter, unless it's a dry
+// run, in which case the alert is only logged.
+func (p *Probe) notify(desc string) error {
+	if p.isDryRun() {
+		log.Printf("[%s] [dry run] would alert with badness %d: %s\n", p.Name, p.Badness(), desc)
+		return nil
+	}
+	return p.callNotifier("alert", func() error {
+		if fa, ok := p.Prober.(FingerprintAlerter); ok {
+			return fa.AlertFingerprint(p.Fingerprint(), p.Name, desc, p.Badness(), p.Records())
+		}
+		return p.Alert(p.Name, desc, p.Badness(), p.Records())
+	})
+}
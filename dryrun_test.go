@@ -0,0 +1,27 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProbe_sendAlert_dryRun(t *testing.T) {
+	alerts := make(chan string, 1)
+	p := &Probe{
+		Prober: countingProber{testProber{FailedWith(errors.New("failing on purpose"))}, alerts},
+		Name:   "DryRunProber",
+		t:      fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	DryRun()(p)
+	p.setBadness(500)
+	p.sendAlert()
+	select {
+	case name := <-alerts:
+		t.Errorf("%s alerted in dry run\n", name)
+	default:
+	}
+	if p.Badness() != 0 || p.getLastAlert().IsZero() {
+		t.Errorf("after dry run sendAlert(), badness %d and lastAlert %v; want alert handled as sent\n", p.Badness(), p.getLastAlert())
+	}
+}
@@ -0,0 +1,179 @@
+package prober
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// engineCount is the number of unnamed engines whose probes published
+// their metrics, for naming them.
+var engineCount int64
+
+// Engine holds the settings that are otherwise global, so that
+// independent sets of probes with different settings can run in one
+// process, e.g. for different tenants. Probes created with
+// Engine.NewProbe() use the settings of the engine instead of the
+// flags and package variables:
+//
+//	staging := &prober.Engine{LogPath: "/var/log/prober/staging.log", AlertThreshold: 500}
+//	prod := &prober.Engine{LogPath: "/var/log/prober/prod.log", Location: "eu-west-1"}
+//	r := prober.NewRegistry(
+//		staging.NewProbe(stagingProber, "StagingWeb", "Staging web server is up"),
+//		prod.NewProbe(prodProber, "ProdWeb", "Prod web server is up"),
+//	)
+//
+// Engines that are no longer needed, e.g. of a tenant that was
+// removed, should be closed with Close() once their probes have
+// stopped.
+//
+// The status of probes of an engine is published via expvar in a map
+// of its own, under the Name of the engine in the "engines" map rather
+// than in "probes", so probes of different engines can have the same
+// name.
+//
+// The -no_alerts and -dry_run flags still apply to all engines, so
+// that alerts can be turned off for the whole process at once. The
+// -max_record_bytes limit is also shared, since it's a limit on the
+// memory of the process.
+type Engine struct {
+	// Name of the engine, e.g. of its tenant, under which the status of
+	// its probes is published, or "" for "engine1", "engine2", etc.
+	// Engines should have different names.
+	Name string
+	// Path of the YAML log file to write records to, or "" for
+	// "prober.outcomes.log" in the temporary directory.
+	LogPath string
+	// Encoding of records in the log file, or nil for -log_format.
+	LogCodec Codec
+	// How timestamps of records are written, by default as given by
+	// -time_format and -time_zone.
+	TimeFormat TimeFormat
+	// Level of badness at which probes alert, or 0 for
+	// -alert_threshold. Probes with Thresholds() use their own.
+	AlertThreshold int
+	// Most often to call Alert() for a probe, or 0 for
+	// MaxAlertFrequency.
+	MaxAlertFrequency time.Duration
+	// Interval of probes, unless given with Interval(), or 0 for
+	// DefaultInterval.
+	DefaultInterval time.Duration
+	// Where the probes run from, or "" for -location or the hostname.
+	Location       string
+	AlertsDisabled bool // whether alerts and warnings are disabled, like -no_alerts
+	DryRun         bool // whether alerts and warnings are only logged, like -dry_run
+	lw             *logWriter
+	logFile        *os.File
+	logOnce        sync.Once
+	metrics        *expvar.Map // status of the probes of the engine
+	metricsName    string      // name metrics is published under
+	metricsOnce    sync.Once
+}
+
+// NewProbe returns a new probe using the settings of the engine, see
+// NewProbe().
+func (e *Engine) NewProbe(p Prober, name, desc string, options ...Option) *Probe {
+	options = append([]Option{func(probe *Probe) {
+		probe.engine = e
+		if e.DefaultInterval > 0 {
+			probe.Interval = e.DefaultInterval
+		}
+		if e.Location != "" {
+			probe.Location = e.Location
+		}
+	}}, options...)
+	return NewProbe(p, name, desc, options...)
+}
+
+// LogStats returns stats on the writes of records to the YAML log file
+// of the engine.
+func (e *Engine) LogStats() LogStats {
+	return e.logWriter().Stats()
+}
+
+// FlushLog writes all queued records to the YAML log file of the
+// engine, blocking until they are written.
+func (e *Engine) FlushLog() {
+	e.logWriter().flush()
+}
+
+// Close writes all queued records to the YAML log file of the engine
+// and closes it, stopping the goroutine writing to it, and unpublishes
+// the status of its probes. Records of probes of the engine that run
+// after Close are dropped.
+func (e *Engine) Close() error {
+	e.metricsOnce.Do(func() {
+		// No probe published its status, and any publishing later
+		// mustn't be visible.
+		e.metrics = new(expvar.Map).Init()
+	})
+	if e.metricsName != "" {
+		engineMetrics.Delete(e.metricsName)
+	}
+	e.logOnce.Do(func() {
+		// The log file was never opened, and mustn't be after Close.
+		e.lw = newLogWriter(io.Discard, 1, time.Hour)
+	})
+	e.lw.stop()
+	if e.logFile == nil {
+		return nil
+	}
+	return e.logFile.Close()
+}
+
+// metricsMap returns the map the probes of the engine publish their
+// status in, publishing it in the "engines" map if needed.
+func (e *Engine) metricsMap() *expvar.Map {
+	e.metricsOnce.Do(func() {
+		e.metricsName = e.Name
+		if e.metricsName == "" {
+			e.metricsName = fmt.Sprintf("engine%d", atomic.AddInt64(&engineCount, 1))
+		}
+		e.metrics = new(expvar.Map).Init()
+		engineMetrics.Set(e.metricsName, e.metrics)
+	})
+	return e.metrics
+}
+
+// logWriter returns the writer of records to the YAML log file of the
+// engine, opening it if needed.
+func (e *Engine) logWriter() *logWriter {
+	e.logOnce.Do(func() {
+		path := e.LogPath
+		if path == "" {
+			path = filepath.Join(logDir, logName)
+		}
+		e.lw, e.logFile = openLogWriter(path)
+	})
+	return e.lw
+}
+
+// logWriter returns the writer of records to the YAML log file of the
+// probe's engine, or the global one if it has none.
+func (p *Probe) logWriter() *logWriter {
+	if p.engine != nil {
+		return p.engine.logWriter()
+	}
+	onceOpen.Do(openLog)
+	return getLogWriter()
+}
+
+// alertsDisabled returns true if the probe shouldn't send alerts or
+// warnings.
+func (p *Probe) alertsDisabled() bool {
+	return *alertsDisabled || p.engine != nil && p.engine.AlertsDisabled
+}
+
+// maxAlertFrequency returns the least time between calls to Alert() for
+// the probe.
+func (p *Probe) maxAlertFrequency() time.Duration {
+	if p.engine != nil && p.engine.MaxAlertFrequency > 0 {
+		return p.engine.MaxAlertFrequency
+	}
+	return MaxAlertFrequency
+}
@@ -0,0 +1,129 @@
+package prober
+
+import (
+	"errors"
+	"expvar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngine(t *testing.T) {
+	dir := t.TempDir()
+	e1 := &Engine{
+		LogPath:           filepath.Join(dir, "one.log"),
+		AlertThreshold:    20,
+		MaxAlertFrequency: time.Hour,
+		DefaultInterval:   time.Second,
+		Location:          "eu",
+	}
+	e2 := &Engine{LogPath: filepath.Join(dir, "two.log"), DryRun: true}
+	p1 := e1.NewProbe(testProber{Passed()}, "EngineProber1", "Runs in engine one.")
+	p2 := e2.NewProbe(testProber{Passed()}, "EngineProber2", "Runs in engine two.", Interval(time.Hour))
+
+	if p1.Interval != time.Second || p1.Location != "eu" {
+		t.Errorf("probe of engine one has interval %v and location %q; want 1s and eu\n", p1.Interval, p1.Location)
+	}
+	if p2.Interval != time.Hour {
+		t.Errorf("probe of engine two has interval %v; want Interval() to take precedence\n", p2.Interval)
+	}
+	if got := p1.threshold(); got != 20 {
+		t.Errorf("threshold() of engine one => %d; want 20\n", got)
+	}
+	if got, want := p2.threshold(), *alertThreshold; got != want {
+		t.Errorf("threshold() of engine two => %d; want -alert_threshold %d\n", got, want)
+	}
+	if got := p1.maxAlertFrequency(); got != time.Hour {
+		t.Errorf("maxAlertFrequency() of engine one => %v; want 1h\n", got)
+	}
+	if p1.isDryRun() || !p2.isDryRun() {
+		t.Errorf("isDryRun() => %v, %v; want only engine two to be a dry run\n", p1.isDryRun(), p2.isDryRun())
+	}
+
+	p1.logResult(FailedWith(errors.New("failing in engine one")))
+	p2.logResult(Passed())
+	e1.FlushLog()
+	e2.FlushLog()
+	for _, tt := range []struct {
+		e         *Engine
+		want, not string
+	}{
+		{e1, "failing in engine one", "code: 0"},
+		{e2, "code: 0", "failing in engine one"},
+	} {
+		b, err := os.ReadFile(tt.e.LogPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), tt.want) || strings.Contains(string(b), tt.not) {
+			t.Errorf("log %s holds %q; want %q but not %q\n", tt.e.LogPath, b, tt.want, tt.not)
+		}
+		if got := tt.e.LogStats().Written; got != 1 {
+			t.Errorf("LogStats() of %s has %d written records; want 1\n", tt.e.LogPath, got)
+		}
+	}
+}
+
+func TestEngine_Close(t *testing.T) {
+	e := &Engine{LogPath: filepath.Join(t.TempDir(), "closed.log")}
+	p := e.NewProbe(testProber{Passed()}, "ClosedEngineProber", "Runs in a closed engine.")
+	p.logResult(FailedWith(errors.New("failing before close")))
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close() => %v; want nil\n", err)
+	}
+	b, err := os.ReadFile(e.LogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "failing before close") {
+		t.Errorf("log holds %q after Close(); want queued record written\n", b)
+	}
+
+	// Records after Close are dropped, rather than blocking or panicking.
+	p.logResult(FailedWith(errors.New("failing after close")))
+	e.FlushLog()
+	if got := e.LogStats(); got.Written != 1 || got.Dropped != 1 {
+		t.Errorf("LogStats() after Close() => %+v; want 1 written and 1 dropped\n", got)
+	}
+
+	if err := (&Engine{}).Close(); err != nil {
+		t.Errorf("Close() of unused engine => %v; want nil\n", err)
+	}
+}
+
+func TestEngine_metrics(t *testing.T) {
+	e1, e2 := &Engine{Name: "tenant1"}, &Engine{Name: "tenant2"}
+	defer e1.Close()
+	defer e2.Close()
+	p1 := e1.NewProbe(testProber{Passed()}, "Web", "Web server of tenant one.")
+	p2 := e2.NewProbe(testProber{Passed()}, "Web", "Web server of tenant two.")
+	r1 := NewRegistry(p1)
+	NewRegistry(p2)
+
+	status := func(engine string) *Status {
+		m, ok := engineMetrics.Get(engine).(*expvar.Map)
+		if !ok {
+			return nil
+		}
+		v, ok := m.Get("Web").(expvar.Func)
+		if !ok {
+			return nil
+		}
+		s := v.Value().(Status)
+		return &s
+	}
+	if s1, s2 := status("tenant1"), status("tenant2"); s1 == nil || s2 == nil || s1.Desc != p1.Desc || s2.Desc != p2.Desc {
+		t.Errorf("status of Web in tenant1 and tenant2 => %+v, %+v; want each of its own engine\n", s1, s2)
+	}
+	if metrics.Get("Web") != nil {
+		t.Errorf("probes of engines are published in \"probes\"; want only in \"engines\"\n")
+	}
+
+	// Removing the probe of one tenant leaves that of the other.
+	r1.Remove("Web")
+	if s1, s2 := status("tenant1"), status("tenant2"); s1 != nil || s2 == nil {
+		t.Errorf("after removing Web of tenant1, status => %+v, %+v; want only tenant2\n", s1, s2)
+	}
+}
@@ -0,0 +1,268 @@
+package prober
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxExecResponse is the longest response line ExecProber accepts.
+const maxExecResponse = 1 << 20
+
+type (
+	// ExecProber is a Prober that runs probes in a separate process,
+	// so that they can be written in any language, and crashes or
+	// leaks in them can't take the prober down.
+	//
+	// The process is started on the first run and kept running. For
+	// each run, a request is written to its stdin as a line of JSON:
+	//
+	//	{"run_id": "9f86d081884c7d65", "config": {"queue": "jobs"}}
+	//
+	// where config is the Config of the ExecProber, and the process
+	// answers on stdout with a line of JSON:
+	//
+	//	{"code": "fail", "error": "queue too long", "value": 1200, "details": {"queue": "jobs"}}
+	//
+	// where code is "pass", "fail" or "degraded", and all other fields
+	// are optional: error, info, info_url, value, weight and details.
+	// The stderr of the process goes to the log of the prober.
+	//
+	// If the process exits, or doesn't answer before the run times
+	// out, the run fails, and a new process is started for the next
+	// run.
+	//
+	// The Alert() part of the Prober interface is provided by the
+	// embedded AlertFn.
+	ExecProber struct {
+		AlertFn
+		Command []string    // command to run and its arguments
+		Config  interface{} // configuration sent with each request, e.g. the target to probe
+		Env     []string    // extra environment variables, as "KEY=value"
+		// How long a run may take, or 0 for 10 seconds, if the
+		// context of the run has no deadline.
+		Timeout time.Duration
+		proc    *execProcess
+		lock    sync.Mutex // protects proc and serializes runs
+	}
+
+	// execProcess is a running process of an ExecProber.
+	execProcess struct {
+		cmd       *exec.Cmd
+		stdin     io.WriteCloser
+		responses chan []byte   // lines written to stdout
+		quit      chan struct{} // closed when the process is stopped
+		done      chan struct{} // closed when the process has exited
+		err       error         // why the process exited, set before done is closed
+	}
+
+	// execRequest is a request to the process of an ExecProber.
+	execRequest struct {
+		RunID  string      `json:"run_id,omitempty"`
+		Config interface{} `json:"config,omitempty"`
+	}
+
+	// execResponse is a response from the process of an ExecProber.
+	execResponse struct {
+		Code    string            `json:"code"`
+		Error   string            `json:"error"`
+		Info    string            `json:"info"`
+		InfoURL string            `json:"info_url"`
+		Value   *float64          `json:"value"`
+		Weight  float64           `json:"weight"`
+		Details map[string]string `json:"details"`
+	}
+)
+
+// NewExecProber returns an ExecProber running the command with the
+// arguments.
+func NewExecProber(name string, args ...string) *ExecProber {
+	return &ExecProber{Command: append([]string{name}, args...)}
+}
+
+// Probe sends a request to the process and waits for its response.
+func (ep *ExecProber) Probe() Result {
+	return ep.ProbeContext(context.Background())
+}
+
+// ProbeContext sends a request with the RunID() of ctx to the process,
+// and waits for its response until ctx is done.
+func (ep *ExecProber) ProbeContext(ctx context.Context) Result {
+	if _, ok := ctx.Deadline(); !ok || ep.Timeout > 0 {
+		timeout := ep.Timeout
+		if timeout == 0 {
+			timeout = 10 * time.Second
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := json.Marshal(execRequest{RunID: RunID(ctx), Config: ep.Config})
+	if err != nil {
+		return FailedWith(fmt.Errorf("failed to encode request: %v", err))
+	}
+
+	ep.lock.Lock()
+	defer ep.lock.Unlock()
+	if ep.proc == nil {
+		proc, err := ep.start()
+		if err != nil {
+			return FailedWith(err)
+		}
+		ep.proc = proc
+	}
+	proc := ep.proc
+	if _, err := proc.stdin.Write(append(req, '\n')); err != nil {
+		ep.stop()
+		return FailedWith(fmt.Errorf("failed to send request to %s: %v", ep.Command[0], err))
+	}
+	select {
+	case line := <-proc.responses:
+		var resp execResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			// The process may be out of step with requests now.
+			ep.stop()
+			return FailedWith(fmt.Errorf("%s sent bad response %q: %v", ep.Command[0], truncate(string(line), 512), err))
+		}
+		return resp.result()
+	case <-proc.done:
+		ep.stop()
+		return FailedWith(fmt.Errorf("%s exited: %v", ep.Command[0], proc.err))
+	case <-ctx.Done():
+		// A late response would be taken as that of the next run, so
+		// start over with a new process.
+		ep.stop()
+		return FailedWith(fmt.Errorf("%s didn't respond: %v", ep.Command[0], ctx.Err()))
+	}
+}
+
+// Close stops the process, if it's running.
+func (ep *ExecProber) Close() error {
+	ep.lock.Lock()
+	defer ep.lock.Unlock()
+	ep.stop()
+	return nil
+}
+
+// start starts the process.
+func (ep *ExecProber) start() (*execProcess, error) {
+	if len(ep.Command) == 0 {
+		return nil, errors.New("no command to run")
+	}
+	cmd := exec.Command(ep.Command[0], ep.Command[1:]...)
+	cmd.Env = append(os.Environ(), ep.Env...)
+	cmd.Stderr = &lineLogger{prefix: fmt.Sprintf("[%s] ", ep.Command[0])}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %v", strings.Join(ep.Command, " "), err)
+	}
+	proc := &execProcess{
+		cmd:       cmd,
+		stdin:     stdin,
+		responses: make(chan []byte),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go proc.read(stdout)
+	return proc, nil
+}
+
+// read sends the lines written to stdout as responses, until the
+// process exits. Lines written after the process is stopped are
+// dropped.
+func (proc *execProcess) read(stdout io.Reader) {
+	s := bufio.NewScanner(stdout)
+	s.Buffer(make([]byte, 4096), maxExecResponse)
+	for s.Scan() {
+		line := append([]byte{}, s.Bytes()...)
+		select {
+		case proc.responses <- line:
+		case <-proc.quit:
+		}
+	}
+	proc.err = s.Err()
+	if werr := proc.cmd.Wait(); proc.err == nil {
+		proc.err = werr
+	}
+	if proc.err == nil {
+		proc.err = errors.New("exit status 0")
+	}
+	close(proc.done)
+}
+
+// stop kills the process, if it's running. The caller must hold lock.
+func (ep *ExecProber) stop() {
+	if ep.proc == nil {
+		return
+	}
+	close(ep.proc.quit)
+	ep.proc.stdin.Close()
+	if ep.proc.cmd.Process != nil {
+		ep.proc.cmd.Process.Kill()
+	}
+	ep.proc = nil
+}
+
+// result returns the Result the response describes.
+func (resp execResponse) result() Result {
+	r := Result{
+		Info:    resp.Info,
+		InfoUrl: resp.InfoURL,
+		Details: resp.Details,
+		Weight:  resp.Weight,
+		Value:   resp.Value,
+	}
+	switch resp.Code {
+	case "pass":
+		r.Code = Pass
+	case "degraded":
+		r.Code = Degraded
+	case "fail":
+		r.Code = Fail
+	default:
+		r.Code = Fail
+		resp.Error = fmt.Sprintf("unknown code %q in response: %s", resp.Code, resp.Error)
+	}
+	if resp.Error != "" {
+		r.Error = errors.New(resp.Error)
+	} else if r.Code != Pass {
+		r.Error = fmt.Errorf("probe %s without an error", resp.Code)
+	}
+	return r
+}
+
+// lineLogger logs each line written to it with the prefix.
+type lineLogger struct {
+	prefix string
+	buf    []byte
+}
+
+func (w *lineLogger) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		log.Printf("%s%s\n", w.prefix, w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(b), nil
+}
@@ -0,0 +1,74 @@
+package prober
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecProber_Probe(t *testing.T) {
+	cases := []struct {
+		script  string
+		want    ResultCode
+		wantErr string
+	}{
+		{
+			script: `while read req; do echo '{"code": "pass", "info": "ok", "details": {"queue": "jobs"}}'; done`,
+			want:   Pass,
+		},
+		{
+			script:  `while read req; do echo '{"code": "degraded", "error": "1 of 3 down"}'; done`,
+			want:    Degraded,
+			wantErr: "1 of 3 down",
+		},
+		{
+			script:  `read req; echo 'not json'`,
+			want:    Fail,
+			wantErr: "sh sent bad response",
+		},
+		{
+			script:  `read req; exit 3`,
+			want:    Fail,
+			wantErr: "sh exited: exit status 3",
+		},
+		{
+			script:  `read req; sleep 5`,
+			want:    Fail,
+			wantErr: "sh didn't respond: context deadline exceeded",
+		},
+	}
+	for i, tt := range cases {
+		ep := NewExecProber("sh", "-c", tt.script)
+		ep.Timeout = 100 * time.Millisecond
+		got := ep.Probe()
+		ep.Close()
+		if got.Code != tt.want {
+			t.Errorf("[%d] Probe() => %v (%v); want %v\n", i, got.Code, got.Error, tt.want)
+		}
+		if tt.wantErr != "" && (got.Error == nil || !strings.HasPrefix(got.Error.Error(), tt.wantErr)) {
+			t.Errorf("[%d] Probe() => error %v; want %s\n", i, got.Error, tt.wantErr)
+		}
+	}
+}
+
+func TestExecProber_restart(t *testing.T) {
+	// The process echoes the request back as the info of the result,
+	// and exits after the first request.
+	ep := NewExecProber("sh", "-c", `read req; printf '{"code": "pass", "info": "%s"}\n' "$(echo "$req" | sed 's/"/\\"/g')"`)
+	ep.Config = map[string]string{"queue": "jobs"}
+	defer ep.Close()
+	ctx := withRunID(context.Background(), "run-1")
+	got := ep.ProbeContext(ctx)
+	if want := `{"run_id":"run-1","config":{"queue":"jobs"}}`; got.Code != Pass || got.Info != want {
+		t.Fatalf("ProbeContext() => %v; want Pass with info %s\n", got, want)
+	}
+	// The process has exited, so the next run fails, and the one after
+	// that gets a new process.
+	if got := ep.Probe(); got.Code != Fail {
+		t.Errorf("Probe() after exit => %v; want Fail\n", got)
+	}
+	if got := ep.Probe(); got.Code != Pass {
+		t.Errorf("Probe() after restart => %v; want Pass\n", got)
+	}
+}
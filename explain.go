@@ -0,0 +1,175 @@
+package prober
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// badnessStepsSize is the number of changes of badness Explain()
+	// keeps the causes of.
+	badnessStepsSize = 20
+	// explainNoteSteps is the most changes of badness notes in alerts
+	// spell out.
+	explainNoteSteps = 10
+)
+
+type (
+	// Explanation describes why a probe is or isn't alerting: how its
+	// badness came about, what it alerts on, and what happened after
+	// its last run.
+	Explanation struct {
+		Name          string
+		Alerting      bool
+		AlertingSince time.Time     // when the probe last started alerting, if it is
+		Condition     string        // what the probe alerts on, e.g. "badness >= 100"
+		Badness       int           // current badness
+		Threshold     int           // badness at which the probe alerts, unless it alerts on something else
+		Steps         []BadnessStep // recent changes of badness, oldest first
+		Decision      string        // why the probe did or didn't alert after its last run
+		timeFormat    TimeFormat
+	}
+
+	// BadnessStep is a change of the badness of a probe, and its cause.
+	BadnessStep struct {
+		Time    time.Time
+		Change  int    // e.g. 50 for a failure, or -1 for a pass
+		Badness int    // badness after the change
+		Cause   string // e.g. "failed: connection refused, penalty 100 × 0.5 (degraded)"
+	}
+)
+
+// Explain returns an explanation of why the probe is or isn't alerting,
+// e.g. for a responder wondering why an alert fired, or didn't.
+func (p *Probe) Explain() Explanation {
+	p.alertLock.RLock()
+	e := Explanation{
+		Name:      p.Name,
+		Alerting:  p.alerting,
+		Badness:   p.badness,
+		Steps:     append([]BadnessStep{}, p.badnessSteps...),
+		Decision:  p.alertDecision,
+		Threshold: p.threshold(),
+	}
+	if p.alerting {
+		e.AlertingSince = p.alertingSince
+	}
+	p.alertLock.RUnlock()
+	e.Condition = p.conditionString()
+	e.timeFormat = p.timeFormat()
+	return e
+}
+
+// String returns the explanation in a few lines of English.
+func (e Explanation) String() string {
+	var b strings.Builder
+	if e.Alerting {
+		fmt.Fprintf(&b, "%s is alerting", e.Name)
+		if !e.AlertingSince.IsZero() {
+			fmt.Fprintf(&b, " since %s", e.timeFormat.Format(e.AlertingSince))
+		}
+	} else {
+		fmt.Fprintf(&b, "%s is not alerting", e.Name)
+	}
+	fmt.Fprintf(&b, ", and alerts when %s. Badness is %d of threshold %d.\n", e.Condition, e.Badness, e.Threshold)
+	for _, s := range e.Steps {
+		fmt.Fprintf(&b, "  %s  %+d = %d: %s\n", e.timeFormat.Format(s.Time), s.Change, s.Badness, s.Cause)
+	}
+	if e.Decision != "" {
+		fmt.Fprintf(&b, "After the last run: %s.\n", e.Decision)
+	}
+	return b.String()
+}
+
+// conditionString returns what the probe alerts on.
+func (p *Probe) conditionString() string {
+	var conds []string
+	if p.rateWindow > 0 {
+		conds = append(conds, fmt.Sprintf("its value grows by more than %g/min", p.maxRate))
+	}
+	switch {
+	case p.alertWhen != nil && p.alertWhenExprLang:
+		conds = append(conds, fmt.Sprintf("%s holds in expr-lang", p.alertWhenSrc))
+	case p.alertWhen != nil:
+		conds = append(conds, fmt.Sprintf("%s holds", p.alertWhenSrc))
+	case p.sloTarget > 0:
+		conds = append(conds, fmt.Sprintf("it burns the error budget of its %g%% SLO too fast", p.sloTarget*100))
+	default:
+		conds = append(conds, fmt.Sprintf("badness >= %d", p.threshold()))
+	}
+	return strings.Join(conds, " or ")
+}
+
+// noteBadnessStep adds the change of badness to b at the time and its
+// cause to the steps Explain() shows.
+func (p *Probe) noteBadnessStep(t time.Time, change, b int, cause string) {
+	p.alertLock.Lock()
+	defer p.alertLock.Unlock()
+	p.badnessSteps = append(p.badnessSteps, BadnessStep{Time: t, Change: change, Badness: b, Cause: cause})
+	if over := len(p.badnessSteps) - badnessStepsSize; over > 0 {
+		p.badnessSteps = append(p.badnessSteps[:0], p.badnessSteps[over:]...)
+	}
+}
+
+// setAlertDecision sets why the probe did or didn't alert after the
+// last run.
+func (p *Probe) setAlertDecision(format string, args ...interface{}) {
+	p.alertLock.Lock()
+	defer p.alertLock.Unlock()
+	p.alertDecision = fmt.Sprintf(format, args...)
+}
+
+// resultCause returns the cause of a change of badness from the result.
+func (p *Probe) resultCause(r Result, change int) string {
+	if r.Passed() {
+		if change == 0 {
+			return "passed, with badness already 0"
+		}
+		return fmt.Sprintf("passed, reward %d", p.successReward)
+	}
+	_, factors := p.weighPenalty(r)
+	base, timeout := p.basePenalty(r)
+	kind := "penalty"
+	if timeout {
+		kind = "timeout penalty"
+	}
+	cause := fmt.Sprintf("%s: %v, %s %d", strings.ToLower(r.Code.String()), r.Error, kind, base)
+	if len(factors) > 0 {
+		cause += " × " + strings.Join(factors, " × ")
+	}
+	return cause
+}
+
+// explainNote returns a note on how badness reached its current level
+// since it was last 0 for alerts, e.g. " [badness +50 +50 = 100,
+// threshold 100]", or "" if the probe doesn't alert on badness.
+func (p *Probe) explainNote() string {
+	if p.alertWhen != nil || p.sloTarget > 0 {
+		return ""
+	}
+	p.alertLock.RLock()
+	steps := p.badnessSteps
+	i := len(steps)
+	for i > 0 && steps[i-1].Badness-steps[i-1].Change > 0 {
+		i--
+	}
+	if i > 0 {
+		i--
+	}
+	var changes []string
+	for _, s := range steps[i:] {
+		if s.Change != 0 {
+			changes = append(changes, fmt.Sprintf("%+d", s.Change))
+		}
+	}
+	badness := p.badness
+	p.alertLock.RUnlock()
+	if len(changes) == 0 {
+		return ""
+	}
+	if len(changes) > explainNoteSteps {
+		changes = append([]string{"…"}, changes[len(changes)-explainNoteSteps:]...)
+	}
+	return fmt.Sprintf(" [badness %s = %d, threshold %d]", strings.Join(changes, " "), badness, p.threshold())
+}
@@ -0,0 +1,54 @@
+package prober
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbe_Explain(t *testing.T) {
+	p := &Probe{
+		Prober:         testProber{},
+		Name:           "ExplainedProber",
+		failurePenalty: 50,
+		successReward:  1,
+		critThreshold:  100,
+		engine:         &Engine{AlertsDisabled: true},
+		t:              fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	p.handleResult(DegradedWith(errors.New("1 of 2 down"), ""))
+	p.handleResult(FailedWith(errors.New("connection refused")))
+	e := p.Explain()
+	if e.Alerting || e.Badness != 75 || e.Threshold != 100 || e.Condition != "badness >= 100" {
+		t.Errorf("Explain() => %+v; want not alerting with badness 75 of threshold 100\n", e)
+	}
+	wantSteps := []BadnessStep{
+		{Change: 25, Badness: 25, Cause: "degraded: 1 of 2 down, penalty 50 × 0.5 (degraded)"},
+		{Change: 50, Badness: 75, Cause: "fail: connection refused, penalty 50"},
+	}
+	if len(e.Steps) != len(wantSteps) {
+		t.Fatalf("Explain() => steps %v; want %v\n", e.Steps, wantSteps)
+	}
+	for i, want := range wantSteps {
+		got := e.Steps[i]
+		if got.Change != want.Change || got.Badness != want.Badness || got.Cause != want.Cause {
+			t.Errorf("[%d] Explain() => step %+v; want %+v\n", i, got, want)
+		}
+	}
+	if want := "not alerting, since badness >= 100 doesn't hold"; e.Decision != want {
+		t.Errorf("Explain() => decision %q; want %q\n", e.Decision, want)
+	}
+
+	p.handleResult(FailedWith(errors.New("connection refused")))
+	e = p.Explain()
+	if !e.Alerting || e.AlertingSince.IsZero() || e.Decision != "alerting, but alerts are disabled" {
+		t.Errorf("Explain() after crossing threshold => %+v; want alerting, with alerts disabled\n", e)
+	}
+	if got, want := p.explainNote(), " [badness +25 +50 +50 = 125, threshold 100]"; got != want {
+		t.Errorf("explainNote() => %q; want %q\n", got, want)
+	}
+	if got := e.String(); !strings.Contains(got, "ExplainedProber is alerting since 1998-11-19T15:14:00Z") || !strings.Contains(got, "+50 = 125: fail: connection refused") {
+		t.Errorf("Explain().String() => %q; want the alert and steps\n", got)
+	}
+}
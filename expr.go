@@ -0,0 +1,663 @@
+package prober
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type (
+	// Expr is an expression in a small language for checks, which can
+	// be written in config rather than Go, e.g.
+	//
+	//	status == 200 && latency < 300ms && body contains "ok"
+	//
+	// Values are numbers, durations like 300ms or 1h30m, "strings",
+	// true and false, and variables given when evaluating the
+	// expression. Values are compared with ==, !=, <, <=, > and >=,
+	// strings also with contains and matches (a regular expression),
+	// and booleans combined with &&, || and !. Functions are called as
+	// name(args); the built-in ones are len(s), lower(s), hour(t) and
+	// weekday(t), where weekday is 0 for Sunday.
+	Expr struct {
+		src   string
+		root  exprNode
+		names []string // variables the expression refers to
+	}
+
+	// Vars are the values of the variables an Expr can refer to, each
+	// a number of any Go numeric type, a time.Duration, a time.Time, a
+	// string, a bool, or a Func.
+	Vars map[string]interface{}
+
+	// Func is a function that an Expr can call.
+	Func func(args ...interface{}) (interface{}, error)
+
+	// exprNode is a node in the syntax tree of an expression.
+	exprNode interface {
+		eval(vars Vars) (interface{}, error)
+	}
+
+	// literal is a constant value.
+	literal struct{ v interface{} }
+
+	// variable is a reference to a variable.
+	variable struct{ name string }
+
+	// call is a call of a function.
+	call struct {
+		name string
+		args []exprNode
+	}
+
+	// not negates a boolean.
+	not struct{ x exprNode }
+
+	// logical is && or || of two booleans.
+	logical struct {
+		op   string
+		x, y exprNode
+	}
+
+	// comparison compares two values.
+	comparison struct {
+		op   string
+		x, y exprNode
+		re   *regexp.Regexp // compiled pattern for matches, if the pattern is a literal
+	}
+
+	// token is a lexical token of an expression.
+	token struct {
+		kind string // "num", "dur", "str", "ident", "op" or "" at the end
+		text string
+		v    interface{} // value of literals
+	}
+
+	// exprParser parses an expression from its tokens.
+	exprParser struct {
+		toks  []token
+		pos   int
+		names map[string]bool
+	}
+)
+
+// builtins are the functions every Expr can call.
+var builtins = map[string]Func{
+	"len": func(args ...interface{}) (interface{}, error) {
+		s, err := stringArg("len", args)
+		return float64(len(s)), err
+	},
+	"lower": func(args ...interface{}) (interface{}, error) {
+		s, err := stringArg("lower", args)
+		return strings.ToLower(s), err
+	},
+	"hour": func(args ...interface{}) (interface{}, error) {
+		t, err := timeArg("hour", args)
+		return float64(t.Hour()), err
+	},
+	"weekday": func(args ...interface{}) (interface{}, error) {
+		t, err := timeArg("weekday", args)
+		return float64(t.Weekday()), err
+	},
+}
+
+// ParseExpr parses the expression.
+func ParseExpr(s string) (*Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, fmt.Errorf("bad expression %q: %v", s, err)
+	}
+	p := &exprParser{toks: toks, names: map[string]bool{}}
+	root, err := p.or()
+	if err == nil && p.peek().kind != "" {
+		err = fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bad expression %q: %v", s, err)
+	}
+	e := &Expr{src: s, root: root}
+	for name := range p.names {
+		e.names = append(e.names, name)
+	}
+	sort.Strings(e.names)
+	return e, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// MarshalText returns the source of the expression, so an Expr can be
+// part of e.g. YAML or JSON config.
+func (e *Expr) MarshalText() ([]byte, error) {
+	return []byte(e.src), nil
+}
+
+// UnmarshalText parses the expression.
+func (e *Expr) UnmarshalText(b []byte) error {
+	parsed, err := ParseExpr(string(b))
+	if err != nil {
+		return err
+	}
+	*e = *parsed
+	return nil
+}
+
+// Eval evaluates the expression with the variables.
+func (e *Expr) Eval(vars Vars) (interface{}, error) {
+	return e.root.eval(vars)
+}
+
+// Check evaluates the expression, which must be a boolean, with the
+// variables.
+func (e *Expr) Check(vars Vars) (bool, error) {
+	v, err := e.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%q is %s, not a boolean", e.src, typeName(v))
+	}
+	return b, nil
+}
+
+// describe returns the values of the variables the expression refers
+// to, e.g. to explain why a check failed.
+func (e *Expr) describe(vars Vars) string {
+	var parts []string
+	for _, name := range e.names {
+		v, ok := vars[name]
+		if !ok {
+			continue
+		}
+		switch v := normalize(v).(type) {
+		case string:
+			if len(v) > 64 {
+				v = v[:64] + "..."
+			}
+			parts = append(parts, fmt.Sprintf("%s=%q", name, v))
+		case Func:
+		default:
+			parts = append(parts, fmt.Sprintf("%s=%v", name, v))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkAssertion returns an error describing why the expression
+// doesn't hold for the variables, if it doesn't.
+func checkAssertion(s string, vars Vars) error {
+	e, err := ParseExpr(s)
+	if err != nil {
+		return err
+	}
+	ok, err := e.Check(vars)
+	if err != nil {
+		return fmt.Errorf("failed to check %q: %v", s, err)
+	}
+	if !ok {
+		return fmt.Errorf("assertion %q failed with %s", s, e.describe(vars))
+	}
+	return nil
+}
+
+// lex splits the expression into tokens.
+func lex(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(s) {
+				if strings.HasPrefix(s[j:], "µ") {
+					j += len("µ")
+				} else if isDigit(s[j]) || s[j] == '.' || isLetter(s[j]) {
+					j++
+				} else {
+					break
+				}
+			}
+			text := s[i:j]
+			if strings.IndexFunc(text, unicode.IsLetter) < 0 {
+				f, err := strconv.ParseFloat(text, 64)
+				if err != nil {
+					return nil, fmt.Errorf("bad number %q", text)
+				}
+				toks = append(toks, token{kind: "num", text: text, v: f})
+			} else {
+				d, err := time.ParseDuration(text)
+				if err != nil {
+					return nil, fmt.Errorf("bad duration %q", text)
+				}
+				toks = append(toks, token{kind: "dur", text: text, v: d})
+			}
+			i = j
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string %s", s[i:])
+			}
+			str, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("bad string %s", s[i:j+1])
+			}
+			toks = append(toks, token{kind: "str", text: s[i : j+1], v: str})
+			i = j + 1
+		case isLetter(s[i]) || c == '_':
+			j := i
+			for j < len(s) && (isLetter(s[j]) || isDigit(s[j]) || s[j] == '_') {
+				j++
+			}
+			toks = append(toks, token{kind: "ident", text: s[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", ","} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q", s[i:i+1])
+			}
+			toks = append(toks, token{kind: "op", text: op})
+			i += len(op)
+		}
+	}
+	return toks, nil
+}
+
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+// peek returns the next token, without consuming it.
+func (p *exprParser) peek() token {
+	if p.pos >= len(p.toks) {
+		return token{}
+	}
+	return p.toks[p.pos]
+}
+
+// next consumes the next token.
+func (p *exprParser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// isOp returns true if the next token is one of the operators.
+func (p *exprParser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.kind != "op" && t.kind != "ident" {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// or parses x || y || ...
+func (p *exprParser) or() (exprNode, error) {
+	x, err := p.and()
+	for err == nil && p.isOp("||") {
+		p.next()
+		var y exprNode
+		if y, err = p.and(); err == nil {
+			x = logical{op: "||", x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+// and parses x && y && ...
+func (p *exprParser) and() (exprNode, error) {
+	x, err := p.not()
+	for err == nil && p.isOp("&&") {
+		p.next()
+		var y exprNode
+		if y, err = p.not(); err == nil {
+			x = logical{op: "&&", x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+// not parses !x, or a comparison.
+func (p *exprParser) not() (exprNode, error) {
+	if p.isOp("!") {
+		p.next()
+		x, err := p.not()
+		return not{x}, err
+	}
+	return p.comparison()
+}
+
+// comparison parses x op y, or a single operand.
+func (p *exprParser) comparison() (exprNode, error) {
+	x, err := p.operand()
+	if err != nil || !p.isOp("==", "!=", "<", "<=", ">", ">=", "contains", "matches") {
+		return x, err
+	}
+	op := p.next().text
+	y, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	c := comparison{op: op, x: x, y: y}
+	if lit, ok := y.(literal); ok && op == "matches" {
+		pattern, ok := lit.v.(string)
+		if !ok {
+			return nil, fmt.Errorf("matches needs a string pattern, got %s", typeName(lit.v))
+		}
+		if c.re, err = regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// operand parses a literal, variable, function call, or parenthesized
+// expression.
+func (p *exprParser) operand() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case "num", "dur", "str":
+		return literal{t.v}, nil
+	case "ident":
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "contains", "matches":
+			return nil, fmt.Errorf("unexpected %q", t.text)
+		}
+		if !p.isOp("(") {
+			p.names[t.text] = true
+			return variable{t.text}, nil
+		}
+		p.next()
+		c := call{name: t.text}
+		for !p.isOp(")") {
+			if len(c.args) > 0 {
+				if !p.isOp(",") {
+					return nil, fmt.Errorf("expected , or ) in call of %s", t.text)
+				}
+				p.next()
+			}
+			arg, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			c.args = append(c.args, arg)
+		}
+		p.next()
+		if _, ok := builtins[c.name]; !ok {
+			p.names[c.name] = true
+		}
+		return c, nil
+	case "op":
+		if t.text == "(" {
+			x, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOp(")") {
+				return nil, fmt.Errorf("missing )")
+			}
+			p.next()
+			return x, nil
+		}
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	return nil, fmt.Errorf("unexpected end of expression")
+}
+
+func (l literal) eval(Vars) (interface{}, error) { return l.v, nil }
+
+func (v variable) eval(vars Vars) (interface{}, error) {
+	val, ok := vars[v.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %q", v.name)
+	}
+	return normalize(val), nil
+}
+
+func (c call) eval(vars Vars) (interface{}, error) {
+	fn, ok := builtins[c.name]
+	if v, found := vars[c.name]; found {
+		fn, ok = v.(Func)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a function", c.name)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", c.name)
+	}
+	args := make([]interface{}, len(c.args))
+	for i, a := range c.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := fn(args...)
+	if err != nil {
+		return nil, err
+	}
+	return normalize(v), nil
+}
+
+func (n not) eval(vars Vars) (interface{}, error) {
+	b, err := evalBool(n.x, vars, "!")
+	return !b, err
+}
+
+func (l logical) eval(vars Vars) (interface{}, error) {
+	x, err := evalBool(l.x, vars, l.op)
+	if err != nil {
+		return nil, err
+	}
+	if (l.op == "&&" && !x) || (l.op == "||" && x) {
+		return x, nil
+	}
+	return evalBool(l.y, vars, l.op)
+}
+
+func (c comparison) eval(vars Vars) (interface{}, error) {
+	x, err := c.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	y, err := c.y.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch c.op {
+	case "contains", "matches":
+		s, ok1 := x.(string)
+		pattern, ok2 := y.(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%s needs strings, got %s and %s", c.op, typeName(x), typeName(y))
+		}
+		if c.op == "contains" {
+			return strings.Contains(s, pattern), nil
+		}
+		re := c.re
+		if re == nil {
+			if re, err = regexp.Compile(pattern); err != nil {
+				return nil, err
+			}
+		}
+		return re.MatchString(s), nil
+	}
+
+	cmp, err := compare(x, y)
+	if err != nil {
+		return nil, fmt.Errorf("can't compare with %s: %v", c.op, err)
+	}
+	switch c.op {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+// compare returns -1, 0 or 1 if x is less than, equal to or greater
+// than y, which must be of the same type. Booleans can only be
+// compared for equality, where 1 means that they differ.
+func compare(x, y interface{}) (int, error) {
+	switch x := x.(type) {
+	case float64:
+		if y, ok := y.(float64); ok {
+			return sign(x - y), nil
+		}
+	case time.Duration:
+		if y, ok := y.(time.Duration); ok {
+			return sign(float64(x - y)), nil
+		}
+	case time.Time:
+		if y, ok := y.(time.Time); ok {
+			return sign(float64(x.Sub(y))), nil
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case bool:
+		if y, ok := y.(bool); ok {
+			if x == y {
+				return 0, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, fmt.Errorf("%s and %s", typeName(x), typeName(y))
+}
+
+// sign returns -1, 0 or 1 for the sign of f.
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
+
+// evalBool evaluates the operand of op, which must be a boolean.
+func evalBool(n exprNode, vars Vars, op string) (bool, error) {
+	v, err := n.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s needs booleans, got %s", op, typeName(v))
+	}
+	return b, nil
+}
+
+// normalize converts numbers of any type to float64, and functions to
+// Func.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case func(args ...interface{}) (interface{}, error):
+		return Func(v)
+	}
+	return v
+}
+
+// typeName returns the name of the type of the value in expressions.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "number"
+	case time.Duration:
+		return "duration"
+	case time.Time:
+		return "time"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case Func:
+		return "function"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// stringArg returns the single string argument of the function.
+func stringArg(fn string, args []interface{}) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%s takes 1 argument, got %d", fn, len(args))
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("%s needs a string, got %s", fn, typeName(args[0]))
+	}
+	return s, nil
+}
+
+// timeArg returns the single time argument of the function.
+func timeArg(fn string, args []interface{}) (time.Time, error) {
+	if len(args) != 1 {
+		return time.Time{}, fmt.Errorf("%s takes 1 argument, got %d", fn, len(args))
+	}
+	t, ok := args[0].(time.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s needs a time, got %s", fn, typeName(args[0]))
+	}
+	return t, nil
+}
@@ -0,0 +1,88 @@
+package prober
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpr(t *testing.T) {
+	now := time.Date(2016, time.June, 15, 15, 4, 0, 0, time.UTC) // a Wednesday
+	vars := Vars{
+		"status":  200,
+		"latency": 120 * time.Millisecond,
+		"body":    `{"status": "OK"}`,
+		"up":      true,
+		"now":     now,
+		"double": Func(func(args ...interface{}) (interface{}, error) {
+			return args[0].(float64) * 2, nil
+		}),
+	}
+	cases := []struct {
+		in        string
+		want      interface{}
+		wantParse bool // whether parsing should fail
+		wantEval  bool // whether evaluation should fail
+	}{
+		{in: `status == 200 && latency < 300ms && body contains "OK"`, want: true},
+		{in: `status != 200 || latency >= 1.5s`, want: false},
+		{in: `!(status > 299) && up`, want: true},
+		{in: `up == false || status <= 199`, want: false},
+		{in: `lower(body) matches "\"status\": *\"ok\""`, want: true},
+		{in: `len(body) == 16`, want: true},
+		{in: `hour(now) > 8 && weekday(now) == 3`, want: true},
+		{in: `double(status) == 400`, want: true},
+		{in: `latency < 1h30m`, want: true},
+		{in: `latency < 200µs`, want: false},
+		{in: `status`, want: 200.0},
+		{in: `"a" < "b"`, want: true},
+		{in: `-1 < 0`, wantParse: true},
+		{in: `status ==`, wantParse: true},
+		{in: `(status == 200`, wantParse: true},
+		{in: `status == 200)`, wantParse: true},
+		{in: `body matches "["`, wantParse: true},
+		{in: `latency < 3xs`, wantParse: true},
+		{in: `"unterminated`, wantParse: true},
+		{in: `status # 200`, wantParse: true},
+		{in: `len(body, body)`, wantEval: true},
+		{in: `latency < 300`, wantEval: true},
+		{in: `status && up`, wantEval: true},
+		{in: `nosuchvar == 1`, wantEval: true},
+		{in: `nosuchfunc(1)`, wantEval: true},
+		{in: `up(1)`, wantEval: true},
+		{in: `status contains "2"`, wantEval: true},
+	}
+	for i, tt := range cases {
+		e, err := ParseExpr(tt.in)
+		if (err != nil) != tt.wantParse {
+			t.Errorf("[%d] ParseExpr(%q) => %v; want error: %v\n", i, tt.in, err, tt.wantParse)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		got, err := e.Eval(vars)
+		if (err != nil) != tt.wantEval {
+			t.Errorf("[%d] Eval(%q) => %v, %v; want error: %v\n", i, tt.in, got, err, tt.wantEval)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("[%d] Eval(%q) => %v; want %v\n", i, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpr_UnmarshalText(t *testing.T) {
+	var e Expr
+	if err := e.UnmarshalText([]byte("status == 200")); err != nil {
+		t.Fatalf("UnmarshalText() => %v; want nil", err)
+	}
+	if ok, err := e.Check(Vars{"status": 200}); !ok || err != nil {
+		t.Errorf("Check() => %v, %v; want true\n", ok, err)
+	}
+	if _, err := e.Check(Vars{"status": "200"}); err == nil {
+		t.Errorf("Check() with string status => nil error; want error\n")
+	}
+	if err := e.UnmarshalText([]byte("status ==")); err == nil {
+		t.Errorf("UnmarshalText() of bad expression => nil; want error\n")
+	}
+}
@@ -0,0 +1,59 @@
+//go:build exprlang
+
+package prober
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// exprCondition is an AlertWhenExpr() condition in expr-lang.
+type exprCondition struct {
+	src  string
+	prog *vm.Program
+}
+
+// conditionEnv has values of the types of the variables of
+// AlertWhenExpr() conditions, see alertVars(), so that conditions are type checked
+// when they're compiled.
+var conditionEnv = map[string]interface{}{
+	"badness":              0,
+	"threshold":            0,
+	"consecutive_failures": 0,
+	"since_success":        time.Duration(0),
+	"health_score":         0.0,
+	"rate":                 0.0,
+	"alerting":             false,
+	"degraded":             false,
+	"now":                  time.Time{},
+}
+
+// compileExprLang compiles an AlertWhenExpr() condition.
+func compileExprLang(src string) (condition, error) {
+	prog, err := expr.Compile(src,
+		expr.Env(conditionEnv),
+		expr.AsBool(),
+		expr.Function("hour", func(args ...interface{}) (interface{}, error) {
+			return args[0].(time.Time).Hour(), nil
+		}, new(func(time.Time) int)),
+		expr.Function("weekday", func(args ...interface{}) (interface{}, error) {
+			return int(args[0].(time.Time).Weekday()), nil
+		}, new(func(time.Time) int)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("bad condition %q: %v", src, err)
+	}
+	return &exprCondition{src: src, prog: prog}, nil
+}
+
+// Check returns the value of the condition with the variables.
+func (c *exprCondition) Check(vars Vars) (bool, error) {
+	v, err := expr.Run(c.prog, map[string]interface{}(vars))
+	if err != nil {
+		return false, fmt.Errorf("failed to check %q: %v", c.src, err)
+	}
+	return v.(bool), nil
+}
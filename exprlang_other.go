@@ -0,0 +1,11 @@
+//go:build !exprlang
+
+package prober
+
+import "errors"
+
+// compileExprLang fails, since AlertWhenExpr() conditions are only
+// supported with the "exprlang" tag.
+func compileExprLang(src string) (condition, error) {
+	return nil, errors.New("AlertWhenExpr() conditions need a build with the \"exprlang\" tag")
+}
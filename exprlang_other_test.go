@@ -0,0 +1,26 @@
+//go:build !exprlang
+
+package prober
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlertWhenExpr(t *testing.T) {
+	p := &Probe{Name: "AlertWhenProber", Prober: testProber{Passed()}, Interval: time.Minute}
+	AlertWhenExpr("badness > threshold / 2")(p)
+	if p.alertWhen != nil {
+		t.Errorf("AlertWhenExpr() without the exprlang tag set a condition; want none\n")
+	}
+	var got bool
+	for _, e := range p.validate() {
+		if e.Path == "AlertWhenProber.AlertWhen" && strings.Contains(e.Err, "exprlang") {
+			got = true
+		}
+	}
+	if !got {
+		t.Errorf("validate() with AlertWhenExpr() => %v; want AlertWhen problem about the exprlang tag\n", p.validate())
+	}
+}
@@ -0,0 +1,72 @@
+//go:build exprlang
+
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAlertWhenExpr(t *testing.T) {
+	start := time.Date(1998, 11, 19, 6, 0, 0, 0, time.UTC)
+	fail := FailedWith(errors.New("failing on purpose"))
+	var records Records
+	// Failures from 06:00 until 10:00, one run every 10 minutes.
+	for i := 0; i < 24; i++ {
+		records = append(records, Record{Timestamp: start.Add(time.Duration(i) * 10 * time.Minute), Result: fail})
+	}
+	p := &Probe{Name: "AlertWhenProber", failurePenalty: 1, successReward: 1}
+
+	cases := []struct {
+		cond      string
+		wantFirst time.Time // time of the first alert, if any
+	}{
+		{cond: `since_success > duration("1h")`, wantFirst: start.Add(70 * time.Minute)},
+		{cond: "consecutive_failures >= 5 and hour(now) >= 8", wantFirst: start.Add(2 * time.Hour)},
+		{cond: "consecutive_failures > 2 and now.Minute() >= 30", wantFirst: start.Add(30 * time.Minute)},
+		{cond: "weekday(now) in [0, 6]"},
+		// Conditions in the language of AlertWhen() fall back to
+		// alerting on badness.
+		{cond: "since_success > 1h"},
+	}
+	for i, tt := range cases {
+		got := p.Replay(records, AlertWhenExpr(tt.cond))
+		if tt.wantFirst.IsZero() {
+			if got.Alerts() != 0 {
+				t.Errorf("[%d] Replay() with AlertWhenExpr(%q) => %v; want no alerts\n", i, tt.cond, got)
+			}
+			continue
+		}
+		if got.Alerts() == 0 || !got.Notifications[0].Time.Equal(tt.wantFirst) {
+			t.Errorf("[%d] Replay() with AlertWhenExpr(%q) => %v; want first alert at %v\n", i, tt.cond, got, tt.wantFirst)
+		}
+	}
+}
+
+func TestAlertWhenExpr_Validate(t *testing.T) {
+	cases := []struct {
+		cond    string
+		wantErr bool
+	}{
+		{cond: "badness > threshold / 2"},
+		{cond: `since_success > duration("1h") && !alerting`},
+		{cond: `since_success > "1h"`, wantErr: true},
+		{cond: "since_success > 1h", wantErr: true},
+		{cond: "uptime > 0", wantErr: true},
+		{cond: "health_score", wantErr: true},
+	}
+	for i, tt := range cases {
+		p := &Probe{Name: "AlertWhenProber", Prober: testProber{Passed()}, Interval: time.Minute}
+		AlertWhenExpr(tt.cond)(p)
+		var got bool
+		for _, e := range p.validate() {
+			if e.Path == "AlertWhenProber.AlertWhen" {
+				got = true
+			}
+		}
+		if got != tt.wantErr {
+			t.Errorf("[%d] validate() with AlertWhenExpr(%q) => %v; want AlertWhen problem: %v\n", i, tt.cond, p.validate(), tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package prober
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// FallbackAlerts returns an AlertFn that tries each of the fns in turn
+// until one succeeds, so an alert isn't lost when one notification
+// provider is down, e.g.:
+//
+//	AlertFn: FallbackAlerts(pagerDuty.Alert, twilio.Alert, spool.Alert)
+//
+// The returned AlertFn only fails if all the fns fail, with an error
+// listing the error of each.
+func FallbackAlerts(fns ...AlertFn) AlertFn {
+	return func(name, desc string, badness int, records Records) error {
+		var errs []string
+		for i, fn := range fns {
+			err := fn(name, desc, badness, records)
+			if err == nil {
+				return nil
+			}
+			log.Printf("[%s] Notifier %d of %d failed to alert: %v\n", name, i+1, len(fns), err)
+			errs = append(errs, err.Error())
+		}
+		return fallbackError(errs)
+	}
+}
+
+// FallbackRecoveries returns a RecoverFn that tries each of the fns in
+// turn until one succeeds, as FallbackAlerts() does for alerts.
+func FallbackRecoveries(fns ...RecoverFn) RecoverFn {
+	return func(name, desc string, records Records) error {
+		var errs []string
+		for i, fn := range fns {
+			err := fn(name, desc, records)
+			if err == nil {
+				return nil
+			}
+			log.Printf("[%s] Notifier %d of %d failed to send recovery notice: %v\n", name, i+1, len(fns), err)
+			errs = append(errs, err.Error())
+		}
+		return fallbackError(errs)
+	}
+}
+
+// fallbackError returns the error for when all notifiers of a fallback
+// chain failed with errs.
+func fallbackError(errs []string) error {
+	if len(errs) == 0 {
+		return fmt.Errorf("no notifiers to fall back on")
+	}
+	return fmt.Errorf("all %d notifiers failed: %s", len(errs), strings.Join(errs, "; "))
+}
@@ -0,0 +1,76 @@
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFallbackAlerts(t *testing.T) {
+	ok := func(called *[]string, id string) AlertFn {
+		return func(string, string, int, Records) error {
+			*called = append(*called, id)
+			return nil
+		}
+	}
+	fail := func(called *[]string, id string) AlertFn {
+		return func(string, string, int, Records) error {
+			*called = append(*called, id)
+			return fmt.Errorf("%s is down", id)
+		}
+	}
+	cases := []struct {
+		fns        func(called *[]string) []AlertFn
+		wantCalled string
+		wantErr    error
+	}{
+		{
+			fns:        func(c *[]string) []AlertFn { return []AlertFn{ok(c, "pagerduty"), ok(c, "email")} },
+			wantCalled: "[pagerduty]",
+		},
+		{
+			fns:        func(c *[]string) []AlertFn { return []AlertFn{fail(c, "pagerduty"), ok(c, "email"), ok(c, "spool")} },
+			wantCalled: "[pagerduty email]",
+		},
+		{
+			fns:        func(c *[]string) []AlertFn { return []AlertFn{fail(c, "pagerduty"), fail(c, "email")} },
+			wantCalled: "[pagerduty email]",
+			wantErr:    errors.New("all 2 notifiers failed: pagerduty is down; email is down"),
+		},
+		{
+			fns:        func(c *[]string) []AlertFn { return nil },
+			wantCalled: "[]",
+			wantErr:    errors.New("no notifiers to fall back on"),
+		},
+	}
+	for i, tt := range cases {
+		var called []string
+		err := FallbackAlerts(tt.fns(&called)...)("FallbackProber", "Fails over.", 100, nil)
+		if fmt.Sprint(err) != fmt.Sprint(tt.wantErr) {
+			t.Errorf("[%d] FallbackAlerts() => %v; want %v\n", i, err, tt.wantErr)
+		}
+		if got := fmt.Sprint(called); got != tt.wantCalled {
+			t.Errorf("[%d] FallbackAlerts() called %s; want %s\n", i, got, tt.wantCalled)
+		}
+	}
+}
+
+func TestFallbackRecoveries(t *testing.T) {
+	var called []string
+	fn := FallbackRecoveries(
+		func(string, string, Records) error {
+			called = append(called, "pagerduty")
+			return errors.New("pagerduty is down")
+		},
+		func(string, string, Records) error {
+			called = append(called, "email")
+			return nil
+		},
+	)
+	if err := fn("FallbackProber", "Fails over.", nil); err != nil {
+		t.Errorf("FallbackRecoveries() => %v; want nil\n", err)
+	}
+	if got, want := fmt.Sprint(called), "[pagerduty email]"; got != want {
+		t.Errorf("FallbackRecoveries() called %s; want %s\n", got, want)
+	}
+}
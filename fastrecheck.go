@@ -0,0 +1,33 @@
+package prober
+
+import "time"
+
+// FastRecheck makes the probe run every d instead of every Interval
+// while it's failing, i.e. from its first failed run until it passes
+// again, giving responders faster feedback during an incident, e.g.
+// FastRecheck(6*time.Second) for a probe with a one minute interval.
+//
+// So that the faster runs don't make the probe alert sooner, failures
+// during the fast rechecks count towards badness in proportion to d
+// over Interval. FastRecheck has no effect on probes with a Schedule or
+// AlignToInterval().
+func FastRecheck(d time.Duration) func(*Probe) {
+	return func(p *Probe) {
+		p.fastRecheck = d
+	}
+}
+
+// inFastRecheck returns true if the probe should run every FastRecheck
+// interval rather than every Interval.
+func (p *Probe) inFastRecheck() bool {
+	return p.fastRecheck > 0 && p.fastRecheck < p.Interval && p.schedule == nil && !p.aligned && p.ConsecutiveFailures() > 0
+}
+
+// fastWeight returns how much a failure counts towards badness while
+// in fast recheck mode, compared to a failure at the usual interval.
+func (p *Probe) fastWeight() float64 {
+	if !p.inFastRecheck() {
+		return 1
+	}
+	return float64(p.fastRecheck) / float64(p.Interval)
+}
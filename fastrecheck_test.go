@@ -0,0 +1,39 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFastRecheck(t *testing.T) {
+	p := &Probe{
+		Name:           "FastRecheckProber",
+		Interval:       time.Minute,
+		failurePenalty: 10,
+		successReward:  1,
+		t:              fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	FastRecheck(6 * time.Second)(p)
+	fail := testProber{FailedWith(errors.New("failing on purpose"))}
+	cases := []struct {
+		prober      Prober
+		wantWait    time.Duration
+		wantBadness int
+	}{
+		{testProber{Passed()}, time.Minute, 0},
+		{fail, 6 * time.Second, 10},
+		{fail, 6 * time.Second, 11},
+		{fail, 6 * time.Second, 12},
+		{testProber{Passed()}, time.Minute, 11},
+	}
+	for i, tt := range cases {
+		p.Prober = tt.prober
+		if got := p.runProbe(); got != tt.wantWait {
+			t.Errorf("[%d] runProbe() => %v; want %v\n", i, got, tt.wantWait)
+		}
+		if got := p.Badness(); got != tt.wantBadness {
+			t.Errorf("[%d] Badness() => %d; want %d\n", i, got, tt.wantBadness)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package prober
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+type (
+	// FingerprintAlerter is implemented by Probers that want a stable
+	// key for deduplicating their alerts downstream, e.g. as the
+	// dedup_key of PagerDuty or a label for Alertmanager. If the Prober
+	// is a FingerprintAlerter, AlertFingerprint() is called instead of
+	// Alert().
+	FingerprintAlerter interface {
+		AlertFingerprint(fingerprint, name, desc string, badness int, records Records) error
+	}
+
+	// FingerprintAlertFn is a function that is called when a probe
+	// alerts, with the fingerprint of the alert.
+	FingerprintAlertFn func(fingerprint, name, desc string, badness int, records Records) error
+)
+
+// AlertFingerprint calls fn, which lets probers embed a
+// FingerprintAlertFn to implement FingerprintAlerter.
+func (fn FingerprintAlertFn) AlertFingerprint(fingerprint, name, desc string, badness int, records Records) error {
+	return fn(fingerprint, name, desc, badness, records)
+}
+
+// numbers matches the parts of error messages that vary between
+// otherwise identical errors, like ports, latencies and addresses.
+var numbers = regexp.MustCompile(`[0-9]+(\.[0-9]+)*`)
+
+// errorClasses are substrings of error messages that put the error in
+// a class regardless of the rest of the message, checked in order.
+var errorClasses = []struct{ substr, class string }{
+	{"no such host", "dns"},
+	{"connection refused", "refused"},
+	{"deadline exceeded", "timeout"},
+	{"timeout", "timeout"},
+	{"timed out", "timeout"},
+	{"certificate", "tls"},
+	{"tls:", "tls"},
+}
+
+// Fingerprint returns a stable key for the current alert of the probe,
+// derived from its name and the class of the most recent failure, so
+// that the alert is deduplicated for as long as the probe keeps failing
+// in the same way. Failures with errors that differ only in numbers,
+// e.g. latencies or ports, have the same fingerprint.
+//
+// Fingerprint returns "" if the probe has no failed records.
+func (p *Probe) Fingerprint() string {
+	rs := p.Records()
+	for i := len(rs) - 1; i >= 0; i-- {
+		if !rs[i].Result.Passed() {
+			return fingerprint(p.Name, errorClass(rs[i].Result))
+		}
+	}
+	return ""
+}
+
+// fingerprint returns the fingerprint of alerts of the named probe for
+// failures of the class.
+func fingerprint(name, class string) string {
+	h := sha256.Sum256([]byte(name + "\x00" + class))
+	return hex.EncodeToString(h[:8])
+}
+
+// errorClass returns the class of the failure, which is one of the
+// errorClasses if the error matches one, and otherwise the error
+// message with numbers replaced.
+func errorClass(r Result) string {
+	if r.Error == nil {
+		return strings.ToLower(r.Code.String())
+	}
+	msg := strings.ToLower(r.Error.Error())
+	for _, c := range errorClasses {
+		if strings.Contains(msg, c.substr) {
+			return c.class
+		}
+	}
+	return numbers.ReplaceAllString(msg, "N")
+}
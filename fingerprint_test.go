@@ -0,0 +1,83 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fingerprintProber is a testProber that is also a FingerprintAlerter.
+type fingerprintProber struct {
+	testProber
+	FingerprintAlertFn
+}
+
+func TestErrorClass(t *testing.T) {
+	cases := []struct {
+		in   Result
+		want string
+	}{
+		{Result{Code: Fail}, "fail"},
+		{FailedWith(errors.New("dial tcp: lookup example.com: no such host")), "dns"},
+		{FailedWith(errors.New("dial tcp 10.0.0.1:443: i/o timeout")), "timeout"},
+		{FailedWith(errors.New("Get \"https://example.com\": context deadline exceeded")), "timeout"},
+		{FailedWith(errors.New("dial tcp 10.0.0.1:443: connect: connection refused")), "refused"},
+		{FailedWith(errors.New("returned status 503, want 200")), "returned status N, want N"},
+	}
+	for i, tt := range cases {
+		if got := errorClass(tt.in); got != tt.want {
+			t.Errorf("[%d] errorClass(%v) => %q; want %q\n", i, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestProbe_Fingerprint(t *testing.T) {
+	p := &Probe{
+		Name: "FingerprintProber",
+		t:    fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	if got := p.Fingerprint(); got != "" {
+		t.Errorf("Fingerprint() without failures => %q; want \"\"\n", got)
+	}
+	p.logResult(FailedWith(errors.New("returned status 503 in 1.5s")))
+	first := p.Fingerprint()
+	p.logResult(FailedWith(errors.New("returned status 502 in 0.2s")))
+	p.logResult(Passed())
+	if got := p.Fingerprint(); got != first {
+		t.Errorf("Fingerprint() after failure differing in numbers => %q; want %q\n", got, first)
+	}
+	if got := p.Status().Fingerprint; got != first {
+		t.Errorf("Status().Fingerprint => %q; want %q\n", got, first)
+	}
+	p.logResult(FailedWith(errors.New("lookup example.com: no such host")))
+	if got := p.Fingerprint(); got == first {
+		t.Errorf("Fingerprint() after failure of another class => %q; want it to change\n", got)
+	}
+	other := &Probe{Name: "OtherProber", t: p.t}
+	other.logResult(FailedWith(errors.New("lookup example.com: no such host")))
+	if other.Fingerprint() == p.Fingerprint() {
+		t.Errorf("Fingerprint() is %q for different probes; want them to differ\n", p.Fingerprint())
+	}
+}
+
+func TestProbe_notify_fingerprint(t *testing.T) {
+	var got string
+	p := &Probe{
+		Prober: fingerprintProber{
+			testProber{FailedWith(errors.New("failing on purpose"))},
+			func(fingerprint, name, desc string, badness int, records Records) error {
+				got = fingerprint
+				return nil
+			},
+		},
+		Name: "FingerprintProber",
+		t:    fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	p.logResult(p.Probe())
+	if err := p.notify(p.alertDesc()); err != nil {
+		t.Fatalf("notify() => %v; want nil\n", err)
+	}
+	if want := p.Fingerprint(); got != want || got == "" {
+		t.Errorf("AlertFingerprint() called with %q; want %q\n", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package prober
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// ReAlertWindow sets how soon after recovering a probe must alert again
+// for it to be considered flapping, e.g. 30*time.Minute.
+//
+// When a probe that recovered alerts again within the window, the
+// notification is escalated: alert descriptions note the recurrence
+// count, and warnings of probes with Thresholds() are sent as alerts
+// instead. A window of 0 disables escalation.
+func ReAlertWindow(d time.Duration) func(*Probe) {
+	return func(p *Probe) {
+		p.reAlertWindow = d
+	}
+}
+
+// Recurrences returns how many times in a row the probe has alerted
+// again within its ReAlertWindow of recovering.
+func (p *Probe) Recurrences() int {
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	return p.recurrences
+}
+
+// noteOutcome records the outcome of a run at the time, and that the
+// probe recovered, if it passed after a notification was sent,
+// returning true if it did.
+func (p *Probe) noteOutcome(passed bool, t time.Time) bool {
+	p.alertLock.Lock()
+	defer p.alertLock.Unlock()
+	if p.firstRun.IsZero() {
+		p.firstRun = t
+	}
+	if passed {
+		p.consecutiveFailures = 0
+	} else {
+		p.consecutiveFailures++
+		if p.consecutiveFailures == 1 {
+			p.noteFailureStart(t)
+		}
+	}
+	if passed && p.notified {
+		p.notified = false
+		p.recoveredAt = t
+		return true
+	}
+	return false
+}
+
+// noteNotification records that a notification is about to be sent at
+// the time, returning true if it should be escalated since the probe
+// recovered within its ReAlertWindow.
+func (p *Probe) noteNotification(t time.Time) bool {
+	p.alertLock.Lock()
+	defer p.alertLock.Unlock()
+	p.notified = true
+	recurring := p.reAlertWindow > 0 && !p.recoveredAt.IsZero() && t.Sub(p.recoveredAt) <= p.reAlertWindow
+	if !recurring {
+		p.recurrences = 0
+		return false
+	}
+	p.recurrences++
+	log.Printf("[%s] is flapping, notifying again %v after recovering (recurrence #%d)\n", p.Name, t.Sub(p.recoveredAt), p.recurrences)
+	p.recoveredAt = time.Time{}
+	return true
+}
+
+// recurrenceNote returns a note on the recurrences of the probe for
+// notifications, or "" if there are none.
+func (p *Probe) recurrenceNote() string {
+	n := p.Recurrences()
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [ESCALATED: recurrence #%d within %v of recovering]", n, p.reAlertWindow)
+}
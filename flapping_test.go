@@ -0,0 +1,35 @@
+package prober
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbe_noteNotification(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{Desc: "A test prober.", t: fakeTime{start}}
+	ReAlertWindow(30 * time.Minute)(p)
+
+	cases := []struct {
+		recovered, notified time.Duration // offsets from start
+		want                int           // recurrences
+	}{
+		{recovered: 0, notified: 10 * time.Minute, want: 1},
+		{recovered: 20 * time.Minute, notified: 40 * time.Minute, want: 2},
+		{recovered: 60 * time.Minute, notified: 2 * time.Hour, want: 0},
+	}
+	p.noteNotification(start.Add(-time.Hour))
+	for i, tt := range cases {
+		p.noteOutcome(false, start.Add(tt.recovered-time.Minute))
+		p.noteOutcome(true, start.Add(tt.recovered))
+		escalated := p.noteNotification(start.Add(tt.notified))
+		if got := p.Recurrences(); got != tt.want || escalated != (tt.want > 0) {
+			t.Errorf("[%d] noteNotification() => %v with Recurrences() %d; want %v with %d\n", i, escalated, got, tt.want > 0, tt.want)
+		}
+		wantNote := tt.want > 0
+		if got := strings.Contains(p.alertDesc(), "ESCALATED"); got != wantNote {
+			t.Errorf("[%d] alertDesc() => %q; want escalation noted: %v\n", i, p.alertDesc(), wantNote)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package prober
+
+import (
+	"fmt"
+)
+
+type (
+	// Gauge is a metric describing one aspect of the state of a probe,
+	// which is read by calling Value, e.g. whenever the metrics library
+	// of the host application polls or scrapes it.
+	Gauge struct {
+		Name   string            // name of the metric, e.g. "prober_badness"
+		Help   string            // human-readable description of the metric
+		Labels map[string]string // the probe's labels, plus "probe" for its name
+		Value  func() float64    // returns the current value of the metric
+	}
+
+	// GaugeRegisterer is implemented by adapters for metrics libraries
+	// that accept callback gauges, e.g. Prometheus' GaugeFunc or
+	// Micrometer-style registries, so probe state can be exported without
+	// this package depending on any of them.
+	GaugeRegisterer interface {
+		RegisterGauge(g Gauge) error
+	}
+)
+
+// Gauges returns the gauges describing the state of the probe.
+func (p *Probe) Gauges() []Gauge {
+	labels := make(map[string]string, len(p.Labels)+1)
+	for k, v := range p.Labels {
+		labels[k] = v
+	}
+	labels["probe"] = p.Name
+	gauge := func(name, help string, value func() float64) Gauge {
+		return Gauge{Name: name, Help: help, Labels: labels, Value: value}
+	}
+	return []Gauge{
+		gauge("prober_up", "Whether the most recent run of the probe passed.", func() float64 {
+			return boolGauge(p.LastSuccess().After(p.LastFailure()))
+		}),
+		gauge("prober_badness", "Current badness of the probe.", func() float64 {
+			return float64(p.Badness())
+		}),
+		gauge("prober_alerting", "Whether the probe is alerting.", func() float64 {
+			return boolGauge(p.IsAlerting())
+		}),
+		gauge("prober_degraded", "Whether the probe is over its warning threshold.", func() float64 {
+			return boolGauge(p.IsDegraded())
+		}),
+		gauge("prober_health_score", "Health of the probe, from 0 (unhealthy) to 1 (healthy).", p.HealthScore),
+		gauge("prober_runs", "Number of times the probe has been started.", func() float64 {
+			return float64(p.Stats().Runs)
+		}),
+		gauge("prober_failures", "Number of runs of the probe that failed or were degraded.", func() float64 {
+			return float64(p.Stats().Failures)
+		}),
+		gauge("prober_timeouts", "Number of runs of the probe that timed out.", func() float64 {
+			return float64(p.Stats().Timeouts)
+		}),
+		gauge("prober_alerts_sent", "Number of alerts sent for the probe.", func() float64 {
+			return float64(p.Stats().AlertsSent)
+		}),
+		gauge("prober_lag_seconds", "How much later than intended the most recent run started.", func() float64 {
+			return p.Stats().Lag.Seconds()
+		}),
+		gauge("prober_latency_p50_seconds", "Median duration of runs in the current latency window.", func() float64 {
+			return p.Stats().Latency.P50.Seconds()
+		}),
+		gauge("prober_latency_p99_seconds", "99th percentile duration of runs in the current latency window.", func() float64 {
+			return p.Stats().Latency.P99.Seconds()
+		}),
+		gauge("prober_latency_max_seconds", "Longest duration of runs in the current latency window.", func() float64 {
+			return p.Stats().Latency.Max.Seconds()
+		}),
+	}
+}
+
+// RegisterGauges registers the gauges of each of the probes with r,
+// returning the first error.
+func (ps Probes) RegisterGauges(r GaugeRegisterer) error {
+	for _, p := range ps {
+		for _, g := range p.Gauges() {
+			if err := r.RegisterGauge(g); err != nil {
+				return fmt.Errorf("failed to register %s for %s: %v", g.Name, p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// boolGauge returns 1 if b is true, otherwise 0.
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
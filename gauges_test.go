@@ -0,0 +1,49 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeGauges is a GaugeRegisterer keeping the gauges by probe and name.
+type fakeGauges map[string]Gauge
+
+func (fg fakeGauges) RegisterGauge(g Gauge) error {
+	key := g.Labels["probe"] + "/" + g.Name
+	if _, ok := fg[key]; ok {
+		return errors.New("already registered")
+	}
+	fg[key] = g
+	return nil
+}
+
+func TestProbes_RegisterGauges(t *testing.T) {
+	p := &Probe{
+		Name:   "web",
+		Labels: map[string]string{"env": "prod"},
+		t:      fakeTime{time.Date(2015, time.June, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	fg := fakeGauges{}
+	if err := (Probes{p}).RegisterGauges(fg); err != nil {
+		t.Fatalf("RegisterGauges() => %v; want nil", err)
+	}
+	up, badness := fg["web/prober_up"], fg["web/prober_badness"]
+	if up.Labels["env"] != "prod" {
+		t.Errorf("prober_up has labels %v; want env=prod\n", up.Labels)
+	}
+	if got := up.Value(); got != 0 {
+		t.Errorf("prober_up => %v before any run; want 0\n", got)
+	}
+	p.setLastOutcome(true, p.t.Now())
+	p.setBadness(3)
+	if got := up.Value(); got != 1 {
+		t.Errorf("prober_up => %v after passing run; want 1\n", got)
+	}
+	if got := badness.Value(); got != 3 {
+		t.Errorf("prober_badness => %v; want 3\n", got)
+	}
+	if err := (Probes{p}).RegisterGauges(fg); err == nil {
+		t.Errorf("RegisterGauges() twice => nil; want error from registerer\n")
+	}
+}
@@ -2,4 +2,25 @@ module hkjn.me/prober
 
 go 1.18
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/chromedp/cdproto v0.0.0-20230220211738-2b1ec77315c9
+	github.com/chromedp/chromedp v0.9.1
+	github.com/expr-lang/expr v1.16.9
+	github.com/segmentio/kafka-go v0.4.38
+	github.com/tetratelabs/wazero v1.0.0
+	go.starlark.net v0.0.0-20230302034142-4b1e35fe2254
+	golang.org/x/net v0.17.0
+	golang.org/x/sys v0.13.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/chromedp/sysutil v1.0.0 // indirect
+	github.com/gobwas/httphead v0.1.0 // indirect
+	github.com/gobwas/pool v0.2.1 // indirect
+	github.com/gobwas/ws v1.1.0 // indirect
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/mailru/easyjson v0.7.7 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+)
@@ -0,0 +1,260 @@
+package prober
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// HTTPProber is a Prober that sends a HTTP request and checks the
+// status of the response.
+//
+// Timings for the phases of the request (DNS, connect, TLS, time to
+// first byte) are included in the Details of the Result, which helps
+// tell network slowness apart from server slowness.
+//
+// The Alert() part of the Prober interface is provided by the embedded
+// AlertFn.
+type HTTPProber struct {
+	AlertFn
+	URL    string       // URL to request
+	Method string       // HTTP method to use, or "" for GET
+	Header http.Header  // extra headers to send with the request
+	Client *http.Client // client to use, or nil for http.DefaultClient
+	// Proxy to send requests through if Client is nil, with a "http",
+	// "https" or "socks5" scheme.
+	Proxy *url.URL
+	// Dialer to open connections with if Client is nil, e.g. with a
+	// LocalAddr to probe from a particular source address, a Resolver
+	// from ResolverAt() for split-horizon DNS, or a Control function
+	// from BindToDevice() for a VRF, or nil for the default.
+	Dialer *net.Dialer
+	// Expected status code of the response, or 0 to accept any 2xx
+	// status, or any status at all if Assert is set.
+	WantStatus int
+	// Expression that must hold for the response, see Expr, e.g.
+	// `status == 200 && latency < 300ms && body contains "ok"`. The
+	// variables are status, latency, body (up to the first MiB), size,
+	// and the function header(name).
+	Assert string
+	// Whether to always open a new connection, rather than reusing
+	// connections kept alive from earlier runs.
+	NewConnection bool
+}
+
+// Probe sends the request and checks the response.
+func (hp HTTPProber) Probe() Result {
+	return hp.ProbeContext(context.Background())
+}
+
+// ProbeContext sends the request with ctx and checks the response. The
+// RunID() of the run is sent as RunIDHeader, unless Header sets it.
+func (hp HTTPProber) ProbeContext(ctx context.Context) Result {
+	client := hp.client()
+	method := hp.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, hp.URL, nil)
+	if err != nil {
+		return FailedWith(err)
+	}
+	for k, vs := range hp.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if id := RunID(ctx); id != "" && req.Header.Get(RunIDHeader) == "" {
+		req.Header.Set(RunIDHeader, id)
+	}
+	if hp.NewConnection {
+		req.Close = true
+	}
+
+	t := &httpTimings{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), t.trace()))
+	t.start = time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{
+			Code:    Fail,
+			Error:   err,
+			Info:    fmt.Sprintf("%s %s failed: %v", method, hp.URL, err),
+			InfoUrl: hp.URL,
+			Details: t.details(),
+		}
+	}
+	defer resp.Body.Close()
+	body := &bytes.Buffer{}
+	w := io.Writer(io.Discard)
+	if hp.Assert != "" {
+		w = &limitedWriter{w: body, n: maxAssertBody}
+	}
+	size, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return FailedWith(fmt.Errorf("failed to read response from %s: %v", hp.URL, err))
+	}
+	t.done = time.Now()
+
+	info := fmt.Sprintf("%s %s returned %q in %v", method, hp.URL, resp.Status, t.done.Sub(t.start))
+	if hp.Assert != "" {
+		vars := Vars{
+			"status":  resp.StatusCode,
+			"latency": t.done.Sub(t.start),
+			"body":    body.String(),
+			"size":    size,
+			"header": Func(func(args ...interface{}) (interface{}, error) {
+				name, err := stringArg("header", args)
+				return resp.Header.Get(name), err
+			}),
+		}
+		if err := checkAssertion(hp.Assert, vars); err != nil {
+			return Result{
+				Code:    Fail,
+				Error:   fmt.Errorf("%s: %v", hp.URL, err),
+				Info:    info,
+				InfoUrl: hp.URL,
+				Details: t.details(),
+			}
+		}
+	}
+	if !hp.statusOK(resp.StatusCode) {
+		return Result{
+			Code:    Fail,
+			Error:   fmt.Errorf("%s returned unexpected status %q", hp.URL, resp.Status),
+			Info:    info,
+			InfoUrl: hp.URL,
+			Details: t.details(),
+		}
+	}
+	r := PassedWith(info, hp.URL)
+	r.Details = t.details()
+	return r
+}
+
+// maxAssertBody is how much of the response body HTTPProber keeps for
+// the Assert expression.
+const maxAssertBody = 1 << 20
+
+// limitedWriter writes up to n bytes to w, and discards the rest.
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+// Write writes as much of b as fits within the limit, while reporting
+// all of it as written.
+func (lw *limitedWriter) Write(b []byte) (int, error) {
+	keep := b
+	if len(keep) > lw.n {
+		keep = keep[:lw.n]
+	}
+	lw.n -= len(keep)
+	if _, err := lw.w.Write(keep); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// client returns the client to send the request with.
+func (hp HTTPProber) client() *http.Client {
+	var c *http.Client
+	switch {
+	case hp.Client != nil:
+		c = hp.Client
+	case hp.Dialer != nil:
+		c = dialerClient(hp.Dialer, hp.Proxy)
+	case hp.Proxy != nil:
+		c = proxyClient(hp.Proxy)
+	default:
+		c = http.DefaultClient
+	}
+	if hp.NewConnection {
+		return uncachedClient(c)
+	}
+	return c
+}
+
+// uncachedClient returns a copy of the client with a transport of its
+// own that doesn't keep connections alive, so that each request opens
+// a new connection without closing those of other users of the client.
+//
+// Clients with a RoundTripper other than *http.Transport are returned
+// as is, relying on requests setting Close.
+func uncachedClient(c *http.Client) *http.Client {
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return c
+	}
+	t = t.Clone()
+	t.DisableKeepAlives = true
+	uncached := *c
+	uncached.Transport = t
+	return &uncached
+}
+
+// statusOK returns true if the status code is what we expected.
+func (hp HTTPProber) statusOK(code int) bool {
+	if hp.WantStatus == 0 {
+		return hp.Assert != "" || code >= 200 && code < 300
+	}
+	return code == hp.WantStatus
+}
+
+// httpTimings holds the times at which phases of a HTTP request
+// happened.
+type httpTimings struct {
+	start, done               time.Time
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	firstByte                 time.Time
+	reused                    bool
+}
+
+// trace returns a httptrace.ClientTrace which fills in the timings.
+func (t *httpTimings) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { t.connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { t.connectDone = time.Now() },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tlsDone = time.Now() },
+		GotConn:              func(info httptrace.GotConnInfo) { t.reused = info.Reused },
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+	}
+}
+
+// details returns the timings in the form used for Result.Details.
+//
+// Phases that didn't happen, e.g. DNS lookup when a connection was
+// reused, are left out.
+func (t *httpTimings) details() map[string]string {
+	d := map[string]string{
+		"reused_connection": strconv.FormatBool(t.reused),
+	}
+	add := func(name string, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() {
+			d[name] = to.Sub(from).String()
+		}
+	}
+	add("dns", t.dnsStart, t.dnsDone)
+	add("connect", t.connectStart, t.connectDone)
+	add("tls", t.tlsStart, t.tlsDone)
+	add("ttfb", t.start, t.firstByte)
+	add("total", t.start, t.done)
+	return d
+}
@@ -0,0 +1,112 @@
+package prober
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPProber_Probe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		in         HTTPProber
+		want       ResultCode
+		wantReused string // expected "reused_connection" detail on the second run
+	}{
+		{
+			in:         HTTPProber{URL: ts.URL, Client: &http.Client{Transport: &http.Transport{}}},
+			want:       Pass,
+			wantReused: "true",
+		},
+		{
+			in:         HTTPProber{URL: ts.URL, Client: &http.Client{Transport: &http.Transport{}}, NewConnection: true},
+			want:       Pass,
+			wantReused: "false",
+		},
+		{
+			in:         HTTPProber{URL: ts.URL + "/missing", Client: &http.Client{Transport: &http.Transport{}}},
+			want:       Fail,
+			wantReused: "true",
+		},
+		{
+			in:         HTTPProber{URL: ts.URL + "/missing", Client: &http.Client{Transport: &http.Transport{}}, WantStatus: http.StatusNotFound},
+			want:       Pass,
+			wantReused: "true",
+		},
+	}
+	for i, tt := range cases {
+		tt.in.Probe()
+		got := tt.in.Probe()
+		if got.Code != tt.want {
+			t.Errorf("[%d] %+v.Probe() => %v; want code %v\n", i, tt.in, got, tt.want)
+		}
+		if got.Details["reused_connection"] != tt.wantReused {
+			t.Errorf("[%d] %+v.Probe() => %v; want reused_connection %q\n", i, tt.in, got, tt.wantReused)
+		}
+		if _, ok := got.Details["ttfb"]; !ok {
+			t.Errorf("[%d] %+v.Probe() => %v; want ttfb in Details\n", i, tt.in, got)
+		}
+	}
+}
+
+func TestHTTPProber_Probe_newConnection(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// Probes with NewConnection leave the kept-alive connections of
+	// other probes sharing the client alone.
+	client := &http.Client{Transport: &http.Transport{}}
+	reusing := HTTPProber{URL: ts.URL, Client: client}
+	fresh := HTTPProber{URL: ts.URL, Client: client, NewConnection: true}
+	reusing.Probe()
+	for i := 0; i < 2; i++ {
+		if got := fresh.Probe(); got.Details["reused_connection"] != "false" {
+			t.Errorf("[%d] %+v.Probe() => %v; want reused_connection \"false\"\n", i, fresh, got)
+		}
+	}
+	if got := reusing.Probe(); got.Details["reused_connection"] != "true" {
+		t.Errorf("%+v.Probe() => %v; want reused_connection \"true\"\n", reusing, got)
+	}
+}
+
+func TestHTTPProber_Probe_assert(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		fmt.Fprint(w, "status: ok")
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		url, assert string
+		want        ResultCode
+		wantErr     string
+	}{
+		{url: ts.URL, assert: `status == 200 && latency < 1m && body contains "ok"`, want: Pass},
+		{url: ts.URL, assert: `header("Content-Type") matches "^text/" && size == 10`, want: Pass},
+		{url: ts.URL + "/down", assert: `status == 503`, want: Pass},
+		{url: ts.URL + "/down", assert: `status == 200`, want: Fail, wantErr: "status=503"},
+		{url: ts.URL, assert: `body contains "error"`, want: Fail, wantErr: `body="status: ok"`},
+		{url: ts.URL, assert: `status ==`, want: Fail, wantErr: "bad expression"},
+	}
+	for i, tt := range cases {
+		hp := HTTPProber{URL: tt.url, Assert: tt.assert}
+		got := hp.Probe()
+		if got.Code != tt.want {
+			t.Errorf("[%d] Probe() with Assert %q => %v; want code %v\n", i, tt.assert, got, tt.want)
+		}
+		if tt.wantErr != "" && (got.Error == nil || !strings.Contains(got.Error.Error(), tt.wantErr)) {
+			t.Errorf("[%d] Probe() with Assert %q => error %v; want it to contain %q\n", i, tt.assert, got.Error, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package prober
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+type (
+	// junitTestSuite is the root element of a JUnit XML report.
+	junitTestSuite struct {
+		XMLName  xml.Name        `xml:"testsuite"`
+		Name     string          `xml:"name,attr"`
+		Tests    int             `xml:"tests,attr"`
+		Failures int             `xml:"failures,attr"`
+		Skipped  int             `xml:"skipped,attr"`
+		Time     float64         `xml:"time,attr"`
+		Cases    []junitTestCase `xml:"testcase"`
+	}
+
+	// junitTestCase is the outcome of a single probe in a JUnit XML report.
+	junitTestCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Time      float64       `xml:"time,attr"`
+		Failure   *junitMessage `xml:"failure,omitempty"`
+		Skipped   *junitMessage `xml:"skipped,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+
+	// junitMessage describes why a test case failed or was skipped.
+	junitMessage struct {
+		Message string `xml:"message,attr"`
+		Body    string `xml:",chardata"`
+	}
+)
+
+// WriteJUnit writes the report in JUnit XML format, with each probe as
+// a test case in a test suite with the given name, so CI systems can
+// show the outcome of a RunAllOnce() run as test results.
+func (r BatchReport) WriteJUnit(w io.Writer, suite string) error {
+	ts := junitTestSuite{
+		Name:  suite,
+		Tests: len(r),
+	}
+	for _, br := range r {
+		tc := junitTestCase{
+			Name:      br.Name,
+			ClassName: suite,
+			Time:      br.Duration.Seconds(),
+			SystemOut: br.Result.Info,
+		}
+		msg := ""
+		if br.Result.Error != nil {
+			msg = br.Result.Error.Error()
+		}
+		switch {
+		case br.Skipped:
+			ts.Skipped++
+			tc.Skipped = &junitMessage{Message: msg}
+		case !br.Result.Passed():
+			ts.Failures++
+			tc.Failure = &junitMessage{Message: msg, Body: br.Result.String()}
+		}
+		ts.Time += tc.Time
+		ts.Cases = append(ts.Cases, tc)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(ts); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,36 @@
+package prober
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBatchReport_WriteJUnit(t *testing.T) {
+	in := BatchReport{
+		{Name: "dns", Result: Passed(), Duration: time.Second},
+		{Name: "db", Result: FailedWith(errors.New("connection refused")), Duration: 2 * time.Second},
+		{Name: "web", Result: FailedWith(errors.New("web was skipped")), Skipped: true},
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="smoke" tests="3" failures="1" skipped="1" time="3">
+  <testcase name="dns" classname="smoke" time="1"></testcase>
+  <testcase name="db" classname="smoke" time="2">
+    <failure message="connection refused">Result{Code: &#34;Fail&#34;, Error: &#34;connection refused&#34;, Info: &#34;The probe failed with \&#34;connection refused\&#34;&#34;}</failure>
+    <system-out>The probe failed with &#34;connection refused&#34;</system-out>
+  </testcase>
+  <testcase name="web" classname="smoke" time="0">
+    <skipped message="web was skipped"></skipped>
+    <system-out>The probe failed with &#34;web was skipped&#34;</system-out>
+  </testcase>
+</testsuite>
+`
+	var buf bytes.Buffer
+	if err := in.WriteJUnit(&buf, "smoke"); err != nil {
+		t.Fatalf("WriteJUnit() => %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("WriteJUnit() =>\n%s\nwant:\n%s\n", got, want)
+	}
+}
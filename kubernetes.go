@@ -0,0 +1,229 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	inCluster     *http.Client // client trusting the cluster CA, once built by inClusterClient()
+	inClusterLock sync.Mutex   // protects inCluster
+)
+
+const (
+	// DefaultKubernetesAnnotation is the annotation that marks Kubernetes
+	// services and ingresses to probe, with the scheme to probe them
+	// with as value, e.g. `prober.hkjn.me/probe: "https"`.
+	DefaultKubernetesAnnotation = "prober.hkjn.me/probe"
+	// kubernetesPathAnnotation optionally sets the path to probe.
+	kubernetesPathAnnotation = "prober.hkjn.me/path"
+	// serviceAccountDir holds the credentials of the pod's service account.
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+type (
+	// KubernetesDiscoverer finds targets from Kubernetes services and
+	// ingresses that have an annotation. The address of each target is
+	// an URL, e.g. "https://web.default.svc:443/" for a service or
+	// "https://www.example.com/" for an ingress.
+	//
+	// When used with a Discovery, probes for services and ingresses
+	// that are deleted or lose the annotation are removed.
+	KubernetesDiscoverer struct {
+		// Address of the API server, or "" to use the in-cluster address.
+		APIServer string
+		// Bearer token to authenticate with, or "" to use the pod's service account.
+		Token string
+		// Client to use, or nil to trust the in-cluster CA.
+		Client *http.Client
+		// Namespace to look in, or "" for all namespaces.
+		Namespace string
+		// Annotation to look for, or "" for DefaultKubernetesAnnotation.
+		Annotation string
+	}
+
+	// kubernetesMeta is the metadata of a Kubernetes object.
+	kubernetesMeta struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Annotations map[string]string `json:"annotations"`
+	}
+)
+
+// KubernetesTemplate returns a template for HTTP probes of the targets
+// found by a KubernetesDiscoverer.
+func KubernetesTemplate(options ...Option) Template {
+	return Template{
+		Name:    "k8s-{kind}-{namespace}-{name}",
+		Desc:    "Probes {target} for Kubernetes {kind} {namespace}/{name}.",
+		New:     func(t Target) Prober { return HTTPProber{URL: t.Addr} },
+		Options: options,
+	}
+}
+
+// Targets returns a target for each annotated service and ingress.
+func (kd KubernetesDiscoverer) Targets(ctx context.Context) ([]Target, error) {
+	ns := ""
+	if kd.Namespace != "" {
+		ns = "/namespaces/" + kd.Namespace
+	}
+
+	var services struct {
+		Items []struct {
+			Metadata kubernetesMeta `json:"metadata"`
+			Spec     struct {
+				Ports []struct {
+					Port int `json:"port"`
+				} `json:"ports"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := kd.get(ctx, "/api/v1"+ns+"/services", &services); err != nil {
+		return nil, err
+	}
+	var ingresses struct {
+		Items []struct {
+			Metadata kubernetesMeta `json:"metadata"`
+			Spec     struct {
+				Rules []struct {
+					Host string `json:"host"`
+				} `json:"rules"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := kd.get(ctx, "/apis/networking.k8s.io/v1"+ns+"/ingresses", &ingresses); err != nil {
+		return nil, err
+	}
+
+	targets := []Target{}
+	for _, s := range services.Items {
+		scheme, ok := s.Metadata.Annotations[kd.annotation()]
+		if !ok || len(s.Spec.Ports) == 0 {
+			continue
+		}
+		host := fmt.Sprintf("%s.%s.svc:%d", s.Metadata.Name, s.Metadata.Namespace, s.Spec.Ports[0].Port)
+		targets = append(targets, s.Metadata.target("service", scheme, host))
+	}
+	for _, i := range ingresses.Items {
+		scheme, ok := i.Metadata.Annotations[kd.annotation()]
+		if !ok {
+			continue
+		}
+		for _, r := range i.Spec.Rules {
+			if r.Host == "" {
+				continue
+			}
+			targets = append(targets, i.Metadata.target("ingress", scheme, r.Host))
+		}
+	}
+	return targets, nil
+}
+
+// target returns the target for probing the host of the object.
+func (m kubernetesMeta) target(kind, scheme, host string) Target {
+	path := m.Annotations[kubernetesPathAnnotation]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return Target{
+		Addr: fmt.Sprintf("%s://%s%s", scheme, host, path),
+		Labels: map[string]string{
+			"kind":      kind,
+			"namespace": m.Namespace,
+			"name":      m.Name,
+		},
+	}
+}
+
+// annotation returns the annotation to look for.
+func (kd KubernetesDiscoverer) annotation() string {
+	if kd.Annotation != "" {
+		return kd.Annotation
+	}
+	return DefaultKubernetesAnnotation
+}
+
+// get fetches the path from the API server, decoding the JSON
+// response into v.
+func (kd KubernetesDiscoverer) get(ctx context.Context, path string, v interface{}) error {
+	server := kd.APIServer
+	if server == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return fmt.Errorf("no APIServer set and not running in a Kubernetes cluster")
+		}
+		server = "https://" + host + ":" + port
+		if strings.Contains(host, ":") {
+			server = "https://[" + host + "]:" + port
+		}
+	}
+	token := kd.Token
+	if token == "" {
+		b, err := os.ReadFile(serviceAccountDir + "/token")
+		if err != nil && kd.APIServer == "" {
+			return fmt.Errorf("failed to read service account token: %v", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	client := kd.Client
+	if client == nil {
+		var err error
+		if client, err = inClusterClient(); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(server, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kubernetes API returned %q for %s", resp.Status, path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("bad response from kubernetes API for %s: %v", path, err)
+	}
+	return nil
+}
+
+// inClusterClient returns a client that trusts the CA of the cluster
+// the pod runs in.
+//
+// The client is built once and shared, so that connections to the API
+// server are reused across polls.
+func inClusterClient() (*http.Client, error) {
+	inClusterLock.Lock()
+	defer inClusterLock.Unlock()
+	if inCluster != nil {
+		return inCluster, nil
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates in cluster CA %q", serviceAccountDir+"/ca.crt")
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	inCluster = &http.Client{Transport: t}
+	return inCluster, nil
+}
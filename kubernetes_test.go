@@ -0,0 +1,69 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKubernetesDiscoverer_Targets(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/default/services":
+			fmt.Fprint(w, `{"items": [
+				{"metadata": {"name": "web", "namespace": "default", "annotations": {"prober.hkjn.me/probe": "http", "prober.hkjn.me/path": "healthz"}},
+				 "spec": {"ports": [{"port": 8080}]}},
+				{"metadata": {"name": "db", "namespace": "default"},
+				 "spec": {"ports": [{"port": 5432}]}}
+			]}`)
+		case "/apis/networking.k8s.io/v1/namespaces/default/ingresses":
+			fmt.Fprint(w, `{"items": [
+				{"metadata": {"name": "www", "namespace": "default", "annotations": {"prober.hkjn.me/probe": "https"}},
+				 "spec": {"rules": [{"host": "www.example.com"}, {"host": "example.com"}]}}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	kd := KubernetesDiscoverer{
+		APIServer: api.URL,
+		Token:     "t0ken",
+		Client:    api.Client(),
+		Namespace: "default",
+	}
+	got, err := kd.Targets(context.Background())
+	if err != nil {
+		t.Fatalf("Targets() => %v", err)
+	}
+	want := []string{
+		"http://web.default.svc:8080/healthz",
+		"https://www.example.com/",
+		"https://example.com/",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Targets() => %v; want %v", got, want)
+	}
+	for i, w := range want {
+		if got[i].Addr != w {
+			t.Errorf("Targets()[%d] => %v; want %q\n", i, got[i], w)
+		}
+	}
+	if name := KubernetesTemplate().name(got[0]); name != "k8s-service-default-web" {
+		t.Errorf("KubernetesTemplate().name(%v) => %q; want %q\n", got[0], name, "k8s-service-default-web")
+	}
+
+	kd.Token = "wrong"
+	if _, err := kd.Targets(context.Background()); err == nil {
+		t.Errorf("Targets() with bad token => nil error; want error\n")
+	}
+}
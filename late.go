@@ -0,0 +1,84 @@
+package prober
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// LateResults is what to do with the result of a run that returns after
+// timing out, when the timeout was already recorded as a failure.
+type LateResults int
+
+const (
+	// DropLateResults ignores results that arrive after a timeout.
+	DropLateResults LateResults = iota
+	// CorrectLateResults records a late pass as a correction of the
+	// timeout: it's logged, and the badness added by the timeout is
+	// taken back. Late failures are dropped, since the timeout already
+	// counted as one.
+	CorrectLateResults
+	// KeepLateResults handles late results like those of any other
+	// run, in addition to the timeout.
+	KeepLateResults
+)
+
+// OnLateResult sets what to do with results of runs that return after
+// timing out, by default DropLateResults.
+//
+// Late results have "late" set in their Details to how long after the
+// timeout they arrived. Unless they're dropped, ContextProbers aren't
+// cancelled when their runs time out, so they can still return one.
+// Runs abandoned because the probe stopped never count.
+func OnLateResult(lr LateResults) func(*Probe) {
+	return func(p *Probe) {
+		p.lateResults = lr
+	}
+}
+
+// String returns the name of the policy.
+func (lr LateResults) String() string {
+	switch lr {
+	case DropLateResults:
+		return "drop"
+	case CorrectLateResults:
+		return "correct"
+	case KeepLateResults:
+		return "keep"
+	}
+	return fmt.Sprintf("LateResults(%d)", int(lr))
+}
+
+// handleLateResult handles the result of a run that returned at the
+// time now, after timing out at timedOut.
+func (p *Probe) handleLateResult(r Result, timedOut, now time.Time) {
+	if p.lateResults == DropLateResults {
+		return
+	}
+	details := make(map[string]string, len(r.Details)+1)
+	for k, v := range r.Details {
+		details[k] = v
+	}
+	details["late"] = now.Sub(timedOut).String()
+	r.Details = details
+	if p.lateResults == KeepLateResults {
+		log.Printf("[%s] Keeping result that arrived %v after timing out\n", p.Name, now.Sub(timedOut))
+		p.handleResult(r)
+		return
+	}
+	if !r.Passed() {
+		log.Printf("[%s] Dropping failure that arrived %v after timing out\n", p.Name, now.Sub(timedOut))
+		return
+	}
+	p.resultLock.Lock()
+	defer p.resultLock.Unlock()
+	// The timeout was penalized like any timed out run, e.g. by
+	// TimeoutPenalty() rather than FailurePenalty().
+	b := p.Badness() - p.penalty(FailedWith(ErrTimedOut))
+	if b < 0 {
+		b = 0
+	}
+	log.Printf("[%s] Pass arrived %v after timing out, correcting badness to %d.\n", p.Name, now.Sub(timedOut), b)
+	p.setBadness(b)
+	p.logResult(r)
+}
@@ -0,0 +1,76 @@
+package prober
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOnLateResult(t *testing.T) {
+	cases := []struct {
+		policy      LateResults
+		wantBadness int
+		wantRecords int
+	}{
+		{DropLateResults, 10, 1},
+		{CorrectLateResults, 0, 2},
+		{KeepLateResults, 9, 2},
+	}
+	for i, tt := range cases {
+		release := make(chan struct{})
+		p := &Probe{
+			Prober:         blockingProber{release: release},
+			Name:           "LateProber",
+			Interval:       10 * time.Millisecond,
+			failurePenalty: 10,
+			successReward:  1,
+			t:              realTime{},
+		}
+		OnLateResult(tt.policy)(p)
+		p.runProbe()
+		if got := p.Badness(); got != 10 {
+			t.Errorf("[%d] Badness() => %d after timeout; want 10\n", i, got)
+		}
+		close(release)
+		done := func() bool {
+			return p.Stats().AbandonedRunning == 0 && p.Badness() == tt.wantBadness && len(p.Records()) == tt.wantRecords
+		}
+		if !waitFor(done) {
+			t.Errorf("[%d] OnLateResult(%v) => badness %d, %d records; want %d, %d\n", i, tt.policy, p.Badness(), len(p.Records()), tt.wantBadness, tt.wantRecords)
+			continue
+		}
+		if tt.wantRecords < 2 {
+			continue
+		}
+		if r := p.Records()[1]; !r.Result.Passed() || r.Result.Details["late"] == "" {
+			t.Errorf("[%d] late record => %v; want pass with Details[late]\n", i, r.Result)
+		}
+	}
+}
+
+func TestOnLateResult_timeoutPenalty(t *testing.T) {
+	release := make(chan struct{})
+	p := &Probe{
+		Prober:         blockingProber{release: release},
+		Name:           "LateProber",
+		Interval:       10 * time.Millisecond,
+		failurePenalty: 10,
+		successReward:  1,
+		t:              realTime{},
+	}
+	TimeoutPenalty(2)(p)
+	OnLateResult(CorrectLateResults)(p)
+	p.setBadness(20)
+	p.runProbe()
+	if got := p.Badness(); got != 22 {
+		t.Errorf("Badness() => %d after timeout; want 22\n", got)
+	}
+	close(release)
+	done := func() bool {
+		return p.Stats().AbandonedRunning == 0 && len(p.Records()) == 2
+	}
+	// The late pass takes back the timeout penalty, not the failure
+	// penalty, so earlier failures still count.
+	if !waitFor(done) || p.Badness() != 20 {
+		t.Errorf("late pass with TimeoutPenalty(2) => badness %d, %d records; want 20, 2\n", p.Badness(), len(p.Records()))
+	}
+}
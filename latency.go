@@ -0,0 +1,219 @@
+package prober
+
+import (
+	"math/bits"
+	"sort"
+	"time"
+)
+
+const (
+	// latencyBits is the number of significant bits kept for latencies
+	// in a LatencyHistogram, so buckets are less than 1/2^(latencyBits-1)
+	// wide relative to the values they hold.
+	latencyBits = 8
+	// defaultLatencyWindow is how long latencies are collected for
+	// before starting over, unless set with LatencyWindow().
+	defaultLatencyWindow = time.Hour
+)
+
+type (
+	// LatencyHistogram counts the durations of runs of a probe in
+	// buckets of HDR (high dynamic range) precision: durations are kept
+	// to within 1% of their value whether they're microseconds or
+	// minutes, in little memory.
+	//
+	// The zero value is an empty histogram ready for use.
+	LatencyHistogram struct {
+		counts   map[int]uint64 // number of durations recorded per bucket
+		count    uint64
+		sum      time.Duration
+		min, max time.Duration
+	}
+
+	// LatencyStats summarizes the durations of runs of the probe since
+	// Since, see LatencyWindow().
+	LatencyStats struct {
+		Since     time.Time // when the current window started
+		Count     int       // number of runs in the window
+		Min, Max  time.Duration
+		Mean      time.Duration
+		P50, P90  time.Duration
+		P99, P999 time.Duration
+	}
+)
+
+// LatencyWindow sets how long the durations of runs are collected for
+// in the latency histogram of the probe before it starts over, by
+// default an hour.
+//
+// The window rotates on the first run after it ends, so a probe that
+// isn't running keeps the latencies of its last window.
+func LatencyWindow(d time.Duration) func(*Probe) {
+	return func(p *Probe) {
+		p.latencyWindow = d
+	}
+}
+
+// Record adds a duration to the histogram. Negative durations are
+// counted as 0.
+func (h *LatencyHistogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	if h.counts == nil {
+		h.counts = map[int]uint64{}
+	}
+	h.counts[latencyBucket(d)]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+}
+
+// Count returns the number of durations recorded.
+func (h *LatencyHistogram) Count() int {
+	return int(h.count)
+}
+
+// Min returns the smallest duration recorded, or 0 if there are none.
+func (h *LatencyHistogram) Min() time.Duration {
+	return h.min
+}
+
+// Max returns the largest duration recorded, or 0 if there are none.
+func (h *LatencyHistogram) Max() time.Duration {
+	return h.max
+}
+
+// Mean returns the average of the durations recorded, or 0 if there
+// are none.
+func (h *LatencyHistogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// Quantile returns the duration that the fraction q of the recorded
+// durations are at or below, e.g. 0.99 for the 99th percentile, or 0
+// if there are none.
+func (h *LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return h.min
+	}
+	if q >= 1 {
+		return h.max
+	}
+	rank := uint64(q*float64(h.count) + 0.5)
+	if rank == 0 {
+		rank = 1
+	}
+	buckets := make([]int, 0, len(h.counts))
+	for b := range h.counts {
+		buckets = append(buckets, b)
+	}
+	sort.Ints(buckets)
+	var n uint64
+	for _, b := range buckets {
+		n += h.counts[b]
+		if n >= rank {
+			return h.clamp(latencyValue(b))
+		}
+	}
+	return h.max
+}
+
+// clamp returns d limited to the range of durations recorded, which
+// bucket values can be outside of.
+func (h *LatencyHistogram) clamp(d time.Duration) time.Duration {
+	if d < h.min {
+		return h.min
+	}
+	if d > h.max {
+		return h.max
+	}
+	return d
+}
+
+// clone returns a copy of the histogram.
+func (h *LatencyHistogram) clone() *LatencyHistogram {
+	c := *h
+	c.counts = make(map[int]uint64, len(h.counts))
+	for b, n := range h.counts {
+		c.counts[b] = n
+	}
+	return &c
+}
+
+// latencyBucket returns the index of the bucket holding d. Durations
+// below 2^latencyBits ns each have a bucket, and above that each power
+// of two is split into 2^(latencyBits-1) buckets.
+func latencyBucket(d time.Duration) int {
+	v := uint64(d)
+	shift := bits.Len64(v) - latencyBits
+	if shift <= 0 {
+		return int(v)
+	}
+	half := 1 << (latencyBits - 1)
+	return 1<<latencyBits + (shift-1)*half + int(v>>shift) - half
+}
+
+// latencyValue returns the duration in the middle of bucket b.
+func latencyValue(b int) time.Duration {
+	if b < 1<<latencyBits {
+		return time.Duration(b)
+	}
+	half := 1 << (latencyBits - 1)
+	b -= 1 << latencyBits
+	shift := uint(b/half + 1)
+	lo := uint64(b%half+half) << shift
+	return time.Duration(lo + (uint64(1)<<shift)/2)
+}
+
+// Latencies returns a copy of the histogram of durations of runs of the
+// probe in the current window, see LatencyWindow().
+func (p *Probe) Latencies() *LatencyHistogram {
+	p.statsLock.RLock()
+	defer p.statsLock.RUnlock()
+	return p.latency.clone()
+}
+
+// latencyStats summarizes the latency histogram. The caller must hold
+// statsLock.
+func (p *Probe) latencyStats() LatencyStats {
+	h := &p.latency
+	return LatencyStats{
+		Since: p.latencySince,
+		Count: h.Count(),
+		Min:   h.Min(),
+		Max:   h.Max(),
+		Mean:  h.Mean(),
+		P50:   h.Quantile(0.5),
+		P90:   h.Quantile(0.9),
+		P99:   h.Quantile(0.99),
+		P999:  h.Quantile(0.999),
+	}
+}
+
+// recordLatency adds the duration of a run that finished at now to the
+// latency histogram, first starting over if the window has passed.
+func (p *Probe) recordLatency(now time.Time, d time.Duration) {
+	window := p.latencyWindow
+	if window == 0 {
+		window = defaultLatencyWindow
+	}
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	if p.latencySince.IsZero() || now.Sub(p.latencySince) >= window {
+		p.latency = LatencyHistogram{}
+		p.latencySince = now
+	}
+	p.latency.Record(d)
+}
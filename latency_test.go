@@ -0,0 +1,76 @@
+package prober
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	var h LatencyHistogram
+	if got := h.Quantile(0.5); got != 0 {
+		t.Errorf("Quantile(0.5) => %v on empty histogram; want 0\n", got)
+	}
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	cases := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{0.5, 500 * time.Millisecond},
+		{0.9, 900 * time.Millisecond},
+		{0.99, 990 * time.Millisecond},
+		{1, time.Second},
+	}
+	for i, tt := range cases {
+		got := h.Quantile(tt.q)
+		if diff := got - tt.want; diff < -tt.want/100 || diff > tt.want/100 {
+			t.Errorf("[%d] Quantile(%v) => %v; want %v within 1%%\n", i, tt.q, got, tt.want)
+		}
+	}
+	if got, want := h.Count(), 1000; got != want {
+		t.Errorf("Count() => %d; want %d\n", got, want)
+	}
+	if got, want := h.Mean(), 500500*time.Microsecond; got != want {
+		t.Errorf("Mean() => %v; want %v\n", got, want)
+	}
+	if got, want := h.Min(), time.Millisecond; got != want {
+		t.Errorf("Min() => %v; want %v\n", got, want)
+	}
+	if got, want := h.Max(), time.Second; got != want {
+		t.Errorf("Max() => %v; want %v\n", got, want)
+	}
+}
+
+func TestLatencyBucket(t *testing.T) {
+	for _, d := range []time.Duration{0, 1, 255, 256, 257, 511, 512, time.Microsecond, 3 * time.Second, time.Hour} {
+		got := latencyValue(latencyBucket(d))
+		if diff := got - d; diff < -d/100 || diff > d/100 {
+			t.Errorf("latencyValue(latencyBucket(%v)) => %v; want within 1%%\n", d, got)
+		}
+	}
+}
+
+func TestProbe_recordLatency(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{latencyWindow: time.Minute}
+	p.recordLatency(start, time.Second)
+	p.recordLatency(start.Add(30*time.Second), 3*time.Second)
+	s := p.Stats().Latency
+	if s.Count != 2 || s.Max != 3*time.Second || !s.Since.Equal(start) {
+		t.Errorf("Stats().Latency => %+v; want 2 runs up to 3s since %v\n", s, start)
+	}
+	// The window has passed, so the histogram starts over.
+	next := start.Add(time.Minute)
+	p.recordLatency(next, 2*time.Second)
+	s = p.Stats().Latency
+	if s.Count != 1 || s.Max != 2*time.Second || !s.Since.Equal(next) {
+		t.Errorf("Stats().Latency => %+v after window; want 1 run of 2s since %v\n", s, next)
+	}
+	h := p.Latencies()
+	h.Record(time.Hour)
+	if got := p.Stats().Latency.Max; got != 2*time.Second {
+		t.Errorf("Stats().Latency.Max => %v after changing copy; want 2s\n", got)
+	}
+}
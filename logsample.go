@@ -0,0 +1,31 @@
+package prober
+
+// SampleLog makes the probe write only every nth passing record to the
+// log file, and ship and export only those, e.g. for probes running
+// every few seconds. Failures, and the first pass after a failure, are
+// always written, so no outage or recovery goes missing. The records
+// kept in memory, see Records(), and streams get every run.
+func SampleLog(n int) func(*Probe) {
+	return func(p *Probe) {
+		p.logSampling = n
+	}
+}
+
+// sampled returns true if the record of a run with the result should be
+// written to the log file and remote sinks. The caller must hold
+// resultLock.
+func (p *Probe) sampled(r Result) bool {
+	if p.logSampling <= 1 {
+		return true
+	}
+	if !r.Passed() {
+		p.passesUnlogged = -1
+		return true
+	}
+	p.passesUnlogged++
+	if p.passesUnlogged > 0 && p.passesUnlogged < p.logSampling {
+		return false
+	}
+	p.passesUnlogged = 0
+	return true
+}
@@ -0,0 +1,42 @@
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSampleLog(t *testing.T) {
+	e := &Engine{LogPath: filepath.Join(t.TempDir(), "records.log"), LogCodec: JSON}
+	p := e.NewProbe(testProber{Passed()}, "SampledProber", "Logs some passes.", SampleLog(3))
+	defer p.Disable()
+	fail := FailedWith(errors.New("connection refused"))
+	// Passes 3 and 6 are written, as are the failure and the recovery
+	// after it.
+	runs := []Result{Passed(), Passed(), Passed(), Passed(), Passed(), Passed(), fail, Passed(), Passed()}
+	for _, r := range runs {
+		p.handleResult(r)
+	}
+	e.FlushLog()
+	b, err := os.ReadFile(e.LogPath)
+	if err != nil {
+		t.Fatalf("ReadFile() => %v; want nil\n", err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("log line %q => %v; want record\n", line, err)
+		}
+		got = append(got, r.Result.Code.String())
+	}
+	if want := "Pass Pass Fail Pass"; strings.Join(got, " ") != want {
+		t.Errorf("log file with SampleLog(3) => %v; want %s\n", got, want)
+	}
+	if n := len(p.Records()); n != len(runs) {
+		t.Errorf("Records() with SampleLog(3) => %d records; want all %d\n", n, len(runs))
+	}
+}
@@ -0,0 +1,210 @@
+package prober
+
+import (
+	"bufio"
+	"expvar"
+	"flag"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	logFlushInterval = flag.Duration("log_flush_interval", time.Second, "how often to flush records to the YAML log file")
+	logQueueSize     = flag.Int("log_queue_size", 1024, "number of records that may wait to be written to the YAML log file before new ones are dropped")
+	outcomes         *logWriter   // writes records to logFile, once opened
+	outcomesLock     sync.RWMutex // protects outcomes
+)
+
+type (
+	// LogStats describes the writes of records to the YAML log file.
+	LogStats struct {
+		Queued      int // records queued for writing
+		Written     int // records written
+		Dropped     int // records dropped since the queue was full
+		Errors      int // failed writes and flushes
+		Flushes     int // times the buffered records were flushed
+		QueueLen    int // records currently waiting to be written
+		MaxQueueLen int // most records ever waiting to be written
+	}
+
+	// logWriter writes records in batches from its own goroutine, so
+	// that slow disks don't hold up probing.
+	logWriter struct {
+		out       io.Writer
+		w         *bufio.Writer // buffers writes to out
+		queue     chan []byte
+		flushReq  chan chan struct{}
+		stopReq   chan chan struct{}
+		stopped   chan struct{} // closed once the writer has stopped, see stop()
+		stopOnce  sync.Once
+		stats     LogStats
+		statsLock sync.Mutex
+	}
+)
+
+func init() {
+	expvar.Publish("prober_log", expvar.Func(func() interface{} {
+		return GetLogStats()
+	}))
+}
+
+// newLogWriter returns a logWriter writing to w, flushing every
+// interval.
+func newLogWriter(w io.Writer, size int, interval time.Duration) *logWriter {
+	lw := &logWriter{
+		out:      w,
+		w:        bufio.NewWriter(w),
+		queue:    make(chan []byte, size),
+		flushReq: make(chan chan struct{}),
+		stopReq:  make(chan chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go lw.run(interval)
+	return lw
+}
+
+// GetLogStats returns stats on the writes of records to the YAML log
+// file.
+func GetLogStats() LogStats {
+	if lw := getLogWriter(); lw != nil {
+		return lw.Stats()
+	}
+	return LogStats{}
+}
+
+// FlushLog writes all queued records to the YAML log file, blocking
+// until they are written. FlushLog should be called before exiting, to
+// not lose the most recent records.
+func FlushLog() {
+	if lw := getLogWriter(); lw != nil {
+		lw.flush()
+	}
+}
+
+// getLogWriter returns the writer of records to the YAML log file, or
+// nil if it's not yet open.
+func getLogWriter() *logWriter {
+	outcomesLock.RLock()
+	defer outcomesLock.RUnlock()
+	return outcomes
+}
+
+// Stats returns stats on the writes.
+func (lw *logWriter) Stats() LogStats {
+	lw.statsLock.Lock()
+	defer lw.statsLock.Unlock()
+	s := lw.stats
+	s.QueueLen = len(lw.queue)
+	return s
+}
+
+// write queues b for writing, dropping it if the queue is full or the
+// writer has stopped.
+func (lw *logWriter) write(b []byte) {
+	select {
+	case <-lw.stopped:
+		lw.statsLock.Lock()
+		lw.stats.Dropped++
+		lw.statsLock.Unlock()
+		return
+	default:
+	}
+	select {
+	case lw.queue <- b:
+		lw.statsLock.Lock()
+		lw.stats.Queued++
+		if n := len(lw.queue); n > lw.stats.MaxQueueLen {
+			lw.stats.MaxQueueLen = n
+		}
+		lw.statsLock.Unlock()
+	default:
+		lw.statsLock.Lock()
+		lw.stats.Dropped++
+		lw.statsLock.Unlock()
+		log.Printf("queue of %d records for log is full, dropping record\n", cap(lw.queue))
+	}
+}
+
+// flush writes all queued records, blocking until they are written.
+func (lw *logWriter) flush() {
+	done := make(chan struct{})
+	select {
+	case lw.flushReq <- done:
+		<-done
+	case <-lw.stopped:
+	}
+}
+
+// stop writes all queued records and stops the writer, blocking until
+// they are written. Records written after stop are dropped.
+func (lw *logWriter) stop() {
+	lw.stopOnce.Do(func() {
+		done := make(chan struct{})
+		lw.stopReq <- done
+		<-done
+	})
+}
+
+// run writes queued records, flushing them every interval and when
+// asked to, until stopped.
+func (lw *logWriter) run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case b := <-lw.queue:
+			lw.add(b)
+		case <-t.C:
+			lw.flushBuffer()
+		case done := <-lw.flushReq:
+			for n := len(lw.queue); n > 0; n-- {
+				lw.add(<-lw.queue)
+			}
+			lw.flushBuffer()
+			close(done)
+		case done := <-lw.stopReq:
+			for n := len(lw.queue); n > 0; n-- {
+				lw.add(<-lw.queue)
+			}
+			lw.flushBuffer()
+			close(lw.stopped)
+			close(done)
+			return
+		}
+	}
+}
+
+// add adds b to the buffered records.
+func (lw *logWriter) add(b []byte) {
+	if _, err := lw.w.Write(b); err != nil {
+		log.Printf("failed to write record to log: %v", err)
+		lw.statsLock.Lock()
+		lw.stats.Errors++
+		lw.statsLock.Unlock()
+		return
+	}
+	lw.statsLock.Lock()
+	lw.stats.Written++
+	lw.statsLock.Unlock()
+}
+
+// flushBuffer writes out the buffered records, if any.
+func (lw *logWriter) flushBuffer() {
+	if lw.w.Buffered() == 0 {
+		return
+	}
+	if err := lw.w.Flush(); err != nil {
+		log.Printf("failed to flush records to log: %v", err)
+		// A bufio.Writer stays broken after an error, so start over
+		// with a fresh one, losing the buffered records.
+		lw.w.Reset(lw.out)
+		lw.statsLock.Lock()
+		lw.stats.Errors++
+		lw.statsLock.Unlock()
+	}
+	lw.statsLock.Lock()
+	lw.stats.Flushes++
+	lw.statsLock.Unlock()
+}
@@ -0,0 +1,34 @@
+package prober
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	lw := newLogWriter(&buf, 10, time.Hour)
+	lw.write([]byte("a\n"))
+	lw.write([]byte("b\n"))
+	lw.flush()
+	if got, want := buf.String(), "a\nb\n"; got != want {
+		t.Errorf("after flush(), log has %q; want %q\n", got, want)
+	}
+	got := lw.Stats()
+	want := LogStats{Queued: 2, Written: 2, Flushes: 1, MaxQueueLen: got.MaxQueueLen}
+	if got != want {
+		t.Errorf("Stats() => %+v; want %+v\n", got, want)
+	}
+}
+
+func TestLogWriter_full(t *testing.T) {
+	// Without run(), nothing drains the queue.
+	lw := &logWriter{queue: make(chan []byte, 1)}
+	lw.write([]byte("a\n"))
+	lw.write([]byte("b\n"))
+	want := LogStats{Queued: 1, Dropped: 1, QueueLen: 1, MaxQueueLen: 1}
+	if got := lw.Stats(); got != want {
+		t.Errorf("Stats() => %+v; want %+v\n", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package prober
+
+import (
+	"log"
+	"sync"
+)
+
+// recordOverhead is the approximate memory used by a Record, not
+// counting the contents of its strings.
+const recordOverhead = 160
+
+var (
+	totalRecordBytes int                 // approximate memory used by records of all probes
+	recordHolders    = map[*Probe]bool{} // probes that hold records
+	recordBytesLock  sync.Mutex          // protects totalRecordBytes and recordHolders
+)
+
+// size returns the approximate memory used by the record.
+//
+// Strings dominate the memory use of large records, so only those are
+// counted exactly.
+func (r Record) size() int {
+	n := recordOverhead + len(r.TimeMillis) + len(r.UntilMillis) + len(r.Location) + len(r.RunID) +
+		len(r.Result.Info) + len(r.Result.InfoUrl)
+	if r.Result.Error != nil {
+		n += len(r.Result.Error.Error())
+	}
+	if r.Result.Value != nil {
+		n += 8
+	}
+	for k, v := range r.Annotations {
+		n += len(k) + len(v)
+	}
+	for k, v := range r.Result.Details {
+		n += len(k) + len(v)
+	}
+	return n
+}
+
+// RecordBytes returns the approximate memory used by the records of
+// the probe.
+func (p *Probe) RecordBytes() int {
+	p.recordsLock.RLock()
+	defer p.recordsLock.RUnlock()
+	return p.recordBytes
+}
+
+// TotalRecordBytes returns the approximate memory used by the records
+// of all probes.
+func TotalRecordBytes() int {
+	recordBytesLock.Lock()
+	defer recordBytesLock.Unlock()
+	return totalRecordBytes
+}
+
+// trackRecordBytes accounts for the records of the probe growing by
+// the number of bytes, and evicts the oldest records across all probes
+// if that puts the total over -max_record_bytes.
+//
+// The caller must not hold the records lock of any probe.
+func trackRecordBytes(p *Probe, added int) {
+	recordBytesLock.Lock()
+	defer recordBytesLock.Unlock()
+	recordHolders[p] = true
+	totalRecordBytes += added
+	for *maxRecordBytes > 0 && totalRecordBytes > *maxRecordBytes {
+		if !evictOldestRecord() {
+			return
+		}
+	}
+}
+
+// forgetRecords stops accounting for the records of the probe, e.g.
+// since it was removed.
+func forgetRecords(p *Probe) {
+	recordBytesLock.Lock()
+	defer recordBytesLock.Unlock()
+	if !recordHolders[p] {
+		return
+	}
+	delete(recordHolders, p)
+	totalRecordBytes -= p.RecordBytes()
+}
+
+// evictOldestRecord drops the oldest record across all probes,
+// returning false if there were no records.
+//
+// The caller must hold recordBytesLock.
+func evictOldestRecord() bool {
+	var oldest *Probe
+	var oldestRecord Record
+	for p := range recordHolders {
+		p.recordsLock.RLock()
+		if len(p.records) > 0 && (oldest == nil || p.records[0].Timestamp.Before(oldestRecord.Timestamp)) {
+			oldest, oldestRecord = p, p.records[0]
+		}
+		p.recordsLock.RUnlock()
+	}
+	if oldest == nil {
+		return false
+	}
+	oldest.recordsLock.Lock()
+	if len(oldest.records) == 0 {
+		// The records changed after we looked, try again.
+		oldest.recordsLock.Unlock()
+		return true
+	}
+	evicted := oldest.records[0]
+	oldest.records = oldest.records[1:]
+	oldest.recordBytes -= evicted.size()
+	oldest.recordsLock.Unlock()
+	totalRecordBytes -= evicted.size()
+	log.Printf("[%s] records use over %d bytes in total, evicted oldest record from %v\n", oldest.Name, *maxRecordBytes, evicted.Timestamp)
+	return true
+}
@@ -0,0 +1,37 @@
+package prober
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackRecordBytes(t *testing.T) {
+	defer func(max, total int, holders map[*Probe]bool) {
+		*maxRecordBytes, totalRecordBytes, recordHolders = max, total, holders
+	}(*maxRecordBytes, totalRecordBytes, recordHolders)
+	totalRecordBytes, recordHolders = 0, map[*Probe]bool{}
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	a, b := &Probe{Name: "a"}, &Probe{Name: "b"}
+
+	r := Record{Timestamp: start, Result: Passed()}
+	*maxRecordBytes = 3 * r.size()
+	a.addRecord(Record{Timestamp: start, Result: Passed()})
+	b.addRecord(Record{Timestamp: start.Add(time.Minute), Result: Passed()})
+	a.addRecord(Record{Timestamp: start.Add(2 * time.Minute), Result: Passed()})
+	if got, want := a.RecordBytes(), 2*r.size(); got != want {
+		t.Errorf("a.RecordBytes() => %d; want %d\n", got, want)
+	}
+
+	// One more record is over the limit, so the oldest one, from a, is
+	// evicted.
+	b.addRecord(Record{Timestamp: start.Add(3 * time.Minute), Result: Passed()})
+	if got := len(a.Records()); got != 1 {
+		t.Errorf("after eviction, len(a.Records()) => %d; want 1\n", got)
+	}
+	if got := len(b.Records()); got != 2 {
+		t.Errorf("after eviction, len(b.Records()) => %d; want 2\n", got)
+	}
+	if got, want := a.RecordBytes(), r.size(); got != want {
+		t.Errorf("after eviction, a.RecordBytes() => %d; want %d\n", got, want)
+	}
+}
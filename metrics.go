@@ -0,0 +1,36 @@
+package prober
+
+import (
+	"expvar"
+)
+
+var (
+	// metrics exports the Status() of each probe created with NewProbe
+	// via expvar, so it's visible at /debug/vars when the expvar
+	// handler is served.
+	metrics = expvar.NewMap("probes")
+	// engineMetrics holds the map of the Status() of the probes of
+	// each Engine, by the name of the engine.
+	engineMetrics = expvar.NewMap("engines")
+)
+
+// publish makes the status of the probe visible via expvar.
+func (p *Probe) publish() {
+	p.metricsMap().Set(p.Name, expvar.Func(func() interface{} {
+		return p.Status()
+	}))
+}
+
+// unpublish removes the status of the probe from expvar.
+func (p *Probe) unpublish() {
+	p.metricsMap().Delete(p.Name)
+}
+
+// metricsMap returns the map the probe publishes its status in, that
+// of its engine if it has one.
+func (p *Probe) metricsMap() *expvar.Map {
+	if p.engine != nil {
+		return p.engine.metricsMap()
+	}
+	return metrics
+}
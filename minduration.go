@@ -0,0 +1,39 @@
+package prober
+
+import (
+	"fmt"
+	"time"
+)
+
+// MinDurationProber is a Prober that wraps another, failing if it
+// passes faster than a minimum duration, e.g. to check that repeated
+// logins are actually throttled rather than answered suspiciously fast.
+//
+// The time the wrapped prober took is included in the Details of the
+// Result, as "duration". Failures of the wrapped prober are reported
+// as-is.
+//
+// Alert() is provided by the wrapped prober.
+type MinDurationProber struct {
+	Prober
+	Min time.Duration // how long Probe() of the wrapped prober must take
+}
+
+// Probe runs the wrapped prober and checks how long it took.
+func (mp MinDurationProber) Probe() Result {
+	start := time.Now()
+	r := mp.Prober.Probe()
+	d := time.Since(start)
+
+	details := make(map[string]string, len(r.Details)+1)
+	for k, v := range r.Details {
+		details[k] = v
+	}
+	details["duration"] = d.String()
+	r.Details = details
+	if r.Passed() && d < mp.Min {
+		r.Code = Fail
+		r.Error = fmt.Errorf("probe took %v, want at least %v", d, mp.Min)
+	}
+	return r
+}
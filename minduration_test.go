@@ -0,0 +1,48 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// sleepingProber sleeps, then returns its result.
+type sleepingProber struct {
+	AlertFn
+	d      time.Duration
+	result Result
+}
+
+func (sp sleepingProber) Probe() Result {
+	time.Sleep(sp.d)
+	return sp.result
+}
+
+func TestMinDurationProber_Probe(t *testing.T) {
+	cases := []struct {
+		in   MinDurationProber
+		want ResultCode
+	}{
+		{
+			in:   MinDurationProber{sleepingProber{d: 20 * time.Millisecond, result: Passed()}, 10 * time.Millisecond},
+			want: Pass,
+		},
+		{
+			in:   MinDurationProber{sleepingProber{result: Passed()}, time.Second},
+			want: Fail,
+		},
+		{
+			in:   MinDurationProber{sleepingProber{d: 20 * time.Millisecond, result: FailedWith(errors.New("login failed"))}, 10 * time.Millisecond},
+			want: Fail,
+		},
+	}
+	for i, tt := range cases {
+		got := tt.in.Probe()
+		if got.Code != tt.want {
+			t.Errorf("[%d] Probe() => %v; want code %v\n", i, got, tt.want)
+		}
+		if got.Details["duration"] == "" {
+			t.Errorf("[%d] Probe() => %v; want duration detail\n", i, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package prober
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+type (
+	// Recoverer is implemented by Probers that can send a notice when a
+	// probe recovers, i.e. passes after an alert or warning was sent
+	// for it.
+	Recoverer interface {
+		Recover(name, desc string, records Records) error
+	}
+
+	// RecoverFn is a function that is called when a probe recovers.
+	RecoverFn func(name, desc string, records Records) error
+)
+
+// Recover calls fn, which lets probers embed a RecoverFn to implement
+// Recoverer.
+func (fn RecoverFn) Recover(name, desc string, records Records) error {
+	return fn(name, desc, records)
+}
+
+// sendRecovery calls the Recover() implementation, if any.
+func (p *Probe) sendRecovery() {
+	r, ok := p.Prober.(Recoverer)
+	if !ok {
+		return
+	}
+	if p.alertsDisabled() {
+		log.Printf("[%s] would send recovery notice, but alerts are disabled\n", p.Name)
+		return
+	}
+	if p.isDryRun() {
+		log.Printf("[%s] [dry run] would send recovery notice: %s\n", p.Name, p.Desc)
+		return
+	}
+	err := p.callNotifier("recovery notice", func() error {
+		return r.Recover(p.Name, p.Desc, p.Records())
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to send recovery notice: %v\n", p.Name, err)
+	}
+}
+
+// sendJSON sends a request with in encoded as JSON as the body, and
+// decodes the JSON response into out, if it's not nil. It's used by
+// notifiers talking to the APIs of other services.
+func sendJSON(client *http.Client, method, url string, header http.Header, in, out interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s returned %q: %s", method, url, resp.Status, bytes.TrimSpace(body))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("bad response from %s %s: %v", method, url, err)
+	}
+	return nil
+}
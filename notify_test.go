@@ -0,0 +1,73 @@
+package prober
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// recoveringProber is a testProber that is also a Recoverer.
+type recoveringProber struct {
+	testProber
+	RecoverFn
+}
+
+func TestProbe_handleResult_recovery(t *testing.T) {
+	recovered := make(chan string, 2)
+	p := &Probe{
+		Prober: recoveringProber{
+			testProber{Passed()},
+			func(name, desc string, records Records) error {
+				recovered <- name
+				return nil
+			},
+		},
+		Name:          "RecoveringProber",
+		Desc:          "Recovers on purpose.",
+		successReward: 1,
+		t:             fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	p.handleResult(Passed())
+	p.noteNotification(p.t.Now())
+	p.handleResult(Passed())
+	p.handleResult(Passed())
+	select {
+	case name := <-recovered:
+		if name != p.Name {
+			t.Errorf("Recover() called with name %q; want %q\n", name, p.Name)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Recover() was not called\n")
+	}
+	select {
+	case <-recovered:
+		t.Errorf("Recover() called again; want only one recovery notice after a notification\n")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSendJSON(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type => %q; want application/json\n", got)
+		}
+		if r.URL.Path == "/fail" {
+			http.Error(w, "no such thing", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"id": 42}`))
+	}))
+	defer s.Close()
+
+	var out struct{ ID int }
+	if err := sendJSON(nil, "POST", s.URL+"/ok", nil, map[string]string{"a": "b"}, &out); err != nil {
+		t.Fatalf("sendJSON() => %v; want nil\n", err)
+	}
+	if out.ID != 42 {
+		t.Errorf("sendJSON() decoded ID %d; want 42\n", out.ID)
+	}
+	if err := sendJSON(nil, "POST", s.URL+"/fail", nil, nil, nil); err == nil {
+		t.Errorf("sendJSON() to failing endpoint => nil; want error\n")
+	}
+}
@@ -0,0 +1,85 @@
+package prober
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// defaultAlertTimeout is how long notifiers may take, unless
+// AlertTimeout() is given.
+const defaultAlertTimeout = time.Minute
+
+// NotifyStats describes the calls the probe has made to its notifiers,
+// i.e. its Alert(), Warn() and Recover() implementations.
+type NotifyStats struct {
+	Calls       int           // total number of notifier calls
+	Failures    int           // calls that returned an error or timed out
+	Timeouts    int           // calls that were given up on, see AlertTimeout()
+	LastLatency time.Duration // time taken by the most recent call
+	MaxLatency  time.Duration // largest LastLatency seen so far
+}
+
+// AlertTimeout sets how long the probe's notifiers may take before
+// giving up on them, by default one minute.
+//
+// A notifier that times out counts as having failed, so the alert is
+// tried again on the next run, just as when it returns an error. The
+// call itself can't be interrupted, so notifiers should still set
+// their own timeouts, e.g. on their http.Client.
+func AlertTimeout(d time.Duration) func(*Probe) {
+	return func(p *Probe) {
+		p.alertTimeout = d
+	}
+}
+
+// NotifyStats returns stats on the calls to the probe's notifiers.
+func (p *Probe) NotifyStats() NotifyStats {
+	p.statsLock.RLock()
+	defer p.statsLock.RUnlock()
+	return p.notifyStats
+}
+
+// callNotifier calls fn, which sends a notification of the kind, and
+// returns its error, or an error if it doesn't return within the
+// probe's AlertTimeout.
+func (p *Probe) callNotifier(kind string, fn func() error) error {
+	timeout := p.alertTimeout
+	if timeout <= 0 {
+		timeout = defaultAlertTimeout
+	}
+	start := time.Now()
+	c := make(chan error, 1)
+	go func() {
+		c <- fn()
+	}()
+	var err error
+	timedOut := false
+	select {
+	case err = <-c:
+	case <-time.After(timeout):
+		log.Printf("[%s] Gave up on %s after %v\n", p.Name, kind, timeout)
+		err = fmt.Errorf("%s of %s timed out after %v", kind, p.Name, timeout)
+		timedOut = true
+	}
+	p.recordNotifyCall(time.Since(start), err != nil, timedOut)
+	return err
+}
+
+// recordNotifyCall updates the notifier stats with a call.
+func (p *Probe) recordNotifyCall(latency time.Duration, failed, timedOut bool) {
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	s := &p.notifyStats
+	s.Calls++
+	if failed {
+		s.Failures++
+	}
+	if timedOut {
+		s.Timeouts++
+	}
+	s.LastLatency = latency
+	if latency > s.MaxLatency {
+		s.MaxLatency = latency
+	}
+}
@@ -0,0 +1,69 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowAlertProber is a testProber whose Alert() is alertFn, which may
+// take its time.
+type slowAlertProber struct {
+	testProber
+	alertFn AlertFn
+}
+
+func (p slowAlertProber) Alert(name, desc string, badness int, records Records) error {
+	return p.alertFn(name, desc, badness, records)
+}
+
+func TestProbe_sendAlert_timeout(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	cases := []struct {
+		alertFn      AlertFn
+		wantBadness  int
+		wantStats    NotifyStats
+		wantTimedOut bool
+	}{
+		{
+			alertFn:     func(string, string, int, Records) error { return nil },
+			wantBadness: 0,
+			wantStats:   NotifyStats{Calls: 1},
+		},
+		{
+			alertFn:     func(string, string, int, Records) error { return errors.New("mail server is down") },
+			wantBadness: 100,
+			wantStats:   NotifyStats{Calls: 1, Failures: 1},
+		},
+		{
+			alertFn: func(string, string, int, Records) error {
+				<-unblock
+				return nil
+			},
+			wantBadness: 100,
+			wantStats:   NotifyStats{Calls: 1, Failures: 1, Timeouts: 1},
+		},
+	}
+	for i, tt := range cases {
+		p := &Probe{
+			Prober:  slowAlertProber{testProber{Passed()}, tt.alertFn},
+			Name:    "AlertTimeoutProber",
+			badness: 100,
+			t:       fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+		}
+		AlertTimeout(10 * time.Millisecond)(p)
+		p.sendAlert()
+		if got := p.Badness(); got != tt.wantBadness {
+			t.Errorf("[%d] sendAlert() left Badness() %d; want %d\n", i, got, tt.wantBadness)
+		}
+		got := p.NotifyStats()
+		if got.LastLatency <= 0 || got.MaxLatency != got.LastLatency {
+			t.Errorf("[%d] NotifyStats() => latency %v, max %v; want same positive latency\n", i, got.LastLatency, got.MaxLatency)
+		}
+		got.LastLatency, got.MaxLatency = 0, 0
+		if got != tt.wantStats {
+			t.Errorf("[%d] NotifyStats() => %+v; want %+v\n", i, got, tt.wantStats)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package prober
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The OAuth2 flows that OAuthProber can perform.
+const (
+	ClientCredentialsFlow = "client_credentials"
+	DeviceCodeFlow        = "device_code"
+)
+
+type (
+	// OAuthProber is a Prober that gets a token from an OAuth2 or OIDC
+	// identity provider, failing on auth errors, bad or soon-expiring
+	// tokens and slow responses.
+	//
+	// With ClientCredentialsFlow, the prober gets an access token, and
+	// if it's a JWT checks its expiry, issuer, audience and WantClaims. Signatures are not checked, since
+	// the point is to notice the identity provider misbehaving rather
+	// than to authenticate it.
+	//
+	// With DeviceCodeFlow, which needs a user to finish, the prober asks
+	// for a device code and checks that the token endpoint answers that
+	// authorization is pending for it.
+	//
+	// The Alert() part of the Prober interface is provided by the
+	// embedded AlertFn.
+	OAuthProber struct {
+		AlertFn
+		// Issuer of tokens, e.g. "https://accounts.example.com". If set,
+		// endpoints that aren't set are found via OIDC discovery, and the
+		// "iss" claim of tokens must match.
+		Issuer string
+		// Endpoint to get tokens from.
+		TokenURL string
+		// Endpoint to get device codes from, for DeviceCodeFlow.
+		DeviceAuthURL string
+		// Flow to perform, or "" for ClientCredentialsFlow.
+		Flow         string
+		ClientID     string
+		ClientSecret string
+		Scopes       []string
+		// Expected "aud" claim of tokens, if any.
+		Audience string
+		// Other claims that tokens must have, with their expected values.
+		WantClaims map[string]string
+		// How long tokens must be valid for, or 0 to just not be expired.
+		MinExpiry time.Duration
+		// How long the flow may take, or 0 for no limit.
+		MaxLatency time.Duration
+		// Client to use, or nil for http.DefaultClient.
+		Client *http.Client
+	}
+
+	// tokenResponse is the response from a token or device
+	// authorization endpoint.
+	tokenResponse struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		DeviceCode       string `json:"device_code"`
+		UserCode         string `json:"user_code"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+)
+
+// Probe performs the flow and checks the outcome.
+func (op OAuthProber) Probe() Result {
+	start := time.Now()
+	if err := op.discover(); err != nil {
+		return FailedWith(err)
+	}
+	var res Result
+	switch op.flow() {
+	case ClientCredentialsFlow:
+		res = op.clientCredentials(start)
+	case DeviceCodeFlow:
+		res = op.deviceCode()
+	default:
+		return FailedWith(fmt.Errorf("unknown OAuth2 flow %q", op.Flow))
+	}
+	latency := time.Since(start)
+	if res.Details == nil {
+		res.Details = map[string]string{}
+	}
+	res.Details["latency"] = latency.String()
+	if res.Passed() && op.MaxLatency > 0 && latency > op.MaxLatency {
+		res.Code = Fail
+		res.Error = fmt.Errorf("%s flow against %s took %v, more than %v", op.flow(), op.TokenURL, latency, op.MaxLatency)
+	}
+	return res
+}
+
+// clientCredentials gets an access token with the client credentials
+// flow and checks it.
+func (op OAuthProber) clientCredentials(start time.Time) Result {
+	tr, err := op.post(op.TokenURL, url.Values{"grant_type": {"client_credentials"}})
+	if err != nil {
+		return FailedWith(err)
+	}
+	if tr.Error != "" {
+		return FailedWith(tr.err(op.TokenURL))
+	}
+	if tr.AccessToken == "" {
+		return FailedWith(fmt.Errorf("%s returned no access token", op.TokenURL))
+	}
+	res := Passed()
+	res.Details = map[string]string{}
+	if tr.ExpiresIn > 0 {
+		expiresIn := time.Duration(tr.ExpiresIn) * time.Second
+		res.Details["expires_in"] = expiresIn.String()
+		if expiresIn <= op.MinExpiry {
+			return FailedWith(fmt.Errorf("token from %s expires in %v, want more than %v", op.TokenURL, expiresIn, op.MinExpiry))
+		}
+	}
+	if err := op.checkClaims(tr.AccessToken, start); err != nil {
+		return FailedWith(err)
+	}
+	return res
+}
+
+// deviceCode asks for a device code, and checks that the token
+// endpoint says authorization for it is pending.
+func (op OAuthProber) deviceCode() Result {
+	if op.DeviceAuthURL == "" {
+		return FailedWith(fmt.Errorf("no device authorization endpoint to probe"))
+	}
+	dr, err := op.post(op.DeviceAuthURL, url.Values{})
+	if err != nil {
+		return FailedWith(err)
+	}
+	if dr.Error != "" {
+		return FailedWith(dr.err(op.DeviceAuthURL))
+	}
+	if dr.DeviceCode == "" || dr.UserCode == "" {
+		return FailedWith(fmt.Errorf("%s returned no device or user code", op.DeviceAuthURL))
+	}
+	tr, err := op.post(op.TokenURL, url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {dr.DeviceCode},
+	})
+	if err != nil {
+		return FailedWith(err)
+	}
+	if tr.Error != "authorization_pending" && tr.Error != "slow_down" {
+		return FailedWith(fmt.Errorf("%s did not say authorization is pending for new device code: %v", op.TokenURL, tr.err(op.TokenURL)))
+	}
+	return Passed()
+}
+
+// discover fills in the endpoints that aren't set from the OIDC
+// discovery document of the issuer.
+func (op *OAuthProber) discover() error {
+	if op.TokenURL != "" && (op.DeviceAuthURL != "" || op.flow() != DeviceCodeFlow) {
+		return nil
+	}
+	if op.Issuer == "" {
+		if op.TokenURL == "" {
+			return fmt.Errorf("no Issuer or TokenURL to probe")
+		}
+		return nil
+	}
+	u := strings.TrimSuffix(op.Issuer, "/") + "/.well-known/openid-configuration"
+	resp, err := op.client().Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OIDC discovery at %s returned %q", u, resp.Status)
+	}
+	var config struct {
+		TokenEndpoint               string `json:"token_endpoint"`
+		DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return fmt.Errorf("bad OIDC discovery document at %s: %v", u, err)
+	}
+	if op.TokenURL == "" {
+		op.TokenURL = config.TokenEndpoint
+	}
+	if op.DeviceAuthURL == "" {
+		op.DeviceAuthURL = config.DeviceAuthorizationEndpoint
+	}
+	if op.TokenURL == "" {
+		return fmt.Errorf("OIDC discovery document at %s has no token_endpoint", u)
+	}
+	return nil
+}
+
+// post sends the form to the endpoint with the client's credentials,
+// and decodes the response.
+func (op OAuthProber) post(endpoint string, form url.Values) (tokenResponse, error) {
+	if len(op.Scopes) > 0 {
+		form.Set("scope", strings.Join(op.Scopes, " "))
+	}
+	if op.ClientSecret == "" {
+		form.Set("client_id", op.ClientID)
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if op.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(op.ClientID), url.QueryEscape(op.ClientSecret))
+	}
+	resp, err := op.client().Do(req)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	defer resp.Body.Close()
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return tokenResponse{}, fmt.Errorf("bad response from %s (%s): %v", endpoint, resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK && tr.Error == "" {
+		tr.Error = resp.Status
+	}
+	return tr, nil
+}
+
+// err returns the error in the response.
+func (tr tokenResponse) err(endpoint string) error {
+	if tr.ErrorDescription != "" {
+		return fmt.Errorf("%s returned error %q: %s", endpoint, tr.Error, tr.ErrorDescription)
+	}
+	return fmt.Errorf("%s returned error %q", endpoint, tr.Error)
+}
+
+// checkClaims checks the claims of the token, if it's a JWT.
+func (op OAuthProber) checkClaims(token string, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		// Opaque tokens are fine unless claims beyond the issuer
+		// must be checked.
+		if op.Audience == "" && len(op.WantClaims) == 0 {
+			return nil
+		}
+		return fmt.Errorf("token from %s is not a JWT, so its claims can't be checked", op.TokenURL)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("bad JWT payload from %s: %v", op.TokenURL, err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return fmt.Errorf("bad JWT claims from %s: %v", op.TokenURL, err)
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		expires := time.Unix(int64(exp), 0)
+		if !expires.After(now.Add(op.MinExpiry)) {
+			return fmt.Errorf("token from %s expires at %v, want later than %v", op.TokenURL, expires, now.Add(op.MinExpiry))
+		}
+	}
+	if op.Issuer != "" && claims["iss"] != op.Issuer {
+		return fmt.Errorf("token from %s has issuer %v, want %q", op.TokenURL, claims["iss"], op.Issuer)
+	}
+	if op.Audience != "" && !hasAudience(claims["aud"], op.Audience) {
+		return fmt.Errorf("token from %s has audience %v, want %q", op.TokenURL, claims["aud"], op.Audience)
+	}
+	for k, want := range op.WantClaims {
+		if got := fmt.Sprint(claims[k]); claims[k] == nil || got != want {
+			return fmt.Errorf("token from %s has claim %s=%v, want %q", op.TokenURL, k, claims[k], want)
+		}
+	}
+	return nil
+}
+
+// hasAudience returns true if the "aud" claim, which is either a
+// string or a list of them, includes the audience.
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flow returns the flow to perform.
+func (op OAuthProber) flow() string {
+	if op.Flow == "" {
+		return ClientCredentialsFlow
+	}
+	return op.Flow
+}
+
+// client returns the client to use.
+func (op OAuthProber) client() *http.Client {
+	if op.Client != nil {
+		return op.Client
+	}
+	return http.DefaultClient
+}
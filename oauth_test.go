@@ -0,0 +1,89 @@
+package prober
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// jwt returns an unsigned JWT with the claims.
+func jwt(claims map[string]interface{}) string {
+	b, _ := json.Marshal(claims)
+	return "e30." + base64.RawURLEncoding.EncodeToString(b) + ".sig"
+}
+
+func TestOAuthProber_Probe(t *testing.T) {
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"token_endpoint": %q, "device_authorization_endpoint": %q}`, issuer+"/token", issuer+"/device")
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "authorization_pending"}`)
+			return
+		}
+		if id, secret, _ := r.BasicAuth(); id != "probe" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client"}`)
+			return
+		}
+		token := jwt(map[string]interface{}{
+			"iss":   issuer,
+			"aud":   []string{"api"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": r.FormValue("scope"),
+		})
+		fmt.Fprintf(w, `{"access_token": %q, "expires_in": 3600}`, token)
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"device_code": "d", "user_code": "u"}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	issuer = ts.URL
+
+	cases := []struct {
+		in   OAuthProber
+		want ResultCode
+	}{
+		{
+			in:   OAuthProber{Issuer: issuer, ClientID: "probe", ClientSecret: "s3cret", Audience: "api"},
+			want: Pass,
+		},
+		{
+			in:   OAuthProber{Issuer: issuer, ClientID: "probe", ClientSecret: "wrong"},
+			want: Fail,
+		},
+		{
+			in:   OAuthProber{TokenURL: issuer + "/token", ClientID: "probe", ClientSecret: "s3cret", Audience: "other"},
+			want: Fail,
+		},
+		{
+			in:   OAuthProber{Issuer: issuer, ClientID: "probe", ClientSecret: "s3cret", MinExpiry: 2 * time.Hour},
+			want: Fail,
+		},
+		{
+			in:   OAuthProber{Issuer: issuer, ClientID: "probe", ClientSecret: "s3cret", Scopes: []string{"read"}, WantClaims: map[string]string{"scope": "read"}},
+			want: Pass,
+		},
+		{
+			in:   OAuthProber{Issuer: issuer, ClientID: "probe", ClientSecret: "s3cret", MaxLatency: time.Nanosecond},
+			want: Fail,
+		},
+		{
+			in:   OAuthProber{Issuer: issuer, ClientID: "probe", Flow: DeviceCodeFlow},
+			want: Pass,
+		},
+	}
+	for i, tt := range cases {
+		if got := tt.in.Probe(); got.Code != tt.want {
+			t.Errorf("[%d] %+v.Probe() => %v; want code %v\n", i, tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OpenTelemetry severity numbers, see
+// https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber.
+const (
+	otlpSeverityInfo  = 9
+	otlpSeverityError = 17
+)
+
+type (
+	// OTLPExporter sends the records of probes as OpenTelemetry log
+	// records, via OTLP over HTTP with JSON encoding.
+	//
+	// Each log record has the attributes probe.name, probe.desc,
+	// probe.location and probe.result, as well as probe.error,
+	// probe.info, probe.info_url and probe.run_id when set. Labels of the probe are
+	// added as probe.label.<key>, and Details of the result as
+	// probe.detail.<key>. Failed runs have the severity ERROR, and
+	// passed runs INFO.
+	OTLPExporter struct {
+		// Endpoint of the collector, e.g. "http://localhost:4318/v1/logs".
+		Endpoint string
+		// Name of the service, set as the service.name resource
+		// attribute, or "" for "prober".
+		ServiceName string
+		// Headers to send, e.g. for authenticating with the collector.
+		Header http.Header
+		// Client to use, or nil for http.DefaultClient.
+		Client *http.Client
+	}
+
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpLogRecord struct {
+		TimeUnixNano         string          `json:"timeUnixNano"`
+		ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+		SeverityNumber       int             `json:"severityNumber"`
+		SeverityText         string          `json:"severityText"`
+		Body                 otlpValue       `json:"body"`
+		Attributes           []otlpAttribute `json:"attributes"`
+	}
+
+	otlpScopeLogs struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		LogRecords []otlpLogRecord `json:"logRecords"`
+	}
+
+	otlpResourceLogs struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+	}
+
+	// otlpLogs is an ExportLogsServiceRequest in the OTLP JSON encoding.
+	otlpLogs struct {
+		ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+	}
+)
+
+// ExportLogs sends each record of the probe to the OTLP exporter.
+func ExportLogs(e *OTLPExporter) func(*Probe) {
+	return func(p *Probe) {
+		p.otlp = e
+	}
+}
+
+// Export sends the record of the probe to the collector.
+func (e *OTLPExporter) Export(ctx context.Context, p *Probe, r Record) error {
+	service := e.ServiceName
+	if service == "" {
+		service = "prober"
+	}
+	sl := otlpScopeLogs{LogRecords: []otlpLogRecord{otlpRecord(p, r, time.Now())}}
+	sl.Scope.Name = "hkjn.me/prober"
+	rl := otlpResourceLogs{ScopeLogs: []otlpScopeLogs{sl}}
+	rl.Resource.Attributes = []otlpAttribute{attr("service.name", service)}
+	req := otlpLogs{ResourceLogs: []otlpResourceLogs{rl}}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	hr, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, vs := range e.Header {
+		hr.Header[k] = vs
+	}
+	hr.Header.Set("Content-Type", "application/json")
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(hr)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector at %s returned %q", e.Endpoint, resp.Status)
+	}
+	return nil
+}
+
+// otlpRecord returns the OTLP log record for the record of the probe,
+// observed at the time.
+func otlpRecord(p *Probe, r Record, observed time.Time) otlpLogRecord {
+	lr := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(r.Timestamp.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(observed.UnixNano(), 10),
+		SeverityNumber:       otlpSeverityInfo,
+		SeverityText:         "INFO",
+		Body:                 otlpValue{fmt.Sprintf("%s: %s", p.Name, r.Result.Code)},
+		Attributes: []otlpAttribute{
+			attr("probe.name", p.Name),
+			attr("probe.desc", p.Desc),
+			attr("probe.location", r.Location),
+			attr("probe.result", r.Result.Code.String()),
+		},
+	}
+	if !r.Result.Passed() {
+		lr.SeverityNumber = otlpSeverityError
+		lr.SeverityText = "ERROR"
+	}
+	if r.Result.Error != nil {
+		lr.Body.StringValue += ": " + r.Result.Error.Error()
+		lr.Attributes = append(lr.Attributes, attr("probe.error", r.Result.Error.Error()))
+	}
+	if r.Result.Info != "" {
+		lr.Attributes = append(lr.Attributes, attr("probe.info", r.Result.Info))
+	}
+	if r.Result.InfoUrl != "" {
+		lr.Attributes = append(lr.Attributes, attr("probe.info_url", r.Result.InfoUrl))
+	}
+	if r.RunID != "" {
+		lr.Attributes = append(lr.Attributes, attr("probe.run_id", r.RunID))
+	}
+	lr.Attributes = append(lr.Attributes, attrs("probe.label.", p.Labels)...)
+	lr.Attributes = append(lr.Attributes, attrs("probe.detail.", r.Result.Details)...)
+	lr.Attributes = append(lr.Attributes, attrs("probe.annotation.", r.Annotations)...)
+	return lr
+}
+
+// attr returns a string attribute.
+func attr(key, value string) otlpAttribute {
+	return otlpAttribute{key, otlpValue{value}}
+}
+
+// attrs returns string attributes for the map, with the prefix added
+// to each key, sorted by key.
+func attrs(prefix string, m map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	as := make([]otlpAttribute, len(keys))
+	for i, k := range keys {
+		as[i] = attr(prefix+k, m[k])
+	}
+	return as
+}
@@ -0,0 +1,80 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporter_Export(t *testing.T) {
+	var got otlpLogs
+	var auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("bad request body: %v\n", err)
+		}
+	}))
+	defer ts.Close()
+
+	e := &OTLPExporter{
+		Endpoint: ts.URL + "/v1/logs",
+		Header:   http.Header{"Authorization": {"Bearer secret"}},
+	}
+	p := &Probe{Name: "web", Desc: "Probes the web.", Labels: map[string]string{"env": "prod"}}
+	ts0 := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	r := Record{
+		Timestamp: ts0,
+		Location:  "eu",
+		Result: Result{
+			Code:    Fail,
+			Error:   errors.New("connection refused"),
+			Details: map[string]string{"total": "1s"},
+		},
+	}
+	if err := e.Export(context.Background(), p, r); err != nil {
+		t.Fatalf("Export() => %v; want nil\n", err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Export() sent Authorization %q; want %q\n", auth, "Bearer secret")
+	}
+	if len(got.ResourceLogs) != 1 || len(got.ResourceLogs[0].ScopeLogs) != 1 || len(got.ResourceLogs[0].ScopeLogs[0].LogRecords) != 1 {
+		t.Fatalf("Export() sent %+v; want a single log record\n", got)
+	}
+	if a := got.ResourceLogs[0].Resource.Attributes; len(a) != 1 || a[0] != attr("service.name", "prober") {
+		t.Errorf("Export() sent resource attributes %+v; want service.name=prober\n", a)
+	}
+	lr := got.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if lr.TimeUnixNano != "911488440000000000" {
+		t.Errorf("Export() sent timeUnixNano %q; want %q\n", lr.TimeUnixNano, "911488440000000000")
+	}
+	if lr.SeverityNumber != otlpSeverityError || lr.SeverityText != "ERROR" {
+		t.Errorf("Export() sent severity %d %q; want %d %q\n", lr.SeverityNumber, lr.SeverityText, otlpSeverityError, "ERROR")
+	}
+	if want := "web: Fail: connection refused"; lr.Body.StringValue != want {
+		t.Errorf("Export() sent body %q; want %q\n", lr.Body.StringValue, want)
+	}
+	want := []otlpAttribute{
+		attr("probe.name", "web"),
+		attr("probe.desc", "Probes the web."),
+		attr("probe.location", "eu"),
+		attr("probe.result", "Fail"),
+		attr("probe.error", "connection refused"),
+		attr("probe.label.env", "prod"),
+		attr("probe.detail.total", "1s"),
+	}
+	if len(lr.Attributes) != len(want) {
+		t.Fatalf("Export() sent attributes %+v; want %+v\n", lr.Attributes, want)
+	}
+	for i := range want {
+		if lr.Attributes[i] != want[i] {
+			t.Errorf("Export() sent attribute %+v; want %+v\n", lr.Attributes[i], want[i])
+		}
+	}
+}
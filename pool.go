@@ -0,0 +1,143 @@
+package prober
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// PoolProber is a Prober that on each run probes one target picked
+	// at random from a weighted pool, e.g. one of many CDN edges, which
+	// gives broad coverage of the pool at the cost of probing a single
+	// target.
+	//
+	// Failures are accounted per target across runs, see TargetStats().
+	//
+	// The Alert() part of the Prober interface is provided by the
+	// embedded AlertFn.
+	PoolProber struct {
+		AlertFn
+		targets []PoolTarget
+		newFn   func(addr string) Prober
+		stats   map[string]TargetStats
+		rand    *rand.Rand
+		lock    sync.Mutex // protects stats and rand
+	}
+
+	// PoolTarget is a target in the pool of a PoolProber.
+	PoolTarget struct {
+		Addr   string
+		Weight float64 // relative likelihood of picking the target, or 0 for 1
+	}
+
+	// TargetStats describes the outcomes of probing a single target in
+	// a pool.
+	TargetStats struct {
+		Runs        int
+		Failures    int
+		LastFailure time.Time
+		LastError   string
+	}
+)
+
+// NewPoolProber returns a prober for the pool of targets, which uses the
+// function to get the prober for the target picked on each run.
+func NewPoolProber(fn func(addr string) Prober, targets ...PoolTarget) *PoolProber {
+	return &PoolProber{
+		targets: targets,
+		newFn:   fn,
+		stats:   map[string]TargetStats{},
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Probe picks a target and probes it.
+func (pp *PoolProber) Probe() Result {
+	if len(pp.targets) == 0 {
+		return FailedWith(fmt.Errorf("no targets in pool"))
+	}
+	addr := pp.pick()
+	r := pp.newFn(addr).Probe()
+
+	pp.lock.Lock()
+	s := pp.stats[addr]
+	s.Runs++
+	if !r.Passed() {
+		s.Failures++
+		s.LastFailure = time.Now()
+		if r.Error != nil {
+			s.LastError = r.Error.Error()
+		}
+	}
+	pp.stats[addr] = s
+	pp.lock.Unlock()
+
+	details := make(map[string]string, len(r.Details)+1)
+	for k, v := range r.Details {
+		details[k] = v
+	}
+	details["target"] = addr
+	r.Details = details
+	if r.Info != "" {
+		r.Info = fmt.Sprintf("[%s] %s", addr, r.Info)
+	}
+	return r
+}
+
+// pick returns the address of a target picked at random, according to
+// the weights.
+func (pp *PoolProber) pick() string {
+	total := 0.0
+	for _, t := range pp.targets {
+		total += t.weight()
+	}
+	pp.lock.Lock()
+	x := pp.rand.Float64() * total
+	pp.lock.Unlock()
+	for _, t := range pp.targets {
+		x -= t.weight()
+		if x < 0 {
+			return t.Addr
+		}
+	}
+	return pp.targets[len(pp.targets)-1].Addr
+}
+
+// weight returns the weight of the target.
+func (t PoolTarget) weight() float64 {
+	if t.Weight == 0 {
+		return 1
+	}
+	return t.Weight
+}
+
+// TargetStats returns the outcomes of probing each target that has
+// been picked so far, keyed by address.
+func (pp *PoolProber) TargetStats() map[string]TargetStats {
+	pp.lock.Lock()
+	defer pp.lock.Unlock()
+	stats := make(map[string]TargetStats, len(pp.stats))
+	for addr, s := range pp.stats {
+		stats[addr] = s
+	}
+	return stats
+}
+
+// String returns a human-readable summary of the failures per target.
+func (pp *PoolProber) String() string {
+	stats := pp.TargetStats()
+	addrs := make([]string, 0, len(stats))
+	for addr := range stats {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	parts := make([]string, len(addrs))
+	for i, addr := range addrs {
+		parts[i] = fmt.Sprintf("%s: %d/%d failed", addr, stats[addr].Failures, stats[addr].Runs)
+	}
+	return fmt.Sprintf("PoolProber{%s}", strings.Join(parts, ", "))
+}
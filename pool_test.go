@@ -0,0 +1,38 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPoolProber_Probe(t *testing.T) {
+	pp := NewPoolProber(func(addr string) Prober {
+		if addr == "bad" {
+			return testProber{FailedWith(errors.New("bad failing on purpose"))}
+		}
+		return testProber{Passed()}
+	},
+		PoolTarget{Addr: "good", Weight: 3},
+		PoolTarget{Addr: "bad"},
+		PoolTarget{Addr: "never", Weight: 1e-12},
+	)
+	runs := 1000
+	for i := 0; i < runs; i++ {
+		r := pp.Probe()
+		if want := r.Details["target"] != "bad"; r.Passed() != want {
+			t.Fatalf("Probe() => %v; want passed=%v", r, want)
+		}
+	}
+	stats := pp.TargetStats()
+	good, bad := stats["good"], stats["bad"]
+	if good.Runs+bad.Runs+stats["never"].Runs != runs {
+		t.Errorf("TargetStats() => %v; want %d runs in total\n", stats, runs)
+	}
+	if good.Failures != 0 || bad.Failures != bad.Runs {
+		t.Errorf("TargetStats() => %v; want all failures for bad\n", stats)
+	}
+	// With weights 3:1, "good" should be picked about 750 times.
+	if good.Runs < 650 || good.Runs > 850 {
+		t.Errorf("TargetStats() => %v; want about 750 runs of good\n", stats)
+	}
+}
@@ -1,43 +1,57 @@
 // Package prober provides black-box monitoring mechanisms.
 //
 // To use, define Probe() and Alert() on a type, then pass it to NewProbe:
-//   struct FooProber{ someState int }
 //
-//   // Probe "Foo". E.g. do a network call and compare it to what
-//   // was expected.
-//   func (p FooProber) Probe() Result {
-//     // Returning FailedWith(err) indicates that the probe failed.
-//     // Returning Passed() indicates that the probe succeeded.
-//   }
-//   // Send an alert. Called if the probe fails "too often".
-//   //
-//   // By passing in FailurePenalty() and/or SuccessReward() options to NewProbe(),
-//   // the adjustments to the state when probe fails or passes can be modified.
-//   func (p FooProber) Alert(name, desc string, badness int, records Records) error {
-//   }
-//   ...
+//	struct FooProber{ someState int }
 //
-//   // Create the probe.
-//   p := NewProbe(FooProber{1}, "FooProber", "Probes the Foo")
+//	// Probe "Foo". E.g. do a network call and compare it to what
+//	// was expected.
+//	func (p FooProber) Probe() Result {
+//	  // Returning FailedWith(err) indicates that the probe failed.
+//	  // Returning Passed() indicates that the probe succeeded.
+//	}
+//	// Send an alert. Called if the probe fails "too often".
+//	//
+//	// By passing in FailurePenalty() and/or SuccessReward() options to NewProbe(),
+//	// the adjustments to the state when probe fails or passes can be modified.
+//	func (p FooProber) Alert(name, desc string, badness int, records Records) error {
+//	}
+//	...
 //
-//   // Run the probe. This call blocks forever, so you may
-//   // want to do this in a goroutine — you could e.g. register a web
-//   // handler to show the contents of p.Records() here.
-//   go p.Run()
+//	// Create the probe.
+//	p := NewProbe(FooProber{1}, "FooProber", "Probes the Foo")
+//
+//	// Run the probe. This call blocks forever, so you may
+//	// want to do this in a goroutine — you could e.g. register a web
+//	// handler to show the contents of p.Records() here.
+//	go p.Run()
+//
+// For small builds, e.g. for probing from routers or a Raspberry Pi,
+// build with the "minimal" tag, which leaves out optional notifiers
+// (Twilio, tickets, chat and push), the OTLP exporter, Kubernetes
+// discovery, SOCKS5 proxies and streaming records over WebSocket:
+//
+//	GOOS=linux GOARCH=arm GOARM=6 go build -tags minimal
+//
+// The heavyweight probers in probers/browser, probers/kafka,
+// probers/script and probers/wasm are only built with their own tags,
+// so they never add to the core package. Likewise, AlertWhenExpr()
+// conditions in expr-lang are only supported with the "exprlang" tag.
 package prober
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"sort"
 	"strings"
 	"sync"
 	"time"
-
-	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -50,22 +64,33 @@ var (
 	logName               = "prober.outcomes.log" // name of logging f1ile
 	alertThreshold        = flag.Int("alert_threshold", 200, "level of 'badness' before alerting")
 	alertsDisabled        = flag.Bool("no_alerts", false, "disables alerts when probes fail too often")
+	maxRecordBytes        = flag.Int("max_record_bytes", 0, "approximate memory that records of all probes may use before the oldest are evicted, or 0 for no limit")
+	location              = flag.String("location", "", "where the probes run from, e.g. a region (defaults to the hostname)")
 	disabledProbes        = make(selectedProbes)
 	onlyProbes            = make(selectedProbes)
-	defaultFailurePenalty = 10 // default increment of `badness` on failed probe run
-	defaultSuccessReward  = 1  // default decrement of `badness` on successful probe run
+	defaultFailurePenalty = 10   // default increment of `badness` on failed probe run
+	defaultSuccessReward  = 1    // default decrement of `badness` on successful probe run
+	defaultMaxResultLen   = 4096 // default maximum length of Error, Info and Details values
 	onceOpen              sync.Once
 	logFile               *os.File
 	bufferSize            = 200 // maximum number of results per prober to keep
 	parseFlags            = sync.Once{}
-	results               = [2]string{"Pass", "Fail"}
+	results               = [3]string{"Pass", "Fail", "Degraded"}
 )
 
 const (
 	Pass ResultCode = iota
 	Fail
+	// Degraded is a partial success, e.g. 2 of 3 endpoints being up,
+	// which counts towards badness less than a failure, see
+	// DegradedWeight().
+	Degraded
 )
 
+// defaultDegradedWeight is how much a Degraded result counts towards
+// badness compared to a failure, unless DegradedWeight() is given.
+const defaultDegradedWeight = 0.5
+
 type (
 	// Result describes the outcome of a single probe.
 	Result struct {
@@ -73,6 +98,16 @@ type (
 		Error   error
 		Info    string // Optional extra information
 		InfoUrl string // Optional URL to further information
+		// Optional structured details about the probe run, e.g. timings.
+		Details map[string]string
+		// Optional factor for how much a failure counts towards
+		// badness, e.g. 0.2 for a "soft" failure like a slow response,
+		// or 0 for the default of 1.
+		Weight float64
+		// Optional numeric value measured by the probe, e.g. the
+		// length of a queue, see WithValue() and MaxRate().
+		Value *float64 `yaml:",omitempty"`
+		runID string   // ID of the run the result is from, see RunID()
 	}
 
 	// ResultCode describes pass/fail outcomes for probes.
@@ -81,8 +116,18 @@ type (
 	// Record is the result of a single probe run.
 	Record struct {
 		Timestamp  time.Time `yaml:"-"`
-		TimeMillis string    // same as Timestamp but makes it into the YAML logs
+		TimeMillis string    // same as Timestamp in the TimeFormat of the probe, but makes it into the logs
+		Location   string    // where the probe ran from
 		Result     Result    // the result of the probe run
+		// Number of further identical runs merged into the record, see
+		// CompactRecords().
+		Repeats     int       `yaml:",omitempty"`
+		Until       time.Time `yaml:"-"`          // time of the last merged run, if Repeats > 0
+		UntilMillis string    `yaml:",omitempty"` // same as Until but makes it into the logs
+		RunID       string    `yaml:",omitempty"` // correlation ID of the run, see RunID()
+		// Annotations of the host application at the time of the run,
+		// e.g. "version": "1.2.3", see Annotate().
+		Annotations map[string]string `yaml:",omitempty"`
 	}
 
 	// Records is a grouping of probe records that implements sort.Interface.
@@ -105,27 +150,102 @@ type (
 
 	// Probe is a stateful representation of repeated probe runs.
 	Probe struct {
-		Prober                      // underlying prober mechanism
-		Name, Desc    string        // name, description of the probe
-		Interval      time.Duration // how often to probe
-		Disabled      bool          // whether this probe is disabled
-		SilencedUntil SilenceTime   // the earliest time this probe can alert
+		Prober                          // underlying prober mechanism
+		Name, Desc    string            // name, description of the probe
+		Location      string            // where the probe runs from, e.g. a region
+		Labels        map[string]string // labels describing the probe, e.g. "env": "prod"
+		Interval      time.Duration     // how often to probe
+		Disabled      bool              // whether this probe is disabled, see Disable() once it runs
+		SilencedUntil SilenceTime       // the earliest time this probe can alert, see Silence() once it runs
 		// If `badness` reaches alert threshold, an alert email is sent and
 		// the value resets to 0.
-		badness        int
-		failurePenalty int          // how much to increment `badness` on failure
-		successReward  int          // how much to decrement `badness` on success
-		reportFn       func(Result) // function to call to report probe results
-		t              timeT
-		alerting       bool         // whether this probe is currently alerting
-		lastAlert      time.Time    // time of last alert sent, if any
-		alertLock      sync.RWMutex // protects reads and writes to alerting state
-		records        Records      // historical records of probe runs
-		recordsLock    sync.RWMutex // protects reads and writes to stateful records
+		badness             int
+		failurePenalty      int          // how much to increment `badness` on failure
+		timeoutPenalty      int          // how much to increment `badness` on timeouts, or 0 for failurePenalty
+		successReward       int          // how much to decrement `badness` on success
+		reportFn            func(Result) // function to call to report probe results
+		t                   timeT
+		alerting            bool                       // whether this probe is currently alerting
+		degraded            bool                       // whether badness is over the warning threshold, but not alerting
+		warnThreshold       int                        // level of badness at which to warn, or 0 for no warnings
+		critThreshold       int                        // level of badness at which to alert, or 0 for -alert_threshold
+		reAlertWindow       time.Duration              // how soon after recovering alerting again escalates, or 0 for never
+		notified            bool                       // whether an alert or warning was sent since the probe last recovered
+		recoveredAt         time.Time                  // when the probe last passed after a notification, if any
+		recurrences         int                        // number of escalated notifications in a row
+		alertGroup          *AlertGroup                // group to send alerts through, if any
+		notifiers           []Validator                // notifiers whose settings Validate() checks, see Notifiers()
+		badnessPool         *BadnessPool               // pool to add badness to instead of alerting, if any
+		component           string                     // name of the component the probe is part of, if any
+		remediation         *remediation               // remediation of the probe when alerting, if any
+		limiter             *TargetLimiter             // limiter of runs against the target host, if any
+		limitHost           string                     // target host to limit runs against, if limiter is set
+		fastRecheck         time.Duration              // interval to run at while failing, or 0 for Interval
+		alertRetries        int                        // times to retry a failed alert, see RetryAlerts()
+		alertBackoff        time.Duration              // time to wait before the first retry of an alert
+		alertSending        bool                       // whether an alert is being sent
+		alertUndelivered    bool                       // whether the last alert couldn't be sent
+		alertTimeout        time.Duration              // how long notifiers may take, or 0 for defaultAlertTimeout
+		notifyStats         NotifyStats                // stats on calls to notifiers, protected by statsLock
+		chaosRate           float64                    // fraction of runs to fail on purpose, see Chaos()
+		chaosPending        int                        // number of upcoming runs to fail on purpose
+		chaosLock           sync.Mutex                 // protects chaosPending
+		immediate           bool                       // whether to run once on start, even if aligned or scheduled
+		initialDelay        time.Duration              // how long to wait before the first run
+		engine              *Engine                    // engine the probe belongs to, or nil for the global settings
+		allowLongTimeout    bool                       // whether the prober may have a timeout longer than Interval
+		dryRun              bool                       // whether to only log alerts and warnings
+		compact             bool                       // whether to merge runs of passing records
+		logSampling         int                        // write only every nth passing record to the log, or 0 for all
+		passesUnlogged      int                        // passing records not written since the last one, protected by resultLock
+		lastAlert           time.Time                  // time of last alert sent, if any
+		lastSuccess         time.Time                  // time of last passing probe run, if any
+		lastFailure         time.Time                  // time of last failing probe run, if any
+		badnessHistory      []BadnessSample            // recent changes of badness, oldest first
+		silences            []Silence                  // silences of a registry that matched the probe
+		deployGraceUntil    time.Time                  // end of the grace period after a deploy, see Registry.Deployed()
+		alertWhen           condition                  // condition on which to alert, if not badness
+		alertWhenSrc        string                     // source of the AlertWhen() condition, even if it's bad
+		alertWhenExprLang   bool                       // whether the condition is in expr-lang, see AlertWhenExpr()
+		firstRun            time.Time                  // when the first run finished, if any
+		consecutiveFailures int                        // number of runs in a row that failed
+		failureStarts       []time.Time                // when the probe started failing in recent weeks, see FailureHistory()
+		degradedW           float64                    // weight of Degraded results, or 0 for defaultDegradedWeight
+		annotators          []func() map[string]string // functions returning annotations for new records
+		maxRate             float64                    // how fast values may grow per minute, if rateWindow is set
+		rateWindow          time.Duration              // window to measure the rate of values over, or 0 for no MaxRate()
+		sloTarget           float64                    // target availability of the SLO(), or 0 to alert on badness
+		sloWindows          []BurnRateWindow           // burn rates to alert on, or nil for DefaultBurnRateWindows
+		lateResults         LateResults                // what to do with results arriving after a timeout
+		badnessSteps        []BadnessStep              // recent changes of badness and their causes, see Explain()
+		alertDecision       string                     // why the probe did or didn't alert after the last run
+		alertingSince       time.Time                  // when the probe last started alerting
+		alertLock           sync.RWMutex               // protects reads and writes to alerting state
+		records             Records                    // historical records of probe runs
+		recordsLock         sync.RWMutex               // protects reads and writes to stateful records
+		recordBytes         int                        // approximate memory used by records
+		dependencies        []string                   // names of probes that must pass before this one runs
+		shipURL             string                     // URL of Aggregator to ship records to, if any
+		shipCodec           Codec                      // encoding of shipped records, or nil for JSON
+		logCodec            Codec                      // encoding of records in the log file, or nil for -log_format
+		otlp                recordExporter             // exporter to send records to as OpenTelemetry logs, if any
+		streams             []*ResultStream            // streams to send records to, if any
+		sanitizers          []Sanitizer                // functions to scrub results before they're stored
+		maxResultLen        int                        // maximum length of Error, Info and Details values, or 0 for no limit
+		aligned             bool                       // whether runs are aligned to wall-clock multiples of Interval
+		expectFailure       bool                       // whether the probe passes when Probe() fails, and vice versa
+		schedule            *Schedule                  // when to run the probe, if not every Interval
+		stats               SchedulerStats
+		resultLock          sync.Mutex       // serializes handling of results
+		latency             LatencyHistogram // durations of runs since latencySince, protected by statsLock
+		latencySince        time.Time        // when the current latency window started
+		latencyWindow       time.Duration    // how long to collect latencies for, or 0 for defaultLatencyWindow
+		statsLock           sync.RWMutex     // protects reads and writes to scheduler stats
+		controlLock         sync.RWMutex     // protects Disabled and SilencedUntil
 	}
 	Probes []*Probe
 	// SilenceTime represents a Time until which the probe is
-	// silenced. It exists to provide a custom String() method.
+	// silenced. The zero value means that the probe isn't silenced.
 	SilenceTime struct{ time.Time }
 
 	// timeT represents time-dependent functionality.
@@ -133,6 +253,12 @@ type (
 		Now() time.Time
 		Sleep(time.Duration)
 	}
+
+	// recordExporter sends records of a probe elsewhere, e.g. an
+	// *OTLPExporter, which isn't built with the "minimal" tag.
+	recordExporter interface {
+		Export(ctx context.Context, p *Probe, r Record) error
+	}
 )
 
 // realTime implements timeT for actual time.
@@ -141,6 +267,17 @@ type realTime struct{}
 func (realTime) Now() time.Time        { return time.Now() }
 func (realTime) Sleep(d time.Duration) { time.Sleep(d) }
 
+// Alert calls fn, which lets probers embed an AlertFn to implement the
+// Alert() part of the Prober interface. If fn is nil, the alert is only
+// logged.
+func (fn AlertFn) Alert(name, desc string, badness int, records Records) error {
+	if fn == nil {
+		log.Printf("[%s] would alert with badness %d, but has no AlertFn\n", name, badness)
+		return nil
+	}
+	return fn(name, desc, badness, records)
+}
+
 // String returns the English name of the result.
 func (r ResultCode) String() string { return results[r] }
 
@@ -158,6 +295,21 @@ func (r Result) String() string {
 	if r.InfoUrl != "" {
 		parts = append(parts, fmt.Sprintf("InfoUrl: %q", r.InfoUrl))
 	}
+	if r.Weight != 0 {
+		parts = append(parts, fmt.Sprintf("Weight: %v", r.Weight))
+	}
+	if len(r.Details) > 0 {
+		keys := make([]string, 0, len(r.Details))
+		for k := range r.Details {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		details := make([]string, len(keys))
+		for i, k := range keys {
+			details[i] = fmt.Sprintf("%s: %q", k, r.Details[k])
+		}
+		parts = append(parts, fmt.Sprintf("Details: {%s}", strings.Join(details, ", ")))
+	}
 	return fmt.Sprintf("Result{%s}", strings.Join(parts, ", "))
 }
 
@@ -171,6 +323,20 @@ func (r1 Result) Equal(r2 Result) bool {
 	if r1.Info != r2.Info {
 		return false
 	}
+	if r1.Weight != r2.Weight {
+		return false
+	}
+	if (r1.Value == nil) != (r2.Value == nil) || (r1.Value != nil && *r1.Value != *r2.Value) {
+		return false
+	}
+	if len(r1.Details) != len(r2.Details) {
+		return false
+	}
+	for k, v := range r1.Details {
+		if v2, ok := r2.Details[k]; !ok || v != v2 {
+			return false
+		}
+	}
 	equalError := func(err1, err2 error) bool {
 		if err1 == nil {
 			return err2 == nil
@@ -192,6 +358,16 @@ func FailedWith(err error) Result {
 	}
 }
 
+// DegradedWith returns a Result representing partial success, with the
+// error describing what failed.
+func DegradedWith(err error, info string) Result {
+	return Result{
+		Code:  Degraded,
+		Error: err,
+		Info:  info,
+	}
+}
+
 // FailedWith returns a Result representing failure with given error and extra information.
 func FailedWithInfo(err error, info, infoUrl string) Result {
 	return Result{
@@ -202,6 +378,20 @@ func FailedWithInfo(err error, info, infoUrl string) Result {
 	}
 }
 
+// Weighted returns a copy of the Result with the weight, see
+// Result.Weight.
+func (r Result) Weighted(weight float64) Result {
+	r.Weight = weight
+	return r
+}
+
+// WithValue returns a copy of the Result with the numeric value, see
+// Result.Value.
+func (r Result) WithValue(v float64) Result {
+	r.Value = &v
+	return r
+}
+
 // Passed returns a Result representing pass.
 func Passed() Result { return Result{Code: Pass} }
 
@@ -243,6 +433,14 @@ func (d *selectedProbes) Set(value string) error {
 
 // NewProbe returns a new probe from given prober implementation.
 func NewProbe(p Prober, name, desc string, options ...Option) *Probe {
+	probe := newProbe(p, name, desc, options...)
+	probe.publish()
+	return probe
+}
+
+// newProbe returns a new Probe like NewProbe, without publishing its
+// status.
+func newProbe(p Prober, name, desc string, options ...Option) *Probe {
 	parseFlags.Do(func() {
 		if !flag.Parsed() {
 			flag.Parse()
@@ -252,10 +450,12 @@ func NewProbe(p Prober, name, desc string, options ...Option) *Probe {
 		Prober:         p,
 		Name:           name,
 		Desc:           desc,
+		Location:       defaultLocation(),
 		Interval:       DefaultInterval,
 		badness:        0,
 		failurePenalty: defaultFailurePenalty,
 		successReward:  defaultSuccessReward,
+		maxResultLen:   defaultMaxResultLen,
 		records:        Records{},
 		t:              realTime{},
 		alertLock:      sync.RWMutex{},
@@ -266,6 +466,38 @@ func NewProbe(p Prober, name, desc string, options ...Option) *Probe {
 	return probe
 }
 
+// defaultLocation returns the location to use for probes that don't
+// specify one.
+func defaultLocation() string {
+	if *location != "" {
+		return *location
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		log.Printf("failed to get hostname for probe location: %v\n", err)
+	}
+	return host
+}
+
+// Location sets where the probe runs from, e.g. a region, which
+// distinguishes results for the same probe run from many places.
+func Location(loc string) func(*Probe) {
+	return func(p *Probe) {
+		p.Location = loc
+	}
+}
+
+// Label adds a label to the probe, e.g. Label("env", "prod"), which can
+// be used to group and select probes.
+func Label(key, value string) func(*Probe) {
+	return func(p *Probe) {
+		if p.Labels == nil {
+			p.Labels = map[string]string{}
+		}
+		p.Labels[key] = value
+	}
+}
+
 // Interval sets the interval for the prober.
 func Interval(interval time.Duration) func(*Probe) {
 	return func(p *Probe) {
@@ -273,6 +505,35 @@ func Interval(interval time.Duration) func(*Probe) {
 	}
 }
 
+// AlignToInterval makes the probe run on wall-clock boundaries that are
+// multiples of its interval, e.g. on :00 every minute for an interval of
+// time.Minute, so that records from many probes line up.
+func AlignToInterval() func(*Probe) {
+	return func(p *Probe) {
+		p.aligned = true
+	}
+}
+
+// untilAligned returns how long there is until the next wall-clock
+// boundary that's a multiple of the interval.
+func (p *Probe) untilAligned() time.Duration {
+	now := p.t.Now()
+	return now.Truncate(p.Interval).Add(p.Interval).Sub(now)
+}
+
+// ExpectFailure inverts the probe, so that it passes when Probe()
+// fails and fails when Probe() passes, e.g. to check that a
+// decommissioned endpoint stays down or that a firewall blocks a port.
+//
+// Records of an inverted probe have the detail "expected_failure" set,
+// and its alerts say that failure was expected. Timeouts and panics
+// still count as failures.
+func ExpectFailure() func(*Probe) {
+	return func(p *Probe) {
+		p.expectFailure = true
+	}
+}
+
 // Report sets the function to call to report probe results.
 func Report(fn func(Result)) func(*Probe) {
 	return func(p *Probe) {
@@ -294,22 +555,110 @@ func SuccessReward(reward int) func(*Probe) {
 	}
 }
 
+// DegradedWeight sets how much a Degraded result counts towards badness
+// compared to a failure, between 0 and 1, e.g. 0.2 for partial
+// successes to only add a fifth of FailurePenalty. The default is 0.5.
+func DegradedWeight(weight float64) func(*Probe) {
+	return func(p *Probe) {
+		p.degradedW = weight
+	}
+}
+
+// DependsOn declares that the probe requires the named probes to
+// pass before it's meaningful to run it. Dependencies are respected by
+// RunAllOnce().
+func DependsOn(names ...string) func(*Probe) {
+	return func(p *Probe) {
+		p.dependencies = append(p.dependencies, names...)
+	}
+}
+
 // Run repeatedly runs the probe, blocking forever.
 func (p *Probe) Run() {
+	p.RunContext(context.Background())
+}
+
+// RunContext repeatedly runs the probe, blocking until ctx is done.
+func (p *Probe) RunContext(ctx context.Context) {
 	log.Printf("[%s] Starting..\n", p.Name)
 
 	if !enabledInFlags(p.Name) {
-		p.Disabled = true
+		p.setDisabled(true)
 		log.Printf("[%s] is disabled, will now exit", p.Name)
 		return
 	}
 
+	if p.initialDelay > 0 && !p.sleep(ctx, p.initialDelay) {
+		return
+	}
+	if p.immediate && (p.aligned || p.schedule != nil) && !p.IsDisabled() {
+		p.runProbeContext(ctx)
+	}
+	if p.aligned && !p.sleep(ctx, p.untilAligned()) {
+		return
+	}
+	if p.schedule != nil && !p.sleep(ctx, p.untilScheduled()) {
+		return
+	}
 	for {
-		wait := p.runProbe()
-		p.t.Sleep(wait)
+		wait := p.Interval
+		if p.schedule != nil {
+			wait = p.untilScheduled()
+		}
+		if !p.IsDisabled() {
+			wait = p.runProbeContext(ctx)
+		}
+		if !p.sleep(ctx, wait) {
+			log.Printf("[%s] Stopping: %v\n", p.Name, ctx.Err())
+			return
+		}
 	}
 }
 
+// sleep pauses for the duration, returning false if ctx was done
+// before it passed.
+func (p *Probe) sleep(ctx context.Context, d time.Duration) bool {
+	if ctx.Done() == nil {
+		// The context can never be done, so we can use our own clock.
+		p.t.Sleep(d)
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// Disable stops the probe from running, until Enable() is called.
+func (p *Probe) Disable() {
+	p.setDisabled(true)
+	log.Printf("[%s] is now disabled\n", p.Name)
+}
+
+// Enable lets a probe stopped by Disable() run again.
+func (p *Probe) Enable() {
+	p.setDisabled(false)
+	log.Printf("[%s] is now enabled\n", p.Name)
+}
+
+// IsDisabled returns true if the probe is disabled.
+func (p *Probe) IsDisabled() bool {
+	p.controlLock.RLock()
+	defer p.controlLock.RUnlock()
+	return p.Disabled
+}
+
+// setDisabled changes whether the probe is disabled.
+func (p *Probe) setDisabled(disabled bool) {
+	p.controlLock.Lock()
+	p.Disabled = disabled
+	p.controlLock.Unlock()
+}
+
 // String returns a human-readable representation of the Probe.
 func (p *Probe) String() string {
 	parts := []string{
@@ -317,6 +666,9 @@ func (p *Probe) String() string {
 		fmt.Sprintf("Desc: %q", p.Desc),
 		fmt.Sprintf("Records: %s", p.Records()),
 	}
+	if p.Location != "" {
+		parts = append(parts, fmt.Sprintf("Location: %q", p.Location))
+	}
 	if p.Badness() != 0 {
 		parts = append(parts, fmt.Sprintf("Badness: %d", p.Badness()))
 	}
@@ -332,11 +684,11 @@ func (p *Probe) String() string {
 	if !lastAlert.IsZero() {
 		parts = append(parts, fmt.Sprintf("lastAlert: %v", lastAlert))
 	}
-	if p.Disabled {
+	if p.IsDisabled() {
 		parts = append(parts, fmt.Sprintf("Disabled: true"))
 	}
-	if !p.SilencedUntil.Equal(time.Time{}) {
-		parts = append(parts, fmt.Sprintf("SilencedUntil: %v", p.SilencedUntil))
+	if until := p.silencedUntil(); !until.IsZero() {
+		parts = append(parts, fmt.Sprintf("SilencedUntil: %v", SilenceTime{until}))
 	}
 	if p.failurePenalty != defaultFailurePenalty {
 		parts = append(parts, fmt.Sprintf("failurePenalty: %v", p.failurePenalty))
@@ -364,103 +716,232 @@ func enabledInFlags(name string) bool {
 // runProbe runs the probe once, returning the amount of time to wait
 // before the next runProbe() run is due.
 func (p *Probe) runProbe() time.Duration {
-	c := make(chan Result, 1)
+	return p.runProbeContext(context.Background())
+}
+
+// runProbeContext runs the probe once like runProbe(), abandoning the
+// run if ctx is done first.
+func (p *Probe) runProbeContext(ctx context.Context) time.Duration {
 	start := p.t.Now()
+	p.recordStart(start)
+	handled := make(chan struct{})
+	defer close(handled)
+	r, ok := p.probeOnceLate(ctx, handled)
+	if ctx.Err() != nil {
+		// The probe is stopping, so the cancelled run doesn't count.
+		return 0
+	}
+	p.recordTimeout(!ok)
+	end := p.t.Now()
+	p.recordDuration(end.Sub(start))
+	if ok {
+		p.recordLatency(end, end.Sub(start))
+	}
+	p.handleResult(r)
+	if p.aligned {
+		return p.untilAligned()
+	}
+	if p.schedule != nil {
+		return p.untilScheduled()
+	}
+	if !ok {
+		return time.Duration(0)
+	}
+	interval := p.Interval
+	if p.inFastRecheck() {
+		interval = p.fastRecheck
+	}
+	wait := interval - p.t.Now().Sub(start)
+	log.Printf("[%s] needs to sleep %v more here\n", p.Name, wait)
+	return wait
+}
+
+// probeOnce calls Probe(), returning its result after passing it
+// through the sanitizers of the probe. If the probe has a
+// TargetLimiter, probeOnce first waits for it to allow the run.
+//
+// If Probe() doesn't finish within the probe interval, or ctx is done
+// first, a failed result is returned and the second return value is
+// false.
+func (p *Probe) probeOnce(ctx context.Context) (Result, bool) {
+	return p.probeOnceLate(ctx, nil)
+}
+
+// probeOnceLate calls Probe() like probeOnce(). If the run times out
+// and handled isn't nil, a result that arrives later is handled
+// according to OnLateResult() once handled is closed, i.e. after the
+// timeout itself was.
+func (p *Probe) probeOnceLate(ctx context.Context, handled <-chan struct{}) (r Result, ok bool) {
+	id := newRunID()
+	defer func() {
+		r.runID = id
+	}()
+	if r, ok := p.chaosResult(); ok {
+		return r, true
+	}
+	if p.limiter != nil {
+		if err := p.limiter.Wait(ctx, p.limitHost); err != nil {
+			log.Printf("[%s] Cancelled while waiting for run against %s to be allowed: %v\n", p.Name, p.limitHost, err)
+			return FailedWith(fmt.Errorf("%s was cancelled: %v", p.Name, err)), false
+		}
+	}
+	late := handled != nil && p.lateResults != DropLateResults
+	runCtx, cancel := context.WithCancel(withRunID(ctx, id))
+	var timedOut time.Time // when the run timed out, set before it's abandoned
+	defer func() {
+		if timedOut.IsZero() || !late {
+			cancel()
+		}
+	}()
+	run := &probeRun{start: time.Now()}
+	c := make(chan Result, 1)
 	go func() {
+		defer cancel()
 		log.Printf("[%s] Probing..\n", p.Name)
-		c <- p.Probe()
+		r := p.safeProbe(runCtx)
+		c <- r
+		if !run.finish() {
+			return
+		}
+		p.recordAbandonedDone(run)
+		if late && !timedOut.IsZero() {
+			<-handled
+			r = p.sanitize(r)
+			r.runID = id
+			p.handleLateResult(r, timedOut, time.Now())
+		}
 	}()
 	select {
 	case r := <-c:
 		// We got a result of some sort from the prober.
-		p.handleResult(r)
-		wait := p.Interval - p.t.Now().Sub(start)
-		log.Printf("[%s] needs to sleep %v more here\n", p.Name, wait)
-		return wait
+		return p.sanitize(r), true
+	case <-ctx.Done():
+		log.Printf("[%s] Cancelled: %v\n", p.Name, ctx.Err())
+		if run.abandon() {
+			p.recordAbandoned()
+		}
+		return FailedWith(fmt.Errorf("%s was cancelled: %v", p.Name, ctx.Err())), false
 	case <-time.After(p.Interval):
-		// Probe didn't finish in time for us to run the next one, report as failure.
+		// Probe didn't finish in time for us to run the next one, report
+		// as failure. The run is abandoned, and cancelled if the Prober
+		// is a ContextProber, unless late results are wanted.
 		log.Printf("[%s] Timed out\n", p.Name)
-		timeoutFail := FailedWith(
-			fmt.Errorf("%s timed out (with probe interval %1.1f sec)",
+		timedOut = time.Now()
+		if run.abandon() {
+			p.recordAbandoned()
+		}
+		return FailedWith(
+			fmt.Errorf("%w: %s took longer than the probe interval of %1.1f sec",
+				ErrTimedOut,
 				p.Name,
-				p.Interval.Seconds()))
-		p.handleResult(timeoutFail)
-		return time.Duration(0)
+				p.Interval.Seconds())), false
+	}
+}
+
+// safeProbe calls Probe(), or ProbeContext() with ctx for a
+// ContextProber, converting any panic into a failed result
+// holding the stack trace, so a buggy prober can't crash the process.
+func (p *Probe) safeProbe(ctx context.Context) (r Result) {
+	defer func() {
+		if v := recover(); v != nil {
+			stack := string(debug.Stack())
+			log.Printf("[%s] Probe() panicked: %v\n%s", p.Name, v, stack)
+			r = Result{
+				Code:    Fail,
+				Error:   fmt.Errorf("%s panicked: %v", p.Name, v),
+				Info:    fmt.Sprintf("The probe panicked with %q", fmt.Sprint(v)),
+				Details: map[string]string{"stack": stack},
+			}
+		}
+	}()
+	return p.invert(p.callProbe(ctx))
+}
+
+// invert returns the opposite of the result if the probe expects
+// failure, and the result as-is otherwise.
+func (p *Probe) invert(r Result) Result {
+	if !p.expectFailure {
+		return r
+	}
+	details := make(map[string]string, len(r.Details)+1)
+	for k, v := range r.Details {
+		details[k] = v
+	}
+	details["expected_failure"] = "true"
+	if r.Passed() {
+		return Result{
+			Code:    Fail,
+			Error:   fmt.Errorf("%s passed, but was expected to fail", p.Name),
+			Info:    r.Info,
+			InfoUrl: r.InfoUrl,
+			Details: details,
+			Weight:  r.Weight,
+			Value:   r.Value,
+		}
+	}
+	return Result{
+		Code:    Pass,
+		Info:    fmt.Sprintf("failed as expected: %v", r.Error),
+		InfoUrl: r.InfoUrl,
+		Details: details,
+		Weight:  r.Weight,
+		Value:   r.Value,
 	}
 }
 
-// Records returns the historical records of probe runs.
+// Records returns a copy of the historical records of probe runs, since
+// the most recent one changes in place when CompactRecords() merges runs
+// into it.
 func (p *Probe) Records() Records {
 	p.recordsLock.RLock()
 	defer p.recordsLock.RUnlock()
-	return p.records
+	return append(Records(nil), p.records...)
 }
 
 // add appends the record to the buffer for the probe, keeping it within bufferSize.
 func (p *Probe) addRecord(r Record) {
 	p.recordsLock.Lock()
 	p.records = append(p.records, r)
+	added := r.size()
 	if len(p.records) >= bufferSize {
 		over := len(p.records) - bufferSize
 		log.Printf("[%s] buffer is over %d, reslicing it\n", p.Name, bufferSize)
+		for _, dropped := range p.records[:over] {
+			added -= dropped.size()
+		}
 		p.records = p.records[over:]
 	}
+	p.recordBytes += added
 	p.recordsLock.Unlock()
+	trackRecordBytes(p, added)
 	// log.Printf("[%s] buffer is now %d elements\n", p.Name, len(p.Records()))
 }
 
 // Silenced returns true if the probe is currently silenced.
 func (p *Probe) Silenced() bool {
-	return p.SilencedUntil.After(p.t.Now())
+	return SilenceTime{p.silencedUntil()}.IsActive(p.t.Now())
 }
 
 // Silence silences the Probe until specified time.
 func (p *Probe) Silence(until time.Time) {
+	p.controlLock.Lock()
 	p.SilencedUntil = SilenceTime{until}
+	p.controlLock.Unlock()
 	log.Printf("[%s] is now silenced until %v\n", p.Name, until)
 }
 
-// String returns a human-readable description of the time until which a probe is silenced.
-func (t SilenceTime) String() string {
-	return fmt.Sprintf("%s (%f hrs more)", t.Format(time.RFC822), t.Sub(time.Now()).Hours())
+// silencedUntil returns the earliest time the probe can alert.
+func (p *Probe) silencedUntil() time.Time {
+	p.controlLock.RLock()
+	defer p.controlLock.RUnlock()
+	return p.SilencedUntil.Time
 }
 
 // Equal returns true if the probes are equal.
+//
+// Use Diff() to find out what differs.
 func (p1 *Probe) Equal(p2 *Probe) bool {
-	if p2 == nil {
-		return false
-	}
-	if p1.Name != p2.Name {
-		return false
-	}
-	if p1.Desc != p2.Desc {
-		return false
-	}
-	if !p1.Records().Equal(p2.Records()) {
-		return false
-	}
-	if p1.Badness() != p2.Badness() {
-		return false
-	}
-	if p1.Interval != p2.Interval {
-		return false
-	}
-	if p1.IsAlerting() != p2.IsAlerting() {
-		return false
-	}
-	if !p1.getLastAlert().Equal(p2.getLastAlert()) {
-		return false
-	}
-	if p1.Disabled != p2.Disabled {
-		return false
-	}
-	if !p1.SilencedUntil.Equal(p2.SilencedUntil.Time) {
-		return false
-	}
-	if p1.failurePenalty != p2.failurePenalty {
-		return false
-	}
-	return true
+	return len(p1.Diff(p2)) == 0
 }
 
 // Equal returns true if the Records are equal.
@@ -504,16 +985,31 @@ func (pr Records) RecentFailures() Records {
 }
 
 func (r Record) String() string {
+	if r.Repeats > 0 {
+		return fmt.Sprintf(
+			"Record{Timestamp: %v, TimeMillis: %q, Location: %q, Result: %s, Repeats: %d, Until: %v}",
+			r.Timestamp,
+			r.TimeMillis,
+			r.Location,
+			r.Result,
+			r.Repeats,
+			r.Until)
+	}
 	return fmt.Sprintf(
-		"Record{Timestamp: %v, TimeMillis: %q, Result: %s}",
+		"Record{Timestamp: %v, TimeMillis: %q, Location: %q, Result: %s}",
 		r.Timestamp,
 		r.TimeMillis,
+		r.Location,
 		r.Result)
 }
 
 // Ago describes the duration since the record occured.
 func (r Record) Ago() string {
-	d := time.Since(r.Timestamp)
+	return ago(time.Since(r.Timestamp))
+}
+
+// ago describes a duration in the past in human-readable form.
+func ago(d time.Duration) string {
 	if d < time.Minute {
 		return fmt.Sprintf("%0.1f sec ago", d.Seconds())
 	} else if d < time.Hour {
@@ -525,16 +1021,17 @@ func (r Record) Ago() string {
 	}
 }
 
-// Marshal returns the record in YAML form.
-func (r Record) marshal() []byte {
-	b, err := yaml.Marshal(r)
+// marshal returns the record encoded with the codec.
+func (r Record) marshal(c Codec) []byte {
+	b, err := c.Marshal(r)
 	if err != nil {
 		log.Printf("failed to marshal record %+v: %v", r, err)
 	}
 	return b
 }
 
-// Equal returns true if the Record objects are equal.
+// Equal returns true if the Record objects are equal. Their RunID
+// isn't compared, since it's different for every run.
 func (r1 Record) Equal(r2 Record) bool {
 	if !r1.Timestamp.Equal(r2.Timestamp) {
 		return false
@@ -542,30 +1039,54 @@ func (r1 Record) Equal(r2 Record) bool {
 	if r1.TimeMillis != r2.TimeMillis {
 		return false
 	}
+	if r1.Location != r2.Location {
+		return false
+	}
+	if r1.Repeats != r2.Repeats || !r1.Until.Equal(r2.Until) {
+		return false
+	}
+	if !equalStrings(r1.Annotations, r2.Annotations) {
+		return false
+	}
 	if !r1.Result.Equal(r2.Result) {
 		return false
 	}
 	return true
 }
 
-// openLog opens the log file.
+// openLog opens the log file, and starts writing records to it.
 func openLog() {
-	logPath := filepath.Join(logDir, logName)
-	log.Printf("Using YAML log file %q\n", logPath)
-	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.ModePerm)
+	lw, f := openLogWriter(filepath.Join(logDir, logName))
+	logFile = f
+	outcomesLock.Lock()
+	outcomes = lw
+	outcomesLock.Unlock()
+}
+
+// openLogWriter opens the log file at the path, returning a writer of
+// records to it.
+func openLogWriter(path string) (*logWriter, *os.File) {
+	log.Printf("Using log file %q\n", path)
+	if _, err := CodecNamed(*logFormat); err != nil {
+		log.Printf("bad -log_format, writing records as YAML: %v\n", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.ModePerm)
 	if err != nil {
-		log.Printf("failed to open %q: %v\n", logPath, err)
+		log.Printf("failed to open %q: %v\n", path, err)
 	}
-	logFile = f
+	return newLogWriter(f, *logQueueSize, *logFlushInterval), f
 }
 
 // handleResult handles a return value from a Probe() run.
 func (p *Probe) handleResult(r Result) {
+	p.resultLock.Lock()
+	defer p.resultLock.Unlock()
 	if p.reportFn != nil {
 		// Call custom report function, if specified.
 		p.reportFn(r)
 	}
-	b := p.Badness()
+	old := p.Badness()
+	b := old
 	if r.Passed() {
 		b -= p.successReward
 		if b < 0 {
@@ -573,46 +1094,84 @@ func (p *Probe) handleResult(r Result) {
 		}
 		log.Printf("[%s] Pass, badness is now %d.\n", p.Name, b)
 	} else {
-		b += p.failurePenalty
-		log.Printf("[%s] Failed while probing, badness is now %d: %v\n", p.Name, b, r.Error)
+		b += p.penalty(r)
+		if r.Code == Degraded {
+			log.Printf("[%s] Degraded, badness is now %d: %v\n", p.Name, b, r.Error)
+		} else {
+			log.Printf("[%s] Failed while probing, badness is now %d: %v\n", p.Name, b, r.Error)
+		}
 	}
 	p.setBadness(b)
+	p.noteBadnessStep(p.t.Now(), b-old, b, p.resultCause(r, b-old))
+	p.setLastOutcome(r.Passed(), p.t.Now())
+	p.recordFailure(!r.Passed())
+	recovered := p.noteOutcome(r.Passed(), p.t.Now())
 	p.logResult(r)
+	if recovered {
+		p.resetRemediation()
+		go p.sendRecovery()
+	}
 
 	if p.Silenced() {
-		log.Printf("[%s] is silenced until %v, will not alert, resetting badness to 0\n", p.Name, p.SilencedUntil)
+		log.Printf("[%s] is silenced until %v, will not alert, resetting badness to 0\n", p.Name, SilenceTime{p.silencedUntil()})
+		if b > 0 {
+			p.noteBadnessStep(p.t.Now(), -b, 0, fmt.Sprintf("silenced until %s, reset to 0", p.formatTime(p.silencedUntil())))
+		}
 		p.setBadness(0)
 	}
 
-	p.setIsAlerting(p.Badness() >= *alertThreshold)
+	if p.badnessPool != nil {
+		p.setAlertDecision("badness was added to its BadnessPool, which alerts instead")
+		p.badnessPool.update(p)
+		return
+	}
+	alerting := !p.Silenced() && p.alertCondition(p.t.Now())
+	if alerting && !p.IsAlerting() && p.inDeployGrace(p.t.Now()) {
+		log.Printf("[%s] would now be alerting, but is in deploy grace period until %v\n", p.Name, p.DeployGraceUntil())
+		p.setAlertDecision("would have started alerting, but held off in the deploy grace period until %s", p.formatTime(p.DeployGraceUntil()))
+		alerting = false
+	} else if !alerting {
+		if p.Silenced() {
+			p.setAlertDecision("not alerting, since silenced until %s", p.formatTime(p.silencedUntil()))
+		} else {
+			p.setAlertDecision("not alerting, since %s doesn't hold", p.conditionString())
+		}
+	}
+	p.setIsAlerting(alerting)
+	p.updateDegraded()
 	if !p.IsAlerting() {
 		return
 	}
-	if *alertsDisabled {
+	p.remediate()
+	if p.alertsDisabled() {
 		log.Printf("[%s] would now be alerting, but alerts are disabled\n", p.Name)
+		p.setAlertDecision("alerting, but alerts are disabled")
 		return
 	}
 
 	lastAlert := p.getLastAlert()
-	if time.Since(lastAlert) < MaxAlertFrequency {
+	if time.Since(lastAlert) < p.maxAlertFrequency() {
 		log.Printf("[%s] will not alert, since last alert was sent %v back\n", p.Name, time.Since(lastAlert))
+		p.setAlertDecision("alerting, but no alert was sent, since the last one was sent %v back, less than %v ago", time.Since(lastAlert).Round(time.Second), p.maxAlertFrequency())
 		return
 	}
 
 	log.Printf("[%s] is alerting\n", p.Name)
+	p.setAlertDecision("alerting, so an alert is being sent")
 	// Send alert notification in goroutine to not block further
-	// probing.
-	// TODO: There is a race condition here, if email sending takes long
-	// enough for further Probe() runs to finish, which would queue up
-	// several duplicate alert emails. This shouldn't often happen, but
-	// technically should be bounded by a timeout to prevent the
-	// possibility.
-	go p.sendAlert()
+	// probing. Alerts that take long to send are bounded by
+	// AlertTimeout(), and sendAlert() skips sending while another alert
+	// is still in progress.
+	p.noteNotification(p.t.Now())
+	p.alert()
 }
 
 // setIsAlerting changes the alerting status of the probe.
 func (p *Probe) setIsAlerting(alerting bool) {
 	p.alertLock.Lock()
+	if alerting && !p.alerting && p.t != nil {
+		p.alertingSince = p.t.Now()
+	}
 	p.alerting = alerting
 	p.alertLock.Unlock()
 }
@@ -634,6 +1193,9 @@ func (p *Probe) Badness() int {
 // setBadness sets the `badness` to specified value.
 func (p *Probe) setBadness(b int) {
 	p.alertLock.Lock()
+	if b != p.badness {
+		p.recordBadness(b)
+	}
 	p.badness = b
 	p.alertLock.Unlock()
 }
@@ -654,9 +1216,105 @@ func (p *Probe) getLastAlert() time.Time {
 	return p.lastAlert
 }
 
+// setLastOutcome sets the time of the last pass or failure.
+func (p *Probe) setLastOutcome(passed bool, t time.Time) {
+	p.alertLock.Lock()
+	if passed {
+		p.lastSuccess = t
+	} else {
+		p.lastFailure = t
+	}
+	p.alertLock.Unlock()
+}
+
+// LastSuccess returns the time the probe last passed, or the zero
+// value for time.Time if it never has.
+func (p *Probe) LastSuccess() time.Time {
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	return p.lastSuccess
+}
+
+// LastFailure returns the time the probe last failed, or the zero
+// value for time.Time if it never has.
+func (p *Probe) LastFailure() time.Time {
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	return p.lastFailure
+}
+
+// alertDesc returns the description of the probe to use in alerts,
+// which says when the probe last passed, since that's the first thing
+// responders want to know.
+func (p *Probe) alertDesc() string {
+	desc := p.Desc
+	if p.expectFailure {
+		desc = fmt.Sprintf("%s [expected to fail]", desc)
+	}
+	desc += p.recurrenceNote()
+	desc += p.rateNote()
+	desc += p.sloNote()
+	desc += p.weeklyNote()
+	desc += p.annotationNote()
+	desc += p.explainNote()
+	last := p.LastSuccess()
+	if last.IsZero() {
+		return fmt.Sprintf("%s (no successful run since start)", desc)
+	}
+	return fmt.Sprintf("%s (last success %s)", desc, ago(p.t.Now().Sub(last)))
+}
+
+// penalty returns how much badness increases for the failed result.
+func (p *Probe) penalty(r Result) int {
+	n, _ := p.weighPenalty(r)
+	return n
+}
+
+// weighPenalty returns how much badness increases for the failed
+// result, and the factors other than 1 the penalty was weighed by, e.g.
+// "0.5 (degraded)".
+func (p *Probe) weighPenalty(r Result) (int, []string) {
+	base, _ := p.basePenalty(r)
+	weight := 1.0
+	var factors []string
+	if r.Weight > 0 {
+		weight = r.Weight
+		factors = append(factors, fmt.Sprintf("%g (result weight)", r.Weight))
+	}
+	if r.Code == Degraded {
+		weight *= p.degradedWeight()
+		factors = append(factors, fmt.Sprintf("%g (degraded)", p.degradedWeight()))
+	}
+	if fw := p.fastWeight(); fw != 1 {
+		weight *= fw
+		factors = append(factors, fmt.Sprintf("%g (fast recheck)", fw))
+	}
+	if weight == 1 {
+		return base, nil
+	}
+	return int(math.Round(float64(base) * weight)), factors
+}
+
+// degradedWeight returns how much a Degraded result counts towards
+// badness compared to a failure.
+func (p *Probe) degradedWeight() float64 {
+	if p.degradedW > 0 {
+		return p.degradedW
+	}
+	return defaultDegradedWeight
+}
+
 // sendAlert calls the Alert() implementation and handles the outcome.
+//
+// If an alert is already being sent for the probe, e.g. while retrying
+// as set by RetryAlerts(), sendAlert does nothing.
 func (p *Probe) sendAlert() {
-	err := p.Alert(p.Name, p.Desc, p.Badness(), p.Records())
+	if !p.startSending() {
+		log.Printf("[%s] will not alert, since an alert is already being sent\n", p.Name)
+		return
+	}
+	err := p.notifyWithRetries(p.alertDesc())
+	p.doneSending(err == nil)
 	if err != nil {
 		log.Printf("[%s] Failed to alert: %v", p.Name, err)
 		// Note: We don't reset badness here; next cycle we'll keep
@@ -664,24 +1322,57 @@ func (p *Probe) sendAlert() {
 	} else {
 		log.Printf("[%s] Called Alert(), resetting badness to 0\n", p.Name)
 		p.setLastAlert(p.t.Now())
+		p.recordAlertSent()
+		if b := p.Badness(); b > 0 {
+			p.noteBadnessStep(p.t.Now(), -b, 0, "alert sent, reset to 0")
+		}
 		p.setBadness(0)
 	}
 }
 
-// logResult logs the result of a probe run.
+// logResult logs the result of a probe run. The caller must hold
+// resultLock.
 func (p *Probe) logResult(res Result) {
-	onceOpen.Do(openLog)
+	lw := p.logWriter()
 	now := p.t.Now()
 	rec := Record{
-		Timestamp:  now,
-		TimeMillis: now.Format(time.StampMilli),
-		Result:     res,
+		Timestamp:   now,
+		TimeMillis:  p.formatTime(now),
+		Location:    p.Location,
+		Result:      res,
+		RunID:       res.runID,
+		Annotations: p.annotations(),
+	}
+
+	sampled := p.sampled(res)
+	merged := false
+	if p.compact {
+		var ended *Record
+		if ended, merged = p.mergeRecord(rec); ended != nil {
+			lw.write(ended.marshal(p.recordCodec()))
+		}
 	}
-
-	p.addRecord(rec)
-	_, err := logFile.Write(rec.marshal())
-	if err != nil {
-		log.Printf("failed to write record to log: %v", err)
+	if !merged {
+		p.addRecord(rec)
+		if sampled {
+			lw.write(rec.marshal(p.recordCodec()))
+		}
+	}
+	for _, s := range p.streams {
+		s.publish(p, rec)
+	}
+	if !sampled {
+		return
+	}
+	if p.shipURL != "" {
+		go p.ship(rec)
+	}
+	if p.otlp != nil {
+		go func() {
+			if err := p.otlp.Export(context.Background(), p, rec); err != nil {
+				log.Printf("[%s] failed to export record as OpenTelemetry log: %v\n", p.Name, err)
+			}
+		}()
 	}
 }
 
@@ -719,15 +1410,15 @@ func (ps Probes) Len() int { return len(ps) }
 // attention first. Since the default sort order is ascending, this
 // means that "lower values" will correspond to probes in worse state.
 func (ps Probes) Less(i, j int) bool {
-	if ps[i].Disabled != ps[j].Disabled {
+	if d1, d2 := ps[i].IsDisabled(), ps[j].IsDisabled(); d1 != d2 {
 		// Disabled probes sort after (higher value than) non-disabled ones.
-		return ps[j].Disabled
+		return d2
 	}
-	if !ps[i].SilencedUntil.Equal(ps[j].SilencedUntil.Time) {
+	if s1, s2 := ps[i].silencedUntil(), ps[j].silencedUntil(); !s1.Equal(s2) {
 		// Probes that are silenced for a longer time sort after (higher
 		// value than) ones that are silenced shorter. (Possibly not
 		// silenced at all, but that depends on the current time.)
-		return ps[i].SilencedUntil.Before(ps[j].SilencedUntil.Time)
+		return s1.Before(s2)
 	}
 	b1, b2 := ps[i].Badness(), ps[j].Badness()
 	if b1 != b2 {
@@ -739,6 +1430,11 @@ func (ps Probes) Less(i, j int) bool {
 		// Alerting probes sort before (lower value than) non-alerting ones.
 		return a1
 	}
+	d1, d2 := ps[i].IsDegraded(), ps[j].IsDegraded()
+	if d1 != d2 {
+		// Degraded probes sort before (lower value than) healthy ones.
+		return d1
+	}
 	l1, l2 := ps[i].getLastAlert(), ps[j].getLastAlert()
 	if !l1.Equal(l2) {
 		// Probes that alerted longer ago sort after ones that alerted
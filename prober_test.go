@@ -1,9 +1,12 @@
 package prober
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"log"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -21,6 +24,11 @@ func (fakeTime) Sleep(d time.Duration) {}
 func (p testProber) Probe() Result                                               { return p.result }
 func (p testProber) Alert(name, desc string, badness int, records Records) error { return nil }
 
+// panickingProber is a Prober that panics when Probe() is called.
+type panickingProber struct{ testProber }
+
+func (panickingProber) Probe() Result { panic("panicking on purpose") }
+
 func TestProbe_runProbe(t *testing.T) {
 	type (
 		want struct {
@@ -65,7 +73,7 @@ func TestProbe_runProbe(t *testing.T) {
 						// TODO(hkjn): Clean up Timestamp vs TimeMillis.
 						Record{
 							Timestamp:  parseTime("19 Nov 98 15:14 UTC"),
-							TimeMillis: "Nov 19 15:14:00.000",
+							TimeMillis: "1998-11-19T15:14:00Z",
 							Result:     Passed(),
 						},
 					},
@@ -86,6 +94,7 @@ func TestProbe_runProbe(t *testing.T) {
 			want: want{
 				wait: DefaultInterval,
 				state: &Probe{
+					Prober:         testProber{FailedWith(errors.New("TestProber2 failing on purpose"))},
 					Name:           "TestProber2",
 					Desc:           "A test prober that fails.",
 					Interval:       DefaultInterval,
@@ -94,7 +103,7 @@ func TestProbe_runProbe(t *testing.T) {
 					records: Records{
 						Record{
 							Timestamp:  parseTime("19 Nov 98 15:14 UTC"),
-							TimeMillis: "Nov 19 15:14:00.000",
+							TimeMillis: "1998-11-19T15:14:00Z",
 							Result:     FailedWith(errors.New("TestProber2 failing on purpose")),
 						},
 					},
@@ -115,6 +124,7 @@ func TestProbe_runProbe(t *testing.T) {
 			want: want{
 				wait: DefaultInterval,
 				state: &Probe{
+					Prober:         testProber{FailedWith(errors.New("TestProber3 failing on purpose"))},
 					Name:           "TestProber3",
 					Desc:           "A test prober that alerts.",
 					Interval:       time.Minute,
@@ -124,7 +134,7 @@ func TestProbe_runProbe(t *testing.T) {
 					records: Records{
 						Record{
 							Timestamp:  parseTime("19 Nov 98 15:14 UTC"),
-							TimeMillis: "Nov 19 15:14:00.000",
+							TimeMillis: "1998-11-19T15:14:00Z",
 							Result:     FailedWith(errors.New("TestProber3 failing on purpose")),
 						},
 					},
@@ -146,6 +156,7 @@ func TestProbe_runProbe(t *testing.T) {
 			want: want{
 				wait: DefaultInterval,
 				state: &Probe{
+					Prober:         testProber{FailedWith(errors.New("TestProber4 failing on purpose"))},
 					Name:           "TestProber4",
 					Desc:           "A test prober that is silenced.",
 					SilencedUntil:  SilenceTime{parseTime("19 Nov 98 15:30 UTC")},
@@ -155,7 +166,7 @@ func TestProbe_runProbe(t *testing.T) {
 					records: Records{
 						Record{
 							Timestamp:  parseTime("19 Nov 98 15:14 UTC"),
-							TimeMillis: "Nov 19 15:14:00.000",
+							TimeMillis: "1998-11-19T15:14:00Z",
 							Result:     FailedWith(errors.New("TestProber4 failing on purpose")),
 						},
 					},
@@ -178,6 +189,7 @@ func TestProbe_runProbe(t *testing.T) {
 			want: want{
 				wait: DefaultInterval,
 				state: &Probe{
+					Prober:         testProber{FailedWith(errors.New("TestProber5 failing on purpose"))},
 					Name:           "TestProber5",
 					Desc:           "A test prober that was recently silenced.",
 					SilencedUntil:  SilenceTime{parseTime("19 Nov 98 15:13 UTC")},
@@ -188,7 +200,7 @@ func TestProbe_runProbe(t *testing.T) {
 					records: Records{
 						Record{
 							Timestamp:  parseTime("19 Nov 98 15:14 UTC"),
-							TimeMillis: "Nov 19 15:14:00.000",
+							TimeMillis: "1998-11-19T15:14:00Z",
 							Result:     FailedWith(errors.New("TestProber5 failing on purpose")),
 						},
 					},
@@ -211,6 +223,7 @@ func TestProbe_runProbe(t *testing.T) {
 			want: want{
 				wait: DefaultInterval,
 				state: &Probe{
+					Prober:         testProber{FailedWith(errors.New("TestProber6 failing on purpose"))},
 					Name:           "TestProber6",
 					Desc:           "A test prober that is silenced and not alerting.",
 					SilencedUntil:  SilenceTime{parseTime("19 Nov 98 15:30 UTC")},
@@ -220,7 +233,7 @@ func TestProbe_runProbe(t *testing.T) {
 					records: Records{
 						Record{
 							Timestamp:  parseTime("19 Nov 98 15:14 UTC"),
-							TimeMillis: "Nov 19 15:14:00.000",
+							TimeMillis: "1998-11-19T15:14:00Z",
 							Result:     FailedWith(errors.New("TestProber6 failing on purpose")),
 						},
 					},
@@ -228,6 +241,38 @@ func TestProbe_runProbe(t *testing.T) {
 				silenced: true,
 			},
 		},
+		{
+			in: &Probe{
+				Prober:         testProber{Passed()},
+				Name:           "TestProber7",
+				Desc:           "A test prober aligned to the interval.",
+				Interval:       time.Minute,
+				badness:        0,
+				failurePenalty: 10,
+				aligned:        true,
+				t:              fakeTime{parseTime("19 Nov 98 15:14 UTC").Add(20 * time.Second)},
+				records:        Records{},
+			},
+			want: want{
+				wait: 40 * time.Second,
+				state: &Probe{
+					Prober:         testProber{Passed()},
+					Name:           "TestProber7",
+					Desc:           "A test prober aligned to the interval.",
+					Interval:       time.Minute,
+					badness:        0,
+					failurePenalty: 10,
+					aligned:        true,
+					records: Records{
+						Record{
+							Timestamp:  parseTime("19 Nov 98 15:14 UTC").Add(20 * time.Second),
+							TimeMillis: "1998-11-19T15:14:20Z",
+							Result:     Passed(),
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for i, tt := range cases {
@@ -235,9 +280,9 @@ func TestProbe_runProbe(t *testing.T) {
 		if got != tt.want.wait {
 			t.Errorf("[%d] %+v.runProbe() => %v; want %v\n",
 				i, tt.in, got, tt.want.wait)
-		} else if !tt.in.Equal(tt.want.state) {
-			t.Errorf("[%d] Got probe in state:\n%+v\nWant:\n%+v\n",
-				i, tt.in, tt.want.state)
+		} else if diff := tt.in.Diff(tt.want.state); len(diff) > 0 {
+			t.Errorf("[%d] Got probe in state:\n%+v\nWant:\n%+v\nDiff:\n%s\n",
+				i, tt.in, tt.want.state, strings.Join(diff, "\n"))
 		} else if tt.in.Silenced() != tt.want.silenced {
 			t.Errorf("[%d] %v.Silenced()=%v, want %v\n",
 				i, tt.in, tt.in.Silenced(), tt.want.silenced)
@@ -439,3 +484,107 @@ func TestProbes_Sort(t *testing.T) {
 		}
 	}
 }
+
+func TestProbe_probeOnce_panic(t *testing.T) {
+	p := &Probe{
+		Prober:   panickingProber{},
+		Name:     "PanickingProber",
+		Interval: time.Minute,
+	}
+	got, ok := p.probeOnce(context.Background())
+	if !ok {
+		t.Fatalf("probeOnce() => %v, false; want panic to be recovered", got)
+	}
+	if got.Passed() || got.Error.Error() != "PanickingProber panicked: panicking on purpose" {
+		t.Errorf("probeOnce() => %v; want failure with panic value\n", got)
+	}
+	if !strings.Contains(got.Details["stack"], "panickingProber.Probe") {
+		t.Errorf("probeOnce() => %v; want stack trace in Details\n", got)
+	}
+}
+
+func TestProbe_controlWhileRunning(t *testing.T) {
+	ran := make(chan struct{}, 100)
+	fail := FailedWith(errors.New("failing on purpose"))
+	p := newProbe(testProber{fail}, "ControlledProber", "Is silenced and disabled while it runs.",
+		Interval(time.Millisecond), Report(func(Result) {
+			select {
+			case ran <- struct{}{}:
+			default:
+			}
+		}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.RunContext(ctx)
+	for i := 0; i < 20; i++ {
+		<-ran
+		p.Silence(time.Now().Add(time.Hour))
+		p.Disable()
+		p.Enable()
+		if s := p.Status(); s.Disabled || s.SilencedUntil.IsZero() {
+			t.Errorf("[%d] Status() => %+v; want enabled and silenced\n", i, s)
+		}
+	}
+}
+
+func TestProbe_invert(t *testing.T) {
+	p := &Probe{Name: "DecommissionedProber"}
+	ExpectFailure()(p)
+	cases := []struct {
+		in   Result
+		want ResultCode
+	}{
+		{in: Passed(), want: Fail},
+		{in: FailedWith(errors.New("connection refused")), want: Pass},
+	}
+	for i, tt := range cases {
+		got := p.invert(tt.in)
+		if got.Code != tt.want {
+			t.Errorf("[%d] invert(%v) => %v; want code %v\n", i, tt.in, got, tt.want)
+		}
+		if got.Details["expected_failure"] != "true" {
+			t.Errorf("[%d] invert(%v) => %v; want expected_failure detail\n", i, tt.in, got)
+		}
+	}
+}
+
+func TestProbe_penalty(t *testing.T) {
+	p := &Probe{failurePenalty: 10}
+	err := errors.New("failing on purpose")
+	cases := []struct {
+		in   Result
+		want int
+	}{
+		{FailedWith(err), 10},
+		{FailedWith(err).Weighted(0.25), 3},
+		{FailedWith(err).Weighted(2), 20},
+		{FailedWith(err).Weighted(-1), 10},
+		{DegradedWith(err, "1 of 2 endpoints is down"), 5},
+		{DegradedWith(err, "1 of 2 endpoints is down").Weighted(0.5), 3},
+	}
+	for i, tt := range cases {
+		if got := p.penalty(tt.in); got != tt.want {
+			t.Errorf("[%d] penalty(%v) => %d; want %d\n", i, tt.in, got, tt.want)
+		}
+	}
+
+	DegradedWeight(0.2)(p)
+	if got := p.penalty(DegradedWith(err, "")); got != 2 {
+		t.Errorf("penalty() of Degraded result with DegradedWeight(0.2) => %d; want 2\n", got)
+	}
+}
+
+func TestResult_Degraded(t *testing.T) {
+	r := DegradedWith(errors.New("1 of 3 endpoints is down"), "2 of 3 endpoints are up")
+	if r.Passed() || r.Code.String() != "Degraded" {
+		t.Errorf("DegradedWith() => %v; want not passed, with code Degraded\n", r)
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Result
+	if err := json.Unmarshal(b, &got); err != nil || !got.Equal(r) {
+		t.Errorf("JSON round trip of %v via %s => %v, %v\n", r, b, got, err)
+	}
+}
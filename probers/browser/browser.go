@@ -0,0 +1,105 @@
+//go:build browser
+
+// Package browser provides a prober that loads a page in headless
+// Chrome, for synthetic monitoring of what end-users actually see.
+//
+// The package depends on chromedp and a Chrome binary, so it's only
+// built with the "browser" build tag:
+//
+//	go build -tags browser
+package browser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+
+	"hkjn.me/prober"
+)
+
+// Prober loads a page in headless Chrome and checks what was rendered.
+//
+// The Alert() part of the prober.Prober interface is provided by the
+// embedded AlertFn.
+type Prober struct {
+	prober.AlertFn
+	URL           string            // page to load
+	WaitVisible   []string          // CSS selectors that must become visible
+	Contains      map[string]string // CSS selectors to text that the selected element must contain
+	AllowJSErrors bool              // whether uncaught JavaScript exceptions are tolerated
+	Timeout       time.Duration     // how long to wait for the page, or 0 for one minute
+	// Options for starting Chrome, or nil for chromedp.DefaultExecAllocatorOptions.
+	AllocatorOptions []chromedp.ExecAllocatorOption
+}
+
+// Probe loads the page and checks its contents.
+func (p Prober) Probe() prober.Result {
+	timeout := p.ProbeTimeout()
+	opts := p.AllocatorOptions
+	if opts == nil {
+		opts = chromedp.DefaultExecAllocatorOptions[:]
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ctx, cancel = chromedp.NewExecAllocator(ctx, opts...)
+	defer cancel()
+	ctx, cancel = chromedp.NewContext(ctx)
+	defer cancel()
+
+	var (
+		jsErrors     []string
+		jsErrorsLock sync.Mutex
+	)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if ev, ok := ev.(*runtime.EventExceptionThrown); ok {
+			jsErrorsLock.Lock()
+			jsErrors = append(jsErrors, ev.ExceptionDetails.Error())
+			jsErrorsLock.Unlock()
+		}
+	})
+
+	actions := []chromedp.Action{chromedp.Navigate(p.URL)}
+	for _, sel := range p.WaitVisible {
+		actions = append(actions, chromedp.WaitVisible(sel))
+	}
+	texts := make(map[string]*string, len(p.Contains))
+	for sel := range p.Contains {
+		var text string
+		texts[sel] = &text
+		actions = append(actions, chromedp.Text(sel, &text))
+	}
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return prober.FailedWith(fmt.Errorf("failed to load %s: %v", p.URL, err))
+	}
+
+	for sel, want := range p.Contains {
+		if got := *texts[sel]; !strings.Contains(got, want) {
+			return prober.FailedWithInfo(
+				fmt.Errorf("%q on %s doesn't contain %q", sel, p.URL, want),
+				fmt.Sprintf("%q has text %q", sel, got),
+				p.URL)
+		}
+	}
+	jsErrorsLock.Lock()
+	defer jsErrorsLock.Unlock()
+	if len(jsErrors) > 0 && !p.AllowJSErrors {
+		return prober.FailedWithInfo(
+			fmt.Errorf("%d JavaScript errors on %s", len(jsErrors), p.URL),
+			strings.Join(jsErrors, "\n"),
+			p.URL)
+	}
+	return prober.PassedWith(fmt.Sprintf("%s rendered as expected", p.URL), p.URL)
+}
+
+// ProbeTimeout returns how long the prober waits for the page.
+func (p Prober) ProbeTimeout() time.Duration {
+	if p.Timeout == 0 {
+		return time.Minute
+	}
+	return p.Timeout
+}
@@ -0,0 +1,43 @@
+//go:build browser
+
+package browser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	"hkjn.me/prober"
+)
+
+func TestProber_ProbeTimeout(t *testing.T) {
+	cases := []struct {
+		in   Prober
+		want time.Duration
+	}{
+		{in: Prober{}, want: time.Minute},
+		{in: Prober{Timeout: 5 * time.Second}, want: 5 * time.Second},
+	}
+	for i, tt := range cases {
+		if got := tt.in.ProbeTimeout(); got != tt.want {
+			t.Errorf("[%d] ProbeTimeout() => %v; want %v\n", i, got, tt.want)
+		}
+	}
+}
+
+func TestProber_Probe_noChrome(t *testing.T) {
+	p := Prober{
+		URL:     "http://localhost/",
+		Timeout: 10 * time.Second,
+		AllocatorOptions: []chromedp.ExecAllocatorOption{
+			chromedp.ExecPath(filepath.Join(t.TempDir(), "no-chrome")),
+		},
+	}
+	got := p.Probe()
+	if got.Code != prober.Fail || !strings.Contains(got.Error.Error(), "failed to load http://localhost/") {
+		t.Errorf("Probe() without Chrome => %v; want failure to load the page\n", got)
+	}
+}
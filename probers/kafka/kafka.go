@@ -0,0 +1,109 @@
+//go:build kafka
+
+// Package kafka provides a prober that sends a message through a Kafka
+// topic and waits for it to come out the other end, for monitoring of
+// the whole produce/consume pipeline.
+//
+// The package depends on kafka-go, so it's only built with the "kafka"
+// build tag:
+//
+//	go build -tags kafka
+package kafka
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"hkjn.me/prober"
+)
+
+// Prober produces a message with a nonce to a partition of a test
+// topic, and checks that it can be consumed before the deadline.
+//
+// The Details of the result hold the time taken to produce the message
+// as "produce", the time until it was consumed as "end_to_end", and
+// its offset as "offset".
+//
+// The Alert() part of the prober.Prober interface is provided by the
+// embedded AlertFn.
+type Prober struct {
+	prober.AlertFn
+	Brokers   []string      // addresses of brokers, e.g. "kafka-0:9092"
+	Topic     string        // topic to send the message through, which should only be used for probing
+	Partition int           // partition of the topic to use
+	Deadline  time.Duration // how long the round-trip may take, or 0 for 10 seconds
+}
+
+// Probe sends a message through the topic.
+func (p Prober) Probe() prober.Result {
+	if len(p.Brokers) == 0 {
+		return prober.FailedWith(fmt.Errorf("no brokers to probe %s with", p.Topic))
+	}
+	deadline := p.ProbeTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return prober.FailedWith(fmt.Errorf("failed to create nonce: %v", err))
+	}
+	value := []byte("prober-" + hex.EncodeToString(nonce))
+
+	start := time.Now()
+	conn, err := kafka.DialLeader(ctx, "tcp", p.Brokers[0], p.Topic, p.Partition)
+	if err != nil {
+		return prober.FailedWith(fmt.Errorf("failed to connect to leader of %s/%d: %v", p.Topic, p.Partition, err))
+	}
+	defer conn.Close()
+	if d, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(d)
+	}
+	offset, err := conn.ReadLastOffset()
+	if err != nil {
+		return prober.FailedWith(fmt.Errorf("failed to read offset of %s/%d: %v", p.Topic, p.Partition, err))
+	}
+	if _, err := conn.WriteMessages(kafka.Message{Value: value}); err != nil {
+		return prober.FailedWith(fmt.Errorf("failed to produce to %s/%d: %v", p.Topic, p.Partition, err))
+	}
+	produced := time.Since(start)
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   p.Brokers,
+		Topic:     p.Topic,
+		Partition: p.Partition,
+		MaxWait:   100 * time.Millisecond,
+	})
+	defer r.Close()
+	if err := r.SetOffset(offset); err != nil {
+		return prober.FailedWith(fmt.Errorf("failed to seek to offset %d of %s/%d: %v", offset, p.Topic, p.Partition, err))
+	}
+	for {
+		m, err := r.ReadMessage(ctx)
+		if err != nil {
+			return prober.FailedWith(fmt.Errorf("message produced to %s/%d at offset %d or later was not consumed within %v: %v", p.Topic, p.Partition, offset, deadline, err))
+		}
+		if string(m.Value) != string(value) {
+			continue
+		}
+		res := prober.Passed()
+		res.Details = map[string]string{
+			"produce":    produced.String(),
+			"end_to_end": time.Since(start).String(),
+			"offset":     fmt.Sprint(m.Offset),
+		}
+		return res
+	}
+}
+
+// ProbeTimeout returns how long the round-trip may take.
+func (p Prober) ProbeTimeout() time.Duration {
+	if p.Deadline == 0 {
+		return 10 * time.Second
+	}
+	return p.Deadline
+}
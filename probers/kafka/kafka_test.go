@@ -0,0 +1,51 @@
+//go:build kafka
+
+package kafka
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+func TestProber_ProbeTimeout(t *testing.T) {
+	cases := []struct {
+		in   Prober
+		want time.Duration
+	}{
+		{in: Prober{}, want: 10 * time.Second},
+		{in: Prober{Deadline: time.Second}, want: time.Second},
+	}
+	for i, tt := range cases {
+		if got := tt.in.ProbeTimeout(); got != tt.want {
+			t.Errorf("[%d] ProbeTimeout() => %v; want %v\n", i, got, tt.want)
+		}
+	}
+}
+
+func TestProber_Probe_unreachable(t *testing.T) {
+	// Find an address nothing listens on.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	cases := []struct {
+		in      Prober
+		wantErr string
+	}{
+		{in: Prober{Topic: "probes"}, wantErr: "no brokers to probe probes with"},
+		{in: Prober{Brokers: []string{addr}, Topic: "probes", Deadline: 5 * time.Second}, wantErr: "failed to connect to leader of probes/0"},
+	}
+	for i, tt := range cases {
+		got := tt.in.Probe()
+		if got.Code != prober.Fail || !strings.Contains(got.Error.Error(), tt.wantErr) {
+			t.Errorf("[%d] Probe() => %v; want failure with %q\n", i, got, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+//go:build starlark
+
+// Package script provides a prober running checks written as Starlark
+// scripts, which can be loaded from config so that custom checks don't
+// need the prober binary to be rebuilt.
+//
+// The package depends on the Starlark interpreter, so it's only built
+// with the "starlark" build tag:
+//
+//	go build -tags starlark
+//
+// A script defines a probe() function, which fails the run by calling
+// fail() or with any other error, and otherwise passes it, optionally
+// returning a string for the Info of the result:
+//
+//	# Check that the API is up and fast, and finds things.
+//	def probe():
+//	    resp = http.get(config["base"] + "/health")
+//	    if resp.status != 200 or resp.latency > 0.3:
+//	        fail("health check returned %d in %gs" % (resp.status, resp.latency))
+//	    for q in ["shoes", "hats"]:
+//	        resp = http.post(config["base"] + "/v1/search", body=json.encode({"q": q}))
+//	        if not json.decode(resp.body)["results"]:
+//	            fail("no results for %s" % q)
+//	    detail("version", resp.headers.get("X-Version", "unknown"))
+//	    value(resp.latency)
+//	    return "API is up"
+//
+// Besides the Starlark builtins, scripts have:
+//
+//	config                      the Config of the Prober, e.g. its target
+//	http.get(url, headers={})   requests the URL, returning a response with
+//	http.post(url, body="",     status, body (up to the first MiB),
+//	          headers={})       headers and latency in seconds
+//	tcp.connect(addr)           connects to the address, returning the
+//	                            latency in seconds
+//	json.encode(x), json.decode(s)
+//	detail(name, value)         adds the value to the Details of the result
+//	value(x)                    sets the numeric Value of the result
+//
+// Scripts are sandboxed: they can only make the requests and
+// connections above, can't load other files, read files or run
+// commands, and are stopped after MaxSteps steps or when their run
+// times out.
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+
+	"hkjn.me/prober"
+)
+
+const (
+	// maxBody is how much of a response body scripts can see.
+	maxBody = 1 << 20
+	// DefaultMaxSteps is how many steps a run of a script may take,
+	// unless MaxSteps is set.
+	DefaultMaxSteps = 10000000
+)
+
+// predeclared is the names available to scripts besides the Starlark
+// builtins.
+var predeclared = map[string]bool{
+	"config": true,
+	"http":   true,
+	"tcp":    true,
+	"json":   true,
+	"detail": true,
+	"value":  true,
+}
+
+type (
+	// Script is a parsed script.
+	Script struct {
+		Name string // name of the script, e.g. its file, for errors
+		prog *starlark.Program
+	}
+
+	// Prober runs a script.
+	//
+	// The Alert() part of the prober.Prober interface is provided by
+	// the embedded AlertFn.
+	Prober struct {
+		prober.AlertFn
+		Script *Script
+		// Configuration available to the script as config, e.g.
+		// secrets or the target to probe. Values can be strings,
+		// numbers, booleans, and slices and maps of them, as decoded
+		// from JSON or YAML.
+		Config map[string]interface{}
+		// Client for http requests, or nil for http.DefaultClient.
+		Client *http.Client
+		// Most steps a run may take, or 0 for DefaultMaxSteps.
+		MaxSteps uint64
+	}
+
+	// run is the state of a run of a script.
+	run struct {
+		ctx    context.Context
+		client *http.Client
+		res    prober.Result
+	}
+)
+
+// Parse parses the source of a script with the name.
+func Parse(name, src string) (*Script, error) {
+	f, prog, err := starlark.SourceProgram(name, src, func(name string) bool { return predeclared[name] })
+	if err != nil {
+		return nil, err
+	}
+	hasProbe := false
+	for _, stmt := range f.Stmts {
+		switch stmt := stmt.(type) {
+		case *syntax.LoadStmt:
+			return nil, fmt.Errorf("%s: scripts can't load other files", stmt.Load)
+		case *syntax.DefStmt:
+			hasProbe = hasProbe || stmt.Name.Name == "probe"
+		}
+	}
+	if !hasProbe {
+		return nil, fmt.Errorf("%s: no probe() function", name)
+	}
+	return &Script{Name: name, prog: prog}, nil
+}
+
+// Load reads and parses the script in the file.
+func Load(path string) (*Script, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, string(b))
+}
+
+// Probe runs the script.
+func (p Prober) Probe() prober.Result {
+	return p.ProbeContext(context.Background())
+}
+
+// ProbeContext runs the script, stopping it when ctx is done.
+func (p Prober) ProbeContext(ctx context.Context) prober.Result {
+	r := &run{ctx: ctx, client: p.Client, res: prober.Passed()}
+	if r.client == nil {
+		r.client = http.DefaultClient
+	}
+	r.res.Details = map[string]string{}
+	config, err := toValue(p.Config)
+	if err != nil {
+		return prober.FailedWith(fmt.Errorf("bad config for %s: %v", p.Script.Name, err))
+	}
+
+	thread := &starlark.Thread{
+		Name:  p.Script.Name,
+		Print: func(_ *starlark.Thread, msg string) { log.Printf("[%s] %s\n", p.Script.Name, msg) },
+	}
+	steps := p.MaxSteps
+	if steps == 0 {
+		steps = DefaultMaxSteps
+	}
+	thread.SetMaxExecutionSteps(steps)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	// Each run gets its own globals, so runs don't share any state.
+	globals, err := p.Script.prog.Init(thread, r.predeclared(config))
+	if err != nil {
+		return r.failed(p.Script.Name, err)
+	}
+	ret, err := starlark.Call(thread, globals["probe"], nil, nil)
+	if err != nil {
+		return r.failed(p.Script.Name, err)
+	}
+	switch ret := ret.(type) {
+	case starlark.NoneType:
+	case starlark.String:
+		r.res.Info = string(ret)
+	default:
+		return r.failed(p.Script.Name, fmt.Errorf("probe() returned %s, not None or a string", ret.Type()))
+	}
+	return r.res
+}
+
+// failed returns the result of the run failing with the error.
+func (r *run) failed(name string, err error) prober.Result {
+	info := fmt.Sprintf("Script %s failed: %v", name, err)
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		info = fmt.Sprintf("Script %s failed:\n%s", name, evalErr.Backtrace())
+	}
+	return prober.Result{
+		Code:    prober.Fail,
+		Error:   fmt.Errorf("%s: %v", name, err),
+		Info:    info,
+		Details: r.res.Details,
+	}
+}
+
+// predeclared returns the names available to the script in the run.
+func (r *run) predeclared(config starlark.Value) starlark.StringDict {
+	return starlark.StringDict{
+		"config": config,
+		"http": &starlarkstruct.Module{
+			Name: "http",
+			Members: starlark.StringDict{
+				"get":  starlark.NewBuiltin("http.get", r.httpRequest),
+				"post": starlark.NewBuiltin("http.post", r.httpRequest),
+			},
+		},
+		"tcp": &starlarkstruct.Module{
+			Name:    "tcp",
+			Members: starlark.StringDict{"connect": starlark.NewBuiltin("tcp.connect", r.tcpConnect)},
+		},
+		"json":   json.Module,
+		"detail": starlark.NewBuiltin("detail", r.detail),
+		"value":  starlark.NewBuiltin("value", r.value),
+	}
+}
+
+// httpRequest implements http.get() and http.post().
+func (r *run) httpRequest(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		url, body string
+		headers   *starlark.Dict
+	)
+	method := http.MethodGet
+	if fn.Name() == "http.post" {
+		method = http.MethodPost
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "url", &url, "body?", &body, "headers?", &headers); err != nil {
+			return nil, err
+		}
+	} else if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "url", &url, "headers?", &headers); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(r.ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if headers != nil {
+		for _, item := range headers.Items() {
+			k, ok1 := starlark.AsString(item[0])
+			v, ok2 := starlark.AsString(item[1])
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("%s: headers must be strings, got %s: %s", fn.Name(), item[0].Type(), item[1].Type())
+			}
+			req.Header.Set(k, v)
+		}
+	}
+	if id := prober.RunID(r.ctx); id != "" && req.Header.Get(prober.RunIDHeader) == "" {
+		req.Header.Set(prober.RunIDHeader, id)
+	}
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %s %s: %v", method, url, err)
+	}
+	h := starlark.NewDict(len(resp.Header))
+	for k := range resp.Header {
+		h.SetKey(starlark.String(k), starlark.String(resp.Header.Get(k)))
+	}
+	return starlarkstruct.FromStringDict(starlark.String("response"), starlark.StringDict{
+		"status":  starlark.MakeInt(resp.StatusCode),
+		"body":    starlark.String(b),
+		"headers": h,
+		"latency": starlark.Float(time.Since(start).Seconds()),
+	}), nil
+}
+
+// tcpConnect implements tcp.connect().
+func (r *run) tcpConnect(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var addr string
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "addr", &addr); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(r.ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.Close()
+	return starlark.Float(time.Since(start).Seconds()), nil
+}
+
+// detail implements detail().
+func (r *run) detail(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		name string
+		v    starlark.Value
+	)
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "name", &name, "value", &v); err != nil {
+		return nil, err
+	}
+	if s, ok := starlark.AsString(v); ok {
+		r.res.Details[name] = s
+	} else {
+		r.res.Details[name] = v.String()
+	}
+	return starlark.None, nil
+}
+
+// value implements value().
+func (r *run) value(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var f starlark.Value
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "x", &f); err != nil {
+		return nil, err
+	}
+	x, ok := starlark.AsFloat(f)
+	if !ok {
+		return nil, fmt.Errorf("%s: got %s, want a number", fn.Name(), f.Type())
+	}
+	r.res = r.res.WithValue(x)
+	return starlark.None, nil
+}
+
+// toValue returns the Starlark value of v, which may be a string,
+// number, boolean, or a slice or map of them.
+func toValue(v interface{}) (starlark.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case string:
+		return starlark.String(v), nil
+	case bool:
+		return starlark.Bool(v), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case float64:
+		return starlark.Float(v), nil
+	case []interface{}:
+		l := make([]starlark.Value, len(v))
+		for i, e := range v {
+			sv, err := toValue(e)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = sv
+		}
+		return starlark.NewList(l), nil
+	case []string:
+		l := make([]starlark.Value, len(v))
+		for i, e := range v {
+			l[i] = starlark.String(e)
+		}
+		return starlark.NewList(l), nil
+	case map[string]interface{}:
+		d := starlark.NewDict(len(v))
+		for k, e := range v {
+			sv, err := toValue(e)
+			if err != nil {
+				return nil, err
+			}
+			d.SetKey(starlark.String(k), sv)
+		}
+		d.Freeze()
+		return d, nil
+	case map[string]string:
+		d := starlark.NewDict(len(v))
+		for k, e := range v {
+			d.SetKey(starlark.String(k), starlark.String(e))
+		}
+		d.Freeze()
+		return d, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", v)
+}
@@ -0,0 +1,151 @@
+//go:build starlark
+
+package script
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		src     string
+		wantErr string
+	}{
+		{"def probe():\n    pass\n", ""},
+		{"# no probe\nx = 1\n", "test: no probe() function"},
+		{"def probe(:\n", "test:1:12:"},
+		{"def probe():\n    return missing\n", "test:2:12: undefined: missing"},
+		{"load('other.star', 'x')\ndef probe():\n    pass\n", "test:1:1: scripts can't load other files"},
+	}
+	for i, tt := range cases {
+		_, err := Parse("test", tt.src)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)) {
+			t.Errorf("[%d] Parse(%q) => %v; want error %q\n", i, tt.src, err, tt.wantErr)
+		}
+	}
+}
+
+func TestProber_Probe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Version", "1.2.3")
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte(`{"results": 3}`))
+	}))
+	defer ts.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	cases := []struct {
+		src      string
+		want     prober.ResultCode
+		wantErr  string
+		wantInfo string
+		details  map[string]string
+		value    float64
+	}{
+		{
+			src: `
+def probe():
+    for q in ["shoes", "hats"]:
+        resp = http.post(config["url"], body=json.encode({"q": q}))
+        if resp.status != 200 or not json.decode(resp.body)["results"]:
+            fail("no results for %s" % q)
+    detail("version", resp.headers["X-Version"])
+    detail("size", len(resp.body))
+    value(json.decode(resp.body)["results"])
+    return "found things"
+`,
+			want:     prober.Pass,
+			wantInfo: "found things",
+			details:  map[string]string{"version": "1.2.3", "size": "14"},
+			value:    3,
+		},
+		{
+			src: `
+def probe():
+    resp = http.get(config["url"])
+    if resp.status != 200:
+        fail("got status %d" % resp.status)
+`,
+			want:    prober.Fail,
+			wantErr: "test: fail: got status 404",
+		},
+		{
+			src:  "def probe():\n    if tcp.connect(config[\"addr\"]) > 10:\n        fail(\"slow\")\n",
+			want: prober.Pass,
+		},
+		{
+			src:     "def probe():\n    value(\"3\")\n",
+			want:    prober.Fail,
+			wantErr: "test: value: got string, want a number",
+		},
+		{
+			src:     "def probe():\n    config[\"url\"] = \"elsewhere\"\n",
+			want:    prober.Fail,
+			wantErr: "test: cannot insert into frozen hash table",
+		},
+		{
+			src:     "def probe():\n    return 1\n",
+			want:    prober.Fail,
+			wantErr: "test: probe() returned int, not None or a string",
+		},
+	}
+	for i, tt := range cases {
+		s, err := Parse("test", tt.src)
+		if err != nil {
+			t.Fatalf("[%d] Parse() => %v", i, err)
+		}
+		p := Prober{Script: s, Config: map[string]interface{}{"url": ts.URL, "addr": l.Addr().String()}}
+		got := p.Probe()
+		if got.Code != tt.want {
+			t.Errorf("[%d] Probe() => %v (%v); want %v\n", i, got.Code, got.Error, tt.want)
+		}
+		if tt.wantErr != "" && (got.Error == nil || !strings.HasPrefix(got.Error.Error(), tt.wantErr)) {
+			t.Errorf("[%d] Probe() => error %v; want %s\n", i, got.Error, tt.wantErr)
+		}
+		if tt.wantInfo != "" && got.Info != tt.wantInfo {
+			t.Errorf("[%d] Probe() => Info %q; want %q\n", i, got.Info, tt.wantInfo)
+		}
+		for k, v := range tt.details {
+			if got.Details[k] != v {
+				t.Errorf("[%d] Probe() => Details[%q] %q; want %q\n", i, k, got.Details[k], v)
+			}
+		}
+		if tt.value != 0 && (got.Value == nil || *got.Value != tt.value) {
+			t.Errorf("[%d] Probe() => Value %v; want %g\n", i, got.Value, tt.value)
+		}
+	}
+}
+
+func TestProber_Probe_limits(t *testing.T) {
+	s, err := Parse("test", "def probe():\n    for i in range(1000000000):\n        pass\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Prober{Script: s, MaxSteps: 1000}
+	if got := p.Probe(); got.Code != prober.Fail || !strings.Contains(got.Error.Error(), "too many steps") {
+		t.Errorf("Probe() of endless loop with MaxSteps => %v; want failure with too many steps\n", got)
+	}
+
+	// Without a step limit, the run is still stopped when it times out.
+	p = Prober{Script: s, MaxSteps: 1 << 62}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if got := p.ProbeContext(ctx); got.Code != prober.Fail || !strings.Contains(got.Error.Error(), "context deadline exceeded") {
+		t.Errorf("ProbeContext() of endless loop => %v; want failure with deadline exceeded\n", got)
+	}
+}
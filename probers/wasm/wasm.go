@@ -0,0 +1,264 @@
+//go:build wazero
+
+// Package wasm provides a prober running probes compiled to WebAssembly
+// modules, so that teams can write probes in any language that compiles
+// to WebAssembly and ship them as files, without rebuilding the prober
+// binary.
+//
+// The package depends on wazero, so it's only built with the "wazero"
+// build tag:
+//
+//	go build -tags wazero
+//
+// A module implements the probe ABI by exporting its memory and two
+// functions:
+//
+//	alloc(size i32) i32           returns a buffer of size bytes
+//	probe(ptr i32, size i32) i64  runs the probe
+//
+// The prober allocates a buffer with alloc, writes the Config of the
+// Prober there as JSON, and calls probe with it. probe returns the
+// address of the JSON result in the high 32 bits and its length in the
+// low 32 bits, e.g.
+//
+//	{"code": "fail", "error": "queue too long", "value": 1200, "details": {"queue": "jobs"}}
+//
+// where code is "pass", "fail" or "degraded", and all other fields are
+// optional. Modules can also import log(ptr i32, size i32) from the
+// "prober" module to log a message, and WASI for the standard library
+// of languages like TinyGo or Rust, but get no access to files or the
+// network beyond that.
+//
+// The module is loaded again when its file changes, so new versions of
+// a probe are picked up by the next run.
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"hkjn.me/prober"
+)
+
+// defaultMemoryPages is the memory limit of modules in 64 KiB pages,
+// unless MemoryLimit is set.
+const defaultMemoryPages = 256
+
+type (
+	// Prober runs a probe compiled to a WebAssembly module.
+	//
+	// The Alert() part of the prober.Prober interface is provided by
+	// the embedded AlertFn.
+	Prober struct {
+		prober.AlertFn
+		Path   string      // path of the .wasm file
+		Config interface{} // configuration passed to the probe as JSON, e.g. its target
+		// Maximum memory of the module in bytes, or 0 for 16 MiB.
+		MemoryLimit int
+		plugin      *plugin
+	}
+
+	// plugin is the loaded module of a Prober, shared by its copies.
+	plugin struct {
+		sync.RWMutex
+		runtime  wazero.Runtime
+		compiled wazero.CompiledModule
+		modTime  time.Time // modification time of the file when it was compiled
+	}
+
+	// result is the JSON result of the probe ABI.
+	result struct {
+		Code    string            `json:"code"`
+		Error   string            `json:"error"`
+		Info    string            `json:"info"`
+		InfoURL string            `json:"info_url"`
+		Value   *float64          `json:"value"`
+		Weight  float64           `json:"weight"`
+		Details map[string]string `json:"details"`
+	}
+)
+
+// Load returns a Prober running the module in the file, compiling it
+// up front to catch errors in it when the probe is set up.
+func Load(path string, config interface{}) (*Prober, error) {
+	p := &Prober{Path: path, Config: config}
+	if err := p.load(context.Background()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// load compiles the module if it's not been compiled yet or its file
+// has changed since.
+func (p *Prober) load(ctx context.Context) error {
+	if p.plugin == nil {
+		pages := uint32(defaultMemoryPages)
+		if p.MemoryLimit > 0 {
+			pages = uint32((p.MemoryLimit + 65535) / 65536)
+		}
+		rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+			WithMemoryLimitPages(pages).
+			WithCloseOnContextDone(true))
+		if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+			return fmt.Errorf("failed to set up WASI for %s: %v", p.Path, err)
+		}
+		_, err := rt.NewHostModuleBuilder("prober").
+			NewFunctionBuilder().WithFunc(p.log).Export("log").
+			Instantiate(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to set up host functions for %s: %v", p.Path, err)
+		}
+		p.plugin = &plugin{runtime: rt}
+	}
+	fi, err := os.Stat(p.Path)
+	if err != nil {
+		return err
+	}
+	pl := p.plugin
+	pl.RLock()
+	current := pl.compiled != nil && fi.ModTime().Equal(pl.modTime)
+	pl.RUnlock()
+	if current {
+		return nil
+	}
+
+	pl.Lock()
+	defer pl.Unlock()
+	if pl.compiled != nil && fi.ModTime().Equal(pl.modTime) {
+		return nil
+	}
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return err
+	}
+	compiled, err := pl.runtime.CompileModule(ctx, b)
+	if err != nil {
+		return fmt.Errorf("failed to compile %s: %v", p.Path, err)
+	}
+	for _, name := range []string{"alloc", "probe"} {
+		if _, ok := compiled.ExportedFunctions()[name]; !ok {
+			compiled.Close(ctx)
+			return fmt.Errorf("%s doesn't export %s()", p.Path, name)
+		}
+	}
+	if pl.compiled != nil {
+		log.Printf("reloaded %s, which changed at %v\n", p.Path, fi.ModTime())
+		pl.compiled.Close(ctx)
+	}
+	pl.compiled, pl.modTime = compiled, fi.ModTime()
+	return nil
+}
+
+// log is the log() host function of modules.
+func (p *Prober) log(_ context.Context, m api.Module, ptr, size uint32) {
+	b, ok := m.Memory().Read(ptr, size)
+	if !ok {
+		log.Printf("[%s] log() out of bounds of memory\n", p.Path)
+		return
+	}
+	log.Printf("[%s] %s\n", p.Path, b)
+}
+
+// Probe runs the module.
+func (p *Prober) Probe() prober.Result {
+	return p.ProbeContext(context.Background())
+}
+
+// ProbeContext runs the module, stopping it when ctx is done.
+func (p *Prober) ProbeContext(ctx context.Context) prober.Result {
+	if err := p.load(ctx); err != nil {
+		if p.plugin == nil || p.plugin.compiled == nil {
+			return prober.FailedWith(err)
+		}
+		// Keep running the last good version of the module.
+		log.Printf("failed to reload %s, running the previous version: %v\n", p.Path, err)
+	}
+	config, err := json.Marshal(p.Config)
+	if err != nil {
+		return prober.FailedWith(fmt.Errorf("failed to encode config of %s: %v", p.Path, err))
+	}
+
+	p.plugin.RLock()
+	defer p.plugin.RUnlock()
+	// Each run gets a new instance of the module, so runs don't share
+	// any state.
+	mod, err := p.plugin.runtime.InstantiateModule(ctx, p.plugin.compiled, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return prober.FailedWith(fmt.Errorf("failed to instantiate %s: %v", p.Path, err))
+	}
+	defer mod.Close(ctx)
+
+	out, err := call(ctx, mod, config)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%v: %v", ctx.Err(), err)
+		}
+		return prober.FailedWith(fmt.Errorf("%s: %v", p.Path, err))
+	}
+	var r result
+	if err := json.Unmarshal(out, &r); err != nil {
+		return prober.FailedWith(fmt.Errorf("%s returned bad result %q: %v", p.Path, out, err))
+	}
+	return r.toResult()
+}
+
+// call calls probe() of the module with the config, returning the
+// result it wrote to memory.
+func call(ctx context.Context, mod api.Module, config []byte) ([]byte, error) {
+	ret, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(config)))
+	if err != nil {
+		return nil, fmt.Errorf("alloc() failed: %v", err)
+	}
+	ptr := uint32(ret[0])
+	if !mod.Memory().Write(ptr, config) {
+		return nil, fmt.Errorf("alloc() returned %d, out of bounds of memory", ptr)
+	}
+	ret, err = mod.ExportedFunction("probe").Call(ctx, uint64(ptr), uint64(len(config)))
+	if err != nil {
+		return nil, fmt.Errorf("probe() failed: %v", err)
+	}
+	out, ok := mod.Memory().Read(uint32(ret[0]>>32), uint32(ret[0]))
+	if !ok {
+		return nil, fmt.Errorf("probe() returned result out of bounds of memory")
+	}
+	// The memory goes away with the module, so copy the result.
+	return append([]byte{}, out...), nil
+}
+
+// toResult returns the prober.Result the result describes.
+func (r result) toResult() prober.Result {
+	res := prober.Result{
+		Info:    r.Info,
+		InfoUrl: r.InfoURL,
+		Details: r.Details,
+		Weight:  r.Weight,
+		Value:   r.Value,
+	}
+	switch r.Code {
+	case "pass":
+		res.Code = prober.Pass
+	case "degraded":
+		res.Code = prober.Degraded
+	case "fail":
+		res.Code = prober.Fail
+	default:
+		res.Code = prober.Fail
+		r.Error = fmt.Sprintf("unknown code %q in result: %s", r.Code, r.Error)
+	}
+	if r.Error != "" {
+		res.Error = errors.New(r.Error)
+	} else if res.Code != prober.Pass {
+		res.Error = fmt.Errorf("probe %s without an error", r.Code)
+	}
+	return res
+}
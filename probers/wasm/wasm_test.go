@@ -0,0 +1,186 @@
+//go:build wazero
+
+package wasm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+// resultAddr is where modules built by testModule keep their result.
+const resultAddr = 16
+
+// testModule returns a module implementing the probe ABI, which logs
+// and returns the result, and exports the named functions of alloc and
+// probe.
+func testModule(result string, exports ...string) []byte {
+	section := func(id byte, content ...[]byte) []byte {
+		var b []byte
+		for _, c := range content {
+			b = append(b, c...)
+		}
+		return append(append([]byte{id}, uleb(uint64(len(b)))...), b...)
+	}
+	name := func(s string) []byte { return append(uleb(uint64(len(s))), s...) }
+
+	types := section(1,
+		[]byte{3},
+		[]byte{0x60, 1, 0x7f, 1, 0x7f},       // 0: (i32) -> i32
+		[]byte{0x60, 2, 0x7f, 0x7f, 1, 0x7e}, // 1: (i32, i32) -> i64
+		[]byte{0x60, 2, 0x7f, 0x7f, 0},       // 2: (i32, i32) -> ()
+	)
+	imports := section(2, []byte{1}, name("prober"), name("log"), []byte{0, 2})
+	funcs := section(3, []byte{2, 0, 1})
+	memory := section(5, []byte{1, 0, 1})
+	exps := [][]byte{uleb(uint64(len(exports) + 1)), name("memory"), {2, 0}}
+	for _, e := range exports {
+		idx := map[string]byte{"alloc": 1, "probe": 2}[e]
+		exps = append(exps, name(e), []byte{0, idx})
+	}
+	exportSec := section(7, exps...)
+
+	// alloc always returns the same buffer after the result, and probe
+	// logs the result and returns its address and length.
+	alloc := append([]byte{0, 0x41}, sleb(1024)...)
+	alloc = append(alloc, 0x0b)
+	probe := append([]byte{0, 0x41}, sleb(resultAddr)...)
+	probe = append(append(probe, 0x41), sleb(int64(len(result)))...)
+	probe = append(append(probe, 0x10, 0, 0x42), sleb(resultAddr<<32|int64(len(result)))...)
+	probe = append(probe, 0x0b)
+	code := section(10, []byte{2}, uleb(uint64(len(alloc))), alloc, uleb(uint64(len(probe))), probe)
+
+	offset := append(append([]byte{0, 0x41}, sleb(resultAddr)...), 0x0b)
+	data := section(11, []byte{1}, offset, name(result))
+
+	mod := []byte{0, 'a', 's', 'm', 1, 0, 0, 0}
+	for _, s := range [][]byte{types, imports, funcs, memory, exportSec, code, data} {
+		mod = append(mod, s...)
+	}
+	return mod
+}
+
+// uleb returns v encoded as unsigned LEB128.
+func uleb(v uint64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// sleb returns v encoded as signed LEB128.
+func sleb(v int64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 && c&0x40 == 0 || v == -1 && c&0x40 != 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// writeModule writes the module to the path, with the
+// modification time.
+func writeModule(t *testing.T, path string, mod []byte, modTime time.Time) {
+	if err := os.WriteFile(path, mod, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProber_Probe(t *testing.T) {
+	cases := []struct {
+		result    string
+		want      prober.ResultCode
+		wantErr   string
+		wantValue float64
+	}{
+		{result: `{"code": "pass", "value": 42}`, want: prober.Pass, wantValue: 42},
+		{result: `{"code": "degraded", "error": "slow"}`, want: prober.Degraded, wantErr: "slow"},
+		{result: `{"code": "fail", "error": "queue too long", "details": {"queue": "jobs"}}`, want: prober.Fail, wantErr: "queue too long"},
+		{result: `{"code": "fail"}`, want: prober.Fail, wantErr: "probe fail without an error"},
+		{result: `{"code": "maybe"}`, want: prober.Fail, wantErr: `unknown code "maybe"`},
+		{result: `not json`, want: prober.Fail, wantErr: "bad result"},
+	}
+	dir := t.TempDir()
+	for i, tt := range cases {
+		path := filepath.Join(dir, "probe.wasm")
+		writeModule(t, path, testModule(tt.result, "alloc", "probe"), time.Now().Add(time.Duration(i)*time.Second))
+		p, err := Load(path, map[string]string{"queue": "jobs"})
+		if err != nil {
+			t.Fatalf("[%d] Load() => %v; want nil\n", i, err)
+		}
+		got := p.Probe()
+		if got.Code != tt.want {
+			t.Errorf("[%d] Probe() of module returning %s => %v; want code %v\n", i, tt.result, got, tt.want)
+		}
+		if tt.wantErr != "" && (got.Error == nil || !strings.Contains(got.Error.Error(), tt.wantErr)) {
+			t.Errorf("[%d] Probe() of module returning %s => %v; want error with %q\n", i, tt.result, got, tt.wantErr)
+		}
+		if tt.wantValue != 0 && (got.Value == nil || *got.Value != tt.wantValue) {
+			t.Errorf("[%d] Probe() of module returning %s => %v; want value %g\n", i, tt.result, got, tt.wantValue)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		mod     []byte
+		wantErr string
+	}{
+		{mod: testModule(`{"code": "pass"}`, "alloc", "probe")},
+		{mod: testModule(`{"code": "pass"}`, "alloc"), wantErr: "doesn't export probe()"},
+		{mod: testModule(`{"code": "pass"}`, "probe"), wantErr: "doesn't export alloc()"},
+		{mod: []byte("not wasm"), wantErr: "failed to compile"},
+	}
+	for i, tt := range cases {
+		path := filepath.Join(dir, "probe.wasm")
+		writeModule(t, path, tt.mod, time.Now())
+		_, err := Load(path, nil)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("[%d] Load() => %v; want error with %q\n", i, err, tt.wantErr)
+		}
+	}
+	if _, err := Load(filepath.Join(dir, "missing.wasm"), nil); err == nil {
+		t.Errorf("Load() of missing file => nil; want error\n")
+	}
+}
+
+func TestProber_reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probe.wasm")
+	start := time.Now()
+	writeModule(t, path, testModule(`{"code": "pass"}`, "alloc", "probe"), start)
+	p, err := Load(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Probe(); got.Code != prober.Pass {
+		t.Fatalf("Probe() => %v; want pass\n", got)
+	}
+
+	// A changed module is picked up by the next run.
+	writeModule(t, path, testModule(`{"code": "fail", "error": "new version"}`, "alloc", "probe"), start.Add(time.Second))
+	if got := p.Probe(); got.Code != prober.Fail || !strings.Contains(got.Error.Error(), "new version") {
+		t.Errorf("Probe() after change => %v; want failure from new version\n", got)
+	}
+
+	// A broken module keeps the last good version running.
+	writeModule(t, path, []byte("not wasm"), start.Add(2*time.Second))
+	if got := p.Probe(); got.Code != prober.Fail || !strings.Contains(got.Error.Error(), "new version") {
+		t.Errorf("Probe() after breaking change => %v; want failure from last good version\n", got)
+	}
+}
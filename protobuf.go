@@ -0,0 +1,434 @@
+package prober
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Protobuf encodes records as the messages defined in
+// proto/prober.proto, each prefixed by its length as a varint so that
+// many can be written to one log file. It supports Record, Snapshot and
+// records shipped to an Aggregator.
+var Protobuf Codec = protobufCodec{}
+
+// Wire types of protobuf fields.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+type (
+	// protobufCodec encodes values as length-delimited protobuf
+	// messages.
+	protobufCodec struct{}
+
+	// protoField is a field of a decoded protobuf message.
+	protoField struct {
+		num   int
+		wire  int
+		x     uint64 // value of varint and fixed fields
+		bytes []byte // value of length-delimited fields
+	}
+)
+
+func init() {
+	RegisterCodec(Protobuf)
+}
+
+func (protobufCodec) Name() string        { return "protobuf" }
+func (protobufCodec) ContentType() string { return "application/x-protobuf" }
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	var msg []byte
+	switch v := v.(type) {
+	case Record:
+		msg = v.MarshalProto()
+	case *Record:
+		msg = v.MarshalProto()
+	case Snapshot:
+		msg = v.MarshalProto()
+	case *Snapshot:
+		msg = v.MarshalProto()
+	case shippedRecord:
+		msg = v.marshalProto()
+	default:
+		return nil, fmt.Errorf("can't encode %T as protobuf", v)
+	}
+	return append(appendUvarint(nil, uint64(len(msg))), msg...), nil
+}
+
+func (protobufCodec) Unmarshal(b []byte, v interface{}) error {
+	n, l := binary.Uvarint(b)
+	if l <= 0 || uint64(len(b)-l) < n {
+		return errors.New("bad length of protobuf message")
+	}
+	msg := b[l : l+int(n)]
+	switch v := v.(type) {
+	case *Record:
+		return v.UnmarshalProto(msg)
+	case *Snapshot:
+		return v.UnmarshalProto(msg)
+	case *shippedRecord:
+		return v.unmarshalProto(msg)
+	default:
+		return fmt.Errorf("can't decode protobuf into %T", v)
+	}
+}
+
+// MarshalProto returns the Result encoded as the Result message of
+// proto/prober.proto.
+func (r Result) MarshalProto() []byte {
+	var b []byte
+	b = appendProtoVarint(b, 1, uint64(r.Code))
+	if r.Error != nil {
+		b = appendProtoString(b, 2, r.Error.Error())
+	}
+	b = appendProtoString(b, 3, r.Info)
+	b = appendProtoString(b, 4, r.InfoUrl)
+	b = appendProtoMap(b, 5, r.Details)
+	if r.Weight != 0 {
+		b = appendProtoDouble(b, 6, r.Weight)
+	}
+	if r.Value != nil {
+		b = appendProtoDouble(b, 7, *r.Value)
+	}
+	return b
+}
+
+// UnmarshalProto sets the Result from its encoding as the Result
+// message of proto/prober.proto.
+func (r *Result) UnmarshalProto(b []byte) error {
+	*r = Result{}
+	return parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			if f.x >= uint64(len(results)) {
+				return fmt.Errorf("unknown result code %d", f.x)
+			}
+			r.Code = ResultCode(f.x)
+		case 2:
+			r.Error = errors.New(string(f.bytes))
+		case 3:
+			r.Info = string(f.bytes)
+		case 4:
+			r.InfoUrl = string(f.bytes)
+		case 5:
+			if r.Details == nil {
+				r.Details = map[string]string{}
+			}
+			return parseProtoMapEntry(f.bytes, r.Details)
+		case 6:
+			r.Weight = math.Float64frombits(f.x)
+		case 7:
+			v := math.Float64frombits(f.x)
+			r.Value = &v
+		}
+		return nil
+	})
+}
+
+// MarshalProto returns the Record encoded as the Record message of
+// proto/prober.proto.
+func (r Record) MarshalProto() []byte {
+	var b []byte
+	b = appendProtoTime(b, 1, r.Timestamp)
+	b = appendProtoString(b, 2, r.Location)
+	b = appendProtoMessage(b, 3, r.Result.MarshalProto())
+	b = appendProtoVarint(b, 4, uint64(r.Repeats))
+	b = appendProtoTime(b, 5, r.Until)
+	b = appendProtoMap(b, 6, r.Annotations)
+	b = appendProtoString(b, 7, r.RunID)
+	return b
+}
+
+// UnmarshalProto sets the Record from its encoding as the Record
+// message of proto/prober.proto.
+func (r *Record) UnmarshalProto(b []byte) error {
+	*r = Record{}
+	err := parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			r.Timestamp = protoTime(f.x)
+		case 2:
+			r.Location = string(f.bytes)
+		case 3:
+			return r.Result.UnmarshalProto(f.bytes)
+		case 4:
+			r.Repeats = int(int32(f.x))
+		case 5:
+			r.Until = protoTime(f.x)
+		case 6:
+			if r.Annotations == nil {
+				r.Annotations = map[string]string{}
+			}
+			return parseProtoMapEntry(f.bytes, r.Annotations)
+		case 7:
+			r.RunID = string(f.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !r.Timestamp.IsZero() {
+		r.TimeMillis = TimeFormat{}.Format(r.Timestamp)
+	}
+	if r.Repeats > 0 {
+		r.UntilMillis = TimeFormat{}.Format(r.Until)
+	}
+	return nil
+}
+
+// MarshalProto returns the Snapshot encoded as the Probe message of
+// proto/prober.proto. Its scheduler stats and failure history aren't
+// included.
+func (s Snapshot) MarshalProto() []byte {
+	var b []byte
+	b = appendProtoString(b, 1, s.Name)
+	b = appendProtoString(b, 2, s.Desc)
+	b = appendProtoString(b, 3, s.Location)
+	b = appendProtoMap(b, 4, s.Labels)
+	b = appendProtoVarint(b, 5, uint64(s.Interval))
+	b = appendProtoBool(b, 6, s.Disabled)
+	b = appendProtoTime(b, 7, s.SilencedUntil)
+	b = appendProtoVarint(b, 8, uint64(int64(s.Badness)))
+	b = appendProtoVarint(b, 9, uint64(int64(s.FailurePenalty)))
+	b = appendProtoVarint(b, 10, uint64(int64(s.SuccessReward)))
+	b = appendProtoBool(b, 11, s.Alerting)
+	b = appendProtoTime(b, 12, s.LastAlert)
+	b = appendProtoTime(b, 13, s.LastSuccess)
+	b = appendProtoTime(b, 14, s.LastFailure)
+	for _, r := range s.Records {
+		b = appendProtoMessage(b, 15, r.MarshalProto())
+	}
+	return b
+}
+
+// UnmarshalProto sets the Snapshot from its encoding as the Probe
+// message of proto/prober.proto.
+func (s *Snapshot) UnmarshalProto(b []byte) error {
+	*s = Snapshot{}
+	return parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			s.Name = string(f.bytes)
+		case 2:
+			s.Desc = string(f.bytes)
+		case 3:
+			s.Location = string(f.bytes)
+		case 4:
+			if s.Labels == nil {
+				s.Labels = map[string]string{}
+			}
+			return parseProtoMapEntry(f.bytes, s.Labels)
+		case 5:
+			s.Interval = time.Duration(f.x)
+		case 6:
+			s.Disabled = f.x != 0
+		case 7:
+			s.SilencedUntil = protoTime(f.x)
+		case 8:
+			s.Badness = int(int32(f.x))
+		case 9:
+			s.FailurePenalty = int(int32(f.x))
+		case 10:
+			s.SuccessReward = int(int32(f.x))
+		case 11:
+			s.Alerting = f.x != 0
+		case 12:
+			s.LastAlert = protoTime(f.x)
+		case 13:
+			s.LastSuccess = protoTime(f.x)
+		case 14:
+			s.LastFailure = protoTime(f.x)
+		case 15:
+			var r Record
+			if err := r.UnmarshalProto(f.bytes); err != nil {
+				return err
+			}
+			s.Records = append(s.Records, r)
+		}
+		return nil
+	})
+}
+
+// marshalProto returns the shipped record encoded as the ShippedRecord
+// message of proto/prober.proto.
+func (sr shippedRecord) marshalProto() []byte {
+	b := appendProtoString(nil, 1, sr.Probe)
+	return appendProtoMessage(b, 2, sr.record().MarshalProto())
+}
+
+// unmarshalProto sets the shipped record from its encoding as the
+// ShippedRecord message of proto/prober.proto.
+func (sr *shippedRecord) unmarshalProto(b []byte) error {
+	var (
+		name string
+		r    Record
+	)
+	err := parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			name = string(f.bytes)
+		case 2:
+			return r.UnmarshalProto(f.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*sr = r.shipped(name)
+	return nil
+}
+
+// appendUvarint appends x as a varint.
+func appendUvarint(b []byte, x uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], x)]...)
+}
+
+// appendUint64 appends x in little-endian order.
+func appendUint64(b []byte, x uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], x)
+	return append(b, buf[:]...)
+}
+
+// appendProtoTag appends the tag of the field.
+func appendProtoTag(b []byte, num, wire int) []byte {
+	return appendUvarint(b, uint64(num)<<3|uint64(wire))
+}
+
+// appendProtoVarint appends the varint field, unless it's zero.
+func appendProtoVarint(b []byte, num int, x uint64) []byte {
+	if x == 0 {
+		return b
+	}
+	return appendUvarint(appendProtoTag(b, num, wireVarint), x)
+}
+
+// appendProtoBool appends the bool field, unless it's false.
+func appendProtoBool(b []byte, num int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendProtoVarint(b, num, 1)
+}
+
+// appendProtoDouble appends the double field.
+func appendProtoDouble(b []byte, num int, v float64) []byte {
+	return appendUint64(appendProtoTag(b, num, wireFixed64), math.Float64bits(v))
+}
+
+// appendProtoTime appends the time as an int64 field of nanoseconds
+// since the Unix epoch, unless it's the zero time.
+func appendProtoTime(b []byte, num int, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	return appendProtoVarint(b, num, uint64(t.UnixNano()))
+}
+
+// appendProtoString appends the string field, unless it's empty.
+func appendProtoString(b []byte, num int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendProtoMessage(b, num, []byte(s))
+}
+
+// appendProtoMessage appends the length-delimited field.
+func appendProtoMessage(b []byte, num int, msg []byte) []byte {
+	b = appendUvarint(appendProtoTag(b, num, wireBytes), uint64(len(msg)))
+	return append(b, msg...)
+}
+
+// appendProtoMap appends the map<string, string> field, with its
+// entries sorted by key so the encoding is deterministic.
+func appendProtoMap(b []byte, num int, m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		entry := appendProtoMessage(nil, 1, []byte(k))
+		entry = appendProtoMessage(entry, 2, []byte(m[k]))
+		b = appendProtoMessage(b, num, entry)
+	}
+	return b
+}
+
+// protoTime returns the time of the nanoseconds since the Unix epoch,
+// or the zero time for 0.
+func protoTime(x uint64) time.Time {
+	if x == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(x))
+}
+
+// parseProtoMapEntry adds the encoded map entry to m.
+func parseProtoMapEntry(b []byte, m map[string]string) error {
+	var k, v string
+	err := parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			k = string(f.bytes)
+		case 2:
+			v = string(f.bytes)
+		}
+		return nil
+	})
+	m[k] = v
+	return err
+}
+
+// parseProto calls fn with each field of the encoded message, skipping
+// fields of unknown wire types.
+func parseProto(b []byte, fn func(protoField) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("bad protobuf field tag")
+		}
+		b = b[n:]
+		f := protoField{num: int(tag >> 3), wire: int(tag & 7)}
+		switch f.wire {
+		case wireVarint:
+			if f.x, n = binary.Uvarint(b); n <= 0 {
+				return fmt.Errorf("bad varint in protobuf field %d", f.num)
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return fmt.Errorf("short protobuf field %d", f.num)
+			}
+			f.x, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return fmt.Errorf("short protobuf field %d", f.num)
+			}
+			f.x, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return fmt.Errorf("bad length of protobuf field %d", f.num)
+			}
+			f.bytes, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return fmt.Errorf("unsupported wire type %d of protobuf field %d", f.wire, f.num)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package prober
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResult_MarshalProto(t *testing.T) {
+	// Same as protoc-generated code encodes Result{code: FAIL, info: "x"}.
+	r := Result{Code: Fail, Info: "x"}
+	if got, want := r.MarshalProto(), []byte{0x08, 0x01, 0x1a, 0x01, 'x'}; !bytes.Equal(got, want) {
+		t.Errorf("MarshalProto() => % x; want % x\n", got, want)
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	ts := time.Date(1998, 11, 19, 15, 14, 0, 0, time.Local)
+	v := 42.5
+	rec := Record{
+		Timestamp:  ts,
+		TimeMillis: ts.Format(time.RFC3339Nano),
+		Location:   "eu",
+		Result: Result{
+			Code:    Degraded,
+			Error:   errors.New("2 of 3 endpoints are up"),
+			Info:    "Partial outage",
+			InfoUrl: "https://example.com/runbook",
+			Details: map[string]string{"b": "2", "a": "1"},
+			Weight:  0.5,
+			Value:   &v,
+		},
+		Repeats:     2,
+		Until:       ts.Add(time.Minute),
+		UntilMillis: ts.Add(time.Minute).Format(time.RFC3339Nano),
+		RunID:       "0123456789abcdef",
+		Annotations: map[string]string{"version": "1.2.3"},
+	}
+
+	b, err := Protobuf.Marshal(rec)
+	if err != nil {
+		t.Fatalf("Marshal(Record) => %v; want nil\n", err)
+	}
+	var gotRec Record
+	if err := Protobuf.Unmarshal(b, &gotRec); err != nil {
+		t.Fatalf("Unmarshal(Record) => %v; want nil\n", err)
+	}
+	if !gotRec.Equal(rec) || gotRec.RunID != rec.RunID || gotRec.Result.Weight != 0.5 || *gotRec.Result.Value != v {
+		t.Errorf("Record round trip => %+v; want %+v\n", gotRec, rec)
+	}
+
+	s := Snapshot{
+		Name:          "ProtoProber",
+		Labels:        map[string]string{"env": "prod"},
+		Interval:      time.Minute,
+		SilencedUntil: ts,
+		Badness:       30,
+		Alerting:      true,
+		Records:       Records{rec, {Timestamp: ts, TimeMillis: ts.Format(time.RFC3339Nano), Result: Passed()}},
+	}
+	b, err = Protobuf.Marshal(&s)
+	if err != nil {
+		t.Fatalf("Marshal(Snapshot) => %v; want nil\n", err)
+	}
+	var gotSnap Snapshot
+	if err := Protobuf.Unmarshal(b, &gotSnap); err != nil {
+		t.Fatalf("Unmarshal(Snapshot) => %v; want nil\n", err)
+	}
+	if gotSnap.Name != s.Name || gotSnap.Labels["env"] != "prod" || gotSnap.Interval != s.Interval ||
+		!gotSnap.SilencedUntil.Equal(ts) || gotSnap.Badness != 30 || !gotSnap.Alerting || !gotSnap.Records.Equal(s.Records) {
+		t.Errorf("Snapshot round trip => %+v; want %+v\n", gotSnap, s)
+	}
+
+	sr := rec.shipped("ProtoProber")
+	b, err = Protobuf.Marshal(sr)
+	if err != nil {
+		t.Fatalf("Marshal(shippedRecord) => %v; want nil\n", err)
+	}
+	var gotSR shippedRecord
+	if err := Protobuf.Unmarshal(b, &gotSR); err != nil {
+		t.Fatalf("Unmarshal(shippedRecord) => %v; want nil\n", err)
+	}
+	if gotSR.Probe != "ProtoProber" || !gotSR.record().Equal(sr.record()) {
+		t.Errorf("shippedRecord round trip => %+v; want %+v\n", gotSR, sr)
+	}
+
+	if _, err := Protobuf.Marshal("not a record"); err == nil {
+		t.Errorf("Marshal(string) => nil; want error\n")
+	}
+	if err := Protobuf.Unmarshal([]byte{0x05, 0x08}, &gotRec); err == nil {
+		t.Errorf("Unmarshal(truncated) => nil; want error\n")
+	}
+	if err := Protobuf.Unmarshal([]byte{0x02, 0x08, 0x07}, &gotRec.Result); err == nil {
+		t.Errorf("Unmarshal(*Result) => nil; want error for unsupported type\n")
+	}
+}
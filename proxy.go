@@ -0,0 +1,97 @@
+package prober
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	proxyTransports     = map[string]*http.Transport{} // transports for each proxy URL
+	proxyTransportsLock sync.Mutex                     // protects proxyTransports
+)
+
+// dialFunc is a function that opens network connections.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// proxyClient returns a client that sends its requests through the
+// proxy.
+//
+// Clients for the same proxy share a transport, so connections to
+// the proxy can be reused across probe runs.
+func proxyClient(proxyURL *url.URL) *http.Client {
+	proxyTransportsLock.Lock()
+	defer proxyTransportsLock.Unlock()
+	t, ok := proxyTransports[proxyURL.String()]
+	if !ok {
+		t = http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = http.ProxyURL(proxyURL)
+		proxyTransports[proxyURL.String()] = t
+	}
+	return &http.Client{Transport: t}
+}
+
+// proxyDialer returns a function that opens connections through the
+// proxy, which should have a "socks5" or "http" scheme.
+//
+// If proxyURL is nil, connections are opened directly.
+func proxyDialer(proxyURL *url.URL, d *net.Dialer) (dialFunc, error) {
+	if proxyURL == nil {
+		return d.DialContext, nil
+	}
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		return socksDialer(proxyURL, d)
+	case "http":
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialConnect(ctx, d, proxyURL, addr)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+}
+
+// dialConnect opens a tunnel to addr through a HTTP proxy, using the
+// CONNECT method.
+func dialConnect(ctx context.Context, d *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := d.DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if u := proxyURL.User; u != nil {
+		pass, _ := u.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(u.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused CONNECT to %s: %s", proxyURL.Host, addr, resp.Status)
+	}
+	return conn, nil
+}
@@ -0,0 +1,199 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type (
+	// NtfyNotifier sends alerts, warnings and recovery notices as push
+	// notifications to a ntfy topic, e.g. for alerts on a phone from a
+	// self-hosted ntfy server.
+	//
+	// Alerts, warnings and recoveries are sent with decreasing
+	// priority, and notifications open the InfoUrl of the most recent
+	// failure when clicked, if any.
+	//
+	// Use its Alert, Warn and Recover methods as the AlertFn, WarnFn and
+	// RecoverFn of probers, see TicketNotifier for an example.
+	NtfyNotifier struct {
+		Topic     string       // topic to publish to
+		ServerURL string       // URL of the ntfy server, or "" for "https://ntfy.sh"
+		Token     string       // access token, if the topic is protected
+		Client    *http.Client // client to use, or nil for http.DefaultClient
+		// Priorities of alerts, warnings and recoveries from 1 (min) to
+		// 5 (max), or 0 for 5, 3 and 2.
+		AlertPriority, WarnPriority, RecoverPriority int
+	}
+
+	// GotifyNotifier sends alerts, warnings and recovery notices as push
+	// notifications through a Gotify server.
+	//
+	// Alerts, warnings and recoveries are sent with decreasing
+	// priority, and notifications open the InfoUrl of the most recent
+	// failure when clicked, if any.
+	//
+	// Use its Alert, Warn and Recover methods as the AlertFn, WarnFn and
+	// RecoverFn of probers, see TicketNotifier for an example.
+	GotifyNotifier struct {
+		ServerURL string       // URL of the Gotify server
+		Token     string       // token of the application to send as
+		Client    *http.Client // client to use, or nil for http.DefaultClient
+		// Priorities of alerts, warnings and recoveries from 0 to 10, or
+		// 0 for 8, 5 and 2.
+		AlertPriority, WarnPriority, RecoverPriority int
+	}
+
+	// pushKind is the kind of a push notification, which decides its
+	// priority.
+	pushKind int
+)
+
+const (
+	pushAlert pushKind = iota
+	pushWarn
+	pushRecover
+)
+
+// priority returns the priority of the kind of notification, from the
+// configured priorities or else the defaults.
+func (k pushKind) priority(configured, defaults [3]int) int {
+	if configured[k] > 0 {
+		return configured[k]
+	}
+	return defaults[k]
+}
+
+// Alert sends a notification that the probe is alerting.
+func (nn NtfyNotifier) Alert(name, desc string, badness int, records Records) error {
+	return nn.send(pushAlert, fmt.Sprintf("%s is alerting", name), pushMessage(desc, badness, records), "rotating_light", clickURL(records))
+}
+
+// Warn sends a notification that the probe is degraded.
+func (nn NtfyNotifier) Warn(name, desc string, badness int, records Records) error {
+	return nn.send(pushWarn, fmt.Sprintf("%s is degraded", name), pushMessage(desc, badness, records), "warning", clickURL(records))
+}
+
+// Recover sends a notification that the probe has recovered.
+func (nn NtfyNotifier) Recover(name, desc string, records Records) error {
+	return nn.send(pushRecover, fmt.Sprintf("%s recovered", name), desc, "white_check_mark", "")
+}
+
+// Validate checks that the topic is set, see Notifiers(). The token is
+// optional, since only protected topics need one.
+func (nn NtfyNotifier) Validate() ConfigErrors {
+	var errs ConfigErrors
+	if nn.Topic == "" {
+		errs.add("Topic", "must be set")
+	}
+	if nn.ServerURL != "" {
+		validateURL(&errs, "ServerURL", nn.ServerURL, "http", "https")
+	}
+	return errs
+}
+
+// send publishes the notification to the topic.
+func (nn NtfyNotifier) send(kind pushKind, title, msg, tag, click string) error {
+	server := nn.ServerURL
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+	u := strings.TrimSuffix(server, "/") + "/" + nn.Topic
+	req, err := http.NewRequest("POST", u, strings.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	priority := kind.priority([3]int{nn.AlertPriority, nn.WarnPriority, nn.RecoverPriority}, [3]int{5, 3, 2})
+	req.Header.Set("Title", title)
+	req.Header.Set("Priority", fmt.Sprint(priority))
+	req.Header.Set("Tags", tag)
+	if click != "" {
+		req.Header.Set("Click", click)
+	}
+	if nn.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+nn.Token)
+	}
+	client := nn.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("ntfy returned %q for %s: %s", resp.Status, u, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// Alert sends a notification that the probe is alerting.
+func (gn GotifyNotifier) Alert(name, desc string, badness int, records Records) error {
+	return gn.send(pushAlert, fmt.Sprintf("%s is alerting", name), pushMessage(desc, badness, records), clickURL(records))
+}
+
+// Warn sends a notification that the probe is degraded.
+func (gn GotifyNotifier) Warn(name, desc string, badness int, records Records) error {
+	return gn.send(pushWarn, fmt.Sprintf("%s is degraded", name), pushMessage(desc, badness, records), clickURL(records))
+}
+
+// Recover sends a notification that the probe has recovered.
+func (gn GotifyNotifier) Recover(name, desc string, records Records) error {
+	return gn.send(pushRecover, fmt.Sprintf("%s recovered", name), desc, "")
+}
+
+// Validate checks that the server and token are set, see Notifiers().
+func (gn GotifyNotifier) Validate() ConfigErrors {
+	var errs ConfigErrors
+	validateURL(&errs, "ServerURL", gn.ServerURL, "http", "https")
+	if gn.Token == "" {
+		errs.add("Token", "must be set")
+	}
+	return errs
+}
+
+// send sends the notification as a message of the application.
+func (gn GotifyNotifier) send(kind pushKind, title, msg, click string) error {
+	in := map[string]interface{}{
+		"title":    title,
+		"message":  msg,
+		"priority": kind.priority([3]int{gn.AlertPriority, gn.WarnPriority, gn.RecoverPriority}, [3]int{8, 5, 2}),
+	}
+	if click != "" {
+		in["extras"] = map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]string{"url": click},
+			},
+		}
+	}
+	u := strings.TrimSuffix(gn.ServerURL, "/") + "/message"
+	return sendJSON(gn.Client, "POST", u, http.Header{"X-Gotify-Key": {gn.Token}}, in, nil)
+}
+
+// pushMessage returns the message of a push notification for an alert
+// or warning.
+func pushMessage(desc string, badness int, records Records) string {
+	msg := fmt.Sprintf("%s\nBadness: %d", desc, badness)
+	if err := lastError(records); err != "" {
+		msg += "\n" + err
+	}
+	return msg
+}
+
+// clickURL returns the InfoUrl of the most recent failed record, or ""
+// if it has none.
+func clickURL(records Records) string {
+	for i := len(records) - 1; i >= 0; i-- {
+		if r := records[i].Result; !r.Passed() {
+			return r.InfoUrl
+		}
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNtfyNotifier(t *testing.T) {
+	var got []http.Header
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ops" {
+			t.Errorf("got request to %q; want topic /ops\n", r.URL.Path)
+		}
+		got = append(got, r.Header)
+	}))
+	defer s.Close()
+
+	nn := NtfyNotifier{Topic: "ops", ServerURL: s.URL, Token: "secret", WarnPriority: 4}
+	records := Records{{Timestamp: time.Now(), Result: Result{Code: Fail, Error: errors.New("failing on purpose"), InfoUrl: "https://example.com/logs"}}}
+	if err := nn.Alert("web", "Web is up.", 200, records); err != nil {
+		t.Fatalf("Alert() => %v; want nil\n", err)
+	}
+	if err := nn.Warn("web", "Web is up.", 100, records); err != nil {
+		t.Fatalf("Warn() => %v; want nil\n", err)
+	}
+	if err := nn.Recover("web", "Web is up.", records); err != nil {
+		t.Fatalf("Recover() => %v; want nil\n", err)
+	}
+	for i, want := range []struct{ title, priority, click string }{
+		{"web is alerting", "5", "https://example.com/logs"},
+		{"web is degraded", "4", "https://example.com/logs"},
+		{"web recovered", "2", ""},
+	} {
+		h := got[i]
+		if h.Get("Title") != want.title || h.Get("Priority") != want.priority || h.Get("Click") != want.click {
+			t.Errorf("[%d] got title %q, priority %q, click %q; want %q, %q, %q\n", i, h.Get("Title"), h.Get("Priority"), h.Get("Click"), want.title, want.priority, want.click)
+		}
+		if h.Get("Authorization") != "Bearer secret" {
+			t.Errorf("[%d] Authorization => %q; want bearer token\n", i, h.Get("Authorization"))
+		}
+	}
+}
+
+func TestGotifyNotifier(t *testing.T) {
+	var got []map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/message" || r.Header.Get("X-Gotify-Key") != "secret" {
+			t.Errorf("got request to %q with key %q; want /message with app token\n", r.URL.Path, r.Header.Get("X-Gotify-Key"))
+		}
+		var in map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&in)
+		got = append(got, in)
+		w.Write([]byte(`{}`))
+	}))
+	defer s.Close()
+
+	gn := GotifyNotifier{ServerURL: s.URL, Token: "secret"}
+	records := Records{{Timestamp: time.Now(), Result: Result{Code: Fail, InfoUrl: "https://example.com/logs"}}}
+	if err := gn.Alert("web", "Web is up.", 200, records); err != nil {
+		t.Fatalf("Alert() => %v; want nil\n", err)
+	}
+	if err := gn.Recover("web", "Web is up.", records); err != nil {
+		t.Fatalf("Recover() => %v; want nil\n", err)
+	}
+	if got[0]["title"] != "web is alerting" || got[0]["priority"] != 8.0 {
+		t.Errorf("got alert %v; want title and priority 8\n", got[0])
+	}
+	wantExtras := map[string]interface{}{
+		"client::notification": map[string]interface{}{
+			"click": map[string]interface{}{"url": "https://example.com/logs"},
+		},
+	}
+	if !reflect.DeepEqual(got[0]["extras"], wantExtras) {
+		t.Errorf("got alert extras %v; want %v\n", got[0]["extras"], wantExtras)
+	}
+	if got[1]["priority"] != 2.0 || got[1]["extras"] != nil {
+		t.Errorf("got recovery %v; want priority 2 and no click URL\n", got[1])
+	}
+}
+
+func TestPushNotifiers_Validate(t *testing.T) {
+	cases := []struct {
+		v    Validator
+		want []string
+	}{
+		{NtfyNotifier{Topic: "ops"}, nil},
+		{NtfyNotifier{ServerURL: "ntfy.example.com"}, []string{"Topic", "ServerURL"}},
+		{GotifyNotifier{ServerURL: "https://gotify.example.com", Token: "s3cret"}, nil},
+		{GotifyNotifier{ServerURL: "https://gotify.example.com"}, []string{"Token"}},
+	}
+	for i, tt := range cases {
+		if got := errPaths(tt.v.Validate()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%d] Validate() => %v; want problems at %v\n", i, tt.v.Validate(), tt.want)
+		}
+	}
+}
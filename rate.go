@@ -0,0 +1,88 @@
+package prober
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxRate makes the probe alert when the Value of its results grows
+// faster than perMinute, measured over the window, e.g. a queue that
+// grows by more than 100 items per minute over 10 minutes:
+//
+//	MaxRate(100, 10*time.Minute)
+//
+// The rate is the difference between the oldest and newest values
+// within the window, divided by the time between them, so at least two
+// results with a Value are needed. The probe alerts on the rate in
+// addition to its badness, and the rate is available to AlertWhen()
+// conditions as "rate", per minute.
+func MaxRate(perMinute float64, window time.Duration) func(*Probe) {
+	return func(p *Probe) {
+		p.maxRate = perMinute
+		p.rateWindow = window
+	}
+}
+
+// Rate returns how fast the Value of the probe's results has changed
+// per minute, over the window of MaxRate(), or the last 10 minutes if
+// it's not set. The returned bool is false if there aren't enough
+// values in the window to tell.
+func (p *Probe) Rate() (float64, bool) {
+	now := time.Now()
+	if p.t != nil {
+		now = p.t.Now()
+	}
+	return p.rate(now)
+}
+
+// rate returns how fast the Value of the results has changed per
+// minute, over the window ending at the time now.
+func (p *Probe) rate(now time.Time) (float64, bool) {
+	window := p.rateWindow
+	if window == 0 {
+		window = 10 * time.Minute
+	}
+	rs := p.Records()
+	var first, last *Record
+	for i := len(rs) - 1; i >= 0; i-- {
+		r := &rs[i]
+		if r.Timestamp.After(now) || r.Result.Value == nil {
+			continue
+		}
+		if now.Sub(r.Timestamp) > window {
+			break
+		}
+		if last == nil {
+			last = r
+		}
+		first = r
+	}
+	if first == nil || first == last || !last.Timestamp.After(first.Timestamp) {
+		return 0, false
+	}
+	return (*last.Result.Value - *first.Result.Value) / last.Timestamp.Sub(first.Timestamp).Minutes(), true
+}
+
+// rateExceeded returns true if the probe has a MaxRate() and its values
+// grow faster than that at the time now.
+func (p *Probe) rateExceeded(now time.Time) bool {
+	if p.rateWindow == 0 {
+		return false
+	}
+	r, ok := p.rate(now)
+	return ok && r > p.maxRate
+}
+
+// rateNote returns a note on the rate of growth of the values for
+// notifications, or "" if it's not exceeded.
+func (p *Probe) rateNote() string {
+	now := time.Now()
+	if p.t != nil {
+		now = p.t.Now()
+	}
+	if !p.rateExceeded(now) {
+		return ""
+	}
+	r, _ := p.rate(now)
+	return fmt.Sprintf(" [value growing by %.4g/min, over %.4g/min]", r, p.maxRate)
+}
@@ -0,0 +1,94 @@
+package prober
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbe_Rate(t *testing.T) {
+	now := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{Name: "RateProber", t: fakeTime{now}}
+	if _, ok := p.Rate(); ok {
+		t.Errorf("Rate() with no records => ok; want not ok\n")
+	}
+	// A value growing by 5 per minute, with a run without a value and
+	// an old outlier outside the window.
+	p.records = Records{
+		{Timestamp: now.Add(-time.Hour), Result: Passed().WithValue(1000)},
+		{Timestamp: now.Add(-4 * time.Minute), Result: Passed().WithValue(10)},
+		{Timestamp: now.Add(-2 * time.Minute), Result: Passed()},
+		{Timestamp: now, Result: Passed().WithValue(30)},
+	}
+	if got, ok := p.Rate(); !ok || got != 5 {
+		t.Errorf("Rate() => %v, %v; want 5, true\n", got, ok)
+	}
+	MaxRate(1, time.Minute)(p)
+	if _, ok := p.Rate(); ok {
+		t.Errorf("Rate() with a single value in window => ok; want not ok\n")
+	}
+}
+
+func TestMaxRate(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	var records Records
+	// A value that's flat for 30 minutes, then grows by 20 per minute.
+	for i := 0; i < 60; i++ {
+		v := 100.0
+		if i >= 30 {
+			v += float64(i-30) * 20
+		}
+		records = append(records, Record{Timestamp: start.Add(time.Duration(i) * time.Minute), Result: Passed().WithValue(v)})
+	}
+	p := &Probe{Name: "RateProber", failurePenalty: 10, successReward: 1}
+
+	cases := []struct {
+		options   []Option
+		wantFirst time.Time
+	}{
+		{},
+		{options: []Option{MaxRate(10, 5*time.Minute)}, wantFirst: start.Add(33 * time.Minute)},
+		{options: []Option{MaxRate(10, 10*time.Minute)}, wantFirst: start.Add(36 * time.Minute)},
+		{options: []Option{MaxRate(50, 5*time.Minute)}},
+		{options: []Option{AlertWhen("rate > 15")}, wantFirst: start.Add(38 * time.Minute)},
+	}
+	for i, tt := range cases {
+		got := p.Replay(records, tt.options...)
+		if tt.wantFirst.IsZero() {
+			if got.Alerts() != 0 {
+				t.Errorf("[%d] Replay() => %v; want no alerts\n", i, got)
+			}
+			continue
+		}
+		if got.Alerts() == 0 || !got.Notifications[0].Time.Equal(tt.wantFirst) {
+			t.Errorf("[%d] Replay() => %v; want first alert at %v\n", i, got, tt.wantFirst)
+		}
+	}
+}
+
+func TestResult_Value(t *testing.T) {
+	r := Passed().WithValue(1.5)
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Result
+	if err := json.Unmarshal(b, &got); err != nil || !got.Equal(r) {
+		t.Errorf("JSON round trip of %v via %s => %v, %v\n", r, b, got, err)
+	}
+	if got.Equal(Passed().WithValue(2)) || got.Equal(Passed()) {
+		t.Errorf("%v.Equal() of results with other values => true; want false\n", got)
+	}
+
+	now := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{Name: "RateProber", Desc: "Queue is short.", t: fakeTime{now}}
+	MaxRate(1, 10*time.Minute)(p)
+	p.records = Records{
+		{Timestamp: now.Add(-time.Minute), Result: Passed().WithValue(10)},
+		{Timestamp: now, Result: Passed().WithValue(20)},
+	}
+	if desc := p.alertDesc(); !strings.Contains(desc, "growing by 10/min") {
+		t.Errorf("alertDesc() => %q; want note on rate\n", desc)
+	}
+}
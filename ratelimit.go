@@ -0,0 +1,143 @@
+package prober
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type (
+	// TargetLimiter limits how often probes may run against each target
+	// host, shared by all the probes given it with LimitTarget(), so
+	// that short intervals or many probes against one host can't
+	// overload it by mistake.
+	//
+	// Each host has a token bucket, which holds up to Burst runs and is
+	// refilled at PerMinute runs per minute.
+	TargetLimiter struct {
+		PerMinute float64 // runs per minute allowed against each host
+		Burst     int     // runs that may happen at once, or 0 for 1
+		buckets   map[string]*bucket
+		lock      sync.Mutex // protects buckets
+	}
+
+	// bucket is the token bucket of a host.
+	bucket struct {
+		tokens float64
+		last   time.Time // when tokens was last updated
+	}
+)
+
+// NewTargetLimiter returns a limiter allowing perMinute runs per minute
+// against each host, with bursts of up to burst runs.
+func NewTargetLimiter(perMinute float64, burst int) *TargetLimiter {
+	return &TargetLimiter{PerMinute: perMinute, Burst: burst}
+}
+
+// LimitTarget makes the probe wait for the limiter to allow a run
+// against the host before each run. If host is "", it's taken from the
+// URL or address of HTTPProber, TCPProber and ChecksumProber.
+//
+// Time spent waiting counts as lag of the probe, see Stats().
+func LimitTarget(l *TargetLimiter, host string) func(*Probe) {
+	return func(p *Probe) {
+		if host == "" {
+			host = targetHost(p.Prober)
+		}
+		if host == "" {
+			log.Printf("[%s] can't tell the target host of %T, not limiting its runs\n", p.Name, p.Prober)
+			return
+		}
+		p.limiter = l
+		p.limitHost = host
+	}
+}
+
+// Wait blocks until a run against the host is allowed, or ctx is done.
+func (l *TargetLimiter) Wait(ctx context.Context, host string) error {
+	d := l.reserve(host, time.Now())
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(host)
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from the bucket of the host, returning how long
+// to wait until the token is available.
+func (l *TargetLimiter) reserve(host string, now time.Time) time.Duration {
+	if l.PerMinute <= 0 {
+		return 0
+	}
+	burst := float64(l.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.buckets == nil {
+		l.buckets = map[string]*bucket{}
+	}
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[host] = b
+	}
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Minutes() * l.PerMinute
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.PerMinute * float64(time.Minute))
+}
+
+// cancel returns a reserved token that wasn't used to the bucket of
+// the host.
+func (l *TargetLimiter) cancel(host string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if b, ok := l.buckets[host]; ok {
+		b.tokens++
+	}
+}
+
+// targetHost returns the host that the prober probes, or "" if it
+// can't be told.
+func targetHost(p Prober) string {
+	var u string
+	switch pr := p.(type) {
+	case HTTPProber:
+		u = pr.URL
+	case *HTTPProber:
+		u = pr.URL
+	case *ChecksumProber:
+		u = pr.URL
+	case TCPProber:
+		host, _, err := net.SplitHostPort(pr.Addr)
+		if err != nil {
+			return ""
+		}
+		return host
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
@@ -0,0 +1,63 @@
+package prober
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTargetLimiter_reserve(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	l := NewTargetLimiter(6, 2)
+	cases := []struct {
+		host string
+		at   time.Duration
+		want time.Duration
+	}{
+		{"a.example.com", 0, 0},
+		{"a.example.com", 0, 0},
+		{"a.example.com", 0, 10 * time.Second},
+		{"b.example.com", 0, 0},
+		{"a.example.com", 0, 20 * time.Second},
+		{"a.example.com", time.Minute, 0},
+	}
+	for i, tt := range cases {
+		if got := l.reserve(tt.host, start.Add(tt.at)); got != tt.want {
+			t.Errorf("[%d] reserve(%q, +%v) => %v; want %v\n", i, tt.host, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestTargetLimiter_Wait(t *testing.T) {
+	l := NewTargetLimiter(60, 1)
+	ctx := context.Background()
+	if err := l.Wait(ctx, "example.com"); err != nil {
+		t.Fatalf("first Wait() => %v; want nil\n", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, "example.com"); err == nil {
+		t.Errorf("Wait() over the limit => nil; want context error\n")
+	}
+}
+
+func TestLimitTarget(t *testing.T) {
+	l := NewTargetLimiter(60, 1)
+	cases := []struct {
+		prober Prober
+		host   string
+		want   string
+	}{
+		{HTTPProber{URL: "https://example.com:8443/healthz"}, "", "example.com"},
+		{TCPProber{Addr: "db.example.com:5432"}, "", "db.example.com"},
+		{&ChecksumProber{URL: "https://mirror.example.com/file"}, "", "mirror.example.com"},
+		{testProber{Passed()}, "api.example.com", "api.example.com"},
+		{testProber{Passed()}, "", ""},
+	}
+	for i, tt := range cases {
+		p := newProbe(tt.prober, "LimitedProber", "Probes a limited host.", LimitTarget(l, tt.host))
+		if p.limitHost != tt.want {
+			t.Errorf("[%d] LimitTarget(%T, %q) limits host %q; want %q\n", i, tt.prober, tt.host, p.limitHost, tt.want)
+		}
+	}
+}
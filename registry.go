@@ -0,0 +1,250 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrStalled is the class of errors for probes that have stopped
+// running, e.g. since their goroutine is blocked forever.
+var ErrStalled = errors.New("probe stalled")
+
+// Registry is a set of probes, identified by their names.
+//
+// Once Run() is called, the registry runs each of its probes, including
+// ones added later, and stops probes that are removed. While running,
+// the registry also watches for probes that have stalled, see
+// StallFactor.
+type Registry struct {
+	// How many intervals a probe may go without starting a new run
+	// before it's considered stalled, or 0 for 3. Probes on a Schedule
+	// may start up to StallFactor-1 intervals later than scheduled. A
+	// stalled probe gets a failed record with an ErrStalled error, and
+	// alerts.
+	StallFactor int
+	// File to keep the silences of the registry and its probes in, so
+	// they survive restarts, or "" to keep them only in memory. Run()
+	// loads the silences from the file, and they're saved to it when
+	// added through the registry or the admin API.
+	SilencePath string
+	probes      map[string]*Probe
+	ctx         context.Context               // context to run probes in, once Run() is called
+	cancels     map[string]context.CancelFunc // functions to stop each running probe
+	started     map[string]time.Time          // when each running probe was started
+	stalled     map[string]bool               // whether each probe is known to be stalled
+	silences    []Silence                     // silences of probes matching selectors
+	deploys     []Deploy                      // recent deploys of probes matching selectors
+	// Conditions on the status of components on which to alert.
+	componentRules []*componentRule
+	lock           sync.RWMutex  // protects all of the above
+	checkEvery     time.Duration // how often Run() checks for stalls, or 0 for DefaultInterval
+}
+
+// NewRegistry returns a new registry holding the probes.
+//
+// NewRegistry panics if more than one probe has the same name.
+func NewRegistry(probes ...*Probe) *Registry {
+	r := &Registry{
+		probes:  map[string]*Probe{},
+		cancels: map[string]context.CancelFunc{},
+		started: map[string]time.Time{},
+		stalled: map[string]bool{},
+	}
+	for _, p := range probes {
+		if err := r.Add(p); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Add adds the probe to the registry, returning an error if a probe
+// with the same name is already registered.
+//
+// If the registry is running, the probe starts running too.
+func (r *Registry) Add(p *Probe) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.probes[p.Name]; ok {
+		return fmt.Errorf("probe %q is already registered", p.Name)
+	}
+	r.probes[p.Name] = p
+	r.applySilences(p)
+	r.applyDeploys(p)
+	if r.ctx != nil {
+		r.start(p)
+	}
+	return nil
+}
+
+// Remove removes the named probe from the registry, stopping it if it
+// was running. Remove returns false if there was no such probe.
+func (r *Registry) Remove(name string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	p, ok := r.probes[name]
+	if !ok {
+		return false
+	}
+	if cancel, ok := r.cancels[name]; ok {
+		cancel()
+		delete(r.cancels, name)
+	}
+	delete(r.probes, name)
+	delete(r.started, name)
+	delete(r.stalled, name)
+	p.unpublish()
+	forgetRecords(p)
+	log.Printf("[%s] was removed from registry\n", name)
+	return true
+}
+
+// Get returns the probe with the name, if any.
+func (r *Registry) Get(name string) (*Probe, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	p, ok := r.probes[name]
+	return p, ok
+}
+
+// Probes returns the registered probes, with the ones requiring
+// attention first.
+func (r *Registry) Probes() Probes {
+	r.lock.RLock()
+	ps := make(Probes, 0, len(r.probes))
+	for _, p := range r.probes {
+		ps = append(ps, p)
+	}
+	r.lock.RUnlock()
+	sort.Sort(ps)
+	return ps
+}
+
+// Run runs all the probes in the registry, blocking until ctx is done.
+// Before returning, Run flushes queued records to the YAML log file.
+func (r *Registry) Run(ctx context.Context) {
+	if err := r.LoadSilences(); err != nil {
+		log.Printf("failed to load silences: %v\n", err)
+	}
+	r.lock.Lock()
+	r.ctx = ctx
+	for _, p := range r.probes {
+		r.start(p)
+	}
+	r.lock.Unlock()
+
+	every := r.checkEvery
+	if every == 0 {
+		every = DefaultInterval
+	}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case now := <-t.C:
+			r.checkStalled(now)
+			r.checkComponents()
+		}
+	}
+
+	r.lock.Lock()
+	r.ctx = nil
+	r.cancels = map[string]context.CancelFunc{}
+	r.started = map[string]time.Time{}
+	r.lock.Unlock()
+	FlushLog()
+}
+
+// start runs the probe in a new goroutine. The caller must hold the
+// lock.
+func (r *Registry) start(p *Probe) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.cancels[p.Name] = cancel
+	// Probes with an InitialDelay() aren't expected to run before it
+	// has passed.
+	r.started[p.Name] = time.Now().Add(p.initialDelay)
+	go p.RunContext(ctx)
+}
+
+// checkStalled looks for running probes that haven't started a run
+// within StallFactor intervals, recording a failure and alerting for
+// each probe that newly stalled, see alertStalled().
+func (r *Registry) checkStalled(now time.Time) {
+	factor := r.StallFactor
+	if factor == 0 {
+		factor = 3
+	}
+	var newlyStalled []*Probe
+	r.lock.Lock()
+	for name, started := range r.started {
+		p := r.probes[name]
+		last := p.Stats().LastStart
+		if last.Before(started) {
+			last = started
+		}
+		next := p.nextRun(last)
+		stalled := !p.IsDisabled() && !next.IsZero() && now.Sub(next) > time.Duration(factor-1)*p.Interval
+		if stalled && !r.stalled[name] {
+			newlyStalled = append(newlyStalled, p)
+		}
+		r.stalled[name] = stalled
+	}
+	r.lock.Unlock()
+
+	for _, p := range newlyStalled {
+		err := fmt.Errorf("%w: %s has not run since %v", ErrStalled, p.Name, p.Stats().LastStart)
+		log.Printf("[%s] %v\n", p.Name, err)
+		// A probe that stalled may well be stuck handling a result, e.g.
+		// in a Report() function, while holding resultLock, so the
+		// registry must not wait for it.
+		if p.resultLock.TryLock() {
+			p.logResult(FailedWith(err))
+			p.resultLock.Unlock()
+		} else {
+			log.Printf("[%s] not recording stall, since the probe is still handling a result\n", p.Name)
+		}
+		p.alertStalled(err)
+	}
+}
+
+// alertStalled alerts about the probe having stalled with the error,
+// unless alerts are disabled, the probe is silenced or in its deploy
+// grace period, or an alert was sent less than MaxAlertFrequency ago.
+func (p *Probe) alertStalled(err error) {
+	now := p.t.Now()
+	switch {
+	case p.alertsDisabled():
+		return
+	case p.Silenced():
+		log.Printf("[%s] is silenced until %v, will not alert about stall\n", p.Name, SilenceTime{p.silencedUntil()})
+		return
+	case p.inDeployGrace(now):
+		log.Printf("[%s] is in deploy grace period until %v, will not alert about stall\n", p.Name, p.DeployGraceUntil())
+		return
+	case now.Sub(p.getLastAlert()) < p.maxAlertFrequency():
+		log.Printf("[%s] will not alert about stall, since last alert was sent %v back\n", p.Name, now.Sub(p.getLastAlert()))
+		return
+	}
+	if !p.startSending() {
+		log.Printf("[%s] will not alert about stall, since an alert is already being sent\n", p.Name)
+		return
+	}
+	go func() {
+		desc := fmt.Sprintf("%s (probe stalled: %v)", p.Desc, err)
+		err := p.notify(desc)
+		p.doneSending(err == nil)
+		if err != nil {
+			log.Printf("[%s] Failed to alert about stall: %v\n", p.Name, err)
+			return
+		}
+		p.setLastAlert(now)
+		p.recordAlertSent()
+	}()
+}
@@ -0,0 +1,130 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistry_checkStalled(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	alerts := make(chan string, 10)
+	newProbe := func(name string) *Probe {
+		return &Probe{
+			Prober: alertingProber{alerts},
+			Name:   name,
+			// Interval is long enough that the real time used for records
+			// doesn't make the probes look stalled.
+			Interval: time.Minute,
+			t:        fakeTime{start},
+		}
+	}
+	running, stuck, silenced := newProbe("running"), newProbe("stuck"), newProbe("silenced")
+	silenced.SilencedUntil = SilenceTime{start.Add(time.Hour)}
+	reg := NewRegistry(running, stuck, silenced)
+	for _, p := range reg.Probes() {
+		reg.started[p.Name] = start
+		p.recordStart(start)
+	}
+
+	// All probes start a run at first, but only one keeps running.
+	for i := 1; i <= 5; i++ {
+		now := start.Add(time.Duration(i) * time.Minute)
+		running.recordStart(now)
+		reg.checkStalled(now)
+	}
+
+	select {
+	case name := <-alerts:
+		if name != "stuck" {
+			t.Errorf("checkStalled() alerted for %q; want \"stuck\"\n", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("checkStalled() didn't alert for stalled probe")
+	}
+	select {
+	case name := <-alerts:
+		t.Errorf("checkStalled() alerted again for %q; want only one alert\n", name)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if rs := stuck.Records(); len(rs) != 1 || !errors.Is(rs[0].Result.Error, ErrStalled) {
+		t.Errorf("stuck.Records() => %v; want one ErrStalled failure\n", rs)
+	}
+	if rs := silenced.Records(); len(rs) != 1 || !errors.Is(rs[0].Result.Error, ErrStalled) {
+		t.Errorf("silenced.Records() => %v; want one ErrStalled failure\n", rs)
+	}
+
+	// Stalling again soon after the alert doesn't alert again.
+	for i := 0; stuck.Stats().AlertsSent == 0; i++ {
+		if i == 100 {
+			t.Fatalf("stall alert wasn't recorded as sent\n")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	now := start.Add(6 * time.Minute)
+	stuck.recordStart(now)
+	reg.checkStalled(now)
+	now = now.Add(5 * time.Minute)
+	running.recordStart(now)
+	reg.checkStalled(now)
+	select {
+	case name := <-alerts:
+		t.Errorf("checkStalled() alerted for %q after stalling again; want no alert within MaxAlertFrequency\n", name)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if rs := stuck.Records(); len(rs) != 2 {
+		t.Errorf("stuck.Records() => %v; want two ErrStalled failures\n", rs)
+	}
+	if rs := running.Records(); len(rs) != 0 {
+		t.Errorf("running.Records() => %v; want none\n", rs)
+	}
+}
+
+func TestRegistry_Run_stalledInReport(t *testing.T) {
+	alerts := make(chan string, 10)
+	release := make(chan struct{})
+	defer close(release)
+	p := &Probe{
+		Prober:   alertingProber{alerts},
+		Name:     "ReportingProber",
+		Interval: 10 * time.Millisecond,
+		t:        realTime{},
+	}
+	// The first result blocks the probe in its Report() function,
+	// holding resultLock.
+	Report(func(Result) { <-release })(p)
+	reg := NewRegistry(p)
+	reg.checkEvery = 5 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reg.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case name := <-alerts:
+		if name != p.Name {
+			t.Errorf("Run() alerted for %q; want %q\n", name, p.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Run() didn't alert for probe stalled in Report()\n")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run() didn't return with a probe stalled in Report()\n")
+	}
+}
+
+// alertingProber is a Prober that sends the name of the probe on a
+// channel when alerting.
+type alertingProber struct{ alerts chan<- string }
+
+func (alertingProber) Probe() Result { return Passed() }
+func (ap alertingProber) Alert(name, desc string, badness int, records Records) error {
+	ap.alerts <- name
+	return nil
+}
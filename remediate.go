@@ -0,0 +1,183 @@
+package prober
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRemediationAudit is how many remediation attempts of a probe to
+// keep in its audit trail.
+const maxRemediationAudit = 50
+
+type (
+	// Remediator tries to fix the problem that a probe alerts on, e.g.
+	// by restarting a systemd unit or a container.
+	Remediator interface {
+		Remediate(ctx context.Context, name string, records Records) error
+	}
+
+	// RemediateFn is a function that implements Remediator.
+	RemediateFn func(ctx context.Context, name string, records Records) error
+
+	// RemediationLimits are the safety limits of a Remediator, see
+	// Remediate().
+	RemediationLimits struct {
+		// Most attempts to make until the probe recovers, or 0 for 3.
+		MaxAttempts int
+		// Least time between attempts, or 0 for 5 minutes.
+		Cooldown time.Duration
+		// How long an attempt may take, or 0 for a minute.
+		Timeout time.Duration
+	}
+
+	// RemediationRecord is an audit record of a remediation attempt.
+	RemediationRecord struct {
+		Start    time.Time
+		Duration time.Duration
+		Attempt  int    // number of the attempt since the probe last recovered
+		Error    string // error of the attempt, or "" if it succeeded
+		DryRun   bool   // whether the attempt was only logged, see DryRun()
+	}
+
+	// remediation is the state of the remediation of a probe.
+	remediation struct {
+		r        Remediator
+		limits   RemediationLimits
+		attempts int       // attempts since the probe last recovered
+		last     time.Time // start of the last attempt
+		running  bool      // whether an attempt is running
+		audit    []RemediationRecord
+		lock     sync.Mutex // protects all of the above
+	}
+)
+
+// Remediate calls fn with ctx and the arguments.
+func (fn RemediateFn) Remediate(ctx context.Context, name string, records Records) error {
+	return fn(ctx, name, records)
+}
+
+// Remediate makes the probe call the Remediator when it's alerting,
+// turning the prober into a basic self-healing loop.
+//
+// To not make things worse, the Remediator is called at most
+// MaxAttempts times until the probe recovers, with at least Cooldown
+// between attempts, and never while a previous attempt is running. The
+// attempts are kept as audit records, see Remediations(), and logged.
+// Silenced probes are never remediated, and remediation of probes with
+// DryRun() is only logged.
+func Remediate(r Remediator, limits RemediationLimits) func(*Probe) {
+	if limits.MaxAttempts == 0 {
+		limits.MaxAttempts = 3
+	}
+	if limits.Cooldown == 0 {
+		limits.Cooldown = 5 * time.Minute
+	}
+	if limits.Timeout == 0 {
+		limits.Timeout = time.Minute
+	}
+	return func(p *Probe) {
+		p.remediation = &remediation{r: r, limits: limits}
+	}
+}
+
+// CommandRemediator returns a Remediator that runs the command, e.g.
+//
+//	CommandRemediator("systemctl", "restart", "nginx.service")
+//	CommandRemediator("docker", "restart", "web")
+//
+// The remediation fails if the command exits with a non-zero status.
+func CommandRemediator(name string, args ...string) Remediator {
+	return RemediateFn(func(ctx context.Context, probe string, records Records) error {
+		var out bytes.Buffer
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s %s failed: %v: %s", name, strings.Join(args, " "), err, truncate(strings.TrimSpace(out.String()), 512))
+		}
+		return nil
+	})
+}
+
+// Remediations returns the audit records of the most recent
+// remediation attempts of the probe, oldest first.
+func (p *Probe) Remediations() []RemediationRecord {
+	if p.remediation == nil {
+		return nil
+	}
+	p.remediation.lock.Lock()
+	defer p.remediation.lock.Unlock()
+	return append([]RemediationRecord{}, p.remediation.audit...)
+}
+
+// remediate starts a remediation attempt of the alerting probe, if it
+// has a Remediator and the limits allow it.
+func (p *Probe) remediate() {
+	rem := p.remediation
+	if rem == nil {
+		return
+	}
+	now := p.t.Now()
+	rem.lock.Lock()
+	switch {
+	case rem.running:
+		rem.lock.Unlock()
+		return
+	case rem.attempts >= rem.limits.MaxAttempts:
+		rem.lock.Unlock()
+		log.Printf("[%s] not remediating, since all %d attempts were made\n", p.Name, rem.limits.MaxAttempts)
+		return
+	case !rem.last.IsZero() && now.Sub(rem.last) < rem.limits.Cooldown:
+		rem.lock.Unlock()
+		log.Printf("[%s] not remediating, since the last attempt was %v ago\n", p.Name, now.Sub(rem.last))
+		return
+	}
+	rem.attempts++
+	rem.last = now
+	rem.running = true
+	rec := RemediationRecord{Start: now, Attempt: rem.attempts, DryRun: p.isDryRun()}
+	rem.lock.Unlock()
+
+	go func() {
+		if rec.DryRun {
+			log.Printf("[%s] [dry run] would make remediation attempt #%d\n", p.Name, rec.Attempt)
+		} else {
+			log.Printf("[%s] making remediation attempt #%d\n", p.Name, rec.Attempt)
+			ctx, cancel := context.WithTimeout(context.Background(), rem.limits.Timeout)
+			start := time.Now()
+			err := rem.r.Remediate(ctx, p.Name, p.Records())
+			cancel()
+			rec.Duration = time.Since(start)
+			if err != nil {
+				rec.Error = err.Error()
+				log.Printf("[%s] remediation attempt #%d failed: %v\n", p.Name, rec.Attempt, err)
+			} else {
+				log.Printf("[%s] remediation attempt #%d succeeded in %v\n", p.Name, rec.Attempt, rec.Duration)
+			}
+		}
+		rem.lock.Lock()
+		defer rem.lock.Unlock()
+		rem.running = false
+		rem.audit = append(rem.audit, rec)
+		if over := len(rem.audit) - maxRemediationAudit; over > 0 {
+			rem.audit = append(rem.audit[:0], rem.audit[over:]...)
+		}
+	}()
+}
+
+// resetRemediation allows new remediation attempts, once the probe has
+// recovered.
+func (p *Probe) resetRemediation() {
+	if p.remediation == nil {
+		return
+	}
+	p.remediation.lock.Lock()
+	p.remediation.attempts = 0
+	p.remediation.lock.Unlock()
+}
@@ -0,0 +1,104 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// movingTime implements timeT for tests by pretending it's the Time
+// last set, and is safe to set while alerts are sent in the background.
+type movingTime struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (mt *movingTime) Now() time.Time {
+	mt.Lock()
+	defer mt.Unlock()
+	return mt.now
+}
+
+func (mt *movingTime) set(t time.Time) {
+	mt.Lock()
+	mt.now = t
+	mt.Unlock()
+}
+
+func (*movingTime) Sleep(d time.Duration) {}
+
+func TestProbe_remediate(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	calls := make(chan string, 10)
+	clock := &movingTime{now: start}
+	p := &Probe{
+		Prober:         testProber{Passed()},
+		Name:           "RemediatedProber",
+		failurePenalty: 100,
+		t:              clock,
+	}
+	Remediate(RemediateFn(func(ctx context.Context, name string, records Records) error {
+		calls <- name
+		return errors.New("restart failed")
+	}), RemediationLimits{MaxAttempts: 2, Cooldown: 10 * time.Minute})(p)
+
+	// waitAttempt waits for the running attempt, if any, to be audited.
+	waitAttempt := func() {
+		for i := 0; i < 100; i++ {
+			p.remediation.lock.Lock()
+			running := p.remediation.running
+			p.remediation.lock.Unlock()
+			if !running {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("remediation attempt didn't finish\n")
+	}
+	fail := FailedWith(errors.New("failing on purpose"))
+	for _, d := range []time.Duration{0, 2, 5, 12, 24, 36} {
+		clock.set(start.Add(d * time.Minute))
+		p.handleResult(fail)
+		waitAttempt()
+	}
+	audit := p.Remediations()
+	if len(audit) != 2 {
+		t.Fatalf("got %d remediation attempts; want 2 with the cooldown and limit\n", len(audit))
+	}
+	if audit[0].Start != start.Add(2*time.Minute) || audit[1].Start != start.Add(12*time.Minute) {
+		t.Errorf("got attempts at %v and %v; want 2m and 12m after start\n", audit[0].Start, audit[1].Start)
+	}
+	if audit[1].Attempt != 2 || audit[1].Error != "restart failed" {
+		t.Errorf("got audit record %+v; want attempt 2 with error\n", audit[1])
+	}
+	if got := p.Status().Remediations; len(got) != 2 {
+		t.Errorf("Status().Remediations has %d records; want 2\n", len(got))
+	}
+
+	// Recovering allows new attempts.
+	p.noteNotification(p.t.Now())
+	p.handleResult(Passed())
+	clock.set(start.Add(time.Hour))
+	p.handleResult(fail)
+	waitAttempt()
+	if got := p.Remediations(); len(got) != 3 || got[2].Attempt != 1 {
+		t.Errorf("after recovering, got attempts %+v; want a new attempt #1\n", got)
+	}
+	if len(calls) != 3 {
+		t.Errorf("Remediator called %d times; want 3\n", len(calls))
+	}
+}
+
+func TestCommandRemediator(t *testing.T) {
+	ctx := context.Background()
+	if err := CommandRemediator("true").Remediate(ctx, "test", nil); err != nil {
+		t.Errorf("CommandRemediator(\"true\") => %v; want nil\n", err)
+	}
+	err := CommandRemediator("sh", "-c", "echo unit not found; exit 5").Remediate(ctx, "test", nil)
+	if err == nil || !strings.Contains(err.Error(), "unit not found") {
+		t.Errorf("CommandRemediator() of failing command => %v; want error with output\n", err)
+	}
+}
@@ -0,0 +1,150 @@
+package prober
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// ReplayReport describes the notifications a probe would have sent
+	// for historical records, see Probe.Replay().
+	ReplayReport struct {
+		Name          string
+		Runs          int
+		Failures      int
+		Notifications []ReplayNotification
+	}
+
+	// ReplayNotification is a notification that would have been sent
+	// during a replay.
+	ReplayNotification struct {
+		Time        time.Time
+		Badness     int
+		Warning     bool // whether this was a warning rather than an alert
+		Recurrences int  // number of escalated notifications in a row, see ReAlertWindow
+	}
+)
+
+// Replay feeds the records through the alerting policy of the probe,
+// without running Probe() or sending anything, and reports the alerts
+// and warnings that would have been sent. This helps tune thresholds
+// against real history, e.g.:
+//
+//	p.Replay(p.Records(), Thresholds(0, 400), FailurePenalty(20))
+//
+// The options are applied on top of the probe's own policy, which is
+// left unchanged. Badness, warning and critical thresholds, result
+// weights, AlertWhen() conditions, MaxRate(), SLO(), MaxAlertFrequency
+// and ReAlertWindow are taken into account.
+func (p *Probe) Replay(records Records, options ...Option) ReplayReport {
+	sim := &Probe{
+		Name:           p.Name,
+		Interval:       p.Interval,
+		failurePenalty: p.failurePenalty,
+		timeoutPenalty: p.timeoutPenalty,
+		successReward:  p.successReward,
+		warnThreshold:  p.warnThreshold,
+		critThreshold:  p.critThreshold,
+		reAlertWindow:  p.reAlertWindow,
+		degradedW:      p.degradedW,
+		alertWhen:      p.alertWhen,
+		maxRate:        p.maxRate,
+		rateWindow:     p.rateWindow,
+		sloTarget:      p.sloTarget,
+		sloWindows:     p.sloWindows,
+		engine:         p.engine,
+	}
+	for _, opt := range options {
+		opt(sim)
+	}
+	records = append(Records{}, records...)
+	sort.Stable(records)
+
+	report := ReplayReport{Name: p.Name}
+	var lastAlert time.Time
+	for _, r := range records {
+		report.Runs += 1 + r.Repeats
+		if r.Result.Passed() {
+			// Merged records are always passes, see CompactRecords().
+			sim.badness -= sim.successReward * (1 + r.Repeats)
+			if sim.badness < 0 {
+				sim.badness = 0
+			}
+		} else {
+			report.Failures++
+			sim.badness += sim.penalty(r.Result)
+		}
+		sim.noteOutcome(r.Result.Passed(), r.Timestamp)
+		sim.records = append(sim.records, r)
+		if len(sim.records) > bufferSize {
+			sim.records = sim.records[1:]
+		}
+		if r.Result.Passed() {
+			sim.lastSuccess = r.Timestamp
+		}
+
+		alerting := sim.alertCondition(r.Timestamp)
+		degraded := sim.warnThreshold > 0 && sim.badness >= sim.warnThreshold && !alerting
+		warn := degraded && !sim.degraded
+		sim.degraded = degraded
+		if warn {
+			escalated := sim.noteNotification(r.Timestamp)
+			report.Notifications = append(report.Notifications, ReplayNotification{
+				Time:        r.Timestamp,
+				Badness:     sim.badness,
+				Warning:     !escalated,
+				Recurrences: sim.recurrences,
+			})
+			if escalated {
+				lastAlert = r.Timestamp
+				sim.badness = 0
+			}
+			continue
+		}
+		if !alerting || r.Timestamp.Sub(lastAlert) < sim.maxAlertFrequency() {
+			continue
+		}
+		sim.noteNotification(r.Timestamp)
+		report.Notifications = append(report.Notifications, ReplayNotification{
+			Time:        r.Timestamp,
+			Badness:     sim.badness,
+			Recurrences: sim.recurrences,
+		})
+		lastAlert = r.Timestamp
+		sim.badness = 0
+	}
+	return report
+}
+
+// Alerts returns the number of alerts in the report, not counting
+// warnings.
+func (r ReplayReport) Alerts() int {
+	n := 0
+	for _, a := range r.Notifications {
+		if !a.Warning {
+			n++
+		}
+	}
+	return n
+}
+
+// String returns a human-readable summary of the report.
+func (r ReplayReport) String() string {
+	lines := []string{
+		fmt.Sprintf("%s: %d runs, %d failures, %d alerts, %d warnings", r.Name, r.Runs, r.Failures, r.Alerts(), len(r.Notifications)-r.Alerts()),
+	}
+	for _, n := range r.Notifications {
+		kind := "alert"
+		if n.Warning {
+			kind = "warning"
+		}
+		line := fmt.Sprintf("  %s at %s with badness %d", kind, n.Time.Format(time.RFC3339), n.Badness)
+		if n.Recurrences > 0 {
+			line += fmt.Sprintf(" (recurrence #%d)", n.Recurrences)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
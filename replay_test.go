@@ -0,0 +1,48 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProbe_Replay(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	fail := FailedWith(errors.New("failing on purpose"))
+	var records Records
+	// An hour of failures, then an hour of passes.
+	for i := 0; i < 120; i++ {
+		r := Record{Timestamp: start.Add(time.Duration(i) * time.Minute), Result: fail}
+		if i >= 60 {
+			r.Result = Passed()
+		}
+		records = append(records, r)
+	}
+	p := &Probe{Name: "ReplayProber", failurePenalty: 10, successReward: 1}
+	Thresholds(0, 200)(p)
+
+	cases := []struct {
+		options      []Option
+		wantAlerts   int
+		wantWarnings int
+	}{
+		// Badness hits 200 after 20 minutes, then alerting is limited
+		// by MaxAlertFrequency.
+		{wantAlerts: 3},
+		{options: []Option{Thresholds(0, 400)}, wantAlerts: 1},
+		{options: []Option{Thresholds(100, 400)}, wantAlerts: 1, wantWarnings: 2},
+		{options: []Option{FailurePenalty(1)}, wantAlerts: 0},
+	}
+	for i, tt := range cases {
+		got := p.Replay(records, tt.options...)
+		if got.Runs != 120 || got.Failures != 60 {
+			t.Errorf("[%d] Replay() => %d runs, %d failures; want 120, 60\n", i, got.Runs, got.Failures)
+		}
+		if got.Alerts() != tt.wantAlerts || len(got.Notifications)-got.Alerts() != tt.wantWarnings {
+			t.Errorf("[%d] Replay() => %v; want %d alerts, %d warnings\n", i, got, tt.wantAlerts, tt.wantWarnings)
+		}
+	}
+	if p.critThreshold != 200 {
+		t.Errorf("after Replay(), critical threshold is %d; want it unchanged at 200\n", p.critThreshold)
+	}
+}
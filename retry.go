@@ -0,0 +1,65 @@
+package prober
+
+import (
+	"log"
+	"time"
+)
+
+// RetryAlerts makes the probe retry an alert that couldn't be sent up
+// to retries times, waiting backoff before the first retry and twice
+// as long before each one after that, e.g. RetryAlerts(3, 10*time.Second)
+// retries after 10s, 20s and 40s.
+//
+// Without RetryAlerts, an alert that fails is only tried again on the
+// next run that alerts. Either way, once all tries have failed, the
+// Status of the probe has AlertUndelivered set until an alert is
+// delivered.
+func RetryAlerts(retries int, backoff time.Duration) func(*Probe) {
+	return func(p *Probe) {
+		p.alertRetries = retries
+		p.alertBackoff = backoff
+	}
+}
+
+// AlertUndelivered returns true if the most recent alert of the probe
+// couldn't be sent, even after retrying.
+func (p *Probe) AlertUndelivered() bool {
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	return p.alertUndelivered
+}
+
+// notifyWithRetries sends the alert, retrying with backoff as set by
+// RetryAlerts(), and returns the error of the last try if all failed.
+func (p *Probe) notifyWithRetries(desc string) error {
+	backoff := p.alertBackoff
+	err := p.notify(desc)
+	for i := 0; err != nil && i < p.alertRetries; i++ {
+		log.Printf("[%s] Failed to alert, retrying in %v: %v\n", p.Name, backoff, err)
+		p.t.Sleep(backoff)
+		err = p.notify(desc)
+		backoff *= 2
+	}
+	return err
+}
+
+// startSending returns true and marks the probe as sending an alert,
+// or returns false if it already was.
+func (p *Probe) startSending() bool {
+	p.alertLock.Lock()
+	defer p.alertLock.Unlock()
+	if p.alertSending {
+		return false
+	}
+	p.alertSending = true
+	return true
+}
+
+// doneSending marks the probe as done sending an alert, which was
+// delivered if ok is true.
+func (p *Probe) doneSending(delivered bool) {
+	p.alertLock.Lock()
+	defer p.alertLock.Unlock()
+	p.alertSending = false
+	p.alertUndelivered = !delivered
+}
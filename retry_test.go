@@ -0,0 +1,66 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryAlerts(t *testing.T) {
+	cases := []struct {
+		retries         int
+		failures        int // calls to Alert() that fail before one succeeds
+		wantCalls       int
+		wantBadness     int
+		wantUndelivered bool
+	}{
+		{retries: 0, failures: 0, wantCalls: 1, wantBadness: 0},
+		{retries: 0, failures: 1, wantCalls: 1, wantBadness: 100, wantUndelivered: true},
+		{retries: 3, failures: 2, wantCalls: 3, wantBadness: 0},
+		{retries: 2, failures: 5, wantCalls: 3, wantBadness: 100, wantUndelivered: true},
+	}
+	for i, tt := range cases {
+		calls := 0
+		p := &Probe{
+			Prober: slowAlertProber{testProber{Passed()}, func(string, string, int, Records) error {
+				calls++
+				if calls <= tt.failures {
+					return errors.New("pager service is down")
+				}
+				return nil
+			}},
+			Name:    "RetryProber",
+			badness: 100,
+			t:       fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+		}
+		RetryAlerts(tt.retries, time.Second)(p)
+		p.sendAlert()
+		if calls != tt.wantCalls {
+			t.Errorf("[%d] sendAlert() called Alert() %d times; want %d\n", i, calls, tt.wantCalls)
+		}
+		if got := p.Badness(); got != tt.wantBadness {
+			t.Errorf("[%d] sendAlert() left Badness() %d; want %d\n", i, got, tt.wantBadness)
+		}
+		if got := p.Status().AlertUndelivered; got != tt.wantUndelivered {
+			t.Errorf("[%d] Status().AlertUndelivered => %v; want %v\n", i, got, tt.wantUndelivered)
+		}
+	}
+}
+
+func TestProbe_sendAlert_inProgress(t *testing.T) {
+	calls := 0
+	p := &Probe{
+		Prober: slowAlertProber{testProber{Passed()}, func(string, string, int, Records) error {
+			calls++
+			return nil
+		}},
+		Name:    "InProgressProber",
+		badness: 100,
+		t:       fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	p.startSending()
+	p.sendAlert()
+	if calls != 0 {
+		t.Errorf("sendAlert() while sending called Alert() %d times; want 0\n", calls)
+	}
+}
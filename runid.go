@@ -0,0 +1,42 @@
+package prober
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// RunIDHeader is the header HTTPProber sends the RunID() of the run in,
+// unless its Header already sets it.
+const RunIDHeader = "X-Request-ID"
+
+// runIDKey is the context key of the ID of a run.
+type runIDKey struct{}
+
+// RunID returns the correlation ID of the run of a probe that ctx was
+// passed to ProbeContext() for, or "" if there is none.
+//
+// Each run gets a new ID, which is also set as RunID in its Record.
+// Probers can send it along with their requests, e.g. as RunIDHeader,
+// so failures can be matched to logs of the server being probed.
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// withRunID returns a copy of ctx carrying the ID of a run.
+func withRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// newRunID returns a new random ID for a run.
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// Unique enough to correlate logs, if not unpredictable.
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
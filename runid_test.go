@@ -0,0 +1,44 @@
+package prober
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunID(t *testing.T) {
+	if got := RunID(context.Background()); got != "" {
+		t.Errorf("RunID(context.Background()) => %q; want \"\"\n", got)
+	}
+	ids := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids <- r.Header.Get(RunIDHeader)
+	}))
+	defer ts.Close()
+
+	p := &Probe{
+		Prober:   HTTPProber{URL: ts.URL},
+		Name:     "RunIDProber",
+		Interval: time.Minute,
+		t:        realTime{},
+	}
+	p.runProbe()
+	p.runProbe()
+	first, second := <-ids, <-ids
+	if first == "" || first == second {
+		t.Errorf("%s of runs => %q, %q; want different IDs\n", RunIDHeader, first, second)
+	}
+	rs := p.Records()
+	if len(rs) != 2 || rs[0].RunID != first || rs[1].RunID != second {
+		t.Errorf("Records() => %v; want RunID %q and %q\n", rs, first, second)
+	}
+
+	// An ID set in the Header of the prober is kept.
+	p.Prober = HTTPProber{URL: ts.URL, Header: http.Header{RunIDHeader: {"fixed"}}}
+	p.runProbe()
+	if got := <-ids; got != "fixed" {
+		t.Errorf("%s with Header set => %q; want \"fixed\"\n", RunIDHeader, got)
+	}
+}
@@ -0,0 +1,102 @@
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// Sanitizer is a function that scrubs a Result of data that shouldn't
+// be stored, e.g. secrets or personal data in the Info or Error.
+type Sanitizer func(Result) Result
+
+// Sanitize sets functions that each Result of the probe passes through
+// before it's recorded, written to the log or shipped anywhere.
+func Sanitize(fns ...Sanitizer) func(*Probe) {
+	return func(p *Probe) {
+		p.sanitizers = append(p.sanitizers, fns...)
+	}
+}
+
+// Redact returns a Sanitizer that replaces all matches of the regular
+// expression in the Error, Info, InfoUrl and Details of a Result with
+// "[REDACTED]".
+func Redact(re *regexp.Regexp) Sanitizer {
+	redact := func(s string) string {
+		return re.ReplaceAllString(s, "[REDACTED]")
+	}
+	return func(r Result) Result {
+		if r.Error != nil {
+			if s := redact(r.Error.Error()); s != r.Error.Error() {
+				r.Error = errors.New(s)
+			}
+		}
+		r.Info = redact(r.Info)
+		r.InfoUrl = redact(r.InfoUrl)
+		if r.Details != nil {
+			details := make(map[string]string, len(r.Details))
+			for k, v := range r.Details {
+				details[k] = redact(v)
+			}
+			r.Details = details
+		}
+		return r
+	}
+}
+
+// MaxResultLen sets the maximum length in bytes of each of the Error,
+// Info and Details values of results from the probe, after which they
+// are truncated. A value of 0 means no limit.
+func MaxResultLen(n int) func(*Probe) {
+	return func(p *Probe) {
+		p.maxResultLen = n
+	}
+}
+
+// Truncate returns a Sanitizer that truncates the Error, Info and
+// Details values of a Result that are longer than n bytes, marking
+// where they were cut.
+func Truncate(n int) Sanitizer {
+	return func(r Result) Result {
+		if r.Error != nil {
+			if s := truncate(r.Error.Error(), n); s != r.Error.Error() {
+				r.Error = errors.New(s)
+			}
+		}
+		r.Info = truncate(r.Info, n)
+		if r.Details != nil {
+			details := make(map[string]string, len(r.Details))
+			for k, v := range r.Details {
+				details[k] = truncate(v, n)
+			}
+			r.Details = details
+		}
+		return r
+	}
+}
+
+// truncate returns s cut to at most n bytes, plus a marker saying how
+// much was cut, taking care not to split multi-byte characters.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s… [truncated %d bytes]", s[:cut], len(s)-cut)
+}
+
+// sanitize returns the result after passing it through the sanitizers
+// of the probe, and truncating it to the maximum length.
+func (p *Probe) sanitize(r Result) Result {
+	for _, fn := range p.sanitizers {
+		r = fn(r)
+	}
+	if p.maxResultLen > 0 {
+		r = Truncate(p.maxResultLen)(r)
+	}
+	return r
+}
@@ -0,0 +1,88 @@
+package prober
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	token := regexp.MustCompile(`token=[^& ]+`)
+	cases := []struct {
+		in   Result
+		want Result
+	}{
+		{
+			in:   Passed(),
+			want: Passed(),
+		},
+		{
+			in: FailedWithInfo(
+				errors.New("GET /login?token=abc123 failed"),
+				"tried token=abc123",
+				"https://example.com/?token=abc123&x=1"),
+			want: FailedWithInfo(
+				errors.New("GET /login?[REDACTED] failed"),
+				"tried [REDACTED]",
+				"https://example.com/?[REDACTED]&x=1"),
+		},
+		{
+			in: Result{
+				Code:    Pass,
+				Details: map[string]string{"url": "/?token=abc123"},
+			},
+			want: Result{
+				Code:    Pass,
+				Details: map[string]string{"url": "/?[REDACTED]"},
+			},
+		},
+	}
+	for i, tt := range cases {
+		got := Redact(token)(tt.in)
+		if !got.Equal(tt.want) || got.InfoUrl != tt.want.InfoUrl {
+			t.Errorf("[%d] Redact(%v)(%v) => %v; want %v\n", i, token, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		in   Result
+		n    int
+		want Result
+	}{
+		{
+			in:   PassedWith("short", ""),
+			n:    10,
+			want: PassedWith("short", ""),
+		},
+		{
+			in:   FailedWithInfo(errors.New("0123456789abc"), "0123456789", ""),
+			n:    10,
+			want: FailedWithInfo(errors.New("0123456789… [truncated 3 bytes]"), "0123456789", ""),
+		},
+		{
+			// "é" is two bytes, and shouldn't be split.
+			in:   PassedWith("abcé", ""),
+			n:    4,
+			want: PassedWith("abc… [truncated 2 bytes]", ""),
+		},
+		{
+			in: Result{
+				Code:    Fail,
+				Details: map[string]string{"body": "0123456789abc"},
+			},
+			n: 5,
+			want: Result{
+				Code:    Fail,
+				Details: map[string]string{"body": "01234… [truncated 8 bytes]"},
+			},
+		},
+	}
+	for i, tt := range cases {
+		got := Truncate(tt.n)(tt.in)
+		if !got.Equal(tt.want) {
+			t.Errorf("[%d] Truncate(%d)(%v) => %v; want %v\n", i, tt.n, tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package prober
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type (
+	// Scenario is a Prober that runs a sequence of steps, where each
+	// step can use outputs published by the steps before it, e.g. to
+	// resolve a hostname, then request a URL on the IP that was found
+	// directly, then compare the response with one via the hostname.
+	//
+	// The scenario fails at the first step that fails, and the steps
+	// after it aren't run. The Details of the Result hold the details
+	// and duration of each step that ran, prefixed by the step's name.
+	//
+	// The Alert() part of the Prober interface is provided by the
+	// embedded AlertFn.
+	Scenario struct {
+		AlertFn
+		Steps []Step
+	}
+
+	// Step is a stage of a Scenario.
+	Step struct {
+		Name string // name of the step, e.g. "resolve"
+		// Probe runs the step with the outputs of the steps before
+		// it, returning its result and any outputs to publish for the
+		// steps after it.
+		Probe func(in Outputs) (Result, Outputs)
+	}
+
+	// Outputs are typed values that steps of a Scenario publish for
+	// later steps, e.g. "ip" holding a net.IP.
+	Outputs map[string]interface{}
+)
+
+// ProberStep returns a step that runs the Prober that fn creates from
+// the outputs of earlier steps, e.g. a HTTPProber for a URL built from
+// a resolved IP. The step doesn't publish any outputs.
+func ProberStep(name string, fn func(in Outputs) (Prober, error)) Step {
+	return Step{
+		Name: name,
+		Probe: func(in Outputs) (Result, Outputs) {
+			p, err := fn(in)
+			if err != nil {
+				return FailedWith(err), nil
+			}
+			return p.Probe(), nil
+		},
+	}
+}
+
+// Get sets the value that dst points to to the named output, returning
+// an error if there is no such output or it has a different type, e.g.
+//
+//	var ip net.IP
+//	if err := in.Get("ip", &ip); err != nil {
+//		return FailedWith(err), nil
+//	}
+func (o Outputs) Get(name string, dst interface{}) error {
+	v, ok := o[name]
+	if !ok {
+		return fmt.Errorf("no output %q", name)
+	}
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return fmt.Errorf("can't get output %q into non-pointer %T", name, dst)
+	}
+	val := reflect.ValueOf(v)
+	if !val.IsValid() {
+		d.Elem().Set(reflect.Zero(d.Elem().Type()))
+		return nil
+	}
+	if !val.Type().AssignableTo(d.Elem().Type()) {
+		return fmt.Errorf("output %q is %T, not %v", name, v, d.Elem().Type())
+	}
+	d.Elem().Set(val)
+	return nil
+}
+
+// Probe runs the steps in order, until one fails.
+func (s Scenario) Probe() Result {
+	if len(s.Steps) == 0 {
+		return FailedWith(fmt.Errorf("scenario has no steps"))
+	}
+	outputs := Outputs{}
+	details := map[string]string{}
+	var infos []string
+	for _, step := range s.Steps {
+		// Steps get a copy, so they can't change outputs of others.
+		in := make(Outputs, len(outputs))
+		for k, v := range outputs {
+			in[k] = v
+		}
+		start := time.Now()
+		r, out := step.Probe(in)
+		details[step.Name+".duration"] = time.Since(start).String()
+		for k, v := range r.Details {
+			details[step.Name+"."+k] = v
+		}
+		if r.Info != "" {
+			infos = append(infos, fmt.Sprintf("%s: %s", step.Name, r.Info))
+		}
+		if !r.Passed() {
+			details["failed_step"] = step.Name
+			err := fmt.Errorf("step %s failed", step.Name)
+			if r.Error != nil {
+				err = fmt.Errorf("step %s failed: %v", step.Name, r.Error)
+			}
+			return Result{
+				Code:    Fail,
+				Error:   err,
+				Info:    strings.Join(infos, "; "),
+				InfoUrl: r.InfoUrl,
+				Details: details,
+				Weight:  r.Weight,
+				Value:   r.Value,
+			}
+		}
+		for k, v := range out {
+			outputs[k] = v
+		}
+	}
+	r := PassedWith(strings.Join(infos, "; "), "")
+	r.Details = details
+	return r
+}
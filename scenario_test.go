@@ -0,0 +1,108 @@
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScenario_Probe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := func(ip net.IP) Step {
+		return Step{
+			Name: "resolve",
+			Probe: func(Outputs) (Result, Outputs) {
+				if ip == nil {
+					return FailedWith(errors.New("no such host")), nil
+				}
+				return PassedWith(fmt.Sprintf("resolved to %v", ip), ""), Outputs{"ip": ip}
+			},
+		}
+	}
+	fetch := ProberStep("fetch", func(in Outputs) (Prober, error) {
+		var ip net.IP
+		if err := in.Get("ip", &ip); err != nil {
+			return nil, err
+		}
+		return HTTPProber{URL: fmt.Sprintf("http://%s/", net.JoinHostPort(ip.String(), port))}, nil
+	})
+	badType := ProberStep("fetch", func(in Outputs) (Prober, error) {
+		var ip string
+		return nil, in.Get("ip", &ip)
+	})
+
+	cases := []struct {
+		in             Scenario
+		want           ResultCode
+		wantFailedStep string
+	}{
+		{in: Scenario{Steps: []Step{resolve(net.IPv4(127, 0, 0, 1)), fetch}}, want: Pass},
+		{in: Scenario{Steps: []Step{resolve(nil), fetch}}, want: Fail, wantFailedStep: "resolve"},
+		{in: Scenario{Steps: []Step{fetch}}, want: Fail, wantFailedStep: "fetch"},
+		{in: Scenario{Steps: []Step{resolve(net.IPv4(127, 0, 0, 1)), badType}}, want: Fail, wantFailedStep: "fetch"},
+		{in: Scenario{}, want: Fail},
+	}
+	for i, tt := range cases {
+		got := tt.in.Probe()
+		if got.Code != tt.want || got.Details["failed_step"] != tt.wantFailedStep {
+			t.Errorf("[%d] Probe() => %v; want code %v, failed step %q\n", i, got, tt.want, tt.wantFailedStep)
+		}
+		if tt.want == Pass {
+			if _, ok := got.Details["fetch.ttfb"]; !ok {
+				t.Errorf("[%d] Probe() => %v; want details of fetch step\n", i, got)
+			}
+		}
+	}
+}
+
+func TestOutputs_Get(t *testing.T) {
+	o := Outputs{"ip": net.IPv4(127, 0, 0, 1), "err": error(nil), "token": "s3cret"}
+	var ip net.IP
+	if err := o.Get("ip", &ip); err != nil || !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("Get(ip) => %v, %v; want 127.0.0.1\n", ip, err)
+	}
+	var s fmt.Stringer
+	if err := o.Get("ip", &s); err != nil || s.String() != "127.0.0.1" {
+		t.Errorf("Get(ip) into interface => %v, %v; want 127.0.0.1\n", s, err)
+	}
+	var n int
+	if err := o.Get("token", &n); err == nil {
+		t.Errorf("Get(token) into int => nil; want error\n")
+	}
+	if err := o.Get("token", n); err == nil {
+		t.Errorf("Get(token) into non-pointer => nil; want error\n")
+	}
+	if err := o.Get("missing", &n); err == nil {
+		t.Errorf("Get(missing) => nil; want error\n")
+	}
+	e := errors.New("not nil")
+	if err := o.Get("err", &e); err != nil || e != nil {
+		t.Errorf("Get(err) => %v, %v; want nil\n", e, err)
+	}
+}
+
+func TestScenario_Validate(t *testing.T) {
+	step := Step{Name: "a", Probe: func(Outputs) (Result, Outputs) { return Passed(), nil }}
+	cases := []struct {
+		in   Scenario
+		want int
+	}{
+		{in: Scenario{Steps: []Step{step, {Name: "b", Probe: step.Probe}}}},
+		{in: Scenario{}, want: 1},
+		{in: Scenario{Steps: []Step{step, step, {}}}, want: 3},
+	}
+	for i, tt := range cases {
+		if got := tt.in.Validate(); len(got) != tt.want {
+			t.Errorf("[%d] Validate() => %v; want %d problems\n", i, got, tt.want)
+		}
+	}
+}
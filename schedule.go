@@ -0,0 +1,180 @@
+package prober
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron-like schedule in a time zone, e.g. to probe a
+// target only during its business hours.
+//
+// Schedules have the five fields of crontab(5): minute, hour, day of
+// month, month and day of week, each of which is "*", a number, a
+// range like "1-5", a step like "*/15" or "9-17/2", or a list of those
+// separated by commas. Days of week go from 0 (Sunday) to 7 (also
+// Sunday). Names of months and days are not supported.
+//
+// As with cron, if both day of month and day of week are restricted, a
+// day matches if either does. Times that don't exist since clocks were
+// set forward for daylight saving time are skipped, and times that
+// happen twice since clocks were set back are only run once.
+type Schedule struct {
+	spec              string
+	loc               *time.Location
+	minute, hour, dom uint64 // bit i is set if value i matches
+	month, dow        uint64
+	anyDom, anyDow    bool // whether day of month and day of week are "*"
+}
+
+// ParseSchedule parses the cron-like spec, e.g. "*/5 9-17 * * 1-5",
+// for the named time zone, e.g. "Europe/Stockholm", or "" for UTC.
+func ParseSchedule(spec, tz string) (*Schedule, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("bad time zone for schedule %q: %v", spec, err)
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q has %d fields, want 5", spec, len(fields))
+	}
+	s := &Schedule{spec: spec, loc: loc}
+	for i, f := range []struct {
+		dst      *uint64
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	} {
+		if *f.dst, err = parseScheduleField(fields[i], f.min, f.max); err != nil {
+			return nil, fmt.Errorf("bad schedule %q: %v", spec, err)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 // 7 is also Sunday
+	}
+	s.anyDom, s.anyDow = fields[2] == "*", fields[4] == "*"
+	return s, nil
+}
+
+// parseScheduleField parses a field of a schedule with values in the
+// range, returning the bits of the values that match.
+func parseScheduleField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			rng = part[:i]
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			var err error
+			bounds := strings.SplitN(rng, "-", 2)
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("bad value in %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("bad value in %q", part)
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 to the max, every 15.
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time after t that matches the schedule, or
+// the zero value for time.Time if there is none, e.g. for "0 0 30 2 *".
+func (s *Schedule) Next(t time.Time) time.Time {
+	after := wallClock(t.In(s.loc))
+	t = t.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+	// Five years is enough to find the next match for any valid
+	// schedule, including ones that only match on leap days.
+	for end := t.AddDate(5, 0, 0); t.Before(end); {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case s.minute&(1<<uint(t.Minute())) == 0, !wallClock(t).After(after):
+			// Wall-clock times that repeat when clocks are set back
+			// only count the first time around.
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches returns true if the day of t matches the schedule.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if !s.anyDom && !s.anyDow {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// String returns the spec and time zone of the schedule.
+func (s *Schedule) String() string {
+	return fmt.Sprintf("%s (%s)", s.spec, s.loc)
+}
+
+// wallClock returns the wall-clock time of t, as if it were in UTC, so
+// that wall-clock times can be compared across changes to daylight
+// saving time.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// OnSchedule makes the probe run at the times of the schedule, rather
+// than every Interval. Each run still times out after Interval.
+func OnSchedule(s *Schedule) func(*Probe) {
+	return func(p *Probe) {
+		p.schedule = s
+	}
+}
+
+// nextRun returns when the probe should run next after a run that
+// started at the time.
+func (p *Probe) nextRun(last time.Time) time.Time {
+	if p.schedule != nil {
+		return p.schedule.Next(last)
+	}
+	return last.Add(p.Interval)
+}
+
+// untilScheduled returns how long there is until the next time of the
+// schedule of the probe.
+func (p *Probe) untilScheduled() time.Duration {
+	now := p.t.Now()
+	next := p.schedule.Next(now)
+	if next.IsZero() {
+		// The schedule never matches, so check again in a while rather
+		// than spinning.
+		return 24 * time.Hour
+	}
+	return next.Sub(now)
+}
@@ -0,0 +1,66 @@
+package prober
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	cases := []struct {
+		spec, tz string
+		after    string // in RFC 3339
+		want     string // in RFC 3339, or "" for no next time
+	}{
+		{"*/15 * * * *", "", "1998-11-19T15:14:00Z", "1998-11-19T15:15:00Z"},
+		{"*/15 * * * *", "", "1998-11-19T15:15:00Z", "1998-11-19T15:30:00Z"},
+		{"0 9-17 * * 1-5", "Europe/Stockholm", "1998-11-20T17:30:00+01:00", "1998-11-23T09:00:00+01:00"},
+		{"30 12 1,15 * *", "", "1998-11-19T15:14:00Z", "1998-12-01T12:30:00Z"},
+		{"0 0 29 2 *", "", "1998-11-19T15:14:00Z", "2000-02-29T00:00:00Z"},
+		{"0 0 30 2 *", "", "1998-11-19T15:14:00Z", ""},
+		// Both day of month and day of week restricted: either matches.
+		{"0 12 1 * 0", "", "2023-10-02T00:00:00Z", "2023-10-08T12:00:00Z"},
+		// 02:30 doesn't exist when clocks are set forward, so that day
+		// is skipped.
+		{"30 2 * * *", "Europe/Stockholm", "2023-03-25T03:00:00+01:00", "2023-03-27T02:30:00+02:00"},
+		// 02:30 happens twice when clocks are set back, but only runs
+		// the first time.
+		{"30 2 * * *", "Europe/Stockholm", "2023-10-29T02:30:00+02:00", "2023-10-30T02:30:00+01:00"},
+		{"0 * * * *", "Europe/Stockholm", "2023-10-29T02:00:00+02:00", "2023-10-29T03:00:00+01:00"},
+	}
+	for i, tt := range cases {
+		s, err := ParseSchedule(tt.spec, tt.tz)
+		if err != nil {
+			t.Fatalf("[%d] ParseSchedule(%q, %q) => %v\n", i, tt.spec, tt.tz, err)
+		}
+		after, err := time.Parse(time.RFC3339, tt.after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want time.Time
+		if tt.want != "" {
+			if want, err = time.Parse(time.RFC3339, tt.want); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := s.Next(after); !got.Equal(want) {
+			t.Errorf("[%d] %v.Next(%v) => %v; want %v\n", i, s, after, got, want)
+		}
+	}
+}
+
+func TestParseSchedule_errors(t *testing.T) {
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 5-2 * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseSchedule(spec, ""); err == nil {
+			t.Errorf("ParseSchedule(%q) => nil error; want error\n", spec)
+		}
+	}
+	if _, err := ParseSchedule("* * * * *", "Nowhere/Special"); err == nil {
+		t.Errorf("ParseSchedule() with bad time zone => nil error; want error\n")
+	}
+}
@@ -0,0 +1,134 @@
+package prober
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SelfProber is a Prober that checks the health of the prober itself,
+// so that problems with the monitoring are alerted on like any other
+// problem. It fails if the process has too many goroutines or too large
+// a heap, if probes in the registry start late or have stalled, or if
+// records failed to be written to the YAML log file since the last run.
+//
+// The Alert() part of the Prober interface is provided by the embedded
+// AlertFn.
+type SelfProber struct {
+	AlertFn
+	Registry      *Registry     // registry to check the probes of, or nil
+	MaxGoroutines int           // most goroutines to allow, or 0 for 10000
+	MaxLag        time.Duration // how late probes may start, or 0 for a minute
+	MaxHeapBytes  uint64        // largest heap to allow, or 0 for no limit
+	lastLog       LogStats      // stats of the YAML log at the last run
+	logStats      func() LogStats
+	lock          sync.Mutex // protects lastLog
+}
+
+// Probe checks the health of the prober.
+func (sp *SelfProber) Probe() Result {
+	var problems []string
+	details := map[string]string{}
+
+	maxGoroutines := sp.MaxGoroutines
+	if maxGoroutines == 0 {
+		maxGoroutines = 10000
+	}
+	n := runtime.NumGoroutine()
+	details["goroutines"] = fmt.Sprint(n)
+	if n > maxGoroutines {
+		problems = append(problems, fmt.Sprintf("%d goroutines, over %d", n, maxGoroutines))
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	details["heap_bytes"] = fmt.Sprint(mem.HeapAlloc)
+	if sp.MaxHeapBytes > 0 && mem.HeapAlloc > sp.MaxHeapBytes {
+		problems = append(problems, fmt.Sprintf("heap of %d bytes, over %d", mem.HeapAlloc, sp.MaxHeapBytes))
+	}
+
+	if sp.Registry != nil {
+		problems = append(problems, sp.checkRegistry(details)...)
+	}
+	problems = append(problems, sp.checkLog(details)...)
+
+	if len(problems) > 0 {
+		return Result{
+			Code:    Fail,
+			Error:   fmt.Errorf("prober is unhealthy: %s", strings.Join(problems, "; ")),
+			Details: details,
+		}
+	}
+	r := PassedWith(fmt.Sprintf("prober is healthy, with %d goroutines", n), "")
+	r.Details = details
+	return r
+}
+
+// checkRegistry returns problems with the scheduling of the probes in
+// the registry.
+func (sp *SelfProber) checkRegistry(details map[string]string) []string {
+	var problems []string
+	maxLag := sp.MaxLag
+	if maxLag == 0 {
+		maxLag = time.Minute
+	}
+	var lagging []string
+	worst := time.Duration(0)
+	for _, p := range sp.Registry.Probes() {
+		lag := p.Stats().Lag
+		if lag > worst {
+			worst = lag
+		}
+		if lag > maxLag {
+			lagging = append(lagging, p.Name)
+		}
+	}
+	details["max_lag"] = worst.String()
+	if len(lagging) > 0 {
+		sort.Strings(lagging)
+		problems = append(problems, fmt.Sprintf("probes started over %v late: %s", maxLag, strings.Join(lagging, ", ")))
+	}
+
+	sp.Registry.lock.RLock()
+	var stalled []string
+	for name, s := range sp.Registry.stalled {
+		if s {
+			stalled = append(stalled, name)
+		}
+	}
+	sp.Registry.lock.RUnlock()
+	details["stalled"] = fmt.Sprint(len(stalled))
+	if len(stalled) > 0 {
+		sort.Strings(stalled)
+		problems = append(problems, fmt.Sprintf("probes stalled: %s", strings.Join(stalled, ", ")))
+	}
+	return problems
+}
+
+// checkLog returns problems with writing records to the YAML log file
+// since the last run.
+func (sp *SelfProber) checkLog(details map[string]string) []string {
+	stats := GetLogStats
+	if sp.logStats != nil {
+		stats = sp.logStats
+	}
+	s := stats()
+	sp.lock.Lock()
+	last := sp.lastLog
+	sp.lastLog = s
+	sp.lock.Unlock()
+
+	var problems []string
+	details["log_errors"] = fmt.Sprint(s.Errors)
+	details["log_dropped"] = fmt.Sprint(s.Dropped)
+	if n := s.Errors - last.Errors; n > 0 {
+		problems = append(problems, fmt.Sprintf("%d errors writing to the log", n))
+	}
+	if n := s.Dropped - last.Dropped; n > 0 {
+		problems = append(problems, fmt.Sprintf("%d records dropped from the log", n))
+	}
+	return problems
+}
@@ -0,0 +1,50 @@
+package prober
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelfProber_Probe(t *testing.T) {
+	late := &Probe{Name: "late", Interval: time.Minute, t: realTime{}}
+	late.stats.Lag = 5 * time.Minute
+	stuck := &Probe{Name: "stuck", Interval: time.Minute, t: realTime{}}
+	reg := NewRegistry(late, stuck)
+	reg.stalled["stuck"] = true
+
+	logStats := LogStats{}
+	cases := []struct {
+		in       *SelfProber
+		log      LogStats
+		want     ResultCode
+		wantErrs []string
+	}{
+		{in: &SelfProber{}, want: Pass},
+		{in: &SelfProber{MaxGoroutines: 1}, want: Fail, wantErrs: []string{"goroutines"}},
+		{in: &SelfProber{MaxHeapBytes: 1}, want: Fail, wantErrs: []string{"heap"}},
+		{in: &SelfProber{Registry: reg}, want: Fail, wantErrs: []string{"over 1m0s late: late", "stalled: stuck"}},
+		{in: &SelfProber{Registry: reg, MaxLag: time.Hour}, want: Fail, wantErrs: []string{"stalled: stuck"}},
+		{in: &SelfProber{}, log: LogStats{Errors: 2, Dropped: 1}, want: Fail, wantErrs: []string{"2 errors", "1 records dropped"}},
+	}
+	for i, tt := range cases {
+		logStats = tt.log
+		tt.in.logStats = func() LogStats { return logStats }
+		got := tt.in.Probe()
+		if got.Code != tt.want {
+			t.Errorf("[%d] Probe() => %v; want code %v\n", i, got, tt.want)
+			continue
+		}
+		for _, want := range tt.wantErrs {
+			if got.Error == nil || !strings.Contains(got.Error.Error(), want) {
+				t.Errorf("[%d] Probe() => %v; want error containing %q\n", i, got.Error, want)
+			}
+		}
+		if tt.want == Fail && strings.Contains(got.Error.Error(), "log") {
+			// Problems with the log are only reported once.
+			if again := tt.in.Probe(); again.Code != Pass {
+				t.Errorf("[%d] second Probe() => %v; want pass with no new log problems\n", i, again)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package prober
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IsActive returns true if the silence is in effect at the time now.
+func (t SilenceTime) IsActive(now time.Time) bool {
+	return t.After(now)
+}
+
+// Remaining returns how long the silence is in effect after the time
+// now, or 0 if it isn't active.
+func (t SilenceTime) Remaining(now time.Time) time.Duration {
+	if !t.IsActive(now) {
+		return 0
+	}
+	return t.Sub(now)
+}
+
+// String returns a human-readable description of the time until which a probe is silenced.
+func (t SilenceTime) String() string {
+	if t.IsZero() {
+		return "not silenced"
+	}
+	return fmt.Sprintf("%s (%f hrs more)", t.Format(time.RFC822), t.Sub(time.Now()).Hours())
+}
+
+// MarshalJSON encodes the time in RFC 3339 format, or as null for the
+// zero value.
+func (t SilenceTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Format(time.RFC3339))
+}
+
+// UnmarshalJSON decodes a time in RFC 3339 format, with null or "" as
+// the zero value.
+func (t *SilenceTime) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*t = SilenceTime{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("silence time must be a string: %v", err)
+	}
+	return t.parse(s)
+}
+
+// MarshalYAML encodes the time in RFC 3339 format, or as null for the
+// zero value.
+func (t SilenceTime) MarshalYAML() (interface{}, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t.Format(time.RFC3339), nil
+}
+
+// UnmarshalYAML decodes a time in RFC 3339 format, with null or "" as
+// the zero value.
+func (t *SilenceTime) UnmarshalYAML(n *yaml.Node) error {
+	if n.Tag == "!!null" {
+		*t = SilenceTime{}
+		return nil
+	}
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return fmt.Errorf("silence time must be a string: %v", err)
+	}
+	return t.parse(s)
+}
+
+// parse sets the time from RFC 3339 format, or to the zero value if s
+// is empty.
+func (t *SilenceTime) parse(s string) error {
+	if s == "" {
+		*t = SilenceTime{}
+		return nil
+	}
+	ts, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf("bad silence time %q: %v", s, err)
+	}
+	*t = SilenceTime{ts}
+	return nil
+}
@@ -0,0 +1,80 @@
+package prober
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSilenceTime_IsActive(t *testing.T) {
+	now := time.Date(2016, time.June, 15, 15, 4, 0, 0, time.UTC)
+	cases := []struct {
+		in            SilenceTime
+		wantActive    bool
+		wantRemaining time.Duration
+	}{
+		{in: SilenceTime{}},
+		{in: SilenceTime{now.Add(-time.Hour)}},
+		{in: SilenceTime{now}},
+		{in: SilenceTime{now.Add(time.Hour)}, wantActive: true, wantRemaining: time.Hour},
+	}
+	for i, tt := range cases {
+		if got := tt.in.IsActive(now); got != tt.wantActive {
+			t.Errorf("[%d] %v.IsActive(%v) => %v; want %v\n", i, tt.in, now, got, tt.wantActive)
+		}
+		if got := tt.in.Remaining(now); got != tt.wantRemaining {
+			t.Errorf("[%d] %v.Remaining(%v) => %v; want %v\n", i, tt.in, now, got, tt.wantRemaining)
+		}
+	}
+}
+
+func TestSilenceTime_marshal(t *testing.T) {
+	type config struct {
+		SilencedUntil SilenceTime `json:"silenced_until" yaml:"silenced_until"`
+	}
+	until := SilenceTime{time.Date(2016, time.June, 15, 15, 4, 0, 0, time.UTC)}
+	for _, in := range []SilenceTime{{}, until} {
+		b, err := json.Marshal(config{in})
+		if err != nil {
+			t.Fatalf("json.Marshal(%v) => %v", in, err)
+		}
+		var got config
+		if err := json.Unmarshal(b, &got); err != nil || !got.SilencedUntil.Equal(in.Time) {
+			t.Errorf("JSON round trip of %v via %s => %v, %v\n", in, b, got.SilencedUntil, err)
+		}
+
+		b, err = yaml.Marshal(config{in})
+		if err != nil {
+			t.Fatalf("yaml.Marshal(%v) => %v", in, err)
+		}
+		got = config{}
+		if err := yaml.Unmarshal(b, &got); err != nil || !got.SilencedUntil.Equal(in.Time) {
+			t.Errorf("YAML round trip of %v via %s => %v, %v\n", in, b, got.SilencedUntil, err)
+		}
+	}
+
+	cases := []struct {
+		in      string
+		want    SilenceTime
+		wantErr bool
+	}{
+		{in: "silenced_until: 2016-06-15T15:04:00Z\n", want: until},
+		{in: "silenced_until: \"\"\n"},
+		{in: "silenced_until:\n"},
+		{in: "silenced_until: tomorrow\n", wantErr: true},
+		{in: "silenced_until: [1]\n", wantErr: true},
+	}
+	for i, tt := range cases {
+		var got config
+		err := yaml.Unmarshal([]byte(tt.in), &got)
+		if (err != nil) != tt.wantErr || !got.SilencedUntil.Equal(tt.want.Time) {
+			t.Errorf("[%d] yaml.Unmarshal(%q) => %v, %v; want %v, error: %v\n", i, tt.in, got.SilencedUntil, err, tt.want, tt.wantErr)
+		}
+	}
+	var got config
+	if err := json.Unmarshal([]byte(`{"silenced_until": 1}`), &got); err == nil {
+		t.Errorf("json.Unmarshal() of number => nil; want error\n")
+	}
+}
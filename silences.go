@@ -0,0 +1,196 @@
+package prober
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+)
+
+type (
+	// Silence is a silence of all probes in a registry that match a
+	// selector, see Registry.SilenceMatching().
+	Silence struct {
+		Selector string    // the selector probes are matched by, e.g. "env=staging"
+		Until    time.Time // when the silence ends
+	}
+
+	// selector matches probes by their name and labels.
+	selector struct {
+		names     []string          // globs of which the name must match all
+		labels    map[string]string // globs that the value of each label must match
+		notLabels map[string]string // globs that the value of each label must not match
+	}
+)
+
+// parseSelector parses a comma-separated list of terms that a probe
+// must all satisfy to be selected. Each term is either "key=value" or
+// "key!=value" for the labels of the probe, or a glob for its name, as
+// in "web-*,env=staging". Values of labels may be globs too.
+func parseSelector(s string) (selector, error) {
+	sel := selector{labels: map[string]string{}, notLabels: map[string]string{}}
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return selector{}, fmt.Errorf("empty term in selector %q", s)
+		}
+		key, value, glob := term, "", term
+		dst := map[string]string(nil)
+		if i := strings.Index(term, "!="); i >= 0 {
+			key, value, dst = term[:i], term[i+2:], sel.notLabels
+		} else if i := strings.Index(term, "="); i >= 0 {
+			key, value, dst = term[:i], term[i+1:], sel.labels
+		}
+		if dst != nil {
+			if key == "" {
+				return selector{}, fmt.Errorf("no label in term %q of selector %q", term, s)
+			}
+			glob = value
+		}
+		if _, err := path.Match(glob, ""); err != nil {
+			return selector{}, fmt.Errorf("bad pattern in term %q of selector %q: %v", term, s, err)
+		}
+		if dst != nil {
+			dst[key] = value
+		} else {
+			sel.names = append(sel.names, term)
+		}
+	}
+	return sel, nil
+}
+
+// matches returns true if the probe is selected.
+func (sel selector) matches(p *Probe) bool {
+	for _, glob := range sel.names {
+		if ok, _ := path.Match(glob, p.Name); !ok {
+			return false
+		}
+	}
+	for k, glob := range sel.labels {
+		v, found := p.Labels[k]
+		if ok, _ := path.Match(glob, v); !found || !ok {
+			return false
+		}
+	}
+	for k, glob := range sel.notLabels {
+		if ok, _ := path.Match(glob, p.Labels[k]); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SilenceMatching silences all probes in the registry matching the
+// selector for the duration, including probes added while the silence
+// is active, returning the silence.
+//
+// The selector is a comma-separated list of terms that a probe must all
+// satisfy, each either "key=value" or "key!=value" for its labels, or a
+// glob for its name, e.g. "env=staging" or "web-*,region!=eu-*".
+//
+// Probes that are already silenced for longer stay silenced for longer.
+func (r *Registry) SilenceMatching(sel string, d time.Duration) (Silence, error) {
+	parsed, err := parseSelector(sel)
+	if err != nil {
+		return Silence{}, err
+	}
+	if d <= 0 {
+		return Silence{}, fmt.Errorf("bad duration to silence for: %v", d)
+	}
+	s := Silence{Selector: sel, Until: time.Now().Add(d)}
+
+	r.lock.Lock()
+	r.silences = append(r.activeSilences(time.Now()), s)
+	n := 0
+	for _, p := range r.probes {
+		if parsed.matches(p) {
+			p.addSilence(s)
+			n++
+		}
+	}
+	r.lock.Unlock()
+	log.Printf("silenced %d probes matching %q until %v\n", n, sel, s.Until)
+	if err := r.saveSilences(); err != nil {
+		log.Printf("failed to save silences: %v\n", err)
+	}
+	return s, nil
+}
+
+// Silences returns the silences of the registry that are still
+// active.
+func (r *Registry) Silences() []Silence {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.activeSilences(time.Now())
+}
+
+// activeSilences returns the silences that are active at the time now.
+// The caller must hold the lock.
+func (r *Registry) activeSilences(now time.Time) []Silence {
+	var active []Silence
+	for _, s := range r.silences {
+		if s.Until.After(now) {
+			active = append(active, s)
+		}
+	}
+	return active
+}
+
+// applySilences silences the probe according to any active silences
+// matching it. The caller must hold the lock.
+func (r *Registry) applySilences(p *Probe) {
+	for _, s := range r.activeSilences(time.Now()) {
+		// Selectors were validated when the silence was added.
+		if sel, err := parseSelector(s.Selector); err == nil && sel.matches(p) {
+			p.addSilence(s)
+		}
+	}
+}
+
+// addSilence silences the probe until the silence ends, unless it's
+// already silenced for longer.
+func (p *Probe) addSilence(s Silence) {
+	p.alertLock.Lock()
+	var active []Silence
+	for _, old := range p.silences {
+		if old.Until.After(time.Now()) {
+			active = append(active, old)
+		}
+	}
+	p.silences = append(active, s)
+	p.alertLock.Unlock()
+	p.silenceAtLeast(s.Until)
+}
+
+// silenceAtLeast silences the probe until the time, unless it's
+// already silenced for longer.
+func (p *Probe) silenceAtLeast(until time.Time) {
+	p.controlLock.Lock()
+	longer := until.After(p.SilencedUntil.Time)
+	if longer {
+		p.SilencedUntil = SilenceTime{until}
+	}
+	p.controlLock.Unlock()
+	if longer {
+		log.Printf("[%s] is now silenced until %v\n", p.Name, until)
+	}
+}
+
+// Silences returns the active silences of a registry that matched the
+// probe, see Registry.SilenceMatching().
+func (p *Probe) Silences() []Silence {
+	now := time.Now()
+	if p.t != nil {
+		now = p.t.Now()
+	}
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	var active []Silence
+	for _, s := range p.silences {
+		if s.Until.After(now) {
+			active = append(active, s)
+		}
+	}
+	return active
+}
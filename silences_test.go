@@ -0,0 +1,92 @@
+package prober
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSelector(t *testing.T) {
+	newProbe := func(name string, labels map[string]string) *Probe {
+		return &Probe{Name: name, Labels: labels}
+	}
+	web := newProbe("web-1", map[string]string{"env": "staging", "region": "eu-west"})
+	db := newProbe("db-1", map[string]string{"env": "prod", "region": "us-east"})
+	cases := []struct {
+		in      string
+		want    []bool // whether web and db match
+		wantErr bool
+	}{
+		{in: "env=staging", want: []bool{true, false}},
+		{in: "web-*", want: []bool{true, false}},
+		{in: "*-1,region=*-east", want: []bool{false, true}},
+		{in: "region!=eu-*", want: []bool{false, true}},
+		{in: "owner!=ops", want: []bool{true, true}},
+		{in: "owner=", want: []bool{false, false}},
+		{in: "env=prod, db-*", want: []bool{false, true}},
+		{in: "", wantErr: true},
+		{in: "env=prod,", wantErr: true},
+		{in: "=prod", wantErr: true},
+		{in: "web-[", wantErr: true},
+	}
+	for i, tt := range cases {
+		sel, err := parseSelector(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("[%d] parseSelector(%q) => %v; want error: %v\n", i, tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		for j, p := range []*Probe{web, db} {
+			if got := sel.matches(p); got != tt.want[j] {
+				t.Errorf("[%d] parseSelector(%q).matches(%s) => %v; want %v\n", i, tt.in, p.Name, got, tt.want[j])
+			}
+		}
+	}
+}
+
+func TestRegistry_SilenceMatching(t *testing.T) {
+	newProbe := func(name, env string) *Probe {
+		return &Probe{Name: name, Labels: map[string]string{"env": env}, t: realTime{}}
+	}
+	staging, prod := newProbe("web-staging", "staging"), newProbe("web-prod", "prod")
+	reg := NewRegistry(staging, prod)
+	if _, err := reg.SilenceMatching("env=[", time.Hour); err == nil {
+		t.Errorf("SilenceMatching() with bad selector => nil error; want error\n")
+	}
+	if _, err := reg.SilenceMatching("env=staging", 0); err == nil {
+		t.Errorf("SilenceMatching() for 0s => nil error; want error\n")
+	}
+
+	long := time.Now().Add(24 * time.Hour)
+	staging.Silence(long)
+	s, err := reg.SilenceMatching("env=staging", 4*time.Hour)
+	if err != nil {
+		t.Fatalf("SilenceMatching() => %v; want nil", err)
+	}
+	if !staging.SilencedUntil.Equal(long) {
+		t.Errorf("after SilenceMatching(), %s is silenced until %v; want longer silence until %v kept\n", staging.Name, staging.SilencedUntil, long)
+	}
+	if prod.Silenced() {
+		t.Errorf("after SilenceMatching(), %s is silenced; want it not to match\n", prod.Name)
+	}
+	if got := staging.Status().Silences; len(got) != 1 || got[0] != s {
+		t.Errorf("Status().Silences => %v; want [%v]\n", got, s)
+	}
+
+	later := newProbe("db-staging", "staging")
+	if err := reg.Add(later); err != nil {
+		t.Fatal(err)
+	}
+	if !later.Silenced() || !later.SilencedUntil.Equal(s.Until) {
+		t.Errorf("probe added during silence is silenced until %v; want %v\n", later.SilencedUntil, s.Until)
+	}
+	if got := reg.Silences(); len(got) != 1 || got[0] != s {
+		t.Errorf("Silences() => %v; want [%v]\n", got, s)
+	}
+
+	reg.silences[0].Until = time.Now().Add(-time.Second)
+	if got := reg.Silences(); len(got) != 0 {
+		t.Errorf("Silences() after silence ended => %v; want none\n", got)
+	}
+}
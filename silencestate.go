@@ -0,0 +1,133 @@
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SilenceState is the state of the silences of a registry, which can be
+// exported and imported, e.g. to keep silences across restarts, or to
+// move them to another prober host.
+type SilenceState struct {
+	Silences []Silence            // active silences of probes matching selectors, see Registry.SilenceMatching()
+	Probes   map[string]time.Time // when each individually silenced probe is silenced until, see Probe.Silence()
+}
+
+// ExportSilences returns the state of the active silences of the
+// registry and its probes.
+func (r *Registry) ExportSilences() SilenceState {
+	now := time.Now()
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	st := SilenceState{Silences: r.activeSilences(now), Probes: map[string]time.Time{}}
+	for name, p := range r.probes {
+		if until := p.silencedUntil(); until.After(now) {
+			st.Probes[name] = until
+		}
+	}
+	return st
+}
+
+// ImportSilences adds the silences that are still active in the state
+// to the registry and its probes. Probes that are already silenced for
+// longer stay silenced for longer, and silences of probes that aren't
+// in the registry are skipped.
+func (r *Registry) ImportSilences(st SilenceState) error {
+	now := time.Now()
+	for _, s := range st.Silences {
+		if _, err := parseSelector(s.Selector); err != nil {
+			return err
+		}
+	}
+	r.lock.Lock()
+	r.silences = r.activeSilences(now)
+	for _, s := range st.Silences {
+		if !s.Until.After(now) || hasSilence(r.silences, s) {
+			continue
+		}
+		r.silences = append(r.silences, s)
+		sel, _ := parseSelector(s.Selector)
+		for _, p := range r.probes {
+			if sel.matches(p) {
+				p.addSilence(s)
+			}
+		}
+	}
+	for name, until := range st.Probes {
+		p, ok := r.probes[name]
+		switch {
+		case !ok:
+			log.Printf("not importing silence of unknown probe %q\n", name)
+		case until.After(now):
+			p.silenceAtLeast(until)
+		}
+	}
+	r.lock.Unlock()
+	return r.saveSilences()
+}
+
+// hasSilence returns true if the silence is one of the silences.
+func hasSilence(silences []Silence, s Silence) bool {
+	for _, old := range silences {
+		if old.Selector == s.Selector && old.Until.Equal(s.Until) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadSilences imports the silences saved in the SilencePath of the
+// registry, if it's set and the file exists.
+func (r *Registry) LoadSilences() error {
+	if r.SilencePath == "" {
+		return nil
+	}
+	b, err := os.ReadFile(r.SilencePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var st SilenceState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return fmt.Errorf("bad silences in %s: %v", r.SilencePath, err)
+	}
+	return r.ImportSilences(st)
+}
+
+// saveSilences atomically writes the silences to the SilencePath of the
+// registry, if it's set.
+func (r *Registry) saveSilences() error {
+	if r.SilencePath == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(r.ExportSilences(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.SilencePath), 0700); err != nil {
+		return err
+	}
+	tmp := r.SilencePath + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.SilencePath)
+}
+
+// SilenceProbe silences the named probe until the time, keeping the
+// silence in the SilencePath of the registry if it's set. SilenceProbe
+// returns false if there's no such probe.
+func (r *Registry) SilenceProbe(name string, until time.Time) (bool, error) {
+	p, ok := r.Get(name)
+	if !ok {
+		return false, nil
+	}
+	p.Silence(until)
+	return true, r.saveSilences()
+}
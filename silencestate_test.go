@@ -0,0 +1,62 @@
+package prober
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegistry_ExportSilences(t *testing.T) {
+	newProbe := func(name, env string) *Probe {
+		return &Probe{Name: name, Labels: map[string]string{"env": env}, t: realTime{}}
+	}
+	path := filepath.Join(t.TempDir(), "state", "silences.json")
+	reg := NewRegistry(newProbe("web-staging", "staging"), newProbe("web-prod", "prod"), newProbe("db-prod", "prod"))
+	reg.SilencePath = path
+	s, err := reg.SilenceMatching("env=staging", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := time.Now().Add(2 * time.Hour)
+	if ok, err := reg.SilenceProbe("db-prod", until); !ok || err != nil {
+		t.Fatalf("SilenceProbe() => %v, %v; want true, nil", ok, err)
+	}
+
+	// A new prober on another host picks up the silences from the file,
+	// including for probes added after loading them.
+	moved := NewRegistry(newProbe("web-prod", "prod"), newProbe("db-prod", "prod"))
+	moved.SilencePath = path
+	if err := moved.LoadSilences(); err != nil {
+		t.Fatalf("LoadSilences() => %v; want nil", err)
+	}
+	if err := moved.Add(newProbe("web-staging", "staging")); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		want bool
+	}{{"web-staging", true}, {"web-prod", false}, {"db-prod", true}} {
+		p, _ := moved.Get(tt.name)
+		if got := p.Silenced(); got != tt.want {
+			t.Errorf("%s after LoadSilences() => silenced %v; want %v\n", tt.name, got, tt.want)
+		}
+	}
+	st := moved.ExportSilences()
+	if len(st.Silences) != 1 || !hasSilence(st.Silences, s) {
+		t.Errorf("ExportSilences() => silences %v; want [%v]\n", st.Silences, s)
+	}
+	if got := st.Probes["db-prod"]; !got.Equal(until) {
+		t.Errorf("ExportSilences() => db-prod silenced until %v; want %v\n", got, until)
+	}
+
+	// Importing the same state again adds nothing.
+	if err := moved.ImportSilences(st); err != nil {
+		t.Fatal(err)
+	}
+	if got := moved.Silences(); len(got) != 1 {
+		t.Errorf("Silences() after importing twice => %v; want 1 silence\n", got)
+	}
+	if err := moved.ImportSilences(SilenceState{Silences: []Silence{{Selector: "env=[", Until: until}}}); err == nil {
+		t.Errorf("ImportSilences() with bad selector => nil error; want error\n")
+	}
+}
@@ -0,0 +1,105 @@
+package prober
+
+import (
+	"fmt"
+	"time"
+)
+
+// BurnRateWindow is a condition of an SLO() alert policy: the probe
+// alerts when the error budget burns at least Rate times faster than
+// sustainable over both the Long window and the Short one. The Short
+// window makes the probe stop alerting soon after the errors stop.
+type BurnRateWindow struct {
+	Long, Short time.Duration
+	Rate        float64
+}
+
+// DefaultBurnRateWindows alert when 2% of a 30 day error budget is
+// spent within an hour, or 5% within six hours, as recommended in the
+// Google SRE workbook.
+var DefaultBurnRateWindows = []BurnRateWindow{
+	{Long: time.Hour, Short: 5 * time.Minute, Rate: 14.4},
+	{Long: 6 * time.Hour, Short: 30 * time.Minute, Rate: 6},
+}
+
+// SLO makes the probe alert on the rate at which it's spending its
+// error budget, rather than when its badness reaches the alert
+// threshold, e.g. for a target availability of 99.9%:
+//
+//	SLO(0.999)
+//
+// The error rate over a window is the fraction of runs in it that
+// failed, Degraded results counting as available. The burn rate is the
+// error rate divided by the error budget 1-target, so a burn rate of 1
+// spends exactly the budget. The probe alerts while any of the windows
+// exceed their burn rate, by default DefaultBurnRateWindows.
+//
+// Only the most recent records of the probe are kept, so windows much
+// longer than a few hundred runs of the probe are cut short unless
+// CompactRecords() merges runs. AlertWhen() takes precedence over SLO().
+func SLO(target float64, windows ...BurnRateWindow) func(*Probe) {
+	return func(p *Probe) {
+		p.sloTarget = target
+		p.sloWindows = windows
+	}
+}
+
+// BurnRate returns how many times faster than sustainable the probe
+// spent its error budget over the window until the time now, or 0 if
+// it has no SLO() or no runs in the window.
+func (p *Probe) BurnRate(now time.Time, window time.Duration) float64 {
+	if p.sloTarget <= 0 || p.sloTarget >= 1 {
+		return 0
+	}
+	var runs, errors int
+	for _, r := range p.Records() {
+		last := r.Timestamp
+		if r.Repeats > 0 {
+			last = r.Until
+		}
+		if last.After(now) || now.Sub(last) > window {
+			continue
+		}
+		runs += 1 + r.Repeats
+		if !r.Result.Passed() && r.Result.Code != Degraded {
+			errors += 1 + r.Repeats
+		}
+	}
+	if runs == 0 {
+		return 0
+	}
+	return float64(errors) / float64(runs) / (1 - p.sloTarget)
+}
+
+// burnRateWindows returns the windows of the SLO() of the probe.
+func (p *Probe) burnRateWindows() []BurnRateWindow {
+	if len(p.sloWindows) > 0 {
+		return p.sloWindows
+	}
+	return DefaultBurnRateWindows
+}
+
+// burning returns the first window whose burn rate is exceeded at the
+// time now, and whether there is one.
+func (p *Probe) burning(now time.Time) (BurnRateWindow, bool) {
+	for _, w := range p.burnRateWindows() {
+		if p.BurnRate(now, w.Long) >= w.Rate && p.BurnRate(now, w.Short) >= w.Rate {
+			return w, true
+		}
+	}
+	return BurnRateWindow{}, false
+}
+
+// sloNote returns a note on the burn rate of the error budget for
+// notifications, or "" if the probe has no SLO() or it's not exceeded.
+func (p *Probe) sloNote() string {
+	if p.sloTarget == 0 {
+		return ""
+	}
+	now := p.t.Now()
+	w, ok := p.burning(now)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" [error budget of %.4g%% SLO burning %.3gx over %v]", p.sloTarget*100, p.BurnRate(now, w.Long), w.Long)
+}
@@ -0,0 +1,86 @@
+package prober
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbe_BurnRate(t *testing.T) {
+	now := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{Name: "SLOProber", t: fakeTime{now}}
+	p.records = Records{
+		{Timestamp: now.Add(-2 * time.Hour), Result: FailedWith(errors.New("old"))},
+		{Timestamp: now.Add(-50 * time.Minute), Result: Passed(), Repeats: 7, Until: now.Add(-10 * time.Minute)},
+		{Timestamp: now.Add(-2 * time.Minute), Result: FailedWith(errors.New("down"))},
+		{Timestamp: now.Add(-time.Minute), Result: DegradedWith(errors.New("slow"), "")},
+	}
+	if got := p.BurnRate(now, time.Hour); got != 0 {
+		t.Errorf("BurnRate() without SLO() => %v; want 0\n", got)
+	}
+	SLO(0.9)(p)
+	cases := []struct {
+		window time.Duration
+		want   float64
+	}{
+		{time.Hour, 1},
+		{5 * time.Minute, 5},
+		{30 * time.Second, 0},
+	}
+	for i, tt := range cases {
+		if got := p.BurnRate(now, tt.window); got < tt.want-1e-9 || got > tt.want+1e-9 {
+			t.Errorf("[%d] BurnRate(%v) => %v; want %v\n", i, tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestSLO(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	var records Records
+	// A run every minute for two hours, failing for five minutes after
+	// the first hour.
+	for i := 0; i < 120; i++ {
+		r := Passed()
+		if i >= 60 && i < 65 {
+			r = FailedWith(errors.New("down"))
+		}
+		records = append(records, Record{Timestamp: start.Add(time.Duration(i) * time.Minute), Result: r})
+	}
+	p := &Probe{Name: "SLOProber", failurePenalty: 10, successReward: 1}
+
+	cases := []struct {
+		options   []Option
+		wantFirst time.Time
+	}{
+		{},
+		{options: []Option{SLO(0.999)}, wantFirst: start.Add(60 * time.Minute)},
+		{options: []Option{SLO(0.9)}},
+		{options: []Option{SLO(0.99, BurnRateWindow{Long: time.Hour, Short: 5 * time.Minute, Rate: 5})}, wantFirst: start.Add(63 * time.Minute)},
+	}
+	for i, tt := range cases {
+		got := p.Replay(records, tt.options...)
+		if tt.wantFirst.IsZero() {
+			if got.Alerts() != 0 {
+				t.Errorf("[%d] Replay() => %v; want no alerts\n", i, got)
+			}
+			continue
+		}
+		if got.Alerts() == 0 || !got.Notifications[0].Time.Equal(tt.wantFirst) {
+			t.Errorf("[%d] Replay() => %v; want first alert at %v\n", i, got, tt.wantFirst)
+		}
+	}
+}
+
+func TestProbe_sloNote(t *testing.T) {
+	now := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{Name: "SLOProber", t: fakeTime{now}}
+	SLO(0.999)(p)
+	if got := p.sloNote(); got != "" {
+		t.Errorf("sloNote() without failures => %q; want \"\"\n", got)
+	}
+	p.records = Records{{Timestamp: now, Result: FailedWith(errors.New("down"))}}
+	if got := p.sloNote(); !strings.Contains(got, "99.9% SLO burning 1e+03x over 1h0m0s") {
+		t.Errorf("sloNote() => %q; want burn rate of the first window\n", got)
+	}
+}
@@ -0,0 +1,192 @@
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	// Snapshot is a copy of the state of a probe, which unlike Probe
+	// itself can be encoded with encoding/json or encoding/gob, e.g. to
+	// send it over RPC or persist it.
+	Snapshot struct {
+		Name, Desc     string
+		Location       string
+		Labels         map[string]string
+		Interval       time.Duration
+		Disabled       bool
+		SilencedUntil  time.Time
+		Badness        int
+		FailurePenalty int
+		SuccessReward  int
+		Alerting       bool
+		LastAlert      time.Time
+		LastSuccess    time.Time
+		LastFailure    time.Time
+		Records        Records
+		Scheduler      SchedulerStats
+		FailureHistory []time.Time // see Probe.FailureHistory()
+	}
+
+	// encodedResult is the form in which a Result is encoded.
+	encodedResult struct {
+		Code    string
+		Error   string            `json:",omitempty"`
+		Info    string            `json:",omitempty"`
+		InfoUrl string            `json:",omitempty"`
+		Details map[string]string `json:",omitempty"`
+		Weight  float64           `json:",omitempty"`
+		Value   *float64          `json:",omitempty"`
+	}
+)
+
+// Snapshot returns a copy of the current state of the probe.
+func (p *Probe) Snapshot() Snapshot {
+	records := p.Records()
+	return Snapshot{
+		Name:           p.Name,
+		Desc:           p.Desc,
+		Location:       p.Location,
+		Labels:         copyLabels(p.Labels),
+		Interval:       p.Interval,
+		Disabled:       p.IsDisabled(),
+		SilencedUntil:  p.silencedUntil(),
+		Badness:        p.Badness(),
+		FailurePenalty: p.failurePenalty,
+		SuccessReward:  p.successReward,
+		Alerting:       p.IsAlerting(),
+		LastAlert:      p.getLastAlert(),
+		LastSuccess:    p.LastSuccess(),
+		LastFailure:    p.LastFailure(),
+		Records:        append(Records{}, records...),
+		Scheduler:      p.Stats(),
+		FailureHistory: p.FailureHistory(),
+	}
+}
+
+// Restore sets the state of the probe to that of the snapshot.
+func (s Snapshot) Restore(p *Probe) {
+	p.Name = s.Name
+	p.Desc = s.Desc
+	p.Location = s.Location
+	p.Labels = copyLabels(s.Labels)
+	p.Interval = s.Interval
+	p.setDisabled(s.Disabled)
+	p.controlLock.Lock()
+	p.SilencedUntil = SilenceTime{s.SilencedUntil}
+	p.controlLock.Unlock()
+	p.failurePenalty = s.FailurePenalty
+	p.successReward = s.SuccessReward
+	p.setBadness(s.Badness)
+	p.setIsAlerting(s.Alerting)
+	p.setLastAlert(s.LastAlert)
+	p.setLastOutcome(true, s.LastSuccess)
+	p.setLastOutcome(false, s.LastFailure)
+	p.alertLock.Lock()
+	p.failureStarts = append([]time.Time{}, s.FailureHistory...)
+	p.alertLock.Unlock()
+	size := 0
+	for _, r := range s.Records {
+		size += r.size()
+	}
+	p.recordsLock.Lock()
+	p.records = append(Records{}, s.Records...)
+	added := size - p.recordBytes
+	p.recordBytes = size
+	p.recordsLock.Unlock()
+	trackRecordBytes(p, added)
+	p.statsLock.Lock()
+	p.stats = s.Scheduler
+	p.statsLock.Unlock()
+}
+
+// copyLabels returns a copy of the labels, or nil if there are none.
+func copyLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	c := make(map[string]string, len(labels))
+	for k, v := range labels {
+		c[k] = v
+	}
+	return c
+}
+
+// encoded returns the Result in the form in which it's encoded.
+func (r Result) encoded() encodedResult {
+	e := encodedResult{
+		Code:    r.Code.String(),
+		Info:    r.Info,
+		InfoUrl: r.InfoUrl,
+		Details: r.Details,
+		Weight:  r.Weight,
+		Value:   r.Value,
+	}
+	if r.Error != nil {
+		e.Error = r.Error.Error()
+	}
+	return e
+}
+
+// decoded returns the Result that was encoded.
+func (e encodedResult) decoded() (Result, error) {
+	r := Result{
+		Info:    e.Info,
+		InfoUrl: e.InfoUrl,
+		Details: e.Details,
+		Weight:  e.Weight,
+		Value:   e.Value,
+	}
+	code, err := parseResultCode(e.Code)
+	if err != nil {
+		return Result{}, err
+	}
+	r.Code = code
+	if e.Error != "" {
+		r.Error = errors.New(e.Error)
+	}
+	return r, nil
+}
+
+// parseResultCode returns the ResultCode with the English name.
+func parseResultCode(s string) (ResultCode, error) {
+	for i, name := range results {
+		if name == s {
+			return ResultCode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown result code %q", s)
+}
+
+// MarshalJSON returns the JSON encoding of the Result, with the error
+// as a string.
+func (r Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.encoded())
+}
+
+// UnmarshalJSON sets the Result from its JSON encoding.
+func (r *Result) UnmarshalJSON(b []byte) error {
+	var e encodedResult
+	if err := json.Unmarshal(b, &e); err != nil {
+		return err
+	}
+	decoded, err := e.decoded()
+	if err != nil {
+		return err
+	}
+	*r = decoded
+	return nil
+}
+
+// GobEncode returns the encoding of the Result for encoding/gob, which
+// can't handle the Error interface on its own.
+func (r Result) GobEncode() ([]byte, error) {
+	return r.MarshalJSON()
+}
+
+// GobDecode sets the Result from its encoding/gob encoding.
+func (r *Result) GobDecode(b []byte) error {
+	return r.UnmarshalJSON(b)
+}
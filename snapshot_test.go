@@ -0,0 +1,97 @@
+package prober
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshot_roundTrip(t *testing.T) {
+	ts := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{
+		Name:           "TestProber1",
+		Desc:           "A test prober.",
+		Location:       "eu",
+		Interval:       time.Minute,
+		SilencedUntil:  SilenceTime{ts.Add(time.Hour)},
+		badness:        30,
+		failurePenalty: 10,
+		successReward:  1,
+		lastAlert:      ts.Add(-time.Hour),
+		records: Records{
+			{Timestamp: ts, TimeMillis: "Nov 19 15:14:00.000", Result: Passed()},
+			{
+				Timestamp:  ts.Add(time.Minute),
+				TimeMillis: "Nov 19 15:15:00.000",
+				Result: Result{
+					Code:    Fail,
+					Error:   errors.New("failing on purpose"),
+					Info:    "more info",
+					Details: map[string]string{"ttfb": "1s"},
+				},
+			},
+		},
+	}
+	want := p.Snapshot()
+
+	encodings := []struct {
+		name   string
+		encode func(Snapshot) ([]byte, error)
+		decode func([]byte, *Snapshot) error
+	}{
+		{
+			name:   "json",
+			encode: func(s Snapshot) ([]byte, error) { return json.Marshal(s) },
+			decode: func(b []byte, s *Snapshot) error { return json.Unmarshal(b, s) },
+		},
+		{
+			name: "gob",
+			encode: func(s Snapshot) ([]byte, error) {
+				var buf bytes.Buffer
+				err := gob.NewEncoder(&buf).Encode(s)
+				return buf.Bytes(), err
+			},
+			decode: func(b []byte, s *Snapshot) error { return gob.NewDecoder(bytes.NewReader(b)).Decode(s) },
+		},
+	}
+	for _, e := range encodings {
+		b, err := e.encode(want)
+		if err != nil {
+			t.Errorf("[%s] failed to encode %+v: %v\n", e.name, want, err)
+			continue
+		}
+		var got Snapshot
+		if err := e.decode(b, &got); err != nil {
+			t.Errorf("[%s] failed to decode %s: %v\n", e.name, b, err)
+			continue
+		}
+		restored := &Probe{}
+		got.Restore(restored)
+		if !restored.Equal(p) || !reflect.DeepEqual(got.Records[1].Result.Details, want.Records[1].Result.Details) {
+			t.Errorf("[%s] round trip of %+v => %+v\n", e.name, want, got)
+		}
+	}
+}
+
+func TestSnapshot_Restore(t *testing.T) {
+	ts := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{Name: "TestProber1", Labels: map[string]string{"env": "prod"}}
+	p.addRecord(Record{Timestamp: ts, Result: Passed()})
+	s := p.Snapshot()
+	s.Records = append(s.Records, Record{Timestamp: ts.Add(time.Minute), Result: FailedWith(errors.New("failing on purpose"))})
+
+	restored := &Probe{}
+	restored.addRecord(Record{Timestamp: ts, Result: FailedWith(errors.New("replaced by the snapshot"))})
+	s.Restore(restored)
+	if got, want := restored.RecordBytes(), s.Records[0].size()+s.Records[1].size(); got != want {
+		t.Errorf("RecordBytes() after Restore() => %d; want %d\n", got, want)
+	}
+	restored.Labels["env"] = "dev"
+	if p.Labels["env"] != "prod" || s.Labels["env"] != "prod" {
+		t.Errorf("changing labels of restored probe changed %v and %v; want them copied\n", p.Labels, s.Labels)
+	}
+}
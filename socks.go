@@ -0,0 +1,20 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"net"
+	"net/url"
+
+	"golang.org/x/net/proxy"
+)
+
+// socksDialer returns a function that opens connections through the
+// SOCKS5 proxy.
+func socksDialer(proxyURL *url.URL, d *net.Dialer) (dialFunc, error) {
+	p, err := proxy.FromURL(proxyURL, d)
+	if err != nil {
+		return nil, err
+	}
+	return p.(proxy.ContextDialer).DialContext, nil
+}
@@ -0,0 +1,15 @@
+//go:build minimal
+
+package prober
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// socksDialer fails, since SOCKS5 proxies aren't supported with the
+// "minimal" tag.
+func socksDialer(proxyURL *url.URL, d *net.Dialer) (dialFunc, error) {
+	return nil, fmt.Errorf("proxy scheme %q isn't supported in minimal builds", proxyURL.Scheme)
+}
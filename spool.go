@@ -0,0 +1,230 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSpoolBackoff = 30 * time.Second // time before the first retry, unless AlertSpool.Backoff is set
+	maxSpoolBackoff     = time.Hour        // longest time between retries
+	spoolRecords        = 20               // number of most recent records kept with each alert
+)
+
+type (
+	// AlertSpool keeps alerts on disk until they're delivered, so they
+	// survive restarts of the process and outages of the notification
+	// provider, giving at-least-once delivery.
+	//
+	// Use its Alert method as the AlertFn of probers, or as the last
+	// of FallbackAlerts(), and call Run() to keep retrying undelivered
+	// alerts, e.g.:
+	//
+	//	spool := &AlertSpool{Dir: "/var/spool/prober", AlertFn: pagerDuty.Alert}
+	//	go spool.Run(ctx)
+	AlertSpool struct {
+		Dir     string        // directory to keep undelivered alerts in
+		AlertFn AlertFn       // function to deliver alerts with
+		Backoff time.Duration // time before the first retry, doubling with each one, or 0 for 30s
+		lock    sync.Mutex    // serializes deliveries, so alerts aren't sent twice at once
+		seq     int           // sequence number of the last spooled alert, protected by lock
+		t       timeT
+	}
+
+	// spooledAlert is an alert kept in the spool.
+	spooledAlert struct {
+		Name, Desc string
+		Badness    int
+		Records    Records
+		Spooled    time.Time // when the alert was added to the spool
+		Attempts   int       // number of failed deliveries so far
+		NextTry    time.Time // when to try delivering the alert again
+		LastError  string    `json:",omitempty"`
+	}
+)
+
+// Alert adds the alert to the spool and tries to deliver it right
+// away, along with any other undelivered alerts that are due.
+//
+// Alert only returns an error if the alert couldn't be written to the
+// spool, since otherwise it will be delivered eventually.
+func (s *AlertSpool) Alert(name, desc string, badness int, records Records) error {
+	if len(records) > spoolRecords {
+		records = records[len(records)-spoolRecords:]
+	}
+	a := spooledAlert{
+		Name:    name,
+		Desc:    desc,
+		Badness: badness,
+		Records: records,
+		Spooled: s.now(),
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.seq++
+	file := fmt.Sprintf("%d-%d.json", a.Spooled.UnixNano(), s.seq)
+	if err := s.write(file, a); err != nil {
+		return fmt.Errorf("failed to spool alert for %s: %v", name, err)
+	}
+	s.drain()
+	return nil
+}
+
+// Run retries delivery of spooled alerts, including ones left over
+// from before a restart, until ctx is done.
+func (s *AlertSpool) Run(ctx context.Context) {
+	tick := time.NewTicker(s.backoff())
+	defer tick.Stop()
+	s.Drain()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			s.Drain()
+		}
+	}
+}
+
+// Drain tries to deliver each spooled alert that is due, oldest first.
+func (s *AlertSpool) Drain() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.drain()
+}
+
+// Pending returns the number of alerts in the spool that have not been
+// delivered yet.
+func (s *AlertSpool) Pending() (int, error) {
+	files, err := s.files()
+	return len(files), err
+}
+
+// drain tries to deliver each spooled alert that is due. The lock must
+// be held.
+func (s *AlertSpool) drain() {
+	files, err := s.files()
+	if err != nil {
+		log.Printf("Failed to read alert spool %s: %v\n", s.Dir, err)
+		return
+	}
+	for _, file := range files {
+		s.deliver(file)
+	}
+}
+
+// deliver tries to deliver the spooled alert in the file, if it's due,
+// removing the file once it's delivered.
+func (s *AlertSpool) deliver(file string) {
+	path := filepath.Join(s.Dir, file)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Failed to read spooled alert %s: %v\n", path, err)
+		return
+	}
+	var a spooledAlert
+	if err := json.Unmarshal(b, &a); err != nil {
+		log.Printf("Failed to decode spooled alert %s, skipping it: %v\n", path, err)
+		return
+	}
+	now := s.now()
+	if now.Before(a.NextTry) {
+		return
+	}
+	if err := s.AlertFn(a.Name, a.Desc, a.Badness, a.Records); err != nil {
+		a.Attempts++
+		a.LastError = err.Error()
+		a.NextTry = now.Add(s.retryAfter(a.Attempts))
+		log.Printf("[%s] Failed to deliver spooled alert (attempt %d), retrying at %v: %v\n", a.Name, a.Attempts, a.NextTry, err)
+		if err := s.write(file, a); err != nil {
+			log.Printf("[%s] Failed to update spooled alert %s: %v\n", a.Name, path, err)
+		}
+		return
+	}
+	log.Printf("[%s] Delivered spooled alert from %v\n", a.Name, a.Spooled)
+	if err := os.Remove(path); err != nil {
+		log.Printf("[%s] Failed to remove delivered alert %s from spool: %v\n", a.Name, path, err)
+	}
+}
+
+// files returns the names of the spooled alerts, oldest first.
+func (s *AlertSpool) files() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			files = append(files, e.Name())
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return spoolOrder(files[i]) < spoolOrder(files[j])
+	})
+	return files, nil
+}
+
+// spoolOrder returns a key that sorts spooled alert files by when they
+// were spooled.
+func spoolOrder(file string) string {
+	var nanos, seq int64
+	fmt.Sscanf(file, "%d-%d.json", &nanos, &seq)
+	return fmt.Sprintf("%020d-%020d", nanos, seq)
+}
+
+// write atomically writes the alert to the file in the spool.
+func (s *AlertSpool) write(file string, a spooledAlert) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.Dir, file+".tmp")
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.Dir, file))
+}
+
+// retryAfter returns how long to wait before the next delivery after
+// the number of failed attempts.
+func (s *AlertSpool) retryAfter(attempts int) time.Duration {
+	d := s.backoff()
+	for i := 1; i < attempts && d < maxSpoolBackoff; i++ {
+		d *= 2
+	}
+	if d > maxSpoolBackoff {
+		d = maxSpoolBackoff
+	}
+	return d
+}
+
+// backoff returns the time before the first retry.
+func (s *AlertSpool) backoff() time.Duration {
+	if s.Backoff <= 0 {
+		return defaultSpoolBackoff
+	}
+	return s.Backoff
+}
+
+// now returns the current time.
+func (s *AlertSpool) now() time.Time {
+	if s.t == nil {
+		return time.Now()
+	}
+	return s.t.Now()
+}
@@ -0,0 +1,76 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAlertSpool(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	var delivered []string
+	down := true
+	alertFn := func(name, desc string, badness int, records Records) error {
+		if down {
+			return errors.New("pager service is down")
+		}
+		delivered = append(delivered, name)
+		return nil
+	}
+	s := &AlertSpool{Dir: dir, AlertFn: alertFn, t: fakeTime{start}}
+	records := make(Records, 30)
+	if err := s.Alert("SpoolProber1", "Spools.", 100, records); err != nil {
+		t.Fatalf("Alert() => %v; want nil\n", err)
+	}
+	if err := s.Alert("SpoolProber2", "Spools.", 100, nil); err != nil {
+		t.Fatalf("Alert() => %v; want nil\n", err)
+	}
+
+	steps := []struct {
+		after         time.Duration // time since start
+		down          bool
+		restart       bool // whether to drain from a new AlertSpool
+		wantPending   int
+		wantDelivered int
+	}{
+		{after: 0, down: false, wantPending: 2},                // not due yet
+		{after: 30 * time.Second, down: true, wantPending: 2},  // second attempts fail
+		{after: 45 * time.Second, down: false, wantPending: 2}, // backed off for 60s
+		{after: 90 * time.Second, down: false, restart: true, wantPending: 0, wantDelivered: 2},
+	}
+	for i, tt := range steps {
+		down = tt.down
+		if tt.restart {
+			s = &AlertSpool{Dir: dir, AlertFn: alertFn}
+		}
+		s.t = fakeTime{start.Add(tt.after)}
+		s.Drain()
+		if got, err := s.Pending(); err != nil || got != tt.wantPending {
+			t.Errorf("[%d] Pending() => %d, %v; want %d\n", i, got, err, tt.wantPending)
+		}
+		if len(delivered) != tt.wantDelivered {
+			t.Errorf("[%d] delivered %v; want %d alerts\n", i, delivered, tt.wantDelivered)
+		}
+	}
+	if len(delivered) == 2 && (delivered[0] != "SpoolProber1" || delivered[1] != "SpoolProber2") {
+		t.Errorf("delivered %v; want oldest first\n", delivered)
+	}
+}
+
+func TestAlertSpool_Alert_delivered(t *testing.T) {
+	var got Records
+	s := &AlertSpool{Dir: t.TempDir(), AlertFn: func(name, desc string, badness int, records Records) error {
+		got = records
+		return nil
+	}}
+	if err := s.Alert("SpoolProber", "Spools.", 100, make(Records, 30)); err != nil {
+		t.Fatalf("Alert() => %v; want nil\n", err)
+	}
+	if n, _ := s.Pending(); n != 0 {
+		t.Errorf("Pending() => %d; want 0\n", n)
+	}
+	if len(got) != spoolRecords {
+		t.Errorf("Alert() delivered %d records; want %d\n", len(got), spoolRecords)
+	}
+}
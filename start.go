@@ -0,0 +1,22 @@
+package prober
+
+import "time"
+
+// ProbeImmediately makes the probe run once as soon as it starts, even
+// if it's on a Schedule or aligned with AlignToInterval(), which
+// otherwise wait for their first time to run. Other probes always run
+// as soon as they start, after any InitialDelay().
+func ProbeImmediately() func(*Probe) {
+	return func(p *Probe) {
+		p.immediate = true
+	}
+}
+
+// InitialDelay makes the probe wait for d after it starts before its
+// first run, e.g. to stagger the start of many probes instead of
+// running them all at once.
+func InitialDelay(d time.Duration) func(*Probe) {
+	return func(p *Probe) {
+		p.initialDelay = d
+	}
+}
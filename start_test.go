@@ -0,0 +1,43 @@
+package prober
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProbe_RunContext_start(t *testing.T) {
+	cases := []struct {
+		opts    []Option
+		wantRun bool          // whether the probe should run within the time
+		within  time.Duration // how long to wait for the first run
+	}{
+		{opts: nil, wantRun: true, within: 50 * time.Millisecond},
+		{opts: []Option{AlignToInterval()}, wantRun: false, within: 50 * time.Millisecond},
+		{opts: []Option{AlignToInterval(), ProbeImmediately()}, wantRun: true, within: 50 * time.Millisecond},
+		{opts: []Option{InitialDelay(time.Hour)}, wantRun: false, within: 50 * time.Millisecond},
+		{opts: []Option{InitialDelay(20 * time.Millisecond)}, wantRun: true, within: time.Second},
+	}
+	for i, tt := range cases {
+		ran := make(chan time.Time, 10)
+		opts := append([]Option{Interval(time.Hour), Report(func(Result) { ran <- time.Now() })}, tt.opts...)
+		p := newProbe(testProber{Passed()}, "StartingProber", "Probes at start.", opts...)
+		ctx, cancel := context.WithCancel(context.Background())
+		start := time.Now()
+		go p.RunContext(ctx)
+		select {
+		case at := <-ran:
+			if !tt.wantRun {
+				t.Errorf("[%d] probe ran after %v; want it to wait\n", i, at.Sub(start))
+			}
+			if p.initialDelay > 0 && at.Sub(start) < p.initialDelay {
+				t.Errorf("[%d] probe ran after %v; want it to wait for InitialDelay %v\n", i, at.Sub(start), p.initialDelay)
+			}
+		case <-time.After(tt.within):
+			if tt.wantRun {
+				t.Errorf("[%d] probe didn't run within %v; want it to\n", i, tt.within)
+			}
+		}
+		cancel()
+	}
+}
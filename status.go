@@ -0,0 +1,263 @@
+package prober
+
+import (
+	"time"
+)
+
+const (
+	// healthWindow is the number of recent records HealthScore() looks at.
+	healthWindow = 10
+	// badnessHistorySize is the number of changes of badness to keep.
+	badnessHistorySize = 100
+)
+
+type (
+	// SchedulerStats describes how well the probe has kept to its
+	// intended schedule.
+	//
+	// These numbers are about the prober itself, not about the target
+	// being probed: a probe with high Lag or many SkippedRuns likely
+	// has a Probe() implementation that takes too long, or a host that
+	// is overloaded.
+	SchedulerStats struct {
+		Runs                int           // number of times the probe has been started
+		Failures            int           // total number of runs that failed or were degraded
+		AlertsSent          int           // total number of alerts sent for the probe
+		LastStart           time.Time     // when the most recent run was started
+		LastInterval        time.Duration // actual time between the two most recent runs
+		Lag                 time.Duration // how much later than intended the most recent run started
+		MaxLag              time.Duration // largest Lag seen so far
+		SkippedRuns         int           // runs that should have happened but didn't
+		Timeouts            int           // total number of runs that timed out
+		ConsecutiveTimeouts int           // number of runs in a row that timed out
+		Duration            time.Duration // total time spent in runs
+		Abandoned           int           // total number of runs given up on after timing out or being cancelled
+		AbandonedRunning    int           // abandoned runs that haven't returned yet, see AbandonedRuns()
+		Latency             LatencyStats  // durations of runs that didn't time out, see Latencies()
+	}
+
+	// BadnessSample is the badness of a probe from a point in time.
+	BadnessSample struct {
+		Time    time.Time
+		Badness int
+	}
+
+	// Status is a point-in-time snapshot of the state of a probe.
+	Status struct {
+		Name, Desc          string
+		Location            string
+		Labels              map[string]string
+		Component           string // component the probe is part of, if any
+		State               string // see Probe.State()
+		Interval            time.Duration
+		Schedule            string // when the probe runs, if not every Interval
+		Disabled            bool
+		ExpectFailure       bool // whether the probe passes when Probe() fails
+		DryRun              bool // whether alerts and warnings are only logged
+		SilencedUntil       time.Time
+		Silences            []Silence // active silences of a registry that matched the probe
+		DeployGraceUntil    time.Time // end of the grace period after the latest deploy, see Registry.Deployed()
+		Badness             int
+		BadnessHistory      []BadnessSample // recent changes of badness, oldest first
+		HealthScore         float64
+		Alerting            bool
+		Degraded            bool
+		Recurrences         int // times in a row the probe alerted again soon after recovering
+		ConsecutiveFailures int
+		AlertWhen           string              // condition on which the probe alerts, if not badness
+		Remediations        []RemediationRecord // recent remediation attempts
+		Fingerprint         string              // key for deduplicating alerts, see Fingerprint()
+		LastAlert           time.Time
+		AlertUndelivered    bool // whether the last alert couldn't be sent, even after retries
+		LastSuccess         time.Time
+		LastFailure         time.Time
+		RecordBytes         int // approximate memory used by records
+		Scheduler           SchedulerStats
+		Notify              NotifyStats
+	}
+)
+
+// Stats returns the scheduler statistics for the probe.
+func (p *Probe) Stats() SchedulerStats {
+	p.statsLock.RLock()
+	defer p.statsLock.RUnlock()
+	s := p.stats
+	s.Latency = p.latencyStats()
+	return s
+}
+
+// Status returns a snapshot of the current state of the probe.
+func (p *Probe) Status() Status {
+	return Status{
+		Name:                p.Name,
+		Desc:                p.Desc,
+		Location:            p.Location,
+		Labels:              p.Labels,
+		Component:           p.Component(),
+		State:               p.State(),
+		Interval:            p.Interval,
+		Schedule:            p.scheduleString(),
+		Disabled:            p.IsDisabled(),
+		ExpectFailure:       p.expectFailure,
+		DryRun:              p.isDryRun(),
+		SilencedUntil:       p.silencedUntil(),
+		Silences:            p.Silences(),
+		DeployGraceUntil:    p.DeployGraceUntil(),
+		Badness:             p.Badness(),
+		BadnessHistory:      p.BadnessHistory(),
+		HealthScore:         p.HealthScore(),
+		Alerting:            p.IsAlerting(),
+		Degraded:            p.IsDegraded(),
+		Recurrences:         p.Recurrences(),
+		ConsecutiveFailures: p.ConsecutiveFailures(),
+		AlertWhen:           p.alertWhenSrc,
+		Remediations:        p.Remediations(),
+		Fingerprint:         p.Fingerprint(),
+		LastAlert:           p.getLastAlert(),
+		AlertUndelivered:    p.AlertUndelivered(),
+		LastSuccess:         p.LastSuccess(),
+		LastFailure:         p.LastFailure(),
+		RecordBytes:         p.RecordBytes(),
+		Scheduler:           p.Stats(),
+		Notify:              p.NotifyStats(),
+	}
+}
+
+// BadnessHistory returns the most recent changes of badness of the
+// probe, oldest first, e.g. to show badness creeping towards the alert
+// threshold.
+func (p *Probe) BadnessHistory() []BadnessSample {
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	return append([]BadnessSample{}, p.badnessHistory...)
+}
+
+// recordBadness adds a sample of the badness to the history. The caller
+// must hold alertLock.
+func (p *Probe) recordBadness(b int) {
+	now := time.Now()
+	if p.t != nil {
+		now = p.t.Now()
+	}
+	p.badnessHistory = append(p.badnessHistory, BadnessSample{Time: now, Badness: b})
+	if over := len(p.badnessHistory) - badnessHistorySize; over > 0 {
+		p.badnessHistory = append(p.badnessHistory[:0], p.badnessHistory[over:]...)
+	}
+}
+
+// HealthScore returns the health of the probe between 0 (unhealthy) and
+// 1 (healthy), which unlike badness is comparable across probes with
+// different FailurePenalty and SuccessReward.
+//
+// The score is the product of how far badness is from the alert
+// threshold, and the ratio of passed runs among the most recent ones.
+func (p *Probe) HealthScore() float64 {
+	badness := float64(p.Badness()) / float64(p.threshold())
+	if badness > 1 {
+		badness = 1
+	}
+	rs := p.Records()
+	if len(rs) > healthWindow {
+		rs = rs[len(rs)-healthWindow:]
+	}
+	passed := 1.0
+	if len(rs) > 0 {
+		n := 0
+		for _, r := range rs {
+			if r.Result.Passed() {
+				n++
+			}
+		}
+		passed = float64(n) / float64(len(rs))
+	}
+	return (1 - badness) * passed
+}
+
+// recordStart updates the scheduler statistics for a run starting at
+// given time.
+func (p *Probe) recordStart(start time.Time) {
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	s := &p.stats
+	if s.Runs > 0 && (p.Interval > 0 || p.schedule != nil) {
+		s.LastInterval = start.Sub(s.LastStart)
+		var missed int
+		s.Lag, missed = p.lag(s.LastStart, start)
+		if s.Lag < 0 {
+			s.Lag = 0
+		}
+		if s.Lag > s.MaxLag {
+			s.MaxLag = s.Lag
+		}
+		if missed > 0 {
+			s.SkippedRuns += missed
+		}
+	}
+	s.Runs++
+	s.LastStart = start
+}
+
+// lag returns how much later than intended a run starting at start is,
+// given that the previous run started at last, and how many runs that
+// should have happened in between were missed.
+func (p *Probe) lag(last, start time.Time) (time.Duration, int) {
+	if p.schedule == nil {
+		d := start.Sub(last)
+		return d - p.Interval, int(d/p.Interval) - 1
+	}
+	next := p.schedule.Next(last)
+	missed := 0
+	for t := p.schedule.Next(next); !t.IsZero() && !t.After(start); t = p.schedule.Next(t) {
+		missed++
+	}
+	return start.Sub(next), missed
+}
+
+// recordTimeout updates the scheduler statistics with the outcome of
+// a run, which either timed out or didn't.
+func (p *Probe) recordTimeout(timedOut bool) {
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	if timedOut {
+		p.stats.Timeouts++
+		p.stats.ConsecutiveTimeouts++
+	} else {
+		p.stats.ConsecutiveTimeouts = 0
+	}
+}
+
+// recordFailure updates the scheduler statistics with the outcome of a
+// run, which either failed or didn't.
+func (p *Probe) recordFailure(failed bool) {
+	if !failed {
+		return
+	}
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	p.stats.Failures++
+}
+
+// recordAlertSent updates the scheduler statistics with an alert sent
+// for the probe.
+func (p *Probe) recordAlertSent() {
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	p.stats.AlertsSent++
+}
+
+// recordDuration updates the scheduler statistics with the duration of
+// a run.
+func (p *Probe) recordDuration(d time.Duration) {
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	p.stats.Duration += d
+}
+
+// scheduleString returns the schedule of the probe, or "" if it runs
+// every Interval.
+func (p *Probe) scheduleString() string {
+	if p.schedule == nil {
+		return ""
+	}
+	return p.schedule.String()
+}
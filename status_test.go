@@ -0,0 +1,183 @@
+package prober
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProbe_recordStart(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	cases := []struct {
+		in   []time.Duration // offsets from start at which runs begin
+		want SchedulerStats
+	}{
+		{
+			in: []time.Duration{0},
+			want: SchedulerStats{
+				Runs:      1,
+				LastStart: start,
+			},
+		},
+		{
+			in: []time.Duration{0, time.Minute, 2 * time.Minute},
+			want: SchedulerStats{
+				Runs:         3,
+				LastStart:    start.Add(2 * time.Minute),
+				LastInterval: time.Minute,
+			},
+		},
+		{
+			in: []time.Duration{0, time.Minute + 5*time.Second, 2 * time.Minute},
+			want: SchedulerStats{
+				Runs:         3,
+				LastStart:    start.Add(2 * time.Minute),
+				LastInterval: 55 * time.Second,
+				MaxLag:       5 * time.Second,
+			},
+		},
+		{
+			in: []time.Duration{0, 3*time.Minute + time.Second},
+			want: SchedulerStats{
+				Runs:         2,
+				LastStart:    start.Add(3*time.Minute + time.Second),
+				LastInterval: 3*time.Minute + time.Second,
+				Lag:          2*time.Minute + time.Second,
+				MaxLag:       2*time.Minute + time.Second,
+				SkippedRuns:  2,
+			},
+		},
+	}
+	for i, tt := range cases {
+		p := &Probe{Interval: time.Minute}
+		for _, d := range tt.in {
+			p.recordStart(start.Add(d))
+		}
+		if got := p.Stats(); got != tt.want {
+			t.Errorf("[%d] recordStart(%v) => %+v; want %+v\n", i, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestProbe_recordTimeout(t *testing.T) {
+	p := &Probe{}
+	for _, timedOut := range []bool{true, true, false, true} {
+		p.recordTimeout(timedOut)
+	}
+	got := p.Stats()
+	if got.Timeouts != 3 || got.ConsecutiveTimeouts != 1 {
+		t.Errorf("after recordTimeout(), Stats() => %+v; want Timeouts=3, ConsecutiveTimeouts=1\n", got)
+	}
+}
+
+func TestProbe_counters(t *testing.T) {
+	p := &Probe{
+		Prober:         testProber{},
+		Name:           "CountedProber",
+		failurePenalty: 1,
+		critThreshold:  1000,
+		t:              realTime{},
+	}
+	fail := FailedWith(errors.New("connection refused"))
+	for _, r := range []Result{Passed(), fail, DegradedWith(fail.Error, ""), Passed(), fail} {
+		p.handleResult(r)
+	}
+	p.sendAlert()
+	// The counters survive a Snapshot() and Restore().
+	restored := &Probe{}
+	p.Snapshot().Restore(restored)
+	for _, got := range []SchedulerStats{p.Stats(), restored.Stats()} {
+		if got.Failures != 3 || got.AlertsSent != 1 {
+			t.Errorf("Stats() => %+v; want Failures=3, AlertsSent=1\n", got)
+		}
+	}
+}
+
+func TestProbe_alertDesc(t *testing.T) {
+	now := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	cases := []struct {
+		lastSuccess time.Time
+		want        string
+	}{
+		{
+			want: "A test prober. (no successful run since start)",
+		},
+		{
+			lastSuccess: now.Add(-90 * time.Minute),
+			want:        "A test prober. (last success 1.5 hrs ago)",
+		},
+	}
+	for i, tt := range cases {
+		p := &Probe{Desc: "A test prober.", t: fakeTime{now}}
+		p.setLastOutcome(true, tt.lastSuccess)
+		p.setLastOutcome(false, now)
+		if got := p.alertDesc(); got != tt.want {
+			t.Errorf("[%d] alertDesc() => %q; want %q\n", i, got, tt.want)
+		}
+		if !p.LastFailure().Equal(now) {
+			t.Errorf("[%d] LastFailure() => %v; want %v\n", i, p.LastFailure(), now)
+		}
+	}
+}
+
+func TestProbe_recordStart_schedule(t *testing.T) {
+	s, err := ParseSchedule("0 * * * *", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(1998, 11, 19, 15, 0, 0, 0, time.UTC)
+	p := &Probe{Interval: time.Minute, schedule: s}
+	p.recordStart(start)
+	p.recordStart(start.Add(3*time.Hour + time.Minute))
+	got := p.Stats()
+	if got.Lag != 2*time.Hour+time.Minute || got.SkippedRuns != 2 {
+		t.Errorf("after recordStart(), Stats() => %+v; want Lag=2h1m, SkippedRuns=2\n", got)
+	}
+}
+
+func TestProbe_HealthScore(t *testing.T) {
+	fail := Record{Result: FailedWith(errors.New("failing on purpose"))}
+	pass := Record{Result: Passed()}
+	cases := []struct {
+		badness int
+		records Records
+		want    float64
+	}{
+		{want: 1},
+		{badness: *alertThreshold / 2, records: Records{pass, pass}, want: 0.5},
+		{records: Records{fail, pass, pass, pass}, want: 0.75},
+		{badness: *alertThreshold / 2, records: Records{fail, pass}, want: 0.25},
+		{badness: *alertThreshold * 2, records: Records{pass}, want: 0},
+		// Only the most recent records count.
+		{records: append(Records{fail, fail}, Records{pass, pass, pass, pass, pass, pass, pass, pass, pass, pass}...), want: 1},
+	}
+	for i, tt := range cases {
+		p := &Probe{records: tt.records}
+		p.setBadness(tt.badness)
+		if got := p.HealthScore(); got != tt.want {
+			t.Errorf("[%d] HealthScore() => %v; want %v\n", i, got, tt.want)
+		}
+	}
+}
+
+func TestProbe_BadnessHistory(t *testing.T) {
+	now := time.Date(2015, time.June, 1, 0, 0, 0, 0, time.UTC)
+	p := &Probe{t: fakeTime{now}}
+	for _, b := range []int{1, 1, 2, 0} {
+		p.setBadness(b)
+	}
+	got := p.BadnessHistory()
+	want := []BadnessSample{{now, 1}, {now, 2}, {now, 0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BadnessHistory() => %v; want %v\n", got, want)
+	}
+
+	for i := 1; i <= badnessHistorySize+5; i++ {
+		p.setBadness(i)
+	}
+	got = p.BadnessHistory()
+	if len(got) != badnessHistorySize || got[len(got)-1].Badness != badnessHistorySize+5 {
+		t.Errorf("after %d changes, BadnessHistory() has %d samples ending in %v; want %d samples\n", badnessHistorySize+5, len(got), got[len(got)-1], badnessHistorySize)
+	}
+}
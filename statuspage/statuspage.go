@@ -0,0 +1,367 @@
+// Package statuspage renders a public, read-only status page for the
+// probes of a prober.Registry, showing the current state of each probe
+// and bars of its daily availability, e.g. to expose to customers.
+//
+// The page only shows the names and descriptions of probes and how
+// available they were, never errors or other details of their records.
+package statuspage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+const dayLayout = "2006-01-02"
+
+type (
+	// Page is a status page for the probes of a registry.
+	//
+	// Once Run() is called, the page collects the records of the probes
+	// every minute into daily availability, which it keeps for Days.
+	Page struct {
+		Title    string // title of the page, or "" for "Status"
+		Registry *prober.Registry
+		Days     int // number of days of availability to show, or 0 for 90
+		// Path of a JSON file to keep the daily availability in across
+		// restarts, or "" to only keep it in memory.
+		HistoryFile string
+		// Function deciding which probes to show, or nil to show all.
+		Filter func(*prober.Probe) bool
+		// Time zone to show when the page was updated in, or nil for
+		// UTC. Days are always UTC days.
+		TimeZone *time.Location
+		history  map[string]map[string]*Day // daily availability, by probe name and day
+		seen     map[string]seen            // most recent record collected, by probe name
+		loaded   bool                       // whether HistoryFile was loaded
+		now      func() time.Time
+		lock     sync.Mutex // protects history, seen and loaded
+	}
+
+	// Day is the availability of a probe over a day.
+	Day struct {
+		Runs, Passes int
+	}
+
+	// seen identifies the most recent record collected of a probe.
+	seen struct {
+		t       time.Time
+		repeats int
+	}
+
+	// component is a row of the status page, for a component of probes
+	// or a single probe.
+	component struct {
+		Name, Desc string
+		State      string // see prober.Probe.State()
+		Bars       []bar
+		Uptime     string // availability over all days with runs, e.g. "99.95%"
+	}
+
+	// bar is the availability of a component on a day.
+	bar struct {
+		Date  string
+		Class string // "none", "good", "fair" or "bad"
+		Title string
+	}
+)
+
+// Availability returns the ratio of runs that passed during the day.
+func (d Day) Availability() float64 {
+	if d.Runs == 0 {
+		return 1
+	}
+	return float64(d.Passes) / float64(d.Runs)
+}
+
+// Run collects records every minute, until ctx is done.
+func (pg *Page) Run(ctx context.Context) {
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+	for {
+		pg.Collect()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Collect adds the records of the probes since the last collection to
+// the daily availability, saving it to HistoryFile if set.
+func (pg *Page) Collect() {
+	probes := pg.Registry.Probes()
+	pg.lock.Lock()
+	defer pg.lock.Unlock()
+	pg.load()
+	if pg.history == nil {
+		pg.history = map[string]map[string]*Day{}
+	}
+	if pg.seen == nil {
+		pg.seen = map[string]seen{}
+	}
+	for _, p := range probes {
+		days := pg.history[p.Name]
+		if days == nil {
+			days = map[string]*Day{}
+			pg.history[p.Name] = days
+		}
+		s := pg.seen[p.Name]
+		for _, r := range p.Records() {
+			n := 1 + r.Repeats
+			switch {
+			case r.Timestamp.Before(s.t):
+				continue
+			case r.Timestamp.Equal(s.t):
+				n = r.Repeats - s.repeats
+			}
+			s = seen{r.Timestamp, r.Repeats}
+			if n <= 0 {
+				continue
+			}
+			key := r.Timestamp.UTC().Format(dayLayout)
+			d := days[key]
+			if d == nil {
+				d = &Day{}
+				days[key] = d
+			}
+			d.Runs += n
+			if r.Result.Passed() {
+				d.Passes += n
+			}
+		}
+		pg.seen[p.Name] = s
+	}
+	pg.prune()
+	pg.save()
+}
+
+// History returns the daily availability of the named probe, by day as
+// "2006-01-02" in UTC.
+func (pg *Page) History(name string) map[string]Day {
+	pg.lock.Lock()
+	defer pg.lock.Unlock()
+	days := map[string]Day{}
+	for k, d := range pg.history[name] {
+		days[k] = *d
+	}
+	return days
+}
+
+// ServeHTTP serves the status page.
+func (pg *Page) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pg.Render(w); err != nil {
+		log.Printf("Failed to render status page: %v\n", err)
+	}
+}
+
+// Render writes the status page as static HTML to w.
+func (pg *Page) Render(w io.Writer) error {
+	title := pg.Title
+	if title == "" {
+		title = "Status"
+	}
+	components := pg.components()
+	overall := "All systems operational"
+	for _, c := range components {
+		if c.State == prober.StateOutage {
+			overall = "Some systems are down"
+			break
+		}
+		if c.State == prober.StateDegraded {
+			overall = "Some systems are degraded"
+		}
+	}
+	return pageTmpl.Execute(w, struct {
+		Title, Overall string
+		Days           int
+		Components     []component
+		Updated        string
+	}{title, overall, pg.days(), components, pg.time().In(pg.timeZone()).Format("2006-01-02 15:04 MST")})
+}
+
+// timeZone returns the time zone to show times in.
+func (pg *Page) timeZone() *time.Location {
+	if pg.TimeZone == nil {
+		return time.UTC
+	}
+	return pg.TimeZone
+}
+
+// components returns the rows of the status page, with one row for
+// each component of probes (see prober.InComponent()), and one for each
+// probe that isn't part of a component.
+func (pg *Page) components() []component {
+	probes := pg.Registry.Probes()
+	sort.Slice(probes, func(i, j int) bool { return probes[i].Name < probes[j].Name })
+	today := pg.time().UTC()
+
+	pg.lock.Lock()
+	defer pg.lock.Unlock()
+	var cs []component
+	members := map[string][]*prober.Probe{}
+	for _, p := range probes {
+		if pg.Filter != nil && !pg.Filter(p) {
+			continue
+		}
+		name := p.Component()
+		if name == "" {
+			cs = append(cs, component{Name: p.Name, Desc: p.Desc})
+			members[p.Name] = []*prober.Probe{p}
+			continue
+		}
+		if _, ok := members[name]; !ok {
+			cs = append(cs, component{Name: name})
+		}
+		members[name] = append(members[name], p)
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Name < cs[j].Name })
+	for i := range cs {
+		c := &cs[i]
+		var states []string
+		for _, p := range members[c.Name] {
+			states = append(states, p.State())
+		}
+		c.State = prober.WorstState(states...)
+		var total Day
+		for i := pg.days() - 1; i >= 0; i-- {
+			date := today.AddDate(0, 0, -i).Format(dayLayout)
+			b := bar{Date: date, Class: "none", Title: date + ": no data"}
+			var d Day
+			for _, p := range members[c.Name] {
+				if pd := pg.history[p.Name][date]; pd != nil {
+					d.Runs += pd.Runs
+					d.Passes += pd.Passes
+				}
+			}
+			if d.Runs > 0 {
+				a := d.Availability()
+				b.Class = "bad"
+				if a >= 0.999 {
+					b.Class = "good"
+				} else if a >= 0.99 {
+					b.Class = "fair"
+				}
+				b.Title = fmt.Sprintf("%s: %.2f%%", date, a*100)
+				total.Runs += d.Runs
+				total.Passes += d.Passes
+			}
+			c.Bars = append(c.Bars, b)
+		}
+		c.Uptime = fmt.Sprintf("%.2f%%", total.Availability()*100)
+	}
+	return cs
+}
+
+// prune drops daily availability older than Days. The caller must hold
+// lock.
+func (pg *Page) prune() {
+	oldest := pg.time().UTC().AddDate(0, 0, -pg.days()+1).Format(dayLayout)
+	for _, days := range pg.history {
+		for k := range days {
+			if k < oldest {
+				delete(days, k)
+			}
+		}
+	}
+}
+
+// load reads the daily availability from HistoryFile, if it's set and
+// wasn't read yet. The caller must hold lock.
+func (pg *Page) load() {
+	if pg.loaded || pg.HistoryFile == "" {
+		return
+	}
+	pg.loaded = true
+	b, err := os.ReadFile(pg.HistoryFile)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(b, &pg.history)
+	}
+	if err != nil {
+		log.Printf("Failed to load status page history from %s: %v\n", pg.HistoryFile, err)
+	}
+}
+
+// save writes the daily availability to HistoryFile, if set. The
+// caller must hold lock.
+func (pg *Page) save() {
+	if pg.HistoryFile == "" {
+		return
+	}
+	b, err := json.Marshal(pg.history)
+	if err == nil {
+		tmp := pg.HistoryFile + ".tmp"
+		if err = os.WriteFile(tmp, b, 0644); err == nil {
+			err = os.Rename(tmp, pg.HistoryFile)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to save status page history to %s: %v\n", pg.HistoryFile, err)
+	}
+}
+
+func (pg *Page) days() int {
+	if pg.Days == 0 {
+		return 90
+	}
+	return pg.Days
+}
+
+func (pg *Page) time() time.Time {
+	if pg.now == nil {
+		return time.Now()
+	}
+	return pg.now()
+}
+
+var pageTmpl = template.Must(template.New("statuspage").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; color: #222; }
+.overall { padding: 1em; border-radius: 4px; background: #eee; font-weight: bold; }
+.component { margin: 1.5em 0; }
+.component h2 { font-size: 1.1em; margin: 0; display: flex; justify-content: space-between; }
+.desc { color: #666; font-size: 0.9em; }
+.state { font-weight: normal; }
+.operational { color: #2e7d32; } .degraded { color: #ef6c00; } .outage { color: #c62828; } .disabled { color: #757575; }
+.bars { display: flex; gap: 2px; height: 2em; margin: 0.4em 0; }
+.bars span { flex: 1; border-radius: 1px; }
+.bars .good { background: #43a047; } .bars .fair { background: #fdd835; } .bars .bad { background: #e53935; } .bars .none { background: #ddd; }
+.legend { display: flex; justify-content: space-between; color: #888; font-size: 0.8em; }
+footer { color: #888; font-size: 0.8em; margin-top: 3em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="overall">{{.Overall}}</p>
+{{range .Components}}
+<div class="component">
+<h2>{{.Name}} <span class="state {{.State}}">{{.State}}</span></h2>
+{{if .Desc}}<div class="desc">{{.Desc}}</div>{{end}}
+<div class="bars">{{range .Bars}}<span class="{{.Class}}" title="{{.Title}}"></span>{{end}}</div>
+<div class="legend"><span>{{$.Days}} days ago</span><span>{{.Uptime}} uptime</span><span>Today</span></div>
+</div>
+{{end}}
+<footer>Updated {{.Updated}}</footer>
+</body>
+</html>
+`))
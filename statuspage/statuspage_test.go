@@ -0,0 +1,114 @@
+package statuspage
+
+import (
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hkjn.me/prober"
+)
+
+type testProber struct{ prober.AlertFn }
+
+func (testProber) Probe() prober.Result { return prober.Passed() }
+
+// newProbe returns a probe with the records.
+func newProbe(name string, records prober.Records, options ...prober.Option) *prober.Probe {
+	p := prober.NewProbe(testProber{}, name, "Probes "+name, options...)
+	s := p.Snapshot()
+	s.Records = records
+	s.Restore(p)
+	return p
+}
+
+func TestPage(t *testing.T) {
+	now := time.Date(2016, time.June, 15, 15, 4, 0, 0, time.UTC)
+	yesterday := now.AddDate(0, 0, -1)
+	fail := prober.FailedWith(errors.New("secret error"))
+	web := newProbe("StatusPageWeb", prober.Records{
+		{Timestamp: yesterday, Result: prober.Passed()},
+		{Timestamp: yesterday.Add(time.Minute), Result: fail},
+		{Timestamp: now.Add(-time.Minute), Result: prober.Passed(), Repeats: 2},
+	})
+	db := newProbe("StatusPageDB", nil)
+	history := filepath.Join(t.TempDir(), "history.json")
+	pg := &Page{
+		Title:       "Acme Status",
+		Registry:    prober.NewRegistry(web, db),
+		Days:        3,
+		HistoryFile: history,
+		now:         func() time.Time { return now },
+	}
+	pg.Collect()
+	pg.Collect()
+
+	days := pg.History(web.Name)
+	if want := (Day{Runs: 2, Passes: 1}); days[yesterday.Format(dayLayout)] != want {
+		t.Errorf("History() yesterday => %+v; want %+v\n", days[yesterday.Format(dayLayout)], want)
+	}
+	if want := (Day{Runs: 3, Passes: 3}); days[now.Format(dayLayout)] != want {
+		t.Errorf("History() today => %+v; want %+v\n", days[now.Format(dayLayout)], want)
+	}
+
+	restarted := &Page{Registry: pg.Registry, Days: 3, HistoryFile: history, now: pg.now}
+	restarted.load()
+	if got := len(restarted.history[web.Name]); got != 2 {
+		t.Errorf("after restart, got %d days of history; want 2\n", got)
+	}
+
+	w := httptest.NewRecorder()
+	pg.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		"<title>Acme Status</title>",
+		"All systems operational",
+		`<span class="bad" title="2016-06-14: 50.00%">`,
+		`<span class="good" title="2016-06-15: 100.00%">`,
+		`<span class="none" title="2016-06-13: no data">`,
+		"80.00% uptime",
+		"StatusPageDB",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("status page doesn't contain %q:\n%s\n", want, body)
+		}
+	}
+	if strings.Contains(body, "secret error") {
+		t.Errorf("status page shows errors of records; want them hidden\n")
+	}
+
+	pg.TimeZone = time.FixedZone("CEST", 2*60*60)
+	w = httptest.NewRecorder()
+	pg.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if want := "2016-06-15 17:04 CEST"; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("status page with TimeZone doesn't contain %q:\n%s\n", want, w.Body.String())
+	}
+}
+
+func TestPage_components(t *testing.T) {
+	now := time.Date(2016, time.June, 15, 15, 4, 0, 0, time.UTC)
+	primary := newProbe("StatusPagePrimary", prober.Records{
+		{Timestamp: now, Result: prober.Passed(), Repeats: 2},
+	}, prober.InComponent("Database"))
+	replica := newProbe("StatusPageReplica", prober.Records{
+		{Timestamp: now, Result: prober.FailedWith(errors.New("replica down"))},
+	}, prober.InComponent("Database"))
+	replica.Disabled = true
+	pg := &Page{
+		Registry: prober.NewRegistry(primary, replica),
+		Days:     1,
+		now:      func() time.Time { return now },
+	}
+	pg.Collect()
+
+	cs := pg.components()
+	if len(cs) != 1 {
+		t.Fatalf("got %d rows; want 1 for the component\n", len(cs))
+	}
+	c := cs[0]
+	if c.Name != "Database" || c.State != prober.StateOperational || c.Uptime != "75.00%" {
+		t.Errorf("got row %+v; want Database, operational with 75.00%% uptime\n", c)
+	}
+}
@@ -0,0 +1,161 @@
+package prober
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// defaultStreamBuffer is the number of records buffered for each
+// subscriber, unless ResultStream.Buffer is set.
+const defaultStreamBuffer = 100
+
+type (
+	// ResultStream sends every record of the probes streaming to it to
+	// its subscribers as it's logged, e.g. for live dashboards or
+	// anomaly detectors.
+	//
+	// Probes stream to it with the StreamResults() option. Subscribers
+	// either call Subscribe(), or connect to the stream served over
+	// HTTP as server-sent events by ServeHTTP(), or over WebSocket by
+	// WebSocketHandler().
+	//
+	// A subscriber that falls behind by more than Buffer records misses
+	// the records that don't fit, rather than slowing down the probes.
+	ResultStream struct {
+		Buffer int // records buffered for each subscriber, or 0 for 100
+		subs   map[*streamSub]bool
+		lock   sync.Mutex // protects subs
+	}
+
+	// StreamedRecord is a record sent to the subscribers of a
+	// ResultStream.
+	StreamedRecord struct {
+		Probe  string
+		Labels map[string]string `json:",omitempty"`
+		Record Record
+	}
+
+	// streamSub is a subscriber of a ResultStream.
+	streamSub struct {
+		sel     selector
+		c       chan StreamedRecord
+		dropped int // records that didn't fit in c, protected by the stream's lock
+	}
+)
+
+// StreamResults makes the probe send each of its records to the
+// stream.
+func StreamResults(s *ResultStream) func(*Probe) {
+	return func(p *Probe) {
+		p.streams = append(p.streams, s)
+	}
+}
+
+// Subscribe returns a channel receiving the records of probes matching
+// the selector, and a function that ends the subscription and closes
+// the channel.
+//
+// The selector is as for Registry.SilenceMatching(), e.g. "env=prod"
+// or "web-*", and "" matches all probes.
+func (s *ResultStream) Subscribe(sel string) (<-chan StreamedRecord, func(), error) {
+	var parsed selector
+	if sel != "" {
+		var err error
+		if parsed, err = parseSelector(sel); err != nil {
+			return nil, nil, err
+		}
+	}
+	buf := s.Buffer
+	if buf <= 0 {
+		buf = defaultStreamBuffer
+	}
+	sub := &streamSub{sel: parsed, c: make(chan StreamedRecord, buf)}
+	s.lock.Lock()
+	if s.subs == nil {
+		s.subs = map[*streamSub]bool{}
+	}
+	s.subs[sub] = true
+	s.lock.Unlock()
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			s.lock.Lock()
+			delete(s.subs, sub)
+			s.lock.Unlock()
+			close(sub.c)
+		})
+	}
+	return sub.c, cancel, nil
+}
+
+// Subscribers returns the number of current subscribers.
+func (s *ResultStream) Subscribers() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.subs)
+}
+
+// publish sends the record of the probe to each subscriber whose
+// selector matches the probe, without waiting for any of them.
+func (s *ResultStream) publish(p *Probe, r Record) {
+	sr := StreamedRecord{Probe: p.Name, Labels: p.Labels, Record: r}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for sub := range s.subs {
+		if !sub.sel.matches(p) {
+			continue
+		}
+		select {
+		case sub.c <- sr:
+		default:
+			sub.dropped++
+			if sub.dropped == 1 || sub.dropped%100 == 0 {
+				log.Printf("[%s] dropped %d records for slow stream subscriber\n", p.Name, sub.dropped)
+			}
+		}
+	}
+}
+
+// ServeHTTP streams the records of the probes matching the selector
+// given by ?match= as server-sent events, each with a StreamedRecord as
+// JSON, until the client disconnects.
+func (s *ResultStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	c, cancel, err := s.Subscribe(r.FormValue("match"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case sr := <-c:
+			b, err := json.Marshal(sr)
+			if err != nil {
+				log.Printf("[%s] failed to encode streamed record: %v\n", sr.Probe, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: record\ndata: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package prober
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultStream_Subscribe(t *testing.T) {
+	s := &ResultStream{Buffer: 1}
+	newProbe := func(name, env string) *Probe {
+		p := &Probe{
+			Prober:   testProber{FailedWith(errors.New("failing on purpose"))},
+			Name:     name,
+			Labels:   map[string]string{"env": env},
+			Interval: time.Minute,
+			t:        fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+		}
+		StreamResults(s)(p)
+		return p
+	}
+	prod, dev := newProbe("StreamProber1", "prod"), newProbe("StreamProber2", "dev")
+
+	all, cancelAll, err := s.Subscribe("")
+	if err != nil {
+		t.Fatalf("Subscribe(\"\") => %v; want nil\n", err)
+	}
+	onlyProd, cancelProd, err := s.Subscribe("env=prod")
+	if err != nil {
+		t.Fatalf("Subscribe(env=prod) => %v; want nil\n", err)
+	}
+	if _, _, err := s.Subscribe("env=["); err == nil {
+		t.Errorf("Subscribe(env=[) => nil; want error\n")
+	}
+
+	dev.handleResult(dev.Probe())
+	if sr := <-all; sr.Probe != "StreamProber2" || sr.Record.Result.Passed() {
+		t.Errorf("all subscriber got %+v; want failed StreamProber2 record\n", sr)
+	}
+	select {
+	case sr := <-onlyProd:
+		t.Errorf("env=prod subscriber got %+v; want nothing\n", sr)
+	default:
+	}
+
+	prod.handleResult(prod.Probe())
+	prod.handleResult(prod.Probe()) // doesn't fit in the buffer, so it's dropped
+	if sr := <-onlyProd; sr.Probe != "StreamProber1" || sr.Labels["env"] != "prod" {
+		t.Errorf("env=prod subscriber got %+v; want StreamProber1 record\n", sr)
+	}
+	select {
+	case sr := <-onlyProd:
+		t.Errorf("env=prod subscriber got %+v; want it dropped\n", sr)
+	default:
+	}
+
+	cancelAll()
+	cancelProd()
+	cancelProd()
+	if got := s.Subscribers(); got != 0 {
+		t.Errorf("Subscribers() => %d after cancelling; want 0\n", got)
+	}
+	for range all {
+		// Drain the buffered StreamProber1 record; the loop only ends
+		// once the channel is closed.
+	}
+}
+
+func TestResultStream_ServeHTTP(t *testing.T) {
+	s := &ResultStream{}
+	p := &Probe{
+		Prober:   testProber{Passed()},
+		Name:     "StreamProber",
+		Interval: time.Minute,
+		t:        fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	StreamResults(s)(p)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?match=Stream*")
+	if err != nil {
+		t.Fatalf("GET => %v; want nil\n", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("GET => Content-Type %q; want text/event-stream\n", ct)
+	}
+	for s.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	p.handleResult(p.Probe())
+
+	r := bufio.NewReader(resp.Body)
+	var data string
+	for data == "" {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading event => %v; want nil\n", err)
+		}
+		data = strings.TrimPrefix(strings.TrimSpace(line), "data: ")
+		if strings.HasPrefix(line, "event:") {
+			data = ""
+		}
+	}
+	var sr StreamedRecord
+	if err := json.Unmarshal([]byte(data), &sr); err != nil {
+		t.Fatalf("event data %q => %v; want StreamedRecord\n", data, err)
+	}
+	if sr.Probe != "StreamProber" || !sr.Record.Result.Passed() {
+		t.Errorf("event => %+v; want passed StreamProber record\n", sr)
+	}
+
+	resp, err = http.Get(srv.URL + "?match=env=[")
+	if err != nil {
+		t.Fatalf("GET => %v; want nil\n", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET with bad selector => %d; want %d\n", resp.StatusCode, http.StatusBadRequest)
+	}
+}
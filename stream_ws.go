@@ -0,0 +1,46 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"log"
+	"net/http"
+
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketHandler returns a handler streaming the records of the
+// probes matching the selector given by ?match= over WebSocket, each
+// as a text message with a StreamedRecord as JSON, until the client
+// disconnects.
+func (s *ResultStream) WebSocketHandler() http.Handler {
+	return websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+		c, cancel, err := s.Subscribe(ws.Request().FormValue("match"))
+		if err != nil {
+			websocket.Message.Send(ws, err.Error())
+			return
+		}
+		defer cancel()
+		// The client isn't expected to send anything, so reading only
+		// notices when it goes away.
+		gone := make(chan struct{})
+		go func() {
+			var discard []byte
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+			close(gone)
+		}()
+		for {
+			select {
+			case <-gone:
+				return
+			case sr := <-c:
+				if err := websocket.JSON.Send(ws, sr); err != nil {
+					log.Printf("[%s] failed to send streamed record over WebSocket: %v\n", sr.Probe, err)
+					return
+				}
+			}
+		}
+	})
+}
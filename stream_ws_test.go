@@ -0,0 +1,51 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestResultStream_WebSocketHandler(t *testing.T) {
+	s := &ResultStream{}
+	p := &Probe{
+		Prober:   testProber{Passed()},
+		Name:     "StreamProber",
+		Interval: time.Minute,
+		t:        fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	StreamResults(s)(p)
+	srv := httptest.NewServer(s.WebSocketHandler())
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?match=StreamProber"
+	ws, err := websocket.Dial(url, "", srv.URL)
+	if err != nil {
+		t.Fatalf("Dial() => %v; want nil\n", err)
+	}
+	defer ws.Close()
+	for s.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	p.handleResult(p.Probe())
+	var sr StreamedRecord
+	if err := websocket.JSON.Receive(ws, &sr); err != nil {
+		t.Fatalf("Receive() => %v; want nil\n", err)
+	}
+	if sr.Probe != "StreamProber" || !sr.Record.Result.Passed() {
+		t.Errorf("Receive() => %+v; want passed StreamProber record\n", sr)
+	}
+	ws.Close()
+	deadline := time.Now().Add(time.Second)
+	for s.Subscribers() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := s.Subscribers(); got != 0 {
+		t.Errorf("Subscribers() => %d after closing; want 0\n", got)
+	}
+}
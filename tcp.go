@@ -0,0 +1,68 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// TCPProber is a Prober that checks that a TCP connection can be
+// opened.
+//
+// The Alert() part of the Prober interface is provided by the embedded
+// AlertFn.
+type TCPProber struct {
+	AlertFn
+	Addr    string        // address to connect to, as "host:port"
+	Timeout time.Duration // how long to wait for the connection, or 0 for 10 seconds
+	// Proxy to connect through, with a "socks5" or "http" scheme, or
+	// nil to connect directly. A "http" proxy must allow the CONNECT
+	// method.
+	Proxy *url.URL
+	// Dialer to connect with, e.g. with a LocalAddr to probe from a
+	// particular source address, a Resolver from ResolverAt() for
+	// split-horizon DNS, or a Control function from BindToDevice()
+	// for a VRF, or nil for the default. Timeout applies regardless.
+	Dialer *net.Dialer
+	// Expression that must hold for the connection, see Expr, e.g.
+	// "latency < 50ms". The only variable is latency.
+	Assert string
+}
+
+// Probe opens and closes a connection to the address.
+func (tp TCPProber) Probe() Result {
+	timeout := tp.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	d := &net.Dialer{}
+	if tp.Dialer != nil {
+		copied := *tp.Dialer
+		d = &copied
+	}
+	d.Timeout = timeout
+	dial, err := proxyDialer(tp.Proxy, d)
+	if err != nil {
+		return FailedWith(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := dial(ctx, "tcp", tp.Addr)
+	if err != nil {
+		return FailedWith(fmt.Errorf("failed to connect to %s: %v", tp.Addr, err))
+	}
+	elapsed := time.Since(start)
+	conn.Close()
+	if tp.Assert != "" {
+		if err := checkAssertion(tp.Assert, Vars{"latency": elapsed}); err != nil {
+			return FailedWith(fmt.Errorf("%s: %v", tp.Addr, err))
+		}
+	}
+	r := PassedWith(fmt.Sprintf("connected to %s in %v", tp.Addr, elapsed), "")
+	r.Details = map[string]string{"connect": elapsed.String()}
+	return r
+}
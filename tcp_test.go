@@ -0,0 +1,87 @@
+package prober
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTCPProber_Probe(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// connectProxy is a minimal HTTP proxy that only supports CONNECT.
+	connectProxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer connectProxy.Close()
+	proxyURL, _ := url.Parse(connectProxy.URL)
+
+	cases := []struct {
+		in   TCPProber
+		want ResultCode
+	}{
+		{
+			in:   TCPProber{Addr: target.Addr().String()},
+			want: Pass,
+		},
+		{
+			in:   TCPProber{Addr: "127.0.0.1:1", Timeout: time.Second},
+			want: Fail,
+		},
+		{
+			in:   TCPProber{Addr: target.Addr().String(), Proxy: proxyURL},
+			want: Pass,
+		},
+		{
+			in:   TCPProber{Addr: "127.0.0.1:1", Proxy: proxyURL, Timeout: time.Second},
+			want: Fail,
+		},
+		{
+			in:   TCPProber{Addr: target.Addr().String(), Proxy: &url.URL{Scheme: "ftp", Host: "localhost"}},
+			want: Fail,
+		},
+	}
+	for i, tt := range cases {
+		got := tt.in.Probe()
+		if got.Code != tt.want {
+			t.Errorf("[%d] %+v.Probe() => %v; want code %v\n", i, tt.in, got, tt.want)
+		}
+	}
+}
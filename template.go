@@ -0,0 +1,73 @@
+package prober
+
+import (
+	"strings"
+)
+
+type (
+	// Template describes how to create probes for many targets that
+	// are all probed in the same way, e.g. "HTTPS returns 200".
+	//
+	// In the Name, Desc and Labels of the template, "{target}" is
+	// replaced by the address of the target, and "{key}" by the value
+	// of the target's label "key".
+	Template struct {
+		Name    string                // name of the probes, e.g. "https-{target}"
+		Desc    string                // description of the probes
+		New     func(t Target) Prober // returns the prober for the target
+		Labels  map[string]string     // labels to add to all probes
+		Options []Option              // options to pass to NewProbe for all probes
+	}
+
+	// Target is something to probe, created from a Template.
+	Target struct {
+		Addr   string            // address of the target, e.g. a hostname or URL
+		Labels map[string]string // labels to add to the probe for the target
+	}
+)
+
+// Probe returns a new probe for the target.
+func (t Template) Probe(target Target) *Probe {
+	p := t.probe(target)
+	p.publish()
+	return p
+}
+
+// probe returns a new probe for the target, without publishing its
+// status.
+func (t Template) probe(target Target) *Probe {
+	r := target.replacer()
+	opts := make([]Option, 0, len(t.Options)+len(t.Labels)+len(target.Labels))
+	opts = append(opts, t.Options...)
+	for k, v := range t.Labels {
+		opts = append(opts, Label(k, r.Replace(v)))
+	}
+	for k, v := range target.Labels {
+		opts = append(opts, Label(k, v))
+	}
+	return newProbe(t.New(target), r.Replace(t.Name), r.Replace(t.Desc), opts...)
+}
+
+// name returns the name of the probe for the target.
+func (t Template) name(target Target) string {
+	return target.replacer().Replace(t.Name)
+}
+
+// Probes returns new probes for each of the targets.
+func (t Template) Probes(targets ...Target) Probes {
+	ps := make(Probes, len(targets))
+	for i, target := range targets {
+		ps[i] = t.Probe(target)
+	}
+	return ps
+}
+
+// replacer returns a replacer for "{target}" and "{key}" for each
+// label of the target.
+func (t Target) replacer() *strings.Replacer {
+	oldnew := []string{"{target}", t.Addr}
+	for k, v := range t.Labels {
+		oldnew = append(oldnew, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(oldnew...)
+}
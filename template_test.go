@@ -0,0 +1,45 @@
+package prober
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTemplate_Probes(t *testing.T) {
+	tmpl := Template{
+		Name:   "https-{target}",
+		Desc:   "Checks that https://{target} in {env} returns 200.",
+		New:    func(t Target) Prober { return HTTPProber{URL: "https://" + t.Addr} },
+		Labels: map[string]string{"check": "https", "host": "{target}"},
+	}
+	got := tmpl.Probes(
+		Target{Addr: "a.example.com", Labels: map[string]string{"env": "prod"}},
+		Target{Addr: "b.example.com", Labels: map[string]string{"env": "staging"}},
+	)
+	want := []struct {
+		name, desc, url string
+		labels          map[string]string
+	}{
+		{
+			name:   "https-a.example.com",
+			desc:   "Checks that https://a.example.com in prod returns 200.",
+			url:    "https://a.example.com",
+			labels: map[string]string{"check": "https", "host": "a.example.com", "env": "prod"},
+		},
+		{
+			name:   "https-b.example.com",
+			desc:   "Checks that https://b.example.com in staging returns 200.",
+			url:    "https://b.example.com",
+			labels: map[string]string{"check": "https", "host": "b.example.com", "env": "staging"},
+		},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Probes() => %d probes; want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		p := got[i]
+		if p.Name != w.name || p.Desc != w.desc || p.Prober.(HTTPProber).URL != w.url || !reflect.DeepEqual(p.Labels, w.labels) {
+			t.Errorf("[%d] Probes() => %v with labels %v; want %+v\n", i, p, p.Labels, w)
+		}
+	}
+}
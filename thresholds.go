@@ -0,0 +1,100 @@
+package prober
+
+import (
+	"log"
+)
+
+type (
+	// Warner is implemented by Probers that can send low-severity
+	// notifications, used when a probe crosses its warning threshold.
+	// See Thresholds().
+	Warner interface {
+		Warn(name, desc string, badness int, records Records) error
+	}
+
+	// WarnFn is a function that is called when a probe becomes degraded.
+	WarnFn func(name, desc string, badness int, records Records) error
+)
+
+// Warn calls fn, which lets probers embed a WarnFn to implement Warner.
+func (fn WarnFn) Warn(name, desc string, badness int, records Records) error {
+	return fn(name, desc, badness, records)
+}
+
+// Thresholds sets the warning and critical levels of badness for the
+// probe.
+//
+// When badness reaches the warning level, the probe is degraded and
+// its Prober is asked to Warn(), if it's a Warner. When badness reaches
+// the critical level, the probe alerts as usual. A warning level of 0
+// disables warnings, and a critical level of 0 uses the AlertThreshold
+// of the probe's Engine, if any, or else -alert_threshold.
+func Thresholds(warning, critical int) func(*Probe) {
+	return func(p *Probe) {
+		p.warnThreshold = warning
+		p.critThreshold = critical
+	}
+}
+
+// threshold returns the level of badness at which the probe alerts.
+func (p *Probe) threshold() int {
+	if p.critThreshold > 0 {
+		return p.critThreshold
+	}
+	if p.engine != nil && p.engine.AlertThreshold > 0 {
+		return p.engine.AlertThreshold
+	}
+	return *alertThreshold
+}
+
+// IsDegraded returns true if the probe's badness has reached its
+// warning threshold, but it's not alerting.
+func (p *Probe) IsDegraded() bool {
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	return p.degraded
+}
+
+// updateDegraded updates whether the probe is degraded, and warns if it
+// just became so.
+func (p *Probe) updateDegraded() {
+	b := p.Badness()
+	degraded := p.warnThreshold > 0 && b >= p.warnThreshold && !p.IsAlerting()
+	p.alertLock.Lock()
+	was := p.degraded
+	p.degraded = degraded
+	p.alertLock.Unlock()
+	if !degraded || was {
+		return
+	}
+	if p.alertsDisabled() {
+		log.Printf("[%s] would now warn, but alerts are disabled\n", p.Name)
+		return
+	}
+	log.Printf("[%s] is degraded, with badness %d\n", p.Name, b)
+	if p.noteNotification(p.t.Now()) {
+		// The probe is flapping, so the warning is escalated.
+		p.alert()
+		return
+	}
+	go p.sendWarning(b)
+}
+
+// sendWarning calls the Warn() implementation, if any.
+func (p *Probe) sendWarning(badness int) {
+	w, ok := p.Prober.(Warner)
+	if !ok {
+		log.Printf("[%s] would warn with badness %d, but is not a Warner\n", p.Name, badness)
+		return
+	}
+	if p.isDryRun() {
+		log.Printf("[%s] [dry run] would warn with badness %d: %s\n", p.Name, badness, p.alertDesc())
+		return
+	}
+	err := p.callNotifier("warning", func() error {
+		return w.Warn(p.Name, p.alertDesc(), badness, p.Records())
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to warn: %v\n", p.Name, err)
+	}
+}
@@ -0,0 +1,59 @@
+package prober
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// warningProber is a testProber that is also a Warner.
+type warningProber struct {
+	testProber
+	WarnFn
+}
+
+func TestProbe_handleResult_thresholds(t *testing.T) {
+	warned := make(chan int, 1)
+	p := &Probe{
+		Prober: warningProber{
+			testProber{FailedWith(errors.New("failing on purpose"))},
+			func(name, desc string, badness int, records Records) error {
+				warned <- badness
+				return nil
+			},
+		},
+		Name:           "WarningProber",
+		Interval:       time.Minute,
+		failurePenalty: 10,
+		t:              fakeTime{time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)},
+	}
+	Thresholds(20, 40)(p)
+	wantStates := []struct{ degraded, alerting bool }{
+		{false, false}, // badness 10
+		{true, false},  // badness 20
+		{true, false},  // badness 30
+		{false, true},  // badness 40
+	}
+	for i, want := range wantStates {
+		p.handleResult(p.Probe())
+		if got := p.IsDegraded(); got != want.degraded {
+			t.Errorf("[%d] IsDegraded() => %v; want %v\n", i, got, want.degraded)
+		}
+		if got := p.IsAlerting(); got != want.alerting {
+			t.Errorf("[%d] IsAlerting() => %v; want %v\n", i, got, want.alerting)
+		}
+	}
+	select {
+	case b := <-warned:
+		if b != 20 {
+			t.Errorf("Warn() called with badness %d; want 20\n", b)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Warn() was not called\n")
+	}
+	select {
+	case b := <-warned:
+		t.Errorf("Warn() called again with badness %d; want only one warning\n", b)
+	default:
+	}
+}
@@ -0,0 +1,290 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type (
+	// TicketTracker is an issue tracker that TicketNotifier opens
+	// tickets in, e.g. GitHubIssues or Jira.
+	TicketTracker interface {
+		// Open opens a ticket in the project, returning its ID.
+		Open(project, title, body string) (id string, err error)
+		// Comment adds a comment to the ticket.
+		Comment(project, id, body string) error
+		// Close closes the ticket, with a final comment.
+		Close(project, id, body string) error
+	}
+
+	// TicketNotifier sends alerts as tickets: it opens a ticket when a
+	// probe starts alerting, comments on it while the probe keeps
+	// alerting, and closes it when the probe recovers.
+	//
+	// Use its Alert and Recover methods as the AlertFn and RecoverFn of
+	// probers, e.g.
+	//
+	//	type ticketedProber struct {
+	//		prober.HTTPProber
+	//		prober.RecoverFn
+	//	}
+	//
+	//	tn := &prober.TicketNotifier{Tracker: prober.GitHubIssues{Token: token}, Project: "acme/ops"}
+	//	p := prober.NewProbe(ticketedProber{prober.HTTPProber{AlertFn: tn.Alert, URL: u}, tn.Recover}, ...)
+	//
+	// The open tickets are only kept in memory, so a ticket that is open
+	// when the process restarts is left open.
+	TicketNotifier struct {
+		Tracker TicketTracker
+		// Project to open tickets in, e.g. "owner/repo" for GitHubIssues
+		// or a project key for Jira.
+		Project string
+		// Projects to open tickets in for specific probes, by name,
+		// instead of Project.
+		Projects map[string]string
+		open     map[string]ticket // open tickets, by name of probe
+		lock     sync.Mutex        // protects open
+	}
+
+	// ticket is a ticket opened by a TicketNotifier.
+	ticket struct{ project, id string }
+
+	// GitHubIssues is a TicketTracker opening issues in GitHub
+	// repositories, with projects named "owner/repo".
+	GitHubIssues struct {
+		Token   string       // personal access token, or token of a GitHub app
+		BaseURL string       // URL of the API, or "" for "https://api.github.com"
+		Labels  []string     // labels to add to issues, if any
+		Client  *http.Client // client to use, or nil for http.DefaultClient
+	}
+
+	// Jira is a TicketTracker opening issues in Jira, with projects
+	// named by their keys.
+	Jira struct {
+		BaseURL   string // URL of the Jira instance, e.g. "https://acme.atlassian.net"
+		User      string // user to authenticate as, e.g. an email address
+		Token     string // API token of the user
+		IssueType string // type of issues to open, or "" for "Bug"
+		// ID of the workflow transition that closes issues, e.g. "31".
+		// If "", issues are commented on but left open on recovery.
+		CloseTransition string
+		Client          *http.Client // client to use, or nil for http.DefaultClient
+	}
+)
+
+// Alert opens a ticket for the probe, or comments on its open ticket.
+func (tn *TicketNotifier) Alert(name, desc string, badness int, records Records) error {
+	body := ticketBody(desc, badness, records)
+	tn.lock.Lock()
+	t, ok := tn.open[name]
+	tn.lock.Unlock()
+	if ok {
+		return tn.Tracker.Comment(t.project, t.id, "Still alerting.\n\n"+body)
+	}
+	project := tn.project(name)
+	id, err := tn.Tracker.Open(project, fmt.Sprintf("[prober] %s is alerting", name), body)
+	if err != nil {
+		return err
+	}
+	tn.lock.Lock()
+	if tn.open == nil {
+		tn.open = map[string]ticket{}
+	}
+	tn.open[name] = ticket{project: project, id: id}
+	tn.lock.Unlock()
+	return nil
+}
+
+// Recover closes the open ticket of the probe, if any.
+func (tn *TicketNotifier) Recover(name, desc string, records Records) error {
+	tn.lock.Lock()
+	t, ok := tn.open[name]
+	tn.lock.Unlock()
+	if !ok {
+		return nil
+	}
+	if err := tn.Tracker.Close(t.project, t.id, fmt.Sprintf("%s recovered: %s", name, desc)); err != nil {
+		return err
+	}
+	tn.lock.Lock()
+	delete(tn.open, name)
+	tn.lock.Unlock()
+	return nil
+}
+
+// Validate checks that the tracker and a project are set, and the
+// settings of the tracker if it can check them, see Notifiers().
+func (tn *TicketNotifier) Validate() ConfigErrors {
+	var errs ConfigErrors
+	if tn.Project == "" && len(tn.Projects) == 0 {
+		errs.add("Project", "or Projects must be set")
+	}
+	if tn.Tracker == nil {
+		errs.add("Tracker", "must be set")
+	} else if v, ok := tn.Tracker.(Validator); ok {
+		for _, e := range v.Validate() {
+			errs.add("Tracker."+e.Path, "%s", e.Err)
+		}
+	}
+	return errs
+}
+
+// project returns the project to open tickets for the probe in.
+func (tn *TicketNotifier) project(name string) string {
+	if project, ok := tn.Projects[name]; ok {
+		return project
+	}
+	return tn.Project
+}
+
+// ticketBody returns the body of a ticket or comment for an alert,
+// listing the most recent failures.
+func ticketBody(desc string, badness int, records Records) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\nBadness: %d\n", desc, badness)
+	n := 0
+	for i := len(records) - 1; i >= 0 && n < 5; i-- {
+		r := records[i]
+		if r.Result.Passed() {
+			continue
+		}
+		if n == 0 {
+			b.WriteString("\nRecent failures:\n")
+		}
+		ts := r.TimeMillis
+		if ts == "" {
+			ts = TimeFormat{}.Format(r.Timestamp)
+		}
+		fmt.Fprintf(&b, "- %s: %v\n", ts, r.Result.Error)
+		n++
+	}
+	return b.String()
+}
+
+// Validate checks that the token is set.
+func (gh GitHubIssues) Validate() ConfigErrors {
+	var errs ConfigErrors
+	if gh.Token == "" {
+		errs.add("Token", "must be set")
+	}
+	return errs
+}
+
+// Open opens an issue in the repository.
+func (gh GitHubIssues) Open(project, title, body string) (string, error) {
+	in := map[string]interface{}{"title": title, "body": body}
+	if len(gh.Labels) > 0 {
+		in["labels"] = gh.Labels
+	}
+	var out struct{ Number int }
+	if err := sendJSON(gh.Client, "POST", gh.url(project, ""), gh.header(), in, &out); err != nil {
+		return "", err
+	}
+	return fmt.Sprint(out.Number), nil
+}
+
+// Comment comments on the issue.
+func (gh GitHubIssues) Comment(project, id, body string) error {
+	return sendJSON(gh.Client, "POST", gh.url(project, id+"/comments"), gh.header(), map[string]string{"body": body}, nil)
+}
+
+// Close comments on the issue and closes it.
+func (gh GitHubIssues) Close(project, id, body string) error {
+	if err := gh.Comment(project, id, body); err != nil {
+		return err
+	}
+	return sendJSON(gh.Client, "PATCH", gh.url(project, id), gh.header(), map[string]string{"state": "closed"}, nil)
+}
+
+// url returns the URL of the issues API of the repository, with the
+// path appended.
+func (gh GitHubIssues) url(repo, path string) string {
+	base := gh.BaseURL
+	if base == "" {
+		base = "https://api.github.com"
+	}
+	u := fmt.Sprintf("%s/repos/%s/issues", strings.TrimSuffix(base, "/"), repo)
+	if path != "" {
+		u += "/" + path
+	}
+	return u
+}
+
+func (gh GitHubIssues) header() http.Header {
+	return http.Header{
+		"Authorization": {"Bearer " + gh.Token},
+		"Accept":        {"application/vnd.github+json"},
+	}
+}
+
+// Validate checks that the URL and credentials are set.
+func (j Jira) Validate() ConfigErrors {
+	var errs ConfigErrors
+	validateURL(&errs, "BaseURL", j.BaseURL, "http", "https")
+	if j.User == "" {
+		errs.add("User", "must be set")
+	}
+	if j.Token == "" {
+		errs.add("Token", "must be set")
+	}
+	return errs
+}
+
+// Open opens an issue in the project.
+func (j Jira) Open(project, title, body string) (string, error) {
+	issueType := j.IssueType
+	if issueType == "" {
+		issueType = "Bug"
+	}
+	in := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": project},
+			"summary":     title,
+			"description": body,
+			"issuetype":   map[string]string{"name": issueType},
+		},
+	}
+	var out struct{ Key string }
+	if err := sendJSON(j.Client, "POST", j.url(""), j.header(), in, &out); err != nil {
+		return "", err
+	}
+	return out.Key, nil
+}
+
+// Comment comments on the issue.
+func (j Jira) Comment(project, id, body string) error {
+	return sendJSON(j.Client, "POST", j.url(url.PathEscape(id)+"/comment"), j.header(), map[string]string{"body": body}, nil)
+}
+
+// Close comments on the issue and transitions it with CloseTransition,
+// if set.
+func (j Jira) Close(project, id, body string) error {
+	if err := j.Comment(project, id, body); err != nil {
+		return err
+	}
+	if j.CloseTransition == "" {
+		return nil
+	}
+	in := map[string]interface{}{"transition": map[string]string{"id": j.CloseTransition}}
+	return sendJSON(j.Client, "POST", j.url(url.PathEscape(id)+"/transitions"), j.header(), in, nil)
+}
+
+// url returns the URL of the issue API, with the path appended.
+func (j Jira) url(path string) string {
+	u := strings.TrimSuffix(j.BaseURL, "/") + "/rest/api/2/issue"
+	if path != "" {
+		u += "/" + path
+	}
+	return u
+}
+
+func (j Jira) header() http.Header {
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(j.User, j.Token)
+	return req.Header
+}
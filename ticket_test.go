@@ -0,0 +1,151 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeTracker is a TicketTracker that records the calls made to it.
+type fakeTracker struct{ calls []string }
+
+func (ft *fakeTracker) Open(project, title, body string) (string, error) {
+	ft.calls = append(ft.calls, "open "+project)
+	return "7", nil
+}
+
+func (ft *fakeTracker) Comment(project, id, body string) error {
+	ft.calls = append(ft.calls, "comment "+project+"#"+id)
+	return nil
+}
+
+func (ft *fakeTracker) Close(project, id, body string) error {
+	ft.calls = append(ft.calls, "close "+project+"#"+id)
+	return nil
+}
+
+func TestTicketNotifier(t *testing.T) {
+	ft := &fakeTracker{}
+	tn := &TicketNotifier{
+		Tracker:  ft,
+		Project:  "acme/ops",
+		Projects: map[string]string{"db": "acme/dba"},
+	}
+	records := Records{{Timestamp: time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC), Result: FailedWith(errors.New("failing on purpose"))}}
+	for _, call := range []func() error{
+		func() error { return tn.Recover("web", "Web is up.", nil) },
+		func() error { return tn.Alert("web", "Web is up.", 200, records) },
+		func() error { return tn.Alert("web", "Web is up.", 200, records) },
+		func() error { return tn.Alert("db", "Database is up.", 200, records) },
+		func() error { return tn.Recover("web", "Web is up.", nil) },
+		func() error { return tn.Alert("web", "Web is up.", 200, records) },
+	} {
+		if err := call(); err != nil {
+			t.Fatalf("got error %v; want nil\n", err)
+		}
+	}
+	want := []string{
+		"open acme/ops",
+		"comment acme/ops#7",
+		"open acme/dba",
+		"close acme/ops#7",
+		"open acme/ops",
+	}
+	if !reflect.DeepEqual(ft.calls, want) {
+		t.Errorf("tracker got calls %v; want %v\n", ft.calls, want)
+	}
+}
+
+func TestGitHubIssues(t *testing.T) {
+	var got []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization => %q; want bearer token\n", auth)
+		}
+		var in map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&in)
+		got = append(got, r.Method+" "+r.URL.Path)
+		if r.Method == "POST" && r.URL.Path == "/repos/acme/ops/issues" {
+			if in["title"] == "" {
+				t.Errorf("opened issue without title\n")
+			}
+			w.Write([]byte(`{"number": 12}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer s.Close()
+
+	gh := GitHubIssues{Token: "secret", BaseURL: s.URL}
+	id, err := gh.Open("acme/ops", "web is alerting", "details")
+	if err != nil || id != "12" {
+		t.Fatalf("Open() => %q, %v; want \"12\", nil\n", id, err)
+	}
+	if err := gh.Close("acme/ops", id, "recovered"); err != nil {
+		t.Fatalf("Close() => %v; want nil\n", err)
+	}
+	want := []string{
+		"POST /repos/acme/ops/issues",
+		"POST /repos/acme/ops/issues/12/comments",
+		"PATCH /repos/acme/ops/issues/12",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GitHub got requests %v; want %v\n", got, want)
+	}
+}
+
+func TestJira(t *testing.T) {
+	var got []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "ops@acme.com" || pass != "secret" {
+			t.Errorf("got basic auth %q, %q; want user and token\n", user, pass)
+		}
+		got = append(got, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/rest/api/2/issue" {
+			w.Write([]byte(`{"key": "OPS-3"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer s.Close()
+
+	j := Jira{BaseURL: s.URL, User: "ops@acme.com", Token: "secret", CloseTransition: "31"}
+	id, err := j.Open("OPS", "web is alerting", "details")
+	if err != nil || id != "OPS-3" {
+		t.Fatalf("Open() => %q, %v; want \"OPS-3\", nil\n", id, err)
+	}
+	if err := j.Close("OPS", id, "recovered"); err != nil {
+		t.Fatalf("Close() => %v; want nil\n", err)
+	}
+	want := []string{
+		"POST /rest/api/2/issue",
+		"POST /rest/api/2/issue/OPS-3/comment",
+		"POST /rest/api/2/issue/OPS-3/transitions",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Jira got requests %v; want %v\n", got, want)
+	}
+}
+
+func TestTicketNotifier_Validate(t *testing.T) {
+	cases := []struct {
+		tn   *TicketNotifier
+		want []string
+	}{
+		{&TicketNotifier{Tracker: GitHubIssues{Token: "s3cret"}, Project: "acme/ops"}, nil},
+		{&TicketNotifier{Tracker: GitHubIssues{}, Projects: map[string]string{"web": "acme/web"}}, []string{"Tracker.Token"}},
+		{&TicketNotifier{Tracker: Jira{BaseURL: "acme.atlassian.net", User: "ops@acme.com"}, Project: "OPS"}, []string{"Tracker.BaseURL", "Tracker.Token"}},
+		{&TicketNotifier{}, []string{"Project", "Tracker"}},
+	}
+	for i, tt := range cases {
+		if got := errPaths(tt.tn.Validate()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%d] Validate() => %v; want problems at %v\n", i, tt.tn.Validate(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package prober
+
+import (
+	"flag"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	timeFormat = flag.String("time_format", time.RFC3339Nano, "layout of timestamps in records and notifications, as for time.Format")
+	timeZone   = flag.String("time_zone", "UTC", "time zone of timestamps in records and notifications, e.g. \"Local\" or \"Europe/Stockholm\"")
+
+	zones     = map[string]*time.Location{} // time zones loaded by name
+	zonesLock sync.Mutex                    // protects zones
+)
+
+// TimeFormat is how the timestamps of records are written, in the log
+// file, in notifications and in dashboards. The Timestamp of a Record
+// keeps full precision regardless, and the default layout of RFC 3339
+// with nanoseconds keeps it in the log file too.
+type TimeFormat struct {
+	Layout   string         // layout as for time.Format, or "" for -time_format
+	Location *time.Location // time zone, or nil for -time_zone
+}
+
+// Format returns the time formatted with the layout, in the time zone.
+func (tf TimeFormat) Format(t time.Time) string {
+	layout := tf.Layout
+	if layout == "" {
+		layout = *timeFormat
+	}
+	return t.In(tf.zone()).Format(layout)
+}
+
+// zone returns the time zone of the format.
+func (tf TimeFormat) zone() *time.Location {
+	if tf.Location != nil {
+		return tf.Location
+	}
+	return zoneNamed(*timeZone)
+}
+
+// zoneNamed returns the named time zone, or UTC if there's no such
+// zone.
+func zoneNamed(name string) *time.Location {
+	zonesLock.Lock()
+	defer zonesLock.Unlock()
+	if loc, ok := zones[name]; ok {
+		return loc
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("bad -time_zone, using UTC: %v\n", err)
+		loc = time.UTC
+	}
+	zones[name] = loc
+	return loc
+}
+
+// timeFormat returns the TimeFormat of the probe's Engine, if any, or
+// else the one given by -time_format and -time_zone.
+func (p *Probe) timeFormat() TimeFormat {
+	if p.engine != nil {
+		return p.engine.TimeFormat
+	}
+	return TimeFormat{}
+}
+
+// formatTime returns the time formatted with the TimeFormat of the
+// probe.
+func (p *Probe) formatTime(t time.Time) string {
+	return p.timeFormat().Format(t)
+}
@@ -0,0 +1,34 @@
+package prober
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeFormat_Format(t *testing.T) {
+	ts := time.Date(1998, 11, 19, 15, 14, 0, 123456789, time.FixedZone("CET", 60*60))
+	cases := []struct {
+		in   TimeFormat
+		want string
+	}{
+		{TimeFormat{}, "1998-11-19T14:14:00.123456789Z"},
+		{TimeFormat{Layout: time.StampMilli}, "Nov 19 14:14:00.123"},
+		{TimeFormat{Location: time.FixedZone("EST", -5*60*60)}, "1998-11-19T09:14:00.123456789-05:00"},
+		{TimeFormat{Layout: "2006-01-02 15:04 MST", Location: time.FixedZone("CET", 60*60)}, "1998-11-19 15:14 CET"},
+	}
+	for i, tt := range cases {
+		if got := tt.in.Format(ts); got != tt.want {
+			t.Errorf("[%d] %+v.Format(%v) => %q; want %q\n", i, tt.in, ts, got, tt.want)
+		}
+	}
+}
+
+func TestProbe_formatTime(t *testing.T) {
+	ts := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	e := &Engine{TimeFormat: TimeFormat{Layout: time.Kitchen}}
+	p := e.NewProbe(testProber{Passed()}, "TimeFormatProber", "Formats times")
+	defer p.unpublish()
+	if got, want := p.formatTime(ts), "3:14PM"; got != want {
+		t.Errorf("formatTime() => %q; want %q\n", got, want)
+	}
+}
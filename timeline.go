@@ -0,0 +1,131 @@
+package prober
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"time"
+)
+
+// Defaults for the zero fields of Timeline.
+const (
+	defaultTimelineRuns       = 50
+	defaultTimelineBlockWidth = 6
+	defaultTimelineHeight     = 20
+	timelineGap               = 1 // pixels between blocks
+)
+
+// Colors of the blocks in timelines for each ResultCode.
+var timelineColors = map[ResultCode]color.RGBA{
+	Pass:     {0x5c, 0xb8, 0x5c, 0xff},
+	Fail:     {0xd9, 0x53, 0x4f, 0xff},
+	Degraded: {0xf0, 0xad, 0x4e, 0xff},
+}
+
+type (
+	// Timeline renders records as a strip of green, orange and red
+	// blocks, one per run and oldest on the left, e.g. to embed the
+	// recent history of a probe in a dashboard or alert email.
+	Timeline struct {
+		Runs       int // number of most recent runs to show, or 0 for 50
+		BlockWidth int // width of each block in pixels, or 0 for 6
+		Height     int // height of the strip in pixels, or 0 for 20
+	}
+
+	// timelineBlock is a run shown in a timeline.
+	timelineBlock struct {
+		time   time.Time
+		result Result
+	}
+)
+
+// SVG writes the timeline of the records as an SVG image, with the time
+// and result of each run as the title of its block.
+func (tl Timeline) SVG(w io.Writer, records Records) error {
+	blocks := tl.blocks(records)
+	bw, h := tl.blockWidth(), tl.height()
+	bufw := bufio.NewWriter(w)
+	fmt.Fprintf(bufw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`, tl.width(len(blocks)), h)
+	for i, b := range blocks {
+		c := timelineColors[b.result.Code]
+		title := fmt.Sprintf("%s: %s", b.time.Format(time.RFC3339), b.result.Code)
+		if b.result.Error != nil {
+			title += ": " + b.result.Error.Error()
+		}
+		fmt.Fprintf(bufw, `<rect x="%d" y="0" width="%d" height="%d" fill="#%02x%02x%02x"><title>%s</title></rect>`,
+			i*(bw+timelineGap), bw, h, c.R, c.G, c.B, html.EscapeString(title))
+	}
+	fmt.Fprint(bufw, "</svg>\n")
+	return bufw.Flush()
+}
+
+// PNG writes the timeline of the records as a PNG image, with a
+// transparent background between blocks.
+func (tl Timeline) PNG(w io.Writer, records Records) error {
+	blocks := tl.blocks(records)
+	bw, h := tl.blockWidth(), tl.height()
+	img := image.NewRGBA(image.Rect(0, 0, tl.width(len(blocks)), h))
+	for i, b := range blocks {
+		c := timelineColors[b.result.Code]
+		x0 := i * (bw + timelineGap)
+		for x := x0; x < x0+bw; x++ {
+			for y := 0; y < h; y++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// blocks returns the most recent runs of the records to show, oldest
+// first, expanding records that were merged by CompactRecords() into
+// one block per run.
+func (tl Timeline) blocks(records Records) []timelineBlock {
+	runs := tl.Runs
+	if runs <= 0 {
+		runs = defaultTimelineRuns
+	}
+	var blocks []timelineBlock
+	for i := len(records) - 1; i >= 0 && len(blocks) < runs; i-- {
+		r := records[i]
+		for j := 0; j <= r.Repeats && len(blocks) < runs; j++ {
+			t := r.Timestamp
+			if j == 0 && r.Repeats > 0 {
+				t = r.Until
+			}
+			blocks = append(blocks, timelineBlock{time: t, result: r.Result})
+		}
+	}
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return blocks
+}
+
+// width returns the width in pixels of a timeline with n blocks.
+func (tl Timeline) width(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return n*(tl.blockWidth()+timelineGap) - timelineGap
+}
+
+// blockWidth returns the width of each block in pixels.
+func (tl Timeline) blockWidth() int {
+	if tl.BlockWidth <= 0 {
+		return defaultTimelineBlockWidth
+	}
+	return tl.BlockWidth
+}
+
+// height returns the height of the timeline in pixels.
+func (tl Timeline) height() int {
+	if tl.Height <= 0 {
+		return defaultTimelineHeight
+	}
+	return tl.Height
+}
@@ -0,0 +1,65 @@
+package prober
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	records := Records{
+		{Timestamp: start, Result: Passed()},
+		{Timestamp: start.Add(time.Minute), Result: FailedWith(errors.New("<b>down</b>"))},
+		{Timestamp: start.Add(2 * time.Minute), Result: Passed(), Repeats: 2, Until: start.Add(4 * time.Minute)},
+	}
+	cases := []struct {
+		tl        Timeline
+		wantRects int
+		wantWidth int
+	}{
+		{Timeline{}, 5, 5*7 - 1},
+		{Timeline{Runs: 3, BlockWidth: 10}, 3, 3*11 - 1},
+	}
+	for i, tt := range cases {
+		var svg bytes.Buffer
+		if err := tt.tl.SVG(&svg, records); err != nil {
+			t.Fatalf("[%d] SVG() => %v; want nil\n", i, err)
+		}
+		if got := strings.Count(svg.String(), "<rect "); got != tt.wantRects {
+			t.Errorf("[%d] SVG() wrote %d blocks; want %d\n", i, got, tt.wantRects)
+		}
+		if strings.Contains(svg.String(), "<b>") {
+			t.Errorf("[%d] SVG() wrote unescaped error: %s\n", i, svg.String())
+		}
+
+		var b bytes.Buffer
+		if err := tt.tl.PNG(&b, records); err != nil {
+			t.Fatalf("[%d] PNG() => %v; want nil\n", i, err)
+		}
+		img, err := png.Decode(&b)
+		if err != nil {
+			t.Fatalf("[%d] PNG() wrote bad image: %v\n", i, err)
+		}
+		if got := img.Bounds().Dx(); got != tt.wantWidth {
+			t.Errorf("[%d] PNG() wrote image %d pixels wide; want %d\n", i, got, tt.wantWidth)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := (Timeline{BlockWidth: 1, Height: 1}).PNG(&b, records); err != nil {
+		t.Fatalf("PNG() => %v; want nil\n", err)
+	}
+	img, _ := png.Decode(&b)
+	wantColors := []ResultCode{Pass, Fail, Pass, Pass, Pass}
+	for i, code := range wantColors {
+		r, g, _, _ := img.At(i*2, 0).RGBA()
+		want := timelineColors[code]
+		if uint8(r>>8) != want.R || uint8(g>>8) != want.G {
+			t.Errorf("PNG() block %d has color %x,%x; want %s color\n", i, r>>8, g>>8, code)
+		}
+	}
+}
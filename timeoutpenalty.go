@@ -0,0 +1,46 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// ErrTimedOut is the class of errors for runs that took longer than the
+// interval of the probe.
+var ErrTimedOut = errors.New("probe timed out")
+
+// TimeoutPenalty sets the amount badness is incremented by when a run
+// times out, instead of FailurePenalty, e.g. for slow responses to add
+// up to an alert more slowly than hard errors. A run times out if it
+// takes longer than the interval of the probe, or if its error is a
+// context.DeadlineExceeded or a network timeout, e.g. from HTTPProber
+// or TCPProber. Penalties of timeouts are weighed like those of other
+// failures.
+func TimeoutPenalty(penalty int) func(*Probe) {
+	return func(p *Probe) {
+		p.timeoutPenalty = penalty
+	}
+}
+
+// isTimeout returns true if the failed result is from a run that timed
+// out.
+func isTimeout(r Result) bool {
+	if r.Error == nil {
+		return false
+	}
+	if errors.Is(r.Error, ErrTimedOut) || errors.Is(r.Error, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(r.Error, &netErr) && netErr.Timeout()
+}
+
+// basePenalty returns how much badness increases for the failed result
+// before weighing it, and whether that's the TimeoutPenalty().
+func (p *Probe) basePenalty(r Result) (int, bool) {
+	if p.timeoutPenalty > 0 && isTimeout(r) {
+		return p.timeoutPenalty, true
+	}
+	return p.failurePenalty, false
+}
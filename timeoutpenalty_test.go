@@ -0,0 +1,39 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestProbe_timeoutPenalty(t *testing.T) {
+	netTimeout := &net.OpError{Op: "dial", Err: &timeoutErr{}}
+	cases := []struct {
+		timeoutPenalty int
+		in             Result
+		want           int
+	}{
+		{0, FailedWith(ErrTimedOut), 100},
+		{20, FailedWith(errors.New("connection refused")), 100},
+		{20, FailedWith(fmt.Errorf("%w: web took longer than the probe interval of 60.0 sec", ErrTimedOut)), 20},
+		{20, FailedWith(fmt.Errorf("request failed: %w", context.DeadlineExceeded)), 20},
+		{20, FailedWith(netTimeout), 20},
+		{20, FailedWith(ErrTimedOut).Weighted(0.5), 10},
+		{20, DegradedWith(ErrTimedOut, "1 of 2 slow"), 10},
+	}
+	for i, tt := range cases {
+		p := &Probe{failurePenalty: 100, timeoutPenalty: tt.timeoutPenalty}
+		if got := p.penalty(tt.in); got != tt.want {
+			t.Errorf("[%d] penalty(%v) with TimeoutPenalty(%d) => %d; want %d\n", i, tt.in, tt.timeoutPenalty, got, tt.want)
+		}
+	}
+}
+
+// timeoutErr is a network error that timed out.
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
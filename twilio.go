@@ -0,0 +1,206 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TwilioNotifier sends alerts as SMS or voice calls via Twilio, for
+// teams without a paging provider. Since it's meant for waking people
+// up, it's best used only for the most critical probes, with other
+// probes alerting by e.g. email.
+//
+// Alerts go to the first number in To. If EscalateAfter is set and the
+// probe is still alerting that long after the last number was notified,
+// the next number is notified too, and so on. When the probe recovers,
+// the notified numbers get a recovery SMS, unless Call is set.
+//
+// Use its Alert and Recover methods as the AlertFn and RecoverFn of
+// probers, see TicketNotifier for an example.
+type TwilioNotifier struct {
+	AccountSID string
+	AuthToken  string
+	From       string   // number to send from, e.g. "+15005550006"
+	To         []string // numbers to notify, in order of escalation
+	Call       bool     // whether to call the numbers instead of sending SMS
+	// How long a probe may keep alerting before the next number in To
+	// is notified, or 0 to notify all numbers at once.
+	EscalateAfter time.Duration
+	// Most messages and calls to send per hour, or 0 for 20. Alerts
+	// over the limit fail, so the probe tries again later.
+	MaxPerHour int
+	BaseURL    string                 // URL of the API, or "" for "https://api.twilio.com"
+	Client     *http.Client           // client to use, or nil for http.DefaultClient
+	escalation map[string]*escalation // escalations of alerting probes, by name
+	sent       []time.Time            // times of messages and calls in the last hour
+	t          timeT
+	lock       sync.Mutex // protects escalation and sent
+}
+
+// escalation is how far the alerts of a probe have escalated.
+type escalation struct {
+	notified int       // number of numbers in To notified so far
+	last     time.Time // when the last number was first notified
+}
+
+// Alert notifies the numbers that the alert of the probe has escalated
+// to.
+func (tn *TwilioNotifier) Alert(name, desc string, badness int, records Records) error {
+	if len(tn.To) == 0 {
+		return fmt.Errorf("no numbers to notify of %s alerting", name)
+	}
+	to := tn.escalate(name)
+	msg := fmt.Sprintf("ALERT: %s is failing (badness %d): %s", name, badness, desc)
+	for _, number := range to {
+		if err := tn.send(number, msg, tn.Call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Recover sends a recovery SMS to the numbers that were notified of the
+// probe alerting, unless Call is set.
+func (tn *TwilioNotifier) Recover(name, desc string, records Records) error {
+	tn.lock.Lock()
+	e := tn.escalation[name]
+	delete(tn.escalation, name)
+	tn.lock.Unlock()
+	if e == nil || tn.Call {
+		return nil
+	}
+	msg := fmt.Sprintf("RECOVERED: %s is passing again: %s", name, desc)
+	for _, number := range tn.To[:e.notified] {
+		if err := tn.send(number, msg, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks that the credentials and numbers are set, see
+// Notifiers().
+func (tn *TwilioNotifier) Validate() ConfigErrors {
+	var errs ConfigErrors
+	if tn.AccountSID == "" {
+		errs.add("AccountSID", "must be set")
+	}
+	if tn.AuthToken == "" {
+		errs.add("AuthToken", "must be set")
+	}
+	if tn.From == "" {
+		errs.add("From", "must be set")
+	}
+	if len(tn.To) == 0 {
+		errs.add("To", "must have at least one number")
+	}
+	return errs
+}
+
+// escalate returns the numbers to notify of the probe alerting now,
+// notifying the next number if it's time to escalate.
+func (tn *TwilioNotifier) escalate(name string) []string {
+	now := tn.now()
+	tn.lock.Lock()
+	defer tn.lock.Unlock()
+	if tn.EscalateAfter == 0 {
+		return tn.To
+	}
+	if tn.escalation == nil {
+		tn.escalation = map[string]*escalation{}
+	}
+	e, ok := tn.escalation[name]
+	if !ok {
+		e = &escalation{notified: 1, last: now}
+		tn.escalation[name] = e
+	} else if e.notified < len(tn.To) && now.Sub(e.last) >= tn.EscalateAfter {
+		e.notified++
+		e.last = now
+	}
+	return tn.To[:e.notified]
+}
+
+// send sends the message to the number as SMS, or reads it out in a
+// call.
+func (tn *TwilioNotifier) send(number, msg string, call bool) error {
+	if err := tn.allow(); err != nil {
+		return err
+	}
+	form := url.Values{"From": {tn.From}, "To": {number}}
+	resource := "Messages.json"
+	if call {
+		var twiml bytes.Buffer
+		twiml.WriteString("<Response><Say>")
+		xml.EscapeText(&twiml, []byte(msg))
+		twiml.WriteString("</Say></Response>")
+		form.Set("Twiml", twiml.String())
+		resource = "Calls.json"
+	} else {
+		form.Set("Body", msg)
+	}
+	base := tn.BaseURL
+	if base == "" {
+		base = "https://api.twilio.com"
+	}
+	u := fmt.Sprintf("%s/2010-04-01/Accounts/%s/%s", strings.TrimSuffix(base, "/"), tn.AccountSID, resource)
+	req, err := http.NewRequest("POST", u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(tn.AccountSID, tn.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := tn.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Twilio returned %q for %s: %s", resp.Status, number, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// allow returns an error if sending another message or call now would
+// go over MaxPerHour, and otherwise counts it.
+func (tn *TwilioNotifier) allow() error {
+	max := tn.MaxPerHour
+	if max == 0 {
+		max = 20
+	}
+	now := tn.now()
+	tn.lock.Lock()
+	defer tn.lock.Unlock()
+	recent := tn.sent[:0]
+	for _, t := range tn.sent {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	tn.sent = recent
+	if len(tn.sent) >= max {
+		return fmt.Errorf("not sending via Twilio, since %d messages and calls were sent in the last hour", len(tn.sent))
+	}
+	tn.sent = append(tn.sent, now)
+	return nil
+}
+
+func (tn *TwilioNotifier) now() time.Time {
+	if tn.t == nil {
+		return time.Now()
+	}
+	return tn.t.Now()
+}
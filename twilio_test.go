@@ -0,0 +1,97 @@
+//go:build !minimal
+
+package prober
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTwilioNotifier(t *testing.T) {
+	var got []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "AC123" || pass != "secret" {
+			t.Errorf("got basic auth %q, %q; want account SID and token\n", user, pass)
+		}
+		if want := "/2010-04-01/Accounts/AC123/"; !strings.HasPrefix(r.URL.Path, want) {
+			t.Errorf("got request to %q; want prefix %q\n", r.URL.Path, want)
+		}
+		r.ParseForm()
+		kind := "sms"
+		if strings.HasSuffix(r.URL.Path, "/Calls.json") {
+			kind = "call"
+			if twiml := r.Form.Get("Twiml"); !strings.Contains(twiml, "<Say>ALERT: web is failing") {
+				t.Errorf("got TwiML %q; want alert read out\n", twiml)
+			}
+		}
+		got = append(got, kind+" "+r.Form.Get("To"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer s.Close()
+
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	tn := &TwilioNotifier{
+		AccountSID:    "AC123",
+		AuthToken:     "secret",
+		From:          "+15005550006",
+		To:            []string{"+1111", "+2222"},
+		EscalateAfter: 30 * time.Minute,
+		MaxPerHour:    4,
+		BaseURL:       s.URL,
+		t:             fakeTime{start},
+	}
+	for _, d := range []time.Duration{0, 15 * time.Minute, 30 * time.Minute} {
+		tn.t = fakeTime{start.Add(d)}
+		if err := tn.Alert("web", "Web is up.", 200, nil); err != nil {
+			t.Fatalf("Alert() after %v => %v; want nil\n", d, err)
+		}
+	}
+	if err := tn.Alert("web", "Web is up.", 200, nil); err == nil {
+		t.Errorf("Alert() over MaxPerHour => nil; want error\n")
+	}
+	tn.t = fakeTime{start.Add(2 * time.Hour)}
+	if err := tn.Recover("web", "Web is up.", nil); err != nil {
+		t.Fatalf("Recover() => %v; want nil\n", err)
+	}
+	want := []string{
+		"sms +1111",
+		"sms +1111",
+		"sms +1111", "sms +2222",
+		"sms +1111", "sms +2222",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Twilio got %v; want %v\n", got, want)
+	}
+
+	got = nil
+	tn.Call = true
+	if err := tn.Alert("web", "Web is up.", 200, nil); err != nil {
+		t.Fatalf("Alert() with Call => %v; want nil\n", err)
+	}
+	if err := tn.Recover("web", "Web is up.", nil); err != nil {
+		t.Fatalf("Recover() with Call => %v; want nil\n", err)
+	}
+	if want := []string{"call +1111"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Twilio got %v with Call; want %v\n", got, want)
+	}
+}
+
+func TestTwilioNotifier_Validate(t *testing.T) {
+	cases := []struct {
+		tn   *TwilioNotifier
+		want []string
+	}{
+		{&TwilioNotifier{AccountSID: "AC123", AuthToken: "secret", From: "+15005550006", To: []string{"+15005550001"}}, nil},
+		{&TwilioNotifier{AccountSID: "AC123", From: "+15005550006"}, []string{"AuthToken", "To"}},
+		{&TwilioNotifier{}, []string{"AccountSID", "AuthToken", "From", "To"}},
+	}
+	for i, tt := range cases {
+		if got := errPaths(tt.tn.Validate()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%d] Validate() => %v; want problems at %v\n", i, tt.tn.Validate(), tt.want)
+		}
+	}
+}
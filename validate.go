@@ -0,0 +1,352 @@
+package prober
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type (
+	// ConfigError is a problem with the settings of a probe, at a path
+	// like "web.Interval" or "web.Prober.URL".
+	ConfigError struct {
+		Path string
+		Err  string
+	}
+
+	// ConfigErrors holds all problems found by Validate().
+	ConfigErrors []ConfigError
+
+	// Validator is implemented by Probers that can check their own
+	// settings. Each problem is reported at a path relative to the
+	// prober, e.g. "URL".
+	Validator interface {
+		Validate() ConfigErrors
+	}
+
+	// TimeoutProber is implemented by Probers that give up on their
+	// target after a timeout, which Validate() checks against the
+	// interval of the probe.
+	TimeoutProber interface {
+		ProbeTimeout() time.Duration
+	}
+)
+
+// Error returns the problem with its path.
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+// Error returns all the problems, one per line.
+func (errs ConfigErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, e := range errs {
+		lines[i] = e.Error()
+	}
+	return fmt.Sprintf("%d problems with probe config:\n%s", len(errs), strings.Join(lines, "\n"))
+}
+
+// add adds a problem at the path.
+func (errs *ConfigErrors) add(path, format string, args ...interface{}) {
+	*errs = append(*errs, ConfigError{Path: path, Err: fmt.Sprintf(format, args...)})
+}
+
+// err returns the problems as an error, or nil if there are none.
+func (errs ConfigErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// AllowTimeoutOverInterval lets the probe have a prober with a timeout
+// longer than its interval, which otherwise fails Validate() since the
+// run would be cut short when the interval passes.
+func AllowTimeoutOverInterval() func(*Probe) {
+	return func(p *Probe) {
+		p.allowLongTimeout = true
+	}
+}
+
+// Validate checks the settings of the probe, returning all problems at
+// once as ConfigErrors, or nil if there are none.
+func (p *Probe) Validate() error {
+	return p.validate().err()
+}
+
+// validate returns all problems with the settings of the probe.
+func (p *Probe) validate() ConfigErrors {
+	var errs ConfigErrors
+	name := p.Name
+	if name == "" {
+		name = "<unnamed>"
+		errs.add(name+".Name", "must be set")
+	}
+	path := func(field string) string { return name + "." + field }
+
+	if p.Interval <= 0 {
+		errs.add(path("Interval"), "must be positive, got %v", p.Interval)
+	}
+	if tp, ok := p.Prober.(TimeoutProber); ok && p.Interval > 0 && !p.allowLongTimeout {
+		if timeout := tp.ProbeTimeout(); timeout > p.Interval {
+			errs.add(path("Prober.Timeout"), "%v is longer than interval %v, use AllowTimeoutOverInterval() if that's intended", timeout, p.Interval)
+		}
+	}
+	if p.failurePenalty <= 0 {
+		errs.add(path("FailurePenalty"), "must be positive, got %d", p.failurePenalty)
+	}
+	if p.logSampling < 0 {
+		errs.add(path("SampleLog"), "must not be negative, got %d", p.logSampling)
+	}
+	if p.timeoutPenalty < 0 {
+		errs.add(path("TimeoutPenalty"), "must not be negative, got %d", p.timeoutPenalty)
+	}
+	if p.successReward < 0 {
+		errs.add(path("SuccessReward"), "must not be negative, got %d", p.successReward)
+	}
+	if p.critThreshold < 0 {
+		errs.add(path("Thresholds.critical"), "must not be negative, got %d", p.critThreshold)
+	} else if p.threshold() <= 0 {
+		errs.add(path("Thresholds.critical"), "must be positive, got %d", p.threshold())
+	}
+	if p.warnThreshold < 0 {
+		errs.add(path("Thresholds.warning"), "must not be negative, got %d", p.warnThreshold)
+	} else if p.warnThreshold > 0 && p.warnThreshold >= p.threshold() {
+		errs.add(path("Thresholds.warning"), "%d must be below critical threshold %d", p.warnThreshold, p.threshold())
+	}
+	if p.degradedW < 0 || p.degradedW > 1 {
+		errs.add(path("DegradedWeight"), "must be between 0 and 1, got %v", p.degradedW)
+	}
+	if p.fastRecheck < 0 || p.fastRecheck > 0 && p.fastRecheck >= p.Interval {
+		errs.add(path("FastRecheck"), "must be between 0 and the interval %v, got %v", p.Interval, p.fastRecheck)
+	}
+	if p.initialDelay < 0 {
+		errs.add(path("InitialDelay"), "must not be negative, got %v", p.initialDelay)
+	}
+	if p.reAlertWindow < 0 {
+		errs.add(path("ReAlertWindow"), "must not be negative, got %v", p.reAlertWindow)
+	}
+	if p.sloTarget < 0 || p.sloTarget >= 1 {
+		errs.add(path("SLO"), "target must be between 0 and 1, got %v", p.sloTarget)
+	}
+	for i, w := range p.sloWindows {
+		if w.Long <= 0 || w.Short <= 0 || w.Rate <= 0 {
+			errs.add(path(fmt.Sprintf("SLO.windows[%d]", i)), "windows and rate must be positive, got %+v", w)
+		}
+	}
+	if p.latencyWindow < 0 {
+		errs.add(path("LatencyWindow"), "must not be negative, got %v", p.latencyWindow)
+	}
+	if p.alertWhenSrc != "" {
+		// Checking the condition against the current state catches
+		// unknown variables and mismatched types.
+		if c, err := p.compileAlertWhen(); err != nil {
+			errs.add(path("AlertWhen"), "%v", err)
+		} else if _, err := c.Check(p.alertVars(time.Now())); err != nil {
+			errs.add(path("AlertWhen"), "%v", err)
+		}
+	}
+	if p.schedule != nil && p.schedule.Next(time.Now()).IsZero() {
+		errs.add(path("Schedule"), "%v never matches", p.schedule)
+	}
+	if af, ok := p.Prober.(interface{ hasAlertFn() bool }); ok && !af.hasAlertFn() {
+		errs.add(path("Prober.AlertFn"), "must be set, or alerts are only logged")
+	}
+	if p.warnThreshold > 0 {
+		if _, ok := p.Prober.(Warner); !ok {
+			errs.add(path("Prober"), "has a warning threshold, but is not a Warner, so warnings are only logged")
+		}
+	}
+	if p.alertGroup != nil && p.alertGroup.Notify == nil {
+		errs.add(path("AlertGroup.Notify"), "must be set")
+	}
+	if v, ok := p.Prober.(Validator); ok {
+		for _, e := range v.Validate() {
+			errs.add(path("Prober."+e.Path), "%s", e.Err)
+		}
+	}
+	for i, n := range p.notifiers {
+		for _, e := range n.Validate() {
+			errs.add(path(fmt.Sprintf("Notifiers[%d].%s", i, e.Path)), "%s", e.Err)
+		}
+	}
+	return errs
+}
+
+// Notifiers makes Validate() check the settings of the notifiers that
+// the AlertFn, WarnFn and RecoverFn of the probe use, e.g. that their
+// credentials are set, since it can't find them from the functions:
+//
+//	tn := &prober.TwilioNotifier{AccountSID: sid, AuthToken: os.Getenv("TWILIO_TOKEN"), From: from, To: oncall}
+//	p := prober.NewProbe(prober.HTTPProber{AlertFn: tn.Alert, URL: u}, "Web", "Web is up", prober.Notifiers(tn))
+//
+// The built-in notifiers, e.g. TwilioNotifier, TicketNotifier,
+// TelegramNotifier and NtfyNotifier, are all Validators.
+func Notifiers(ns ...Validator) func(*Probe) {
+	return func(p *Probe) {
+		p.notifiers = append(p.notifiers, ns...)
+	}
+}
+
+// Validate checks the settings of all probes, as well as that their
+// names are unique and their dependencies exist, returning all problems
+// at once as ConfigErrors, or nil if there are none.
+func (ps Probes) Validate() error {
+	var errs ConfigErrors
+	names := map[string]bool{}
+	for _, p := range ps {
+		if names[p.Name] {
+			errs.add(p.Name+".Name", "is used by more than one probe")
+		}
+		names[p.Name] = true
+	}
+	for _, p := range ps {
+		errs = append(errs, p.validate()...)
+		for _, dep := range p.dependencies {
+			if !names[dep] {
+				errs.add(p.Name+".DependsOn", "no probe is named %q", dep)
+			}
+		}
+	}
+	return errs.err()
+}
+
+// Validate checks the settings of all probes in the registry, see
+// Probes.Validate(). It's meant to be called before Run(), so that
+// problems are found before any probe starts.
+func (r *Registry) Validate() error {
+	return r.Probes().Validate()
+}
+
+// hasAlertFn returns true if fn is set, which lets Validate() find
+// probers that embed an AlertFn but have none.
+func (fn AlertFn) hasAlertFn() bool {
+	return fn != nil
+}
+
+// validateAssert adds a problem at "Assert" if the expression is set
+// but doesn't parse.
+func validateAssert(errs *ConfigErrors, s string) {
+	if s == "" {
+		return
+	}
+	if _, err := ParseExpr(s); err != nil {
+		errs.add("Assert", "%v", err)
+	}
+}
+
+// validateURL adds a problem at the path if u isn't an absolute URL with
+// one of the schemes.
+func validateURL(errs *ConfigErrors, path, u string, schemes ...string) {
+	if u == "" {
+		errs.add(path, "must be set")
+		return
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		errs.add(path, "%v", err)
+		return
+	}
+	for _, s := range schemes {
+		if parsed.Scheme == s && parsed.Host != "" {
+			return
+		}
+	}
+	errs.add(path, "%q must be an absolute URL with scheme %s", u, strings.Join(schemes, " or "))
+}
+
+// Validate checks the settings of the prober.
+func (hp HTTPProber) Validate() ConfigErrors {
+	var errs ConfigErrors
+	validateURL(&errs, "URL", hp.URL, "http", "https")
+	if hp.WantStatus != 0 && (hp.WantStatus < 100 || hp.WantStatus > 599) {
+		errs.add("WantStatus", "%d is not a HTTP status", hp.WantStatus)
+	}
+	validateAssert(&errs, hp.Assert)
+	return errs
+}
+
+// ProbeTimeout returns the timeout of the client used, if any.
+func (hp HTTPProber) ProbeTimeout() time.Duration {
+	return hp.client().Timeout
+}
+
+// Validate checks the settings of the prober.
+func (s Scenario) Validate() ConfigErrors {
+	var errs ConfigErrors
+	if len(s.Steps) == 0 {
+		errs.add("Steps", "must not be empty")
+	}
+	seen := map[string]bool{}
+	for i, step := range s.Steps {
+		path := fmt.Sprintf("Steps[%d]", i)
+		if step.Name == "" {
+			errs.add(path+".Name", "must be set")
+		} else if seen[step.Name] {
+			errs.add(path+".Name", "%q is used by more than one step", step.Name)
+		}
+		seen[step.Name] = true
+		if step.Probe == nil {
+			errs.add(path+".Probe", "must be set")
+		}
+	}
+	return errs
+}
+
+// Validate checks the settings of the prober.
+func (tp TCPProber) Validate() ConfigErrors {
+	var errs ConfigErrors
+	if !strings.Contains(tp.Addr, ":") {
+		errs.add("Addr", "%q must be host:port", tp.Addr)
+	}
+	if tp.Timeout < 0 {
+		errs.add("Timeout", "must not be negative, got %v", tp.Timeout)
+	}
+	validateAssert(&errs, tp.Assert)
+	return errs
+}
+
+// ProbeTimeout returns how long the prober waits for the connection.
+func (tp TCPProber) ProbeTimeout() time.Duration {
+	if tp.Timeout == 0 {
+		return 10 * time.Second
+	}
+	return tp.Timeout
+}
+
+// Validate checks the settings of the prober.
+func (op OAuthProber) Validate() ConfigErrors {
+	var errs ConfigErrors
+	if op.Issuer == "" && op.TokenURL == "" {
+		errs.add("Issuer", "or TokenURL must be set")
+	}
+	if op.ClientID == "" {
+		errs.add("ClientID", "must be set")
+	}
+	switch op.flow() {
+	case ClientCredentialsFlow:
+		if op.ClientSecret == "" {
+			errs.add("ClientSecret", "must be set for the client credentials flow")
+		}
+	case DeviceCodeFlow:
+		if op.Issuer == "" && op.DeviceAuthURL == "" {
+			errs.add("DeviceAuthURL", "or Issuer must be set for the device code flow")
+		}
+	default:
+		errs.add("Flow", "unknown flow %q", op.Flow)
+	}
+	return errs
+}
+
+// Validate checks the settings of the prober.
+func (cp ChecksumProber) Validate() ConfigErrors {
+	var errs ConfigErrors
+	validateURL(&errs, "URL", cp.URL, "http", "https")
+	if cp.SHA256 != "" {
+		if len(cp.SHA256) != 64 || strings.Trim(strings.ToLower(cp.SHA256), "0123456789abcdef") != "" {
+			errs.add("SHA256", "%q is not a hex SHA-256 checksum", cp.SHA256)
+		}
+	}
+	return errs
+}
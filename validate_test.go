@@ -0,0 +1,93 @@
+package prober
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProbes_Validate(t *testing.T) {
+	alert := AlertFn(func(name, desc string, badness int, records Records) error { return nil })
+	good := NewProbe(HTTPProber{AlertFn: alert, URL: "https://example.com/"}, "ValidateGood", "A valid probe.")
+	defer good.unpublish()
+	bad := NewProbe(
+		TCPProber{Addr: "example.com", Timeout: 2 * time.Minute, Assert: "latency <"},
+		"ValidateBad",
+		"An invalid probe.",
+		Thresholds(300, 200),
+		DependsOn("Missing"),
+	)
+	defer bad.unpublish()
+
+	if err := (Probes{good}).Validate(); err != nil {
+		t.Errorf("Validate() of valid probe => %v; want nil\n", err)
+	}
+	err := Probes{good, bad}.Validate()
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Validate() => %v; want ConfigErrors\n", err)
+	}
+	want := map[string]bool{
+		"ValidateBad.Prober.Timeout":     true,
+		"ValidateBad.Thresholds.warning": true,
+		"ValidateBad.Prober.AlertFn":     true,
+		"ValidateBad.Prober":             true, // not a Warner
+		"ValidateBad.Prober.Addr":        true,
+		"ValidateBad.Prober.Assert":      true,
+		"ValidateBad.DependsOn":          true,
+	}
+	got := map[string]bool{}
+	for _, e := range errs {
+		got[e.Path] = true
+	}
+	for path := range want {
+		if !got[path] {
+			t.Errorf("Validate() => %v; want problem at %s\n", err, path)
+		}
+	}
+	for path := range got {
+		if !want[path] {
+			t.Errorf("Validate() => %v; want no problem at %s\n", err, path)
+		}
+	}
+
+	AllowTimeoutOverInterval()(bad)
+	for _, e := range bad.validate() {
+		if e.Path == "ValidateBad.Prober.Timeout" {
+			t.Errorf("validate() with AllowTimeoutOverInterval() => %v; want no timeout problem\n", e)
+		}
+	}
+}
+
+// errPaths returns the paths of the problems.
+func errPaths(errs ConfigErrors) []string {
+	var paths []string
+	for _, e := range errs {
+		paths = append(paths, e.Path)
+	}
+	return paths
+}
+
+// validatorFunc is a Validator returning the problems of the function.
+type validatorFunc func() ConfigErrors
+
+func (fn validatorFunc) Validate() ConfigErrors { return fn() }
+
+func TestNotifiers(t *testing.T) {
+	bad := validatorFunc(func() ConfigErrors { return ConfigErrors{{Path: "Token", Err: "must be set"}} })
+	good := validatorFunc(func() ConfigErrors { return nil })
+	p := &Probe{Name: "NotifiedProber", Prober: testProber{Passed()}, Interval: time.Minute, failurePenalty: 1}
+	Notifiers(good, bad)(p)
+	if got, want := errPaths(p.validate()), []string{"NotifiedProber.Notifiers[1].Token"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("validate() with Notifiers() => %v; want problems at %v\n", p.validate(), want)
+	}
+}
+
+func TestChecksumProber_Validate(t *testing.T) {
+	// Probers embedding a ChecksumProber by value are Validators too.
+	var v Validator = struct{ ChecksumProber }{ChecksumProber{URL: "ftp://example.com/", SHA256: "abc"}}
+	if got, want := errPaths(v.Validate()), []string{"URL", "SHA256"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() => %v; want problems at %v\n", v.Validate(), want)
+	}
+}
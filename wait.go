@@ -0,0 +1,111 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// WaitOption is a setting for WaitHealthy().
+	WaitOption func(*waitConfig)
+
+	// waitConfig holds the settings for a WaitHealthy() call.
+	waitConfig struct {
+		interval time.Duration // how long to wait between rounds of probing
+	}
+)
+
+// defaultWaitInterval is how long WaitHealthy() waits between rounds of
+// probing, unless WaitInterval() is given.
+const defaultWaitInterval = 2 * time.Second
+
+// WaitInterval sets how long WaitHealthy() waits between rounds of
+// probing.
+func WaitInterval(d time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.interval = d
+	}
+}
+
+// WaitHealthy runs the probes until every probe has passed, or ctx is
+// done, e.g. to gate a rollout from an init container or deployment
+// script on its dependencies being reachable.
+//
+// Probes that fail are run again after the WaitInterval(), while probes
+// that have passed aren't run again. A probe isn't run before all
+// probes it DependsOn() have passed.
+//
+// Like RunAllOnce, WaitHealthy doesn't affect badness of the probes or
+// send any alerts. If ctx is done before every probe has passed, the
+// returned error describes the probes that haven't passed.
+func WaitHealthy(ctx context.Context, probes Probes, opts ...WaitOption) error {
+	conf := waitConfig{interval: defaultWaitInterval}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	ordered, err := probes.inDependencyOrder()
+	if err != nil {
+		return err
+	}
+	passed := map[string]bool{}
+	pending := map[string]error{} // last error of each probe that hasn't passed
+	for _, p := range ordered {
+		pending[p.Name] = fmt.Errorf("%s hasn't been run", p.Name)
+	}
+	for {
+		for _, p := range ordered {
+			if passed[p.Name] || ctx.Err() != nil {
+				continue
+			}
+			if dep, ok := p.failedDependency(passed); ok {
+				pending[p.Name] = fmt.Errorf("%s is waiting for dependency %s", p.Name, dep)
+				continue
+			}
+			r, ok := p.probeOnce(ctx)
+			if !ok {
+				// The run was cancelled since ctx is done, which says
+				// less about the probe than its last error.
+				continue
+			}
+			p.resultLock.Lock()
+			p.logResult(r)
+			p.resultLock.Unlock()
+			if r.Passed() {
+				passed[p.Name] = true
+				delete(pending, p.Name)
+				continue
+			}
+			pending[p.Name] = r.Error
+			if r.Error == nil {
+				pending[p.Name] = fmt.Errorf("%s failed: %s", p.Name, r.Info)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		log.Printf("Waiting for %d of %d probes to pass\n", len(pending), len(ordered))
+		select {
+		case <-ctx.Done():
+			return waitError(ctx.Err(), pending)
+		case <-time.After(conf.interval):
+		}
+	}
+}
+
+// waitError returns an error describing the probes that haven't passed.
+func waitError(err error, pending map[string]error) error {
+	names := make([]string, 0, len(pending))
+	for name := range pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = pending[name].Error()
+	}
+	return fmt.Errorf("%v while waiting for %d probes to pass: %s", err, len(pending), strings.Join(parts, "; "))
+}
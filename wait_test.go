@@ -0,0 +1,58 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startingProber is a testProber that fails until it has been run the
+// specified number of times.
+type startingProber struct {
+	testProber
+	runs  *int32
+	after int32
+}
+
+func (p startingProber) Probe() Result {
+	if atomic.AddInt32(p.runs, 1) <= p.after {
+		return FailedWith(errors.New("not started yet"))
+	}
+	return Passed()
+}
+
+func TestWaitHealthy(t *testing.T) {
+	var dbRuns, webRuns int32
+	newProbe := func(name string, runs *int32, after int32, deps ...string) *Probe {
+		return &Probe{
+			Prober:       startingProber{runs: runs, after: after},
+			Name:         name,
+			Interval:     time.Minute,
+			dependencies: deps,
+			t:            fakeTime{},
+		}
+	}
+	probes := Probes{
+		newProbe("web", &webRuns, 0, "db"),
+		newProbe("db", &dbRuns, 2),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := WaitHealthy(ctx, probes, WaitInterval(time.Millisecond)); err != nil {
+		t.Fatalf("WaitHealthy() => %v; want nil", err)
+	}
+	if dbRuns != 3 || webRuns != 1 {
+		t.Errorf("WaitHealthy() ran db %d times and web %d times; want 3 and 1\n", dbRuns, webRuns)
+	}
+
+	var downRuns int32
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := WaitHealthy(ctx, Probes{newProbe("down", &downRuns, 1000)}, WaitInterval(time.Millisecond))
+	if err == nil || !strings.Contains(err.Error(), "not started yet") {
+		t.Errorf("WaitHealthy() => %v; want error describing failing probe\n", err)
+	}
+}
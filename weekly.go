@@ -0,0 +1,89 @@
+package prober
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// weeklyLookback is the number of weeks alerts compare against.
+	weeklyLookback = 4
+	// weeklySlack is how far from the same time in an earlier week a
+	// failure may have started to count as a recurrence.
+	weeklySlack = time.Hour
+	// failureHistorySize is the maximum number of starts of failures
+	// to keep.
+	failureHistorySize = 500
+)
+
+// FailureHistory returns when the probe started failing after passing,
+// oldest first, over the last weeks. Alerts compare against it to point
+// out failures at the same time in earlier weeks, which hints at a
+// scheduled cause like a weekly batch job or backup.
+func (p *Probe) FailureHistory() []time.Time {
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	return append([]time.Time{}, p.failureStarts...)
+}
+
+// noteFailureStart adds the start of a failure at the time t to the
+// history, dropping starts too old to compare against. The caller must
+// hold alertLock.
+func (p *Probe) noteFailureStart(t time.Time) {
+	oldest := t.Add(-weeklyLookback*7*24*time.Hour - weeklySlack)
+	kept := p.failureStarts[:0]
+	for _, s := range p.failureStarts {
+		if !s.Before(oldest) {
+			kept = append(kept, s)
+		}
+	}
+	p.failureStarts = append(kept, t)
+	if over := len(p.failureStarts) - failureHistorySize; over > 0 {
+		p.failureStarts = append(p.failureStarts[:0], p.failureStarts[over:]...)
+	}
+}
+
+// weeklyRecurrences returns the starts of failures around the same time
+// as now in each of the earlier weeks, most recent first, and the
+// number of earlier weeks the probe is known to have been running,
+// since its first run or its oldest failure restored from a Snapshot.
+func (p *Probe) weeklyRecurrences(now time.Time) ([]time.Time, int) {
+	p.alertLock.RLock()
+	defer p.alertLock.RUnlock()
+	since := p.firstRun
+	if len(p.failureStarts) > 0 && (since.IsZero() || p.failureStarts[0].Before(since)) {
+		since = p.failureStarts[0]
+	}
+	var found []time.Time
+	weeks := 0
+	for w := 1; w <= weeklyLookback; w++ {
+		then := now.AddDate(0, 0, -7*w)
+		if since.IsZero() || then.Add(weeklySlack).Before(since) {
+			break
+		}
+		weeks++
+		for _, s := range p.failureStarts {
+			if d := s.Sub(then); d >= -weeklySlack && d <= weeklySlack {
+				found = append(found, s)
+				break
+			}
+		}
+	}
+	return found, weeks
+}
+
+// weeklyNote returns a note on failures at the same time in earlier
+// weeks for notifications, or "" if there were none.
+func (p *Probe) weeklyNote() string {
+	now := p.t.Now()
+	found, weeks := p.weeklyRecurrences(now)
+	if len(found) == 0 {
+		return ""
+	}
+	last := found[0].In(p.timeFormat().zone())
+	if len(found) == 1 && now.Sub(found[0]) < 8*24*time.Hour {
+		return fmt.Sprintf(" [also failed last %s at %s]", last.Weekday(), last.Format("15:04 MST"))
+	}
+	return fmt.Sprintf(" [also failed around this time %d of the last %d %ss, most recently at %s]",
+		len(found), weeks, now.In(last.Location()).Weekday(), last.Format("2006-01-02 15:04 MST"))
+}
@@ -0,0 +1,69 @@
+package prober
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbe_weeklyNote(t *testing.T) {
+	now := time.Date(1998, 11, 19, 3, 0, 0, 0, time.UTC) // a Thursday
+	week := 7 * 24 * time.Hour
+	cases := []struct {
+		firstRun time.Time
+		starts   []time.Time
+		want     string
+	}{
+		{
+			firstRun: now.Add(-5 * week),
+		},
+		{
+			firstRun: now.Add(-5 * week),
+			starts:   []time.Time{now.Add(-week + 20*time.Minute)},
+			want:     " [also failed last Thursday at 03:20 UTC]",
+		},
+		{
+			// Failures at other times don't count.
+			firstRun: now.Add(-5 * week),
+			starts:   []time.Time{now.Add(-week + 3*time.Hour), now.Add(-6 * 24 * time.Hour)},
+		},
+		{
+			firstRun: now.Add(-5 * week),
+			starts:   []time.Time{now.Add(-3 * week), now.Add(-2*week - 10*time.Minute), now.Add(-2 * week)},
+			want:     " [also failed around this time 2 of the last 4 Thursdays, most recently at 1998-11-05 02:50 UTC]",
+		},
+		{
+			// Weeks before the probe ran don't count.
+			firstRun: now.Add(-2*week - time.Hour),
+			starts:   []time.Time{now.Add(-2 * week)},
+			want:     " [also failed around this time 1 of the last 2 Thursdays, most recently at 1998-11-05 03:00 UTC]",
+		},
+	}
+	for i, tt := range cases {
+		p := &Probe{Name: "WeeklyProber", t: fakeTime{now}}
+		Snapshot{FailureHistory: tt.starts}.Restore(p)
+		p.firstRun = tt.firstRun
+		if got := p.weeklyNote(); got != tt.want {
+			t.Errorf("[%d] weeklyNote() => %q; want %q\n", i, got, tt.want)
+		}
+	}
+}
+
+func TestProbe_noteFailureStart(t *testing.T) {
+	start := time.Date(1998, 11, 19, 15, 14, 0, 0, time.UTC)
+	p := &Probe{Name: "WeeklyProber", t: fakeTime{start}}
+	p.noteOutcome(false, start)
+	p.noteOutcome(false, start.Add(time.Minute))
+	p.noteOutcome(true, start.Add(2*time.Minute))
+	later := start.Add(10 * 7 * 24 * time.Hour)
+	p.noteOutcome(false, later)
+	if got := p.FailureHistory(); len(got) != 1 || !got[0].Equal(later) {
+		t.Errorf("FailureHistory() => %v; want only the recent start %v\n", got, later)
+	}
+	if got := p.Snapshot().FailureHistory; len(got) != 1 {
+		t.Errorf("Snapshot().FailureHistory => %v; want 1 start\n", got)
+	}
+	if desc := p.alertDesc(); strings.Contains(desc, "also failed") {
+		t.Errorf("alertDesc() => %q; want no weekly note\n", desc)
+	}
+}
@@ -0,0 +1,12 @@
+// Package winservice runs probes as a Windows service, and writes their
+// alerts and failures to the Windows Event Log, for monitoring from
+// Windows hosts.
+//
+// The package is only implemented on Windows:
+//
+//	GOOS=windows go build
+//
+// To install a prober built with it as a service, e.g.:
+//
+//	sc.exe create prober binPath= "C:\prober\prober.exe" start= auto
+package winservice
@@ -0,0 +1,140 @@
+//go:build windows
+
+package winservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+
+	"hkjn.me/prober"
+)
+
+// Event IDs of the events written to the Event Log.
+const (
+	AlertEvent   = 1
+	WarnEvent    = 2
+	RecoverEvent = 3
+	FailureEvent = 4
+)
+
+type (
+	// service runs a registry as a Windows service.
+	service struct {
+		registry *prober.Registry
+	}
+
+	// EventLog writes alerts, warnings, recoveries and failures of
+	// probes to the Windows Event Log.
+	//
+	// Use its Alert, Warn and Recover methods as the AlertFn, WarnFn
+	// and RecoverFn of probers, and Failures() with prober.Report() to
+	// also log each failed run.
+	EventLog struct {
+		log *eventlog.Log
+	}
+)
+
+// Run runs the probes of the registry as the named Windows service,
+// until the service is stopped. If the process isn't running as a
+// service, e.g. when started from a console, Run runs the probes until
+// the process is interrupted instead.
+func Run(name string, r *prober.Registry) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return fmt.Errorf("failed to tell if running as a service: %v", err)
+	}
+	if !isService {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		r.Run(ctx)
+		return nil
+	}
+	return svc.Run(name, service{registry: r})
+}
+
+// Execute runs the registry until the service is asked to stop.
+func (s service) Execute(args []string, req <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.registry.Run(ctx)
+		close(done)
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case c := <-req:
+			switch c.Cmd {
+			case svc.Interrogate:
+				status <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				<-done
+				return false, 0
+			default:
+				log.Printf("unexpected service control request %d\n", c.Cmd)
+			}
+		case <-done:
+			// The registry only stops when asked to, so this is a bug.
+			cancel()
+			return false, 1
+		}
+	}
+}
+
+// InstallEventSource registers the source of events with the Event
+// Log, which needs to be done once, as an administrator, before
+// OpenEventLog() is used with it.
+func InstallEventSource(source string) error {
+	return eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info)
+}
+
+// OpenEventLog returns an EventLog writing events from the source.
+func OpenEventLog(source string) (*EventLog, error) {
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return &EventLog{log: l}, nil
+}
+
+// Close closes the Event Log.
+func (el *EventLog) Close() error {
+	return el.log.Close()
+}
+
+// Alert writes an error event that the probe is alerting.
+func (el *EventLog) Alert(name, desc string, badness int, records prober.Records) error {
+	return el.log.Error(AlertEvent, fmt.Sprintf("%s is alerting (badness %d): %s", name, badness, desc))
+}
+
+// Warn writes a warning event that the probe is degraded.
+func (el *EventLog) Warn(name, desc string, badness int, records prober.Records) error {
+	return el.log.Warning(WarnEvent, fmt.Sprintf("%s is degraded (badness %d): %s", name, badness, desc))
+}
+
+// Recover writes an information event that the probe recovered.
+func (el *EventLog) Recover(name, desc string, records prober.Records) error {
+	return el.log.Info(RecoverEvent, fmt.Sprintf("%s recovered: %s", name, desc))
+}
+
+// Failures returns a function for prober.Report() that writes a
+// warning event for each failed run of the named probe.
+func (el *EventLog) Failures(name string) func(prober.Result) {
+	return func(r prober.Result) {
+		if r.Passed() {
+			return
+		}
+		if err := el.log.Warning(FailureEvent, fmt.Sprintf("%s failed: %v", name, r.Error)); err != nil {
+			log.Printf("[%s] failed to write failure to Event Log: %v\n", name, err)
+		}
+	}
+}